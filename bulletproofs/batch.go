@@ -0,0 +1,128 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "errors"
+    "math/big"
+    "sort"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+)
+
+/*
+BatchVerify verifies many BulletProofs at once. The verification equations of
+all proofs are multiplied by independent random weights and merged into a single
+multi-exponentiation, which is much faster than verifying each proof on its own.
+If the batch is rejected, every proof is verified on its own. It returns true if
+and only if every proof is valid, together with the indices of the invalid proofs.
+Like Verify, it uses the setup parameters and the commitment stored in each proof.
+*/
+func BatchVerify(proofs []BulletProof) (bool, []int) {
+    return batchVerify(len(proofs), func(i int, e *multiExp, weight *big.Int) error {
+        return proofs[i].verificationTerms(e, proofs[i].V, proofs[i].Params, weight)
+    })
+}
+
+/*
+BatchVerifyWithParams verifies many BulletProofs at once, where proofs[i] must be
+a range proof for the commitment V[i]. Every generator is taken from the params
+held by the verifier. It returns true if and only if every proof is valid,
+together with the indices of the invalid proofs.
+*/
+func BatchVerifyWithParams(proofs []BulletProof, params BulletProofSetupParams, V []group.Element) (bool, []int, error) {
+    if len(proofs) != len(V) {
+        return false, nil, errors.New("number of proofs is different from the number of commitments")
+    }
+    ok, invalid := batchVerify(len(proofs), func(i int, e *multiExp, weight *big.Int) error {
+        return proofs[i].verificationTerms(e, V[i], params, weight)
+    })
+    return ok, invalid, nil
+}
+
+/*
+BatchVerifyGeneric verifies many generic range proofs at once, in the same way as
+BatchVerify. Like ProofBPRP.Verify, it uses the interval, the setup parameters and
+the commitment stored in each proof.
+*/
+func BatchVerifyGeneric(proofs []ProofBPRP) (bool, []int) {
+    return batchVerify(len(proofs), func(i int, e *multiExp, weight *big.Int) error {
+        params := &bprp{A: proofs[i].A, B: proofs[i].B, BP1: proofs[i].P1.Params, BP2: proofs[i].P2.Params}
+        return proofs[i].verificationTerms(e, params, proofs[i].V, weight)
+    })
+}
+
+/*
+BatchVerifyGenericWithParams verifies many generic range proofs at once, where
+proofs[i] must show that the value committed in V[i] lies in the interval of
+params. It returns true if and only if every proof is valid, together with the
+indices of the invalid proofs.
+*/
+func BatchVerifyGenericWithParams(proofs []ProofBPRP, params *bprp, V []group.Element) (bool, []int, error) {
+    if len(proofs) != len(V) {
+        return false, nil, errors.New("number of proofs is different from the number of commitments")
+    }
+    ok, invalid := batchVerify(len(proofs), func(i int, e *multiExp, weight *big.Int) error {
+        return proofs[i].verificationTerms(e, params, V[i], weight)
+    })
+    return ok, invalid, nil
+}
+
+/*
+batchVerify checks n proofs, given a function that adds the terms of the i-th
+verification equation to e. The terms of each proof are computed in a
+multi-exponentiation of its own, which is merged into the batch only if the
+proof could be checked entirely, so that a proof rejected halfway leaves no
+term in the batch. Proofs that cannot be merged, because they belong to another
+group than the first proof, are checked on their own. Proofs that cannot be
+checked at all, for instance because they are incomplete, are reported as
+invalid. If the batch is rejected, each remaining proof is checked on its own in
+order to find the invalid ones.
+*/
+func batchVerify(n int, terms func(i int, e *multiExp, weight *big.Int) error) (bool, []int) {
+    var invalid, candidates []int
+    e := newMultiExp()
+    for i := 0; i < n; i++ {
+        single := newMultiExp()
+        err := terms(i, single, randomWeight())
+        if err != nil {
+            invalid = append(invalid, i)
+            continue
+        }
+        if e.merge(single) != nil {
+            // the random weight is not zero, so the equation holds if and only if
+            // the weighted one does
+            if !single.isIdentity() {
+                invalid = append(invalid, i)
+            }
+            continue
+        }
+        candidates = append(candidates, i)
+    }
+    if len(candidates) > 0 && !e.isIdentity() {
+        for _, i := range candidates {
+            single := newMultiExp()
+            err := terms(i, single, big.NewInt(1))
+            if err != nil || !single.isIdentity() {
+                invalid = append(invalid, i)
+            }
+        }
+        sort.Ints(invalid)
+    }
+    return len(invalid) == 0, invalid
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/stretchr/testify/assert"
+)
+
+func proveMany(t testing.TB, params BulletProofSetupParams, n int) ([]BulletProof, []group.Element) {
+    proofs := make([]BulletProof, n)
+    V := make([]group.Element, n)
+    for i := 0; i < n; i++ {
+        var err error
+        proofs[i], err = Prove(new(big.Int).SetInt64(int64(1000+i)), params)
+        if err != nil {
+            t.Fatal(err)
+        }
+        V[i] = proofs[i].V
+    }
+    return proofs, V
+}
+
+func TestBatchVerify(t *testing.T) {
+    params, _ := SetupBits(16)
+    proofs, V := proveMany(t, params, 6)
+
+    ok, invalid := BatchVerify(proofs)
+    assert.True(t, ok, "batch of valid proofs should verify")
+    assert.Empty(t, invalid)
+
+    ok, invalid, err := BatchVerifyWithParams(proofs, params, V)
+    assert.Nil(t, err)
+    assert.True(t, ok, "batch of valid proofs should verify")
+    assert.Empty(t, invalid)
+
+    ok, invalid = BatchVerify(nil)
+    assert.True(t, ok, "empty batch should verify")
+    assert.Empty(t, invalid)
+}
+
+func TestBatchVerifyReportsInvalidProofs(t *testing.T) {
+    params, _ := SetupBits(16)
+    proofs, V := proveMany(t, params, 6)
+
+    // wrong commitment, tampered proof and incomplete proof
+    V[1] = proofs[0].V
+    proofs[3].Tprime = new(big.Int).Add(proofs[3].Tprime, big.NewInt(1))
+    proofs[4].S = nil
+
+    ok, invalid, err := BatchVerifyWithParams(proofs, params, V)
+    assert.Nil(t, err)
+    assert.False(t, ok, "batch with invalid proofs should not verify")
+    assert.Equal(t, []int{1, 3, 4}, invalid)
+
+    ok, invalid = BatchVerify(proofs)
+    assert.False(t, ok, "batch with invalid proofs should not verify")
+    assert.Equal(t, []int{3, 4}, invalid)
+
+    _, _, err = BatchVerifyWithParams(proofs, params, V[1:])
+    assert.NotNil(t, err, "number of commitments should match the number of proofs")
+}
+
+func TestBatchVerifyPartialTerms(t *testing.T) {
+    params, _ := SetupBits(16)
+    proofs, V := proveMany(t, params, 5)
+
+    // the range terms of the proof are computed before its Inner Product Proof
+    // is found to have a round missing
+    ipp := &proofs[2].InnerProductProof
+    ipp.Ls = ipp.Ls[:len(ipp.Ls)-1]
+    ipp.Rs = ipp.Rs[:len(ipp.Rs)-1]
+
+    calls := make([]int, len(proofs))
+    ok, invalid := batchVerify(len(proofs), func(i int, e *multiExp, weight *big.Int) error {
+        calls[i]++
+        return proofs[i].verificationTerms(e, V[i], params, weight)
+    })
+    assert.False(t, ok, "batch with an invalid proof should not verify")
+    assert.Equal(t, []int{2}, invalid)
+    // the batch of the valid proofs holds, so none of them is checked again
+    assert.Equal(t, []int{1, 1, 1, 1, 1}, calls)
+}
+
+func TestBatchVerifyGeneric(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    n := 4
+    proofs := make([]ProofBPRP, n)
+    V := make([]group.Element, n)
+    for i := 0; i < n; i++ {
+        proofs[i], _ = ProveGeneric(new(big.Int).SetInt64(int64(20+i)), params)
+        V[i] = proofs[i].V
+    }
+
+    ok, invalid := BatchVerifyGeneric(proofs)
+    assert.True(t, ok, "batch of valid generic proofs should verify")
+    assert.Empty(t, invalid)
+
+    // the second BulletProof of the third proof belongs to another commitment
+    proofs[2].P2 = proofs[0].P2
+    ok, invalid, err := BatchVerifyGenericWithParams(proofs, params, V)
+    assert.Nil(t, err)
+    assert.False(t, ok, "batch with an invalid generic proof should not verify")
+    assert.Equal(t, []int{2}, invalid)
+
+    other, _ := SetupGeneric(21, 200)
+    ok, invalid, _ = BatchVerifyGenericWithParams(proofs[:2], other, V[:2])
+    assert.False(t, ok, "proofs for another interval should not verify")
+    assert.Equal(t, []int{0, 1}, invalid)
+}
+
+func BenchmarkBatchVerify16(b *testing.B) {
+    params, _ := SetupBits(64)
+    proofs, V := proveMany(b, params, 16)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _, _ = BatchVerifyWithParams(proofs, params, V)
+    }
+}
@@ -18,15 +18,18 @@
 package bulletproofs
 
 import (
-    "crypto/sha256"
     "errors"
     "math/big"
 
-    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/group"
     "github.com/ing-bank/zkrp/util/bn"
-    "github.com/ing-bank/zkrp/util/byteconversion"
 )
 
+/*
+SEEDU was the seed of the generator U.
+
+Deprecated: the generators are computed with HashToCurve and DefaultDST.
+*/
 var SEEDU = "BulletproofsDoesNotNeedTrustedSetupU"
 
 /*
@@ -36,34 +39,33 @@ commitments.
 type InnerProductParams struct {
     N  int64
     Cc *big.Int
-    Uu *p256.P256
-    H  *p256.P256
-    Gg []*p256.P256
-    Hh []*p256.P256
-    P  *p256.P256
+    Uu group.Element
+    H  group.Element
+    Gg []group.Element
+    Hh []group.Element
+    // tables are the fixed-base tables of the generators, if any.
+    tables *generatorTables
 }
 
 /*
 InnerProductProof contains the elements used to verify the Inner Product Proof.
+The generators and the commitment are not part of the proof, the verifier
+must compute them.
 */
 type InnerProductProof struct {
-    N      int64
-    Ls     []*p256.P256
-    Rs     []*p256.P256
-    U      *p256.P256
-    P      *p256.P256
-    Gg     *p256.P256
-    Hh     *p256.P256
-    A      *big.Int
-    B      *big.Int
-    Params InnerProductParams
+    N  int64
+    Ls []group.Element
+    Rs []group.Element
+    A  *big.Int
+    B  *big.Int
 }
 
 /*
 SetupInnerProduct is responsible for computing the inner product basic parameters that are common to both
-ProveInnerProduct and Verify algorithms.
+ProveInnerProduct and Verify algorithms. The missing generators are hashed to
+secp256k1 with DefaultDST.
 */
-func setupInnerProduct(H *p256.P256, g, h []*p256.P256, c *big.Int, N int64) (InnerProductParams, error) {
+func setupInnerProduct(H, U group.Element, g, h []group.Element, c *big.Int, N int64) (InnerProductParams, error) {
     var params InnerProductParams
 
     if N <= 0 {
@@ -72,85 +74,88 @@ func setupInnerProduct(H *p256.P256, g, h []*p256.P256, c *big.Int, N int64) (In
         params.N = N
     }
     if H == nil {
-        params.H, _ = p256.MapToGroup(SEEDH)
+        params.H = hashGenerator(group.Secp256k1, DefaultDST, []byte("H"))
     } else {
         params.H = H
     }
-    if g == nil {
-        params.Gg = make([]*p256.P256, params.N)
-        for i := int64(0); i < params.N; i++ {
-            params.Gg[i], _ = p256.MapToGroup(SEEDH + "g" + string(i))
+    if g == nil || h == nil {
+        gg, hh := computeGenerators(group.Secp256k1, DefaultDST, params.N)
+        if g == nil {
+            g = gg
         }
-    } else {
-        params.Gg = g
-    }
-    if h == nil {
-        params.Hh = make([]*p256.P256, params.N)
-        for i := int64(0); i < params.N; i++ {
-            params.Hh[i], _ = p256.MapToGroup(SEEDH + "h" + string(i))
+        if h == nil {
+            h = hh
         }
-    } else {
-        params.Hh = h
     }
+    params.Gg = g
+    params.Hh = h
     params.Cc = c
-    params.Uu, _ = p256.MapToGroup(SEEDU)
-    params.P = new(p256.P256).SetInfinity()
+    if U == nil {
+        params.Uu = hashGenerator(group.Secp256k1, DefaultDST, []byte("U"))
+    } else {
+        params.Uu = U
+    }
 
     return params, nil
 }
 
+/*
+group returns the group of the generators.
+*/
+func (params *InnerProductParams) group() group.Group {
+    if params.Uu == nil {
+        return group.Secp256k1
+    }
+    return params.Uu.Group()
+}
+
 /*
 proveInnerProduct calculates the Zero Knowledge Proof for the Inner Product argument.
 */
-func proveInnerProduct(a, b []*big.Int, P *p256.P256, params InnerProductParams) (InnerProductProof, error) {
+func proveInnerProduct(a, b scalarVector, params InnerProductParams, transcript *Transcript) (InnerProductProof, error) {
     var (
         proof InnerProductProof
         n, m  int64
-        Ls    []*p256.P256
-        Rs    []*p256.P256
+        Ls    []group.Element
+        Rs    []group.Element
     )
 
-    n = int64(len(a))
-    m = int64(len(b))
+    n = int64(a.size())
+    m = int64(b.size())
 
     if n != m {
         return proof, errors.New("size of first array argument must be equal to the second")
     }
 
     // Fiat-Shamir:
-    // x = Hash(g,h,P,c)
-    x, _ := hashIP(params.Gg, params.Hh, P, params.Cc, params.N)
-    // Pprime = P.u^(x.c)
-    ux := new(p256.P256).ScalarMult(params.Uu, x)
-    uxc := new(p256.P256).ScalarMult(ux, params.Cc)
-    PP := new(p256.P256).Multiply(P, uxc)
+    // x = Hash(transcript,u,c)
+    x := appendInnerProduct(transcript, params)
+    ux := params.Uu.ScalarMult(x)
     // Execute Protocol 2 recursively
-    proof = computeBipRecursive(a, b, params.Gg, params.Hh, ux, PP, n, Ls, Rs)
-    proof.Params = params
-    proof.Params.P = PP
+    proof = computeBipRecursive(params.group(), params.tables, a, b, params.Gg, params.Hh, ux, n, Ls, Rs, transcript)
     return proof, nil
 }
 
 /*
 computeBipRecursive is the main recursive function that will be used to compute the inner product argument.
+The fixed-base tables are used to fold the generators of the setup parameters, which
+only appear in the first round. L and R depend on the secret vectors, so they are
+computed in constant time when the group supports it.
 */
-func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n int64, Ls, Rs []*p256.P256) InnerProductProof {
+func computeBipRecursive(grp group.Group, tables *generatorTables, a, b scalarVector, g, h []group.Element, u group.Element, n int64, Ls, Rs []group.Element, transcript *Transcript) InnerProductProof {
     var (
         proof                            InnerProductProof
-        cL, cR, x, xinv, x2, x2inv       *big.Int
-        L, R, Lh, Rh, Pprime             *p256.P256
-        gprime, hprime, gprime2, hprime2 []*p256.P256
-        aprime, bprime, aprime2, bprime2 []*big.Int
+        cL, cR, x, xinv                  *big.Int
+        L, R                             group.Element
+        gprime, hprime                   []group.Element
+        aprime, bprime, aprime2, bprime2 scalarVector
     )
+    order := grp.Order()
 
     if n == 1 {
         // recursion end
-        proof.A = a[0]
-        proof.B = b[0]
-        proof.Gg = g[0]
-        proof.Hh = h[0]
-        proof.P = P
-        proof.U = u
+        proof.A = a.bigs()[0]
+        proof.B = b.bigs()[0]
         proof.Ls = Ls
         proof.Rs = Rs
 
@@ -159,158 +164,240 @@ func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n
 
         // nprime := n / 2
         nprime := n / 2 // (20)
+        alo, ahi := a.slice(0, int(nprime)), a.slice(int(nprime), int(n))
+        blo, bhi := b.slice(0, int(nprime)), b.slice(int(nprime), int(n))
 
         // Compute cL = < a[:n'], b[n':] >                                    // (21)
-        cL, _ = ScalarProduct(a[:nprime], b[nprime:])
+        cL = alo.innerProduct(bhi)
         // Compute cR = < a[n':], b[:n'] >                                    // (22)
-        cR, _ = ScalarProduct(a[nprime:], b[:nprime])
+        cR = ahi.innerProduct(blo)
         // Compute L = g[n':]^(a[:n']).h[:n']^(b[n':]).u^cL                   // (23)
-        L, _ = VectorExp(g[nprime:], a[:nprime])
-        Lh, _ = VectorExp(h[:nprime], b[nprime:])
-        L.Multiply(L, Lh)
-        L.Multiply(L, new(p256.P256).ScalarMult(u, cL))
+        L = secretInnerProductCommit(grp, g[nprime:], h[:nprime], u, alo.bigs(), bhi.bigs(), cL)
 
         // Compute R = g[:n']^(a[n':]).h[n':]^(b[:n']).u^cR                   // (24)
-        R, _ = VectorExp(g[:nprime], a[nprime:])
-        Rh, _ = VectorExp(h[nprime:], b[:nprime])
-        R.Multiply(R, Rh)
-        R.Multiply(R, new(p256.P256).ScalarMult(u, cR))
+        R = secretInnerProductCommit(grp, g[:nprime], h[nprime:], u, ahi.bigs(), blo.bigs(), cR)
 
         // Fiat-Shamir:                                                       // (26)
-        x, _, _ = HashBP(L, R)
-        xinv = bn.ModInverse(x, ORDER)
+        transcript.AppendPoint("L", L)
+        transcript.AppendPoint("R", R)
+        x = transcript.ChallengeScalar("x")
+        xinv = bn.ModInverse(x, order)
 
         // Compute g' = g[:n']^(x^-1) * g[n':]^(x)                            // (29)
-        gprime = vectorScalarExp(g[:nprime], xinv)
-        gprime2 = vectorScalarExp(g[nprime:], x)
-        gprime, _ = VectorECAdd(gprime, gprime2)
+        gprime = foldGenerators(tables, g[:nprime], g[nprime:], xinv, x)
         // Compute h' = h[:n']^(x)    * h[n':]^(x^-1)                         // (30)
-        hprime = vectorScalarExp(h[:nprime], x)
-        hprime2 = vectorScalarExp(h[nprime:], xinv)
-        hprime, _ = VectorECAdd(hprime, hprime2)
-
-        // Compute P' = L^(x^2).P.R^(x^-2)                                    // (31)
-        x2 = bn.Mod(bn.Multiply(x, x), ORDER)
-        x2inv = bn.ModInverse(x2, ORDER)
-        Pprime = new(p256.P256).ScalarMult(L, x2)
-        Pprime.Multiply(Pprime, P)
-        Pprime.Multiply(Pprime, new(p256.P256).ScalarMult(R, x2inv))
+        hprime = foldGenerators(tables, h[:nprime], h[nprime:], x, xinv)
 
         // Compute a' = a[:n'].x      + a[n':].x^(-1)                         // (33)
-        aprime, _ = VectorScalarMul(a[:nprime], x)
-        aprime2, _ = VectorScalarMul(a[nprime:], xinv)
-        aprime, _ = VectorAdd(aprime, aprime2)
+        aprime = alo.scale(x)
+        aprime2 = ahi.scale(xinv)
+        aprime = aprime.add(aprime2)
         // Compute b' = b[:n'].x^(-1) + b[n':].x                              // (34)
-        bprime, _ = VectorScalarMul(b[:nprime], xinv)
-        bprime2, _ = VectorScalarMul(b[nprime:], x)
-        bprime, _ = VectorAdd(bprime, bprime2)
+        bprime = blo.scale(xinv)
+        bprime2 = bhi.scale(x)
+        bprime = bprime.add(bprime2)
 
         Ls = append(Ls, L)
         Rs = append(Rs, R)
         // recursion computeBipRecursive(g',h',u,P'; a', b')                  // (35)
-        proof = computeBipRecursive(aprime, bprime, gprime, hprime, u, Pprime, nprime, Ls, Rs)
+        proof = computeBipRecursive(grp, tables, aprime, bprime, gprime, hprime, u, nprime, Ls, Rs, transcript)
     }
     proof.N = n
     return proof
 }
 
 /*
-Verify is responsible for the verification of the Inner Product Proof.
+Verify is responsible for the verification of the Inner Product Proof for the
+commitment P = g^a.h^b, where <a,b> = params.Cc. The commitment and every
+generator are provided by the verifier.
 */
-func (proof InnerProductProof) Verify() (bool, error) {
+func (proof InnerProductProof) Verify(params InnerProductParams, P group.Element) (bool, error) {
+    e := newMultiExp()
+    err := e.setGroup(params.group())
+    if err != nil {
+        return false, err
+    }
+    err = e.checkElements(P)
+    if err != nil {
+        return false, err
+    }
+    e.add(P, big.NewInt(1))
+    err = proof.verificationTerms(e, params, nil, big.NewInt(1), newInnerProductTranscript(params, P))
+    if err != nil {
+        return false, err
+    }
+    return e.isIdentity(), nil
+}
 
+/*
+verificationTerms adds to e the terms of the verification of Protocol 2 for the
+generators g and h^hScale, all multiplied by weight, except for the commitment P,
+which is added by the caller, whose equation must have the group of the
+generators. The transcript must be in the same state as the
+one used by the prover. Instead of folding the generators round by round, the
+verifier computes the vector s from Section 6.2, such that the generators after
+the last round are g^s and h^(s^-1). The check (16) becomes
+
+    P.u^(w.c).prod(L_j^(x_j^2).R_j^(x_j^-2)).g^(-a.s).h^(-b.s^-1).u^(-w.a.b) = 1
+*/
+func (proof InnerProductProof) verificationTerms(e *multiExp, params InnerProductParams, hScale []*big.Int, weight *big.Int, transcript *Transcript) error {
+    if params.Uu == nil || params.Cc == nil || proof.A == nil || proof.B == nil {
+        return errors.New("proof is incomplete")
+    }
+    n := params.N
+    if int64(len(params.Gg)) != n || int64(len(params.Hh)) != n {
+        return errors.New("invalid inner product parameters")
+    }
+    order := e.order
     logn := len(proof.Ls)
-    var (
-        x, xinv, x2, x2inv                   *big.Int
-        ngprime, nhprime, ngprime2, nhprime2 []*p256.P256
-    )
-
-    gprime := proof.Params.Gg
-    hprime := proof.Params.Hh
-    Pprime := proof.Params.P
-    nprime := proof.N
-    for i := int64(0); i < int64(logn); i++ {
-        nprime = nprime / 2                        // (20)
-        x, _, _ = HashBP(proof.Ls[i], proof.Rs[i]) // (26)
-        xinv = bn.ModInverse(x, ORDER)
-        // Compute g' = g[:n']^(x^-1) * g[n':]^(x)                            // (29)
-        ngprime = vectorScalarExp(gprime[:nprime], xinv)
-        ngprime2 = vectorScalarExp(gprime[nprime:], x)
-        gprime, _ = VectorECAdd(ngprime, ngprime2)
-        // Compute h' = h[:n']^(x)    * h[n':]^(x^-1)                         // (30)
-        nhprime = vectorScalarExp(hprime[:nprime], x)
-        nhprime2 = vectorScalarExp(hprime[nprime:], xinv)
-        hprime, _ = VectorECAdd(nhprime, nhprime2)
-        // Compute P' = L^(x^2).P.R^(x^-2)                                    // (31)
-        x2 = bn.Mod(bn.Multiply(x, x), ORDER)
-        x2inv = bn.ModInverse(x2, ORDER)
-        Pprime.Multiply(Pprime, new(p256.P256).ScalarMult(proof.Ls[i], x2))
-        Pprime.Multiply(Pprime, new(p256.P256).ScalarMult(proof.Rs[i], x2inv))
+    if logn != len(proof.Rs) || n != int64(1)<<uint(logn) {
+        return errors.New("invalid number of rounds")
+    }
+    // Fiat-Shamir:
+    // w = Hash(transcript,u,c)
+    w := appendInnerProduct(transcript, params)
+
+    // Recover the challenges of each round                                 // (26)
+    x := make([]*big.Int, logn)
+    for j := 0; j < logn; j++ {
+        err := e.checkElements(proof.Ls[j], proof.Rs[j])
+        if err != nil {
+            return err
+        }
+        transcript.AppendPoint("L", proof.Ls[j])
+        transcript.AppendPoint("R", proof.Rs[j])
+        x[j] = transcript.ChallengeScalar("x")
+    }
+    s := foldingScalars(x, order)
+
+    // u^(w.(c - a.b))
+    ab := bn.Mod(bn.Multiply(proof.A, proof.B), order)
+    uexp := bn.Multiply(w, bn.Sub(params.Cc, ab))
+    e.add(params.Uu, bn.Multiply(weight, uexp))
+
+    // L_j^(x_j^2).R_j^(x_j^-2)                                             // (31)
+    for j := 0; j < logn; j++ {
+        x2 := bn.Mod(bn.Multiply(x[j], x[j]), order)
+        e.add(proof.Ls[j], bn.Multiply(weight, x2))
+        e.add(proof.Rs[j], bn.Multiply(weight, bn.ModInverse(x2, order)))
     }
 
-    // c == a*b and checks if P = g^a.h^b.u^c                                     // (16)
-    ab := bn.Multiply(proof.A, proof.B)
-    ab = bn.Mod(ab, ORDER)
-    // Compute right hand side
-    rhs := new(p256.P256).ScalarMult(gprime[0], proof.A)
-    hb := new(p256.P256).ScalarMult(hprime[0], proof.B)
-    rhs.Multiply(rhs, hb)
-    rhs.Multiply(rhs, new(p256.P256).ScalarMult(proof.U, ab))
-    // Compute inverse of left hand side
-    nP := Pprime.Neg(Pprime)
-    nP.Multiply(nP, rhs)
-    // If both sides are equal then nP must be zero                               // (17)
-    c := nP.IsZero()
-
-    return c, nil
+    // g^(-a.s).h^(-b.s^-1), where s^-1 is s in reverse order               // (29) (30)
+    wa := bn.Mod(bn.Multiply(weight, proof.A), order)
+    wb := bn.Mod(bn.Multiply(weight, proof.B), order)
+    for i := int64(0); i < n; i++ {
+        e.add(params.Gg[i], bn.Sub(order, bn.Mod(bn.Multiply(wa, s[i]), order)))
+        hexp := bn.Mod(bn.Multiply(wb, s[n-1-i]), order)
+        if hScale != nil {
+            hexp = bn.Mod(bn.Multiply(hexp, hScale[i]), order)
+        }
+        e.add(params.Hh[i], bn.Sub(order, hexp))
+    }
+    return nil
 }
 
 /*
-hashIP is responsible for the computing a Zp element given elements from GT and G1.
+foldingScalars computes the vector s from Section 6.2 given the challenges x of
+every round, namely s_i = prod_j x_j^(b(i,j)) modulo order, where b(i,j) is 1 if
+the j-th most significant bit of i is 1 and -1 otherwise.
 */
-func hashIP(g, h []*p256.P256, P *p256.P256, c *big.Int, n int64) (*big.Int, error) {
-    digest := sha256.New()
-    digest.Write([]byte(P.String()))
-
-    for i := int64(0); i < n; i++ {
-        digest.Write([]byte(g[i].String()))
-        digest.Write([]byte(h[i].String()))
+func foldingScalars(x []*big.Int, order *big.Int) []*big.Int {
+    logn := len(x)
+    n := 1 << uint(logn)
+    s := make([]*big.Int, n)
+    s[0] = big.NewInt(1)
+    for j := 0; j < logn; j++ {
+        s[0] = bn.Mod(bn.Multiply(s[0], x[j]), order)
     }
+    s[0] = bn.ModInverse(s[0], order)
+    for i := 1; i < n; i++ {
+        // the most significant bit of i is set in round logn - 1 - lgi
+        lgi := 0
+        for (1 << uint(lgi+1)) <= i {
+            lgi++
+        }
+        xj := x[logn-1-lgi]
+        s[i] = bn.Mod(bn.Multiply(s[i-(1<<uint(lgi))], bn.Multiply(xj, xj)), order)
+    }
+    return s
+}
 
-    digest.Write([]byte(c.String()))
-    output := digest.Sum(nil)
-    tmp := output[0:]
-    result, err := byteconversion.FromByteArray(tmp)
+/*
+newInnerProductTranscript returns the transcript of a standalone Inner Product
+Proof, which is bound to the generators and to the commitment P.
+*/
+func newInnerProductTranscript(params InnerProductParams, P group.Element) *Transcript {
+    transcript := newTranscript("zkrp bulletproofs inner product", params.group().Order())
+    transcript.AppendPoints("Gg", params.Gg)
+    transcript.AppendPoints("Hh", params.Hh)
+    transcript.AppendPoint("P", P)
+    return transcript
+}
 
-    return result, err
+/*
+appendInnerProduct absorbs the statement of the Inner Product Proof, namely the
+size n, the generator u and the inner product c, and returns the challenge used
+to compute u^w. The commitment P is not absorbed here: in a range proof it is
+determined by the messages that are already in the transcript.
+*/
+func appendInnerProduct(transcript *Transcript, params InnerProductParams) *big.Int {
+    transcript.AppendMessage("dom-sep", []byte("inner product"))
+    transcript.AppendUint64("n", uint64(params.N))
+    transcript.AppendPoint("U", params.Uu)
+    transcript.AppendScalar("c", params.Cc)
+    return transcript.ChallengeScalar("w")
 }
 
 /*
 commitInnerProduct is responsible for calculating g^a.h^b.
 */
-func commitInnerProduct(g, h []*p256.P256, a, b []*big.Int) *p256.P256 {
-    var (
-        result *p256.P256
-    )
-
+func commitInnerProduct(g, h []group.Element, a, b []*big.Int) group.Element {
     ga, _ := VectorExp(g, a)
     hb, _ := VectorExp(h, b)
-    result = new(p256.P256).Multiply(ga, hb)
+    return ga.Add(hb)
+}
+
+/*
+secretInnerProductCommit computes g^a.h^b.u^c, where a, b and c are secret.
+*/
+func secretInnerProductCommit(grp group.Group, g, h []group.Element, u group.Element, a, b []*big.Int, c *big.Int) group.Element {
+    points := make([]group.Element, 0, len(g)+len(h)+1)
+    scalars := make([]*big.Int, 0, len(a)+len(b)+1)
+    points = append(append(append(points, g...), h...), u)
+    scalars = append(append(append(scalars, a...), b...), c)
+    result, _ := group.SecretMultiScalarMult(grp, points, scalars)
+    return result
+}
+
+/*
+foldGenerators computes lo[i]^xlo.hi[i]^xhi for each i. When every generator has
+a fixed-base table, both terms are accumulated with a single lookup pass.
+*/
+func foldGenerators(tables *generatorTables, lo, hi []group.Element, xlo, xhi *big.Int) []group.Element {
+    result := make([]group.Element, len(lo))
+    for i := range lo {
+        tlo, thi := tables.table(lo[i]), tables.table(hi[i])
+        if tlo == nil || thi == nil {
+            result, _ = VectorECAdd(vectorScalarExp(lo, xlo), vectorScalarExp(hi, xhi))
+            return result
+        }
+        result[i], _ = tables.group.FixedBaseMultiScalarMult([]group.FixedBase{tlo, thi}, []*big.Int{xlo, xhi})
+    }
     return result
 }
 
 /*
 VectorScalarExp computes a[i]^b for each i.
 */
-func vectorScalarExp(a []*p256.P256, b *big.Int) []*p256.P256 {
+func vectorScalarExp(a []group.Element, b *big.Int) []group.Element {
     var (
-        result []*p256.P256
+        result []group.Element
         n      int64
     )
     n = int64(len(a))
-    result = make([]*p256.P256, n)
+    result = make([]group.Element, n)
     for i := int64(0); i < n; i++ {
-        result[i] = new(p256.P256).ScalarMult(a[i], b)
+        result[i] = a[i].ScalarMult(b)
     }
     return result
 }
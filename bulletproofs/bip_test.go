@@ -20,6 +20,8 @@ package bulletproofs
 import (
     "math/big"
     "testing"
+
+    "github.com/ing-bank/zkrp/util/bn"
 )
 
 /*
@@ -32,7 +34,7 @@ func TestInnerProduct(t *testing.T) {
         b                  []*big.Int
     )
     c := new(big.Int).SetInt64(142)
-    innerProductParams, _ = setupInnerProduct(nil, nil, nil, c, 4)
+    innerProductParams, _ = setupInnerProduct(nil, nil, nil, nil, c, 4)
 
     a = make([]*big.Int, innerProductParams.N)
     a[0] = new(big.Int).SetInt64(2)
@@ -46,9 +48,37 @@ func TestInnerProduct(t *testing.T) {
     b[3] = new(big.Int).SetInt64(7)
     commit := commitInnerProduct(innerProductParams.Gg, innerProductParams.Hh, a, b)
 
-    proof, _ := proveInnerProduct(a, b, commit, innerProductParams)
-    ok, _ := proof.Verify()
+    proof, _ := proveInnerProduct(newScalarVector(ORDER, a), newScalarVector(ORDER, b), innerProductParams, newInnerProductTranscript(innerProductParams, commit))
+    ok, _ := proof.Verify(innerProductParams, commit)
     if ok != true {
         t.Errorf("Assert failure: expected true, actual: %t", ok)
     }
+
+    // a different commitment must be rejected
+    other := commitInnerProduct(innerProductParams.Gg, innerProductParams.Hh, b, a)
+    ok, _ = proof.Verify(innerProductParams, other)
+    if ok != false {
+        t.Errorf("Assert failure: expected false, actual: %t", ok)
+    }
+}
+
+func TestFoldingScalars(t *testing.T) {
+    x := []*big.Int{big.NewInt(3), big.NewInt(5), big.NewInt(7)}
+    s := foldingScalars(x, ORDER)
+    if len(s) != 8 {
+        t.Fatalf("Assert failure: expected 8 scalars, actual: %d", len(s))
+    }
+    for i := range s {
+        expected := big.NewInt(1)
+        for j := range x {
+            xj := x[j]
+            if (i>>uint(len(x)-1-j))&1 == 0 {
+                xj = bn.ModInverse(xj, ORDER)
+            }
+            expected = bn.Mod(bn.Multiply(expected, xj), ORDER)
+        }
+        if s[i].Cmp(expected) != 0 {
+            t.Errorf("Assert failure: s[%d] expected %s, actual: %s", i, expected, s[i])
+        }
+    }
 }
@@ -21,10 +21,10 @@ import (
     "crypto/rand"
     "errors"
     "fmt"
-    "math"
+    "io"
     "math/big"
 
-    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/group"
     . "github.com/ing-bank/zkrp/util"
     "github.com/ing-bank/zkrp/util/bn"
 )
@@ -34,19 +34,30 @@ BulletProofSetupParams is the structure that stores the parameters for
 the Zero Knowledge Proof system.
 */
 type BulletProofSetupParams struct {
-    // N is the bit-length of the range.
+    // N is the bit-length of the range. It does not need to be a power of 2,
+    // the proof pads every value to paddedN bits.
     N int64
-    // G is the Elliptic Curve generator.
-    G *p256.P256
-    // H is a new generator, computed using MapToGroup function,
+    // Group is the group of the generators. Parameters without a group use
+    // group.Secp256k1.
+    Group group.Group
+    // G is the generator of the group.
+    G group.Element
+    // H is a new generator, computed using HashToElement function,
     // such that there is no discrete logarithm relation with G.
-    H *p256.P256
-    // Gg and Hh are sets of new generators obtained using MapToGroup.
+    H group.Element
+    // Gg and Hh are sets of new generators obtained using HashToElement.
     // They are used to compute Pedersen Vector Commitments.
-    Gg []*p256.P256
-    Hh []*p256.P256
+    Gg []group.Element
+    Hh []group.Element
+    // U is the generator of the Inner Product Proof, obtained using HashToElement.
+    U group.Element
+    // DST is the domain separation tag used to hash the generators.
+    DST string
     // InnerProductParams is the setup parameters for the inner product proof.
     InnerProductParams InnerProductParams
+    // tables are the fixed-base tables of the generators, which are only set
+    // by a Registry.
+    tables *generatorTables
 }
 
 /*
@@ -54,46 +65,188 @@ BulletProofs structure contains the elements that are necessary for the verifica
 of the Zero Knowledge Proof.
 */
 type BulletProof struct {
-    V                 *p256.P256
-    A                 *p256.P256
-    S                 *p256.P256
-    T1                *p256.P256
-    T2                *p256.P256
+    V                 group.Element
+    A                 group.Element
+    S                 group.Element
+    T1                group.Element
+    T2                group.Element
     Taux              *big.Int
     Mu                *big.Int
     Tprime            *big.Int
     InnerProductProof InnerProductProof
-    Commit            *p256.P256
     Params            BulletProofSetupParams
 }
 
 /*
-SetupInnerProduct is responsible for computing the common parameters.
-Only works for ranges to 0 to 2^n, where n is a power of 2 and n <= 32
-TODO: allow n > 32 (need uint64 for that).
+Setup is responsible for computing the common parameters for the interval [0, b).
+Only works for ranges to 0 to 2^n, where b is a power of 2. For ranges that do
+not fit into an int64 use SetupBig or SetupBits.
 */
 func Setup(b int64) (BulletProofSetupParams, error) {
-    if !IsPowerOfTwo(b) {
+    return SetupBig(new(big.Int).SetInt64(b))
+}
+
+/*
+SetupBig computes the common parameters for the interval [0, b), where b is a
+power of 2 given as a big integer, e.g. 2^64.
+*/
+func SetupBig(b *big.Int) (BulletProofSetupParams, error) {
+    if b == nil || b.Sign() <= 0 {
+        return BulletProofSetupParams{}, errors.New("range end must be positive")
+    }
+    n := int64(b.BitLen() - 1)
+    if b.Cmp(new(big.Int).Lsh(big.NewInt(1), uint(n))) != 0 {
         return BulletProofSetupParams{}, errors.New("range end is not a power of 2")
     }
+    return SetupBits(n)
+}
+
+/*
+SetupBits computes the common parameters for the interval [0, 2^n), for any
+bit-length n. When n is not a power of 2 the vectors of the proof are padded
+internally, so Gg and Hh have the size of the next power of 2.
+*/
+func SetupBits(n int64) (BulletProofSetupParams, error) {
+    return SetupBitsWithDST(n, DefaultDST)
+}
 
+/*
+SetupBitsWithDST computes the common parameters for the interval [0, 2^n), where
+every generator is hashed to the curve with the domain separation tag dst.
+Applications that use different tags get independent generators.
+*/
+func SetupBitsWithDST(n int64, dst string) (BulletProofSetupParams, error) {
+    return SetupBitsWithGroup(group.Secp256k1, n, dst)
+}
+
+/*
+SetupBitsWithGroup computes the common parameters for the interval [0, 2^n) in
+the group g, such as group.P256 or group.BN256G1, where every generator is
+hashed to the group with the domain separation tag dst. The tag should end with
+the hash-to-curve suite of the group, as the one of DefaultDSTForGroup.
+*/
+func SetupBitsWithGroup(g group.Group, n int64, dst string) (BulletProofSetupParams, error) {
+    err := checkSetupBits(g, n, dst)
+    if err != nil {
+        return BulletProofSetupParams{}, err
+    }
     params := BulletProofSetupParams{}
-    params.G = new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1))
-    params.H, _ = p256.MapToGroup(SEEDH)
-    params.N = int64(math.Log2(float64(b)))
-    if !IsPowerOfTwo(params.N) {
-        return BulletProofSetupParams{}, fmt.Errorf("range end is a power of 2, but it's exponent should also be. Exponent: %d", params.N)
+    params.Group = g
+    params.G = g.Generator()
+    params.H = hashGenerator(g, dst, []byte("H"))
+    params.U = hashGenerator(g, dst, []byte("U"))
+    params.DST = dst
+    params.N = n
+    params.Gg, params.Hh = computeGenerators(g, dst, params.paddedN())
+    return params, nil
+}
+
+/*
+checkSetupBits returns an error if g, n or dst are not valid arguments of SetupBitsWithGroup.
+*/
+func checkSetupBits(g group.Group, n int64, dst string) error {
+    if g == nil {
+        return errors.New("group must be defined")
     }
-    if params.N > 32 {
-        return BulletProofSetupParams{}, errors.New("range end can not be greater than 2**32")
+    if dst == "" {
+        return errors.New("domain separation tag must not be empty")
     }
-    params.Gg = make([]*p256.P256, params.N)
-    params.Hh = make([]*p256.P256, params.N)
-    for i := int64(0); i < params.N; i++ {
-        params.Gg[i], _ = p256.MapToGroup(SEEDH + "g" + string(i))
-        params.Hh[i], _ = p256.MapToGroup(SEEDH + "h" + string(i))
+    if n <= 0 {
+        return fmt.Errorf("bit-length must be positive. Bit-length: %d", n)
     }
-    return params, nil
+    if n >= int64(g.Order().BitLen()) {
+        return fmt.Errorf("bit-length must be lower than the bit-length of the group order. Bit-length: %d", n)
+    }
+    return nil
+}
+
+/*
+group returns the group of the parameters.
+*/
+func (params *BulletProofSetupParams) group() group.Group {
+    if params.Group == nil {
+        return group.Secp256k1
+    }
+    return params.Group
+}
+
+/*
+Commit returns the Pedersen commitment V = g^x.h^gamma, to which the proofs of
+ProveWithBlinding refer.
+*/
+func (params *BulletProofSetupParams) Commit(x, gamma *big.Int) (group.Element, error) {
+    if x == nil || gamma == nil {
+        return nil, errors.New("secret and blinding factor must be defined")
+    }
+    if params.H == nil {
+        return nil, errors.New("invalid setup parameters")
+    }
+    return commit(params.group(), x, gamma, params.H), nil
+}
+
+/*
+paddedN returns the size of the vectors used to prove a single value, which is
+the bit-length N rounded up to a power of 2, as required by the Inner Product Proof.
+*/
+func (params *BulletProofSetupParams) paddedN() int64 {
+    return nextPowerOfTwo(params.N)
+}
+
+/*
+bitWeights returns the vector 2^n padded with zeros up to size. The padding
+entries are still proven to be bits, but they do not contribute to the value.
+*/
+func bitWeights(n, size int64, order *big.Int) scalarVector {
+    p2n := powerScalarVector(order, new(big.Int).SetInt64(2), n)
+    return p2n.concat(constantScalarVector(order, new(big.Int), size-n))
+}
+
+/*
+computeGenerators returns the vectors of generators Gg and Hh, both of size n,
+hashed to the group g with the tag dst. The i-th generator is the hash of the
+label "Gg" or "Hh" followed by i as 4 big-endian bytes, so vectors of different
+sizes share the same prefix.
+*/
+func computeGenerators(g group.Group, dst string, n int64) ([]group.Element, []group.Element) {
+    return computeGeneratorRange(g, dst, 0, n)
+}
+
+/*
+computeGeneratorRange returns the generators Gg[i] and Hh[i] for i from start
+to end - 1.
+*/
+func computeGeneratorRange(g group.Group, dst string, start, end int64) ([]group.Element, []group.Element) {
+    var Gg, Hh []group.Element
+    for i := start; i < end; i++ {
+        index := []byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
+        Gg = append(Gg, hashGenerator(g, dst, append([]byte("Gg"), index...)))
+        Hh = append(Hh, hashGenerator(g, dst, append([]byte("Hh"), index...)))
+    }
+    return Gg, Hh
+}
+
+/*
+hashGenerator returns the generator for label, using the hash-to-curve suite of
+the group g with the tag dst, e.g. secp256k1_XMD:SHA-256_SSWU_RO_ of RFC 9380.
+*/
+func hashGenerator(g group.Group, dst string, label []byte) group.Element {
+    generator, _ := g.HashToElement(label, []byte(dst))
+    return generator
+}
+
+/*
+innerProductU returns the generator U of the Inner Product Proof. Parameters that
+were not computed by a Setup function use the default tag.
+*/
+func (params *BulletProofSetupParams) innerProductU() group.Element {
+    if params.U != nil {
+        return params.U
+    }
+    dst := params.DST
+    if dst == "" {
+        dst = DefaultDSTForGroup(params.group())
+    }
+    return hashGenerator(params.group(), dst, []byte("U"))
 }
 
 /*
@@ -102,126 +255,214 @@ eprint version of Bulletproofs papers:
 https://eprint.iacr.org/2017/1066.pdf
 */
 func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error) {
+    proof, _, err := ProveWithRand(rand.Reader, secret, params)
+    return proof, err
+}
+
+/*
+Opening contains the commitment V = g^secret.h^gamma of a range proof together
+with its blinding factor gamma, which the prover needs to open V later on.
+*/
+type Opening struct {
+    V     group.Element
+    Gamma *big.Int
+}
+
+/*
+ProveWithOpening computes the ZK rangeproof like Prove, and also returns the
+opening (V, gamma) of the commitment used by the proof.
+*/
+func ProveWithOpening(secret *big.Int, params BulletProofSetupParams) (BulletProof, Opening, error) {
+    return ProveWithRand(rand.Reader, secret, params)
+}
+
+/*
+ProveWithRand computes the ZK rangeproof and the opening of its commitment,
+reading every random value from random. Together with a DeterministicReader it
+computes the same proof for the same seed, which is used for test vectors.
+*/
+func ProveWithRand(random io.Reader, secret *big.Int, params BulletProofSetupParams) (BulletProof, Opening, error) {
+    gamma, err := RandomScalar(random, params.group().Order())
+    if err != nil {
+        return BulletProof{}, Opening{}, err
+    }
+    proof, err := prove(random, secret, gamma, params)
+    if err != nil {
+        return proof, Opening{}, err
+    }
+    return proof, Opening{V: proof.V, Gamma: gamma}, nil
+}
+
+/*
+ProveWithBlinding computes the ZK rangeproof for the existing commitment
+V = g^secret.h^gamma, where gamma is the blinding factor chosen by the caller.
+*/
+func ProveWithBlinding(secret, gamma *big.Int, params BulletProofSetupParams) (BulletProof, error) {
+    return ProveWithBlindingAndRand(rand.Reader, secret, gamma, params)
+}
+
+/*
+ProveWithBlindingAndRand computes the ZK rangeproof for the existing commitment
+V = g^secret.h^gamma, reading every other random value from random.
+*/
+func ProveWithBlindingAndRand(random io.Reader, secret, gamma *big.Int, params BulletProofSetupParams) (BulletProof, error) {
+    if secret == nil || gamma == nil {
+        return BulletProof{}, errors.New("secret and blinding factor must be defined")
+    }
+    return prove(random, secret, bn.Mod(gamma, params.group().Order()), params)
+}
+
+/*
+prove computes the ZK rangeproof for the commitment V = g^secret.h^gamma. The
+random values are read from random, in the order in which they appear in the paper.
+*/
+func prove(random io.Reader, secret, gamma *big.Int, params BulletProofSetupParams) (BulletProof, error) {
     var (
         proof BulletProof
     )
+    grp := params.group()
+    order := grp.Order()
     // ////////////////////////////////////////////////////////////////////////////
     // First phase: page 19
     // ////////////////////////////////////////////////////////////////////////////
 
+    // the vectors are padded up to a power of 2 for the Inner Product Proof
+    n := params.paddedN()
+    if int64(len(params.Gg)) < n || int64(len(params.Hh)) < n {
+        return proof, errors.New("not enough generators for the bit-length")
+    }
+    params.Gg = params.Gg[:n]
+    params.Hh = params.Hh[:n]
+
     // commitment to v and gamma
-    gamma, _ := rand.Int(rand.Reader, ORDER)
-    V, _ := CommitG1(secret, gamma, params.H)
+    V := commit(grp, secret, gamma, params.H)
+    transcript := newRangeProofTranscript(params, []group.Element{V})
 
     // aL, aR and commitment: (A, alpha)
-    aL, _ := Decompose(secret, 2, params.N)                                    // (41)
-    aR, _ := computeAR(aL)                                                     // (42)
-    alpha, _ := rand.Int(rand.Reader, ORDER)                                   // (43)
-    A := commitVector(aL, aR, alpha, params.H, params.Gg, params.Hh, params.N) // (44)
+    aL, _ := Decompose(secret, 2, params.N) // (41)
+    aL = append(aL, make([]int64, n-params.N)...)
+    aR, _ := computeAR(aL)                    // (42)
+    alpha, err := RandomScalar(random, order) // (43)
+    if err != nil {
+        return proof, err
+    }
+    A := commitVector(grp, aL, aR, alpha, params.H, params.Gg, params.Hh, n) // (44)
 
     // sL, sR and commitment: (S, rho)                                     // (45)
-    sL := sampleRandomVector(params.N)
-    sR := sampleRandomVector(params.N)
-    rho, _ := rand.Int(rand.Reader, ORDER)                                      // (46)
-    S := commitVectorBig(sL, sR, rho, params.H, params.Gg, params.Hh, params.N) // (47)
+    sL, errL := randomScalarVector(random, n, order)
+    sR, errR := randomScalarVector(random, n, order)
+    if errL != nil || errR != nil {
+        return proof, errors.New("could not read the random vectors")
+    }
+    rho, err := RandomScalar(random, order) // (46)
+    if err != nil {
+        return proof, err
+    }
+    S := commitVectorBig(grp, sL.bigs(), sR.bigs(), rho, params.H, params.Gg, params.Hh, n) // (47)
 
     // Fiat-Shamir heuristic to compute challenges y and z, corresponds to    (49)
-    y, z, _ := HashBP(A, S)
+    transcript.AppendPoint("A", A)
+    transcript.AppendPoint("S", S)
+    y := transcript.ChallengeScalar("y")
+    z := transcript.ChallengeScalar("z")
 
     // ////////////////////////////////////////////////////////////////////////////
     // Second phase: page 20
     // ////////////////////////////////////////////////////////////////////////////
-    tau1, _ := rand.Int(rand.Reader, ORDER) // (52)
-    tau2, _ := rand.Int(rand.Reader, ORDER) // (52)
+    tau1, err := RandomScalar(random, order) // (52)
+    if err != nil {
+        return proof, err
+    }
+    tau2, err := RandomScalar(random, order) // (52)
+    if err != nil {
+        return proof, err
+    }
 
     /*
        The paper does not describe how to compute t1 and t2.
     */
     // compute t1: < aL - z.1^n, y^n . sR > + < sL, y^n . (aR + z . 1^n) >
-    vz, _ := VectorCopy(z, params.N)
-    vy := powerOf(y, params.N)
+    vz := constantScalarVector(order, z, n)
+    vy := powerScalarVector(order, y, n)
 
     // aL - z.1^n
-    naL, _ := VectorConvertToBig(aL, params.N)
-    aLmvz, _ := VectorSub(naL, vz)
+    aLmvz := int64ScalarVector(order, aL).sub(vz)
 
     // y^n .sR
-    ynsR, _ := VectorMul(vy, sR)
+    ynsR := vy.mul(sR)
 
     // scalar prod: < aL - z.1^n, y^n . sR >
-    sp1, _ := ScalarProduct(aLmvz, ynsR)
+    sp1 := aLmvz.innerProduct(ynsR)
 
     // scalar prod: < sL, y^n . (aR + z . 1^n) >
-    naR, _ := VectorConvertToBig(aR, params.N)
-    aRzn, _ := VectorAdd(naR, vz)
-    ynaRzn, _ := VectorMul(vy, aRzn)
+    aRzn := int64ScalarVector(order, aR).add(vz)
+    ynaRzn := vy.mul(aRzn)
 
-    // Add z^2.2^n to the result
+    // Add z^2.2^n to the result, 2^n is padded with zeros
     // z^2 . 2^n
-    p2n := powerOf(new(big.Int).SetInt64(2), params.N)
+    p2n := bitWeights(params.N, n, order)
     zsquared := bn.Multiply(z, z)
-    z22n, _ := VectorScalarMul(p2n, zsquared)
-    ynaRzn, _ = VectorAdd(ynaRzn, z22n)
-    sp2, _ := ScalarProduct(sL, ynaRzn)
+    z22n := p2n.scale(zsquared)
+    ynaRzn = ynaRzn.add(z22n)
+    sp2 := sL.innerProduct(ynaRzn)
 
     // sp1 + sp2
     t1 := bn.Add(sp1, sp2)
-    t1 = bn.Mod(t1, ORDER)
+    t1 = bn.Mod(t1, order)
 
     // compute t2: < sL, y^n . sR >
-    t2, _ := ScalarProduct(sL, ynsR)
-    t2 = bn.Mod(t2, ORDER)
+    t2 := sL.innerProduct(ynsR)
 
     // compute T1
-    T1, _ := CommitG1(t1, tau1, params.H) // (53)
+    T1 := commit(grp, t1, tau1, params.H) // (53)
 
     // compute T2
-    T2, _ := CommitG1(t2, tau2, params.H) // (53)
+    T2 := commit(grp, t2, tau2, params.H) // (53)
 
     // Fiat-Shamir heuristic to compute 'random' challenge x
-    x, _, _ := HashBP(T1, T2)
+    transcript.AppendPoint("T1", T1)
+    transcript.AppendPoint("T2", T2)
+    x := transcript.ChallengeScalar("x")
 
     // ////////////////////////////////////////////////////////////////////////////
     // Third phase                                                              //
     // ////////////////////////////////////////////////////////////////////////////
 
     // compute bl                                                          // (58)
-    sLx, _ := VectorScalarMul(sL, x)
-    bl, _ := VectorAdd(aLmvz, sLx)
+    sLx := sL.scale(x)
+    bl := aLmvz.add(sLx)
 
     // compute br                                                          // (59)
     // y^n . ( aR + z.1^n + sR.x )
-    sRx, _ := VectorScalarMul(sR, x)
-    aRzn, _ = VectorAdd(aRzn, sRx)
-    ynaRzn, _ = VectorMul(vy, aRzn)
+    sRx := sR.scale(x)
+    aRzn = aRzn.add(sRx)
+    ynaRzn = vy.mul(aRzn)
     // y^n . ( aR + z.1^n sR.x ) + z^2 . 2^n
-    br, _ := VectorAdd(ynaRzn, z22n)
+    br := ynaRzn.add(z22n)
 
     // Compute t` = < bl, br >                                             // (60)
-    tprime, _ := ScalarProduct(bl, br)
+    tprime := bl.innerProduct(br)
 
     // Compute taux = tau2 . x^2 + tau1 . x + z^2 . gamma                  // (61)
-    taux := bn.Multiply(tau2, bn.Multiply(x, x))
-    taux = bn.Add(taux, bn.Multiply(tau1, x))
-    taux = bn.Add(taux, bn.Multiply(bn.Multiply(z, z), gamma))
-    taux = bn.Mod(taux, ORDER)
+    taux := secretCombination(order, []*big.Int{tau2, tau1, gamma}, []*big.Int{bn.Multiply(x, x), x, bn.Multiply(z, z)})
 
     // Compute mu = alpha + rho.x                                          // (62)
-    mu := bn.Multiply(rho, x)
-    mu = bn.Add(mu, alpha)
-    mu = bn.Mod(mu, ORDER)
+    mu := secretCombination(order, []*big.Int{alpha, rho}, []*big.Int{big.NewInt(1), x})
 
     // Inner Product over (g, h', P.h^-mu, tprime)
-    hprime := updateGenerators(params.Hh, y, params.N)
+    hprime := updateGenerators(params.tables, params.Hh, y, order, n)
 
     // SetupInnerProduct Inner Product (Section 4.2)
     var setupErr error
-    params.InnerProductParams, setupErr = setupInnerProduct(params.H, params.Gg, hprime, tprime, params.N)
+    params.InnerProductParams, setupErr = setupInnerProduct(params.H, params.innerProductU(), params.Gg, hprime, tprime, n)
     if setupErr != nil {
         return proof, setupErr
     }
-    commit := commitInnerProduct(params.Gg, hprime, bl, br)
-    proofip, _ := proveInnerProduct(bl, br, commit, params.InnerProductParams)
+    params.InnerProductParams.tables = params.tables
+    transcript.AppendScalar("taux", taux)
+    transcript.AppendScalar("mu", mu)
+    transcript.AppendScalar("tprime", tprime)
+    proofip, _ := proveInnerProduct(bl, br, params.InnerProductParams, transcript)
 
     proof.V = V
     proof.A = A
@@ -232,116 +473,91 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     proof.Mu = mu
     proof.Tprime = tprime
     proof.InnerProductProof = proofip
-    proof.Commit = commit
     proof.Params = params
 
     return proof, nil
 }
 
 /*
-Verify returns true if and only if the proof is valid.
+Verify returns true if and only if the proof is valid. It uses the commitment and
+the parameters stored in the proof, which were chosen by the prover. Use
+VerifyWithParams when the proof comes from an untrusted party.
 */
 func (proof *BulletProof) Verify() (bool, error) {
-    params := proof.Params
-    // Recover x, y, z using Fiat-Shamir heuristic
-    x, _, _ := HashBP(proof.T1, proof.T2)
-    y, z, _ := HashBP(proof.A, proof.S)
-
-    // Switch generators                                                   // (64)
-    hprime := updateGenerators(params.Hh, y, params.N)
-
-    // ////////////////////////////////////////////////////////////////////////////
-    // Check that tprime  = t(x) = t0 + t1x + t2x^2  ----------  Condition (65) //
-    // ////////////////////////////////////////////////////////////////////////////
-
-    // Compute left hand side
-    lhs, _ := CommitG1(proof.Tprime, proof.Taux, params.H)
-
-    // Compute right hand side
-    z2 := bn.Multiply(z, z)
-    z2 = bn.Mod(z2, ORDER)
-    x2 := bn.Multiply(x, x)
-    x2 = bn.Mod(x2, ORDER)
-
-    rhs := new(p256.P256).ScalarMult(proof.V, z2)
-
-    delta := params.delta(y, z)
-
-    gdelta := new(p256.P256).ScalarBaseMult(delta)
-
-    rhs.Multiply(rhs, gdelta)
-
-    T1x := new(p256.P256).ScalarMult(proof.T1, x)
-    T2x2 := new(p256.P256).ScalarMult(proof.T2, x2)
-
-    rhs.Multiply(rhs, T1x)
-    rhs.Multiply(rhs, T2x2)
-
-    // Subtract lhs and rhs and compare with poitn at infinity
-    lhs.Neg(lhs)
-    rhs.Multiply(rhs, lhs)
-    c65 := rhs.IsZero() // Condition (65), page 20, from eprint version
-
-    // Compute P - lhs  #################### Condition (66) ######################
-
-    // S^x
-    Sx := new(p256.P256).ScalarMult(proof.S, x)
-    // A.S^x
-    ASx := new(p256.P256).Add(proof.A, Sx)
-
-    // g^-z
-    mz := bn.Sub(ORDER, z)
-    vmz, _ := VectorCopy(mz, params.N)
-    gpmz, _ := VectorExp(params.Gg, vmz)
-
-    // z.y^n
-    vz, _ := VectorCopy(z, params.N)
-    vy := powerOf(y, params.N)
-    zyn, _ := VectorMul(vy, vz)
-
-    p2n := powerOf(new(big.Int).SetInt64(2), params.N)
-    zsquared := bn.Multiply(z, z)
-    z22n, _ := VectorScalarMul(p2n, zsquared)
-
-    // z.y^n + z^2.2^n
-    zynz22n, _ := VectorAdd(zyn, z22n)
-
-    lP := new(p256.P256)
-    lP.Add(ASx, gpmz)
-
-    // h'^(z.y^n + z^2.2^n)
-    hprimeexp, _ := VectorExp(hprime, zynz22n)
-
-    lP.Add(lP, hprimeexp)
-
-    // Compute P - rhs  #################### Condition (67) ######################
-
-    // h^mu
-    rP := new(p256.P256).ScalarMult(params.H, proof.Mu)
-    rP.Multiply(rP, proof.Commit)
+    return proof.verify(proof.V, proof.Params)
+}
 
-    // Subtract lhs and rhs and compare with poitn at infinity
-    lP = lP.Neg(lP)
-    rP.Add(rP, lP)
-    c67 := rP.IsZero()
+/*
+VerifyWithParams returns true if and only if the proof is a valid range proof for
+the commitment V. The setup parameters stored in the proof are ignored, every
+generator is taken from the params held by the verifier.
+*/
+func VerifyWithParams(proof BulletProof, params BulletProofSetupParams, V group.Element) (bool, error) {
+    return proof.verify(V, params)
+}
 
-    // Verify Inner Product Proof ################################################
-    ok, _ := proof.InnerProductProof.Verify()
+/*
+verify returns true if and only if the proof is valid for the commitment V and
+the setup parameters params, which may be different from the ones stored in the proof.
+*/
+func (proof *BulletProof) verify(V group.Element, params BulletProofSetupParams) (bool, error) {
+    e := newMultiExp()
+    err := proof.verificationTerms(e, V, params, big.NewInt(1))
+    if err != nil {
+        return false, err
+    }
+    return e.isIdentity(), nil
+}
 
-    result := c65 && c67 && ok
+/*
+verificationTerms adds to e the terms of the verification equation of the proof
+for the commitment V, all multiplied by weight. It is the verification of an
+aggregated proof for a single commitment.
+*/
+func (proof *BulletProof) verificationTerms(e *multiExp, V group.Element, params BulletProofSetupParams, weight *big.Int) error {
+    aggregated := AggregatedBulletProof{
+        A:                 proof.A,
+        S:                 proof.S,
+        T1:                proof.T1,
+        T2:                proof.T2,
+        Taux:              proof.Taux,
+        Mu:                proof.Mu,
+        Tprime:            proof.Tprime,
+        InnerProductProof: proof.InnerProductProof,
+    }
+    return aggregated.verificationTerms(e, []group.Element{V}, params, weight)
+}
 
-    return result, nil
+/*
+newRangeProofTranscript returns the transcript of a range proof for the commitments
+V, which is bound to the bit-length and to the generators of the setup parameters.
+The same transcript is used for a single proof and for aggregated proofs.
+*/
+func newRangeProofTranscript(params BulletProofSetupParams, V []group.Element) *Transcript {
+    transcript := newTranscript("zkrp bulletproofs range proof", params.group().Order())
+    transcript.AppendUint64("n", uint64(params.N))
+    transcript.AppendUint64("m", uint64(len(V)))
+    transcript.AppendPoint("H", params.H)
+    transcript.AppendPoints("Gg", params.Gg)
+    transcript.AppendPoints("Hh", params.Hh)
+    transcript.AppendPoints("V", V)
+    return transcript
 }
 
 /*
-SampleRandomVector generates a vector composed by random big numbers.
+SampleRandomVector generates a vector composed by random big numbers modulo order
+read from random.
 */
-func sampleRandomVector(N int64) []*big.Int {
+func sampleRandomVector(random io.Reader, N int64, order *big.Int) ([]*big.Int, error) {
+    var err error
     s := make([]*big.Int, N)
     for i := int64(0); i < N; i++ {
-        s[i], _ = rand.Int(rand.Reader, ORDER)
+        s[i], err = RandomScalar(random, order)
+        if err != nil {
+            return nil, err
+        }
     }
-    return s
+    return s, nil
 }
 
 /*
@@ -349,21 +565,25 @@ updateGenerators is responsible for computing generators in the following format
 [h_1, h_2^(y^-1), ..., h_n^(y^(-n+1))], where [h_1, h_2, ..., h_n] is the original
 vector of generators. This method is used both by prover and verifier. After this
 update we have that A is a vector commitments to (aL, aR . y^n). Also S is a vector
-commitment to (sL, sR . y^n).
+commitment to (sL, sR . y^n). The fixed-base tables of Hh are used when available.
 */
-func updateGenerators(Hh []*p256.P256, y *big.Int, N int64) []*p256.P256 {
+func updateGenerators(tables *generatorTables, Hh []group.Element, y, order *big.Int, N int64) []group.Element {
     var (
         i int64
     )
     // Compute h'                                                          // (64)
-    hprime := make([]*p256.P256, N)
+    hprime := make([]group.Element, N)
     // Switch generators
-    yinv := bn.ModInverse(y, ORDER)
+    yinv := bn.ModInverse(y, order)
     expy := yinv
     hprime[0] = Hh[0]
     i = 1
     for i < N {
-        hprime[i] = new(p256.P256).ScalarMult(Hh[i], expy)
+        if table := tables.table(Hh[i]); table != nil {
+            hprime[i] = table.ScalarMult(expy)
+        } else {
+            hprime[i] = Hh[i].ScalarMult(expy)
+        }
         expy = bn.Multiply(expy, yinv)
         i = i + 1
     }
@@ -387,59 +607,42 @@ func computeAR(x []int64) ([]int64, error) {
     return result, nil
 }
 
-func commitVectorBig(aL, aR []*big.Int, alpha *big.Int, H *p256.P256, g, h []*p256.P256, n int64) *p256.P256 {
-    // Compute h^alpha.vg^aL.vh^aR
-    R := new(p256.P256).ScalarMult(H, alpha)
-    for i := int64(0); i < n; i++ {
-        R.Multiply(R, new(p256.P256).ScalarMult(g[i], aL[i]))
-        R.Multiply(R, new(p256.P256).ScalarMult(h[i], aR[i]))
-    }
-    return R
+/*
+commit computes the Pedersen commitment g^x.h^r in the group grp, where g is the
+generator of grp. The opening is secret, so the fixed-base tables are not used.
+*/
+func commit(grp group.Group, x, r *big.Int, H group.Element) group.Element {
+    C, _ := group.SecretMultiScalarMult(grp, []group.Element{grp.Generator(), H}, []*big.Int{x, r})
+    return C
 }
 
 /*
-Commitvector computes a commitment to the bit of the secret.
+commitVectorBig computes h^alpha.g^aL.h^aR, in constant time when the group
+supports it, since the vectors and alpha are secret.
 */
-func commitVector(aL, aR []int64, alpha *big.Int, H *p256.P256, g, h []*p256.P256, n int64) *p256.P256 {
+func commitVectorBig(grp group.Group, aL, aR []*big.Int, alpha *big.Int, H group.Element, g, h []group.Element, n int64) group.Element {
     // Compute h^alpha.vg^aL.vh^aR
-    R := new(p256.P256).ScalarMult(H, alpha)
-    for i := int64(0); i < n; i++ {
-        gaL := new(p256.P256).ScalarMult(g[i], new(big.Int).SetInt64(aL[i]))
-        haR := new(p256.P256).ScalarMult(h[i], new(big.Int).SetInt64(aR[i]))
-        R.Multiply(R, gaL)
-        R.Multiply(R, haR)
-    }
-    return R
+    points := make([]group.Element, 0, 2*n+1)
+    scalars := make([]*big.Int, 0, 2*n+1)
+    points = append(points, H)
+    scalars = append(scalars, alpha)
+    points = append(points, g[:n]...)
+    scalars = append(scalars, aL[:n]...)
+    points = append(points, h[:n]...)
+    scalars = append(scalars, aR[:n]...)
+    C, _ := group.SecretMultiScalarMult(grp, points, scalars)
+    return C
 }
 
 /*
-delta(y,z) = (z-z^2) . < 1^n, y^n > - z^3 . < 1^n, 2^n >
+Commitvector computes a commitment to the bit of the secret.
 */
-func (params *BulletProofSetupParams) delta(y, z *big.Int) *big.Int {
-    var (
-        result *big.Int
-    )
-    // delta(y,z) = (z-z^2) . < 1^n, y^n > - z^3 . < 1^n, 2^n >
-    z2 := bn.Multiply(z, z)
-    z2 = bn.Mod(z2, ORDER)
-    z3 := bn.Multiply(z2, z)
-    z3 = bn.Mod(z3, ORDER)
-
-    // < 1^n, y^n >
-    v1, _ := VectorCopy(new(big.Int).SetInt64(1), params.N)
-    vy := powerOf(y, params.N)
-    sp1y, _ := ScalarProduct(v1, vy)
-
-    // < 1^n, 2^n >
-    p2n := powerOf(new(big.Int).SetInt64(2), params.N)
-    sp12, _ := ScalarProduct(v1, p2n)
-
-    result = bn.Sub(z, z2)
-    result = bn.Mod(result, ORDER)
-    result = bn.Multiply(result, sp1y)
-    result = bn.Mod(result, ORDER)
-    result = bn.Sub(result, bn.Multiply(z3, sp12))
-    result = bn.Mod(result, ORDER)
-
-    return result
+func commitVector(grp group.Group, aL, aR []int64, alpha *big.Int, H group.Element, g, h []group.Element, n int64) group.Element {
+    vaL := make([]*big.Int, n)
+    vaR := make([]*big.Int, n)
+    for i := int64(0); i < n; i++ {
+        vaL[i] = new(big.Int).SetInt64(aL[i])
+        vaR[i] = new(big.Int).SetInt64(aR[i])
+    }
+    return commitVectorBig(grp, vaL, vaR, alpha, H, g, h, n)
 }
@@ -18,11 +18,14 @@
 package bulletproofs
 
 import (
+    "bytes"
     "encoding/json"
     "math"
     "math/big"
     "testing"
 
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/util"
     "github.com/stretchr/testify/assert"
 )
 
@@ -76,6 +79,157 @@ func TestXWithinRange(t *testing.T) {
     }
 }
 
+func TestXWithin64BitRange(t *testing.T) {
+    rangeEnd := new(big.Int).Lsh(big.NewInt(1), 64)
+    x := new(big.Int).Sub(rangeEnd, big.NewInt(1))
+
+    params, err := SetupBig(rangeEnd)
+    if err != nil {
+        t.Fatalf("Invalid range end: %s", err)
+    }
+    assert.Equal(t, int64(64), params.N)
+    if proveAndVerifyRange(x, params) != true {
+        t.Errorf("x within 64-bit range should verify successfully")
+    }
+}
+
+func TestXEqualTo64BitRangeEnd(t *testing.T) {
+    params, err := SetupBits(64)
+    if err != nil {
+        t.Fatalf("Invalid bit-length: %s", err)
+    }
+    x := new(big.Int).Lsh(big.NewInt(1), 64)
+    if proveAndVerifyRange(x, params) == true {
+        t.Errorf("x equal to 64-bit range end should not verify")
+    }
+}
+
+func TestXWithin128BitRange(t *testing.T) {
+    params, err := SetupBits(128)
+    if err != nil {
+        t.Fatalf("Invalid bit-length: %s", err)
+    }
+    x, _ := new(big.Int).SetString("340282366920938463463374607431768211455", 10) // 2^128 - 1
+    if proveAndVerifyRange(x, params) != true {
+        t.Errorf("x within 128-bit range should verify successfully")
+    }
+}
+
+func TestXWithin40BitRange(t *testing.T) {
+    params, err := SetupBits(40)
+    if err != nil {
+        t.Fatalf("Invalid bit-length: %s", err)
+    }
+    assert.Equal(t, int64(40), params.N)
+    assert.Equal(t, 64, len(params.Gg))
+    x := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 40), big.NewInt(1))
+    if proveAndVerifyRange(x, params) != true {
+        t.Errorf("x within 40-bit range should verify successfully")
+    }
+    x = new(big.Int).Lsh(big.NewInt(1), 40)
+    if proveAndVerifyRange(x, params) == true {
+        t.Errorf("x equal to 40-bit range end should not verify")
+    }
+}
+
+func TestXWithin48BitRange(t *testing.T) {
+    params, err := Setup(int64(1) << 48)
+    if err != nil {
+        t.Fatalf("Invalid range end: %s", err)
+    }
+    assert.Equal(t, int64(48), params.N)
+    x := new(big.Int).SetInt64(123456789012345)
+    if proveAndVerifyRange(x, params) != true {
+        t.Errorf("x within 48-bit range should verify successfully")
+    }
+
+    // a 48-bit proof must not verify against 64-bit parameters
+    proof, _ := Prove(x, params)
+    verifierParams, _ := SetupBits(64)
+    ok, _ := VerifyWithParams(proof, verifierParams, proof.V)
+    assert.False(t, ok, "proof must be bound to its bit-length")
+}
+
+func TestProveWithBlinding(t *testing.T) {
+    params, _ := SetupBits(32)
+    x := new(big.Int).SetInt64(1000)
+    gamma := new(big.Int).SetInt64(123456789)
+
+    // commitment issued by someone else
+    V, _ := params.Commit(x, gamma)
+    proof, err := ProveWithBlinding(x, gamma, params)
+    assert.NoError(t, err)
+    assert.Equal(t, V, proof.V)
+    ok, _ := VerifyWithParams(proof, params, V)
+    assert.True(t, ok, "proof for an existing commitment should verify successfully")
+
+    other, _ := params.Commit(x, new(big.Int).SetInt64(987654321))
+    ok, _ = VerifyWithParams(proof, params, other)
+    assert.False(t, ok, "proof must not verify for a different blinding factor")
+
+    _, err = ProveWithBlinding(x, nil, params)
+    assert.Error(t, err, "missing blinding factor should be rejected")
+}
+
+func TestProveWithOpening(t *testing.T) {
+    params, _ := SetupBits(32)
+    x := new(big.Int).SetInt64(42)
+    proof, opening, err := ProveWithOpening(x, params)
+    assert.NoError(t, err)
+    assert.Equal(t, proof.V, opening.V)
+    V, _ := params.Commit(x, opening.Gamma)
+    assert.Equal(t, V, opening.V)
+    ok, _ := proof.Verify()
+    assert.True(t, ok, "x within range should verify successfully")
+}
+
+func TestProveWithRand(t *testing.T) {
+    params, _ := SetupBits(16)
+    x := new(big.Int).SetInt64(1000)
+    first, _, err := ProveWithRand(util.NewDeterministicReader([]byte("seed")), x, params)
+    assert.NoError(t, err)
+    second, _, _ := ProveWithRand(util.NewDeterministicReader([]byte("seed")), x, params)
+    assert.Equal(t, first, second, "the same seed should give the same proof")
+    ok, _ := first.Verify()
+    assert.True(t, ok, "x within range should verify successfully")
+
+    _, _, err = ProveWithRand(bytes.NewReader(make([]byte, 100)), x, params)
+    assert.Error(t, err, "short source of randomness should be rejected")
+}
+
+func TestSetupWithDST(t *testing.T) {
+    params, err := SetupBitsWithDST(16, "TENANT-A-V01-CS01-with-secp256k1_XMD:SHA-256_SSWU_RO_")
+    assert.NoError(t, err)
+    other, err := SetupBitsWithDST(16, "TENANT-B-V01-CS01-with-secp256k1_XMD:SHA-256_SSWU_RO_")
+    assert.NoError(t, err)
+    assert.NotEqual(t, params.H, other.H)
+    assert.NotEqual(t, params.U, other.U)
+    assert.NotEqual(t, params.Gg[0], other.Gg[0])
+
+    H, _ := group.Secp256k1.HashToElement([]byte("H"), []byte(params.DST))
+    assert.Equal(t, H, params.H)
+
+    // a proof for one tenant must not verify with the generators of another
+    x := new(big.Int).SetInt64(1000)
+    proof, _ := Prove(x, params)
+    ok, _ := VerifyWithParams(proof, params, proof.V)
+    assert.True(t, ok, "x within range should verify successfully")
+    ok, _ = VerifyWithParams(proof, other, proof.V)
+    assert.False(t, ok, "proof must be bound to the generators")
+
+    _, err = SetupBitsWithDST(16, "")
+    assert.Error(t, err, "empty domain separation tag should be rejected")
+}
+
+func TestSetupInvalidRange(t *testing.T) {
+    _, err := SetupBig(new(big.Int).Lsh(big.NewInt(1), 63).Add(new(big.Int).Lsh(big.NewInt(1), 63), big.NewInt(1)))
+    assert.Error(t, err, "range end that is not a power of 2 should be rejected")
+    _, err = SetupBits(256)
+    assert.Error(t, err, "bit-length not lower than the group order should be rejected")
+    _, err = SetupBits(0)
+    assert.Error(t, err, "bit-length 0 should be rejected")
+}
+
 func setupRange(t *testing.T, rangeEnd int64) BulletProofSetupParams {
     params, err := Setup(rangeEnd)
     if err != nil {
@@ -92,7 +246,7 @@ func proveAndVerifyRange(x *big.Int, params BulletProofSetupParams) bool {
 }
 
 func TestJsonEncodeDecode(t *testing.T) {
-    params, _ := Setup(MAX_RANGE_END)
+    params, _ := Setup(1 << 32)
     proof, _ := Prove(new(big.Int).SetInt64(18), params)
     jsonEncoded, err := json.Marshal(proof)
     if err != nil {
@@ -107,7 +261,9 @@ func TestJsonEncodeDecode(t *testing.T) {
         t.Fatal("decode error:", err)
     }
 
-    assert.Equal(t, proof, decodedProof, "should be equal")
+    // the points are compared by their encodings, which do not depend on their coordinates
+    reencoded, _ := json.Marshal(decodedProof)
+    assert.Equal(t, string(jsonEncoded), string(reencoded), "should be equal")
 
     ok, err := decodedProof.Verify()
     if err != nil {
@@ -115,3 +271,152 @@ func TestJsonEncodeDecode(t *testing.T) {
     }
     assert.True(t, ok, "should verify")
 }
+
+func TestVerifyWithParams(t *testing.T) {
+    params, _ := Setup(1 << 32)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    V := proof.V
+
+    // the verifier has its own copy of the parameters
+    verifierParams, _ := Setup(1 << 32)
+    ok, err := VerifyWithParams(proof, verifierParams, V)
+    assert.NoError(t, err)
+    assert.True(t, ok, "should verify")
+
+    // the parameters stored in the proof are ignored
+    proof.Params.Gg[0], proof.Params.Gg[1] = proof.Params.Gg[1], proof.Params.Gg[0]
+    proof.Params.H = group.Secp256k1.Generator().ScalarMult(new(big.Int).SetInt64(2))
+    ok, _ = VerifyWithParams(proof, verifierParams, V)
+    assert.True(t, ok, "should verify with the parameters of the verifier")
+}
+
+func TestVerifyWithParamsUnexpectedCommitment(t *testing.T) {
+    params, _ := Setup(1 << 32)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    other, _ := Prove(new(big.Int).SetInt64(18), params)
+    ok, _ := VerifyWithParams(proof, params, other.V)
+    assert.False(t, ok, "should not verify for another commitment")
+}
+
+func TestVerifyWithParamsMaliciousGenerators(t *testing.T) {
+    // the prover knows the discrete logarithm of H with respect to G
+    params, _ := Setup(1 << 32)
+    params.H = group.Secp256k1.Generator().ScalarMult(new(big.Int).SetInt64(7))
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    ok, _ := proof.Verify()
+    assert.True(t, ok, "proof is valid for the parameters chosen by the prover")
+
+    verifierParams, _ := Setup(1 << 32)
+    ok, _ = VerifyWithParams(proof, verifierParams, proof.V)
+    assert.False(t, ok, "should not verify with the parameters of the verifier")
+}
+
+func TestVerifyWithParamsWrongBitLength(t *testing.T) {
+    params, _ := SetupBits(64)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    verifierParams, _ := SetupBits(32)
+    ok, _ := VerifyWithParams(proof, verifierParams, proof.V)
+    assert.False(t, ok, "should not verify for another bit-length")
+}
+
+func TestTamperedProof(t *testing.T) {
+    params, _ := Setup(1 << 32)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+
+    tampered := proof
+    tampered.Mu = new(big.Int).Add(proof.Mu, big.NewInt(1))
+    ok, _ := VerifyWithParams(tampered, params, proof.V)
+    assert.False(t, ok, "proof with a modified mu should not verify")
+
+    tampered = proof
+    tampered.A = group.Secp256k1.Generator().ScalarMult(big.NewInt(5))
+    ok, _ = VerifyWithParams(tampered, params, proof.V)
+    assert.False(t, ok, "proof with a modified A should not verify")
+
+    tampered = proof
+    tampered.InnerProductProof.A = new(big.Int).Add(proof.InnerProductProof.A, big.NewInt(1))
+    ok, _ = VerifyWithParams(tampered, params, proof.V)
+    assert.False(t, ok, "proof with a modified inner product proof should not verify")
+
+    tampered = proof
+    tampered.InnerProductProof.Ls = proof.InnerProductProof.Ls[1:]
+    tampered.InnerProductProof.Rs = proof.InnerProductProof.Rs[1:]
+    ok, _ = VerifyWithParams(tampered, params, proof.V)
+    assert.False(t, ok, "proof with missing rounds should not verify")
+}
+
+func TestProveWithGroups(t *testing.T) {
+    for _, g := range []group.Group{group.P256, group.BN256G1} {
+        params, err := SetupBitsWithGroup(g, 16, DefaultDSTForGroup(g))
+        assert.NoError(t, err)
+        x := new(big.Int).SetInt64(1000)
+        proof, opening, err := ProveWithOpening(x, params)
+        assert.NoError(t, err, g.Name())
+        assert.Equal(t, g, proof.V.Group())
+        V, _ := params.Commit(x, opening.Gamma)
+        assert.True(t, V.Equal(proof.V), g.Name())
+        ok, err := VerifyWithParams(proof, params, proof.V)
+        assert.NoError(t, err, g.Name())
+        assert.True(t, ok, "x within range should verify successfully over "+g.Name())
+
+        assert.False(t, proveAndVerifyRange(new(big.Int).SetInt64(70000), params),
+            "x higher than range end should not verify over "+g.Name())
+
+        encoded, err := proof.MarshalBinary()
+        assert.NoError(t, err, g.Name())
+        var decoded BulletProof
+        assert.NoError(t, decoded.UnmarshalBinaryWithGroup(encoded, g), g.Name())
+        ok, _ = VerifyWithParams(decoded, params, V)
+        assert.True(t, ok, "decoded proof should verify over "+g.Name())
+        assert.Error(t, decoded.UnmarshalBinary(encoded), "proof must be decoded in its group")
+
+        jsonEncoded, err := json.Marshal(proof)
+        assert.NoError(t, err, g.Name())
+        var jsonDecoded BulletProof
+        assert.NoError(t, json.Unmarshal(jsonEncoded, &jsonDecoded), g.Name())
+        assert.Equal(t, g, jsonDecoded.Params.Group)
+        ok, _ = jsonDecoded.Verify()
+        assert.True(t, ok, "JSON decoded proof should verify over "+g.Name())
+    }
+}
+
+func TestVerifyMixedGroups(t *testing.T) {
+    params, _ := SetupBits(16)
+    other, _ := SetupBitsWithGroup(group.P256, 16, DefaultDSTForGroup(group.P256))
+    proof, _ := Prove(new(big.Int).SetInt64(1000), params)
+    otherProof, _ := Prove(new(big.Int).SetInt64(1000), other)
+
+    ok, err := VerifyWithParams(proof, other, proof.V)
+    assert.Error(t, err, "proof must belong to the group of the parameters")
+    assert.False(t, ok)
+    ok, err = VerifyWithParams(proof, params, otherProof.V)
+    assert.Error(t, err, "commitment must belong to the group of the parameters")
+    assert.False(t, ok)
+
+    // the batch checks the proofs of another group on their own
+    ok, invalid := BatchVerify([]BulletProof{proof, otherProof})
+    assert.True(t, ok, "proofs of different groups should verify")
+    assert.Empty(t, invalid)
+    otherProof.Mu = new(big.Int).Add(otherProof.Mu, big.NewInt(1))
+    ok, invalid = BatchVerify([]BulletProof{proof, otherProof})
+    assert.False(t, ok)
+    assert.Equal(t, []int{1}, invalid)
+}
+
+func BenchmarkProve64(b *testing.B) {
+    params, _ := SetupBits(64)
+    secret := new(big.Int).SetInt64(1234567)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = Prove(secret, params)
+    }
+}
+
+func BenchmarkVerify64(b *testing.B) {
+    params, _ := SetupBits(64)
+    proof, _ := Prove(new(big.Int).SetInt64(1234567), params)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = VerifyWithParams(proof, params, proof.V)
+    }
+}
@@ -0,0 +1,452 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "crypto/rand"
+    "errors"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+AggregatedBulletProof contains the elements that are necessary for the verification
+of m range proofs aggregated in a single proof, as described in Section 4.3 of
+the Bulletproofs paper. All the values share the same Inner Product Proof.
+*/
+type AggregatedBulletProof struct {
+    V                 []group.Element
+    A                 group.Element
+    S                 group.Element
+    T1                group.Element
+    T2                group.Element
+    Taux              *big.Int
+    Mu                *big.Int
+    Tprime            *big.Int
+    InnerProductProof InnerProductProof
+    Params            BulletProofSetupParams
+}
+
+/*
+SetupAggregated computes the common parameters to prove that up to m secrets
+lie in the interval [0, b). The vectors of generators Gg and Hh have size N.M,
+where N is the bit-length of the range and M is m, both rounded up to a power of 2.
+*/
+func SetupAggregated(b, m int64) (BulletProofSetupParams, error) {
+    params, err := Setup(b)
+    if err != nil {
+        return BulletProofSetupParams{}, err
+    }
+    return SetupAggregatedBits(params.N, m)
+}
+
+/*
+SetupAggregatedBits computes the common parameters to prove that up to m secrets
+lie in the interval [0, 2^n).
+*/
+func SetupAggregatedBits(n, m int64) (BulletProofSetupParams, error) {
+    return SetupAggregatedBitsWithDST(n, m, DefaultDST)
+}
+
+/*
+SetupAggregatedBitsWithDST computes the common parameters to prove that up to m
+secrets lie in the interval [0, 2^n), where every generator is hashed to the
+curve with the domain separation tag dst.
+*/
+func SetupAggregatedBitsWithDST(n, m int64, dst string) (BulletProofSetupParams, error) {
+    return SetupAggregatedBitsWithGroup(group.Secp256k1, n, m, dst)
+}
+
+/*
+SetupAggregatedBitsWithGroup computes the common parameters to prove that up to
+m secrets lie in the interval [0, 2^n), where every generator is hashed to the
+group g with the domain separation tag dst.
+*/
+func SetupAggregatedBitsWithGroup(g group.Group, n, m int64, dst string) (BulletProofSetupParams, error) {
+    if m <= 0 {
+        return BulletProofSetupParams{}, errors.New("number of aggregated values must be positive")
+    }
+    params, err := SetupBitsWithGroup(g, n, dst)
+    if err != nil {
+        return BulletProofSetupParams{}, err
+    }
+    if m > 1 {
+        params.Gg, params.Hh = computeGenerators(g, dst, params.paddedN()*nextPowerOfTwo(m))
+    }
+    return params, nil
+}
+
+/*
+ProveAggregated computes a single ZK rangeproof for all the secrets. The parameters
+must contain at least N.M generators, where N and the number of secrets M are
+rounded up to a power of 2. When the number of secrets is not a power of 2 the
+proof is padded with secrets 0, committed with the blinding factor 0: their
+commitments are the identity, so they are implicit for the verifier and not part
+of the proof.
+The equation numbers refer to the eprint version of the Bulletproofs paper:
+https://eprint.iacr.org/2017/1066.pdf
+*/
+func ProveAggregated(secrets []*big.Int, params BulletProofSetupParams) (AggregatedBulletProof, error) {
+    return ProveAggregatedWithRand(rand.Reader, secrets, params)
+}
+
+/*
+ProveAggregatedWithRand computes the aggregated ZK rangeproof, reading every
+random value from random.
+*/
+func ProveAggregatedWithRand(random io.Reader, secrets []*big.Int, params BulletProofSetupParams) (AggregatedBulletProof, error) {
+    var (
+        proof AggregatedBulletProof
+    )
+    grp := params.group()
+    order := grp.Order()
+    m := int64(len(secrets))
+    if m == 0 {
+        return proof, errors.New("no secret to prove")
+    }
+    mp := nextPowerOfTwo(m)
+    n := params.paddedN()
+    nm := n * mp
+    if int64(len(params.Gg)) < nm || int64(len(params.Hh)) < nm {
+        return proof, errors.New("not enough generators for the number of aggregated values")
+    }
+    params.Gg = params.Gg[:nm]
+    params.Hh = params.Hh[:nm]
+
+    // commitments to v_j and gamma_j
+    V := make([]group.Element, m)
+    gamma := make([]*big.Int, m)
+    for j := int64(0); j < m; j++ {
+        var err error
+        gamma[j], err = RandomScalar(random, order)
+        if err != nil {
+            return proof, err
+        }
+        V[j] = commit(grp, secrets[j], gamma[j], params.H)
+    }
+
+    transcript := newRangeProofTranscript(params, V)
+
+    // aL is the concatenation of the bits of each v_j, padded to n bits, and of
+    // the zero bits of the padding values
+    aL := make([]int64, 0, nm)
+    for j := int64(0); j < m; j++ {
+        bits, _ := Decompose(secrets[j], 2, params.N)
+        aL = append(aL, bits...)
+        aL = append(aL, make([]int64, n-params.N)...)
+    }
+    aL = append(aL, make([]int64, nm-n*m)...)
+    aR, _ := computeAR(aL)
+    alpha, err := RandomScalar(random, order)
+    if err != nil {
+        return proof, err
+    }
+    A := commitVector(grp, aL, aR, alpha, params.H, params.Gg, params.Hh, nm)
+
+    // sL, sR and commitment: (S, rho)
+    sL, errL := randomScalarVector(random, nm, order)
+    sR, errR := randomScalarVector(random, nm, order)
+    if errL != nil || errR != nil {
+        return proof, errors.New("could not read the random vectors")
+    }
+    rho, err := RandomScalar(random, order)
+    if err != nil {
+        return proof, err
+    }
+    S := commitVectorBig(grp, sL.bigs(), sR.bigs(), rho, params.H, params.Gg, params.Hh, nm)
+
+    // Fiat-Shamir heuristic to compute challenges y and z
+    transcript.AppendPoint("A", A)
+    transcript.AppendPoint("S", S)
+    y := transcript.ChallengeScalar("y")
+    z := transcript.ChallengeScalar("z")
+
+    tau1, err := RandomScalar(random, order)
+    if err != nil {
+        return proof, err
+    }
+    tau2, err := RandomScalar(random, order)
+    if err != nil {
+        return proof, err
+    }
+
+    // compute t1: < aL - z.1^nm, y^nm . sR > + < sL, y^nm . (aR + z . 1^nm) + zeta >
+    vz := constantScalarVector(order, z, nm)
+    vy := powerScalarVector(order, y, nm)
+
+    aLmvz := int64ScalarVector(order, aL).sub(vz)
+    ynsR := vy.mul(sR)
+    sp1 := aLmvz.innerProduct(ynsR)
+
+    aRzn := int64ScalarVector(order, aR).add(vz)
+    ynaRzn := vy.mul(aRzn)
+
+    // zeta = sum_j z^(1+j) . (0^((j-1).n) || 2^n || 0^((m-j).n))
+    zeta := aggregatedZ2n(z, params.N, n, mp, order)
+    ynaRzn = ynaRzn.add(zeta)
+    sp2 := sL.innerProduct(ynaRzn)
+
+    t1 := bn.Add(sp1, sp2)
+    t1 = bn.Mod(t1, order)
+
+    // compute t2: < sL, y^nm . sR >
+    t2 := sL.innerProduct(ynsR)
+
+    T1 := commit(grp, t1, tau1, params.H)
+    T2 := commit(grp, t2, tau2, params.H)
+
+    // Fiat-Shamir heuristic to compute 'random' challenge x
+    transcript.AppendPoint("T1", T1)
+    transcript.AppendPoint("T2", T2)
+    x := transcript.ChallengeScalar("x")
+
+    // compute bl = aL - z.1^nm + sL.x
+    bl := aLmvz.add(sL.scale(x))
+
+    // compute br = y^nm . (aR + z.1^nm + sR.x) + zeta
+    aRzn = aRzn.add(sR.scale(x))
+    ynaRzn = vy.mul(aRzn)
+    br := ynaRzn.add(zeta)
+
+    tprime := bl.innerProduct(br)
+
+    // Compute taux = tau2 . x^2 + tau1 . x + sum_j z^(1+j) . gamma_j, where the
+    // blinding factors of the padding values are 0
+    factors := append([]*big.Int{tau2, tau1}, gamma...)
+    coefficients := []*big.Int{bn.Multiply(x, x), x}
+    zj := bn.Mod(bn.Multiply(z, z), order)
+    for j := int64(0); j < m; j++ {
+        coefficients = append(coefficients, zj)
+        zj = bn.Mod(bn.Multiply(zj, z), order)
+    }
+    taux := secretCombination(order, factors, coefficients)
+
+    // Compute mu = alpha + rho.x
+    mu := secretCombination(order, []*big.Int{alpha, rho}, []*big.Int{big.NewInt(1), x})
+
+    hprime := updateGenerators(params.tables, params.Hh, y, order, nm)
+
+    var setupErr error
+    params.InnerProductParams, setupErr = setupInnerProduct(params.H, params.innerProductU(), params.Gg, hprime, tprime, nm)
+    if setupErr != nil {
+        return proof, setupErr
+    }
+    params.InnerProductParams.tables = params.tables
+    transcript.AppendScalar("taux", taux)
+    transcript.AppendScalar("mu", mu)
+    transcript.AppendScalar("tprime", tprime)
+    proofip, _ := proveInnerProduct(bl, br, params.InnerProductParams, transcript)
+
+    proof.V = V
+    proof.A = A
+    proof.S = S
+    proof.T1 = T1
+    proof.T2 = T2
+    proof.Taux = taux
+    proof.Mu = mu
+    proof.Tprime = tprime
+    proof.InnerProductProof = proofip
+    proof.Params = params
+
+    return proof, nil
+}
+
+/*
+Verify returns true if and only if the aggregated proof is valid. It uses the
+commitments and the parameters stored in the proof, which were chosen by the prover.
+*/
+func (proof *AggregatedBulletProof) Verify() (bool, error) {
+    return proof.verify(proof.V, proof.Params)
+}
+
+/*
+VerifyAggregatedWithParams returns true if and only if the proof is a valid
+aggregated range proof for the commitments V. The setup parameters stored in the
+proof are ignored, every generator is taken from the params held by the verifier.
+*/
+func VerifyAggregatedWithParams(proof AggregatedBulletProof, params BulletProofSetupParams, V []group.Element) (bool, error) {
+    return proof.verify(V, params)
+}
+
+/*
+verify returns true if and only if the aggregated proof is valid for the
+commitments V and the setup parameters params. Every check is merged into a
+single multi-exponentiation, as explained in Section 6.2.
+*/
+func (proof *AggregatedBulletProof) verify(V []group.Element, params BulletProofSetupParams) (bool, error) {
+    e := newMultiExp()
+    err := proof.verificationTerms(e, V, params, big.NewInt(1))
+    if err != nil {
+        return false, err
+    }
+    return e.isIdentity(), nil
+}
+
+/*
+verificationTerms adds to e the terms of the verification equation of the
+aggregated proof, all multiplied by weight. The check of tprime is multiplied by
+an additional random weight, so that it can be merged with the Inner Product
+Proof. A single BulletProof is the case m = 1. Every point must belong to the
+group of the parameters.
+*/
+func (proof *AggregatedBulletProof) verificationTerms(e *multiExp, V []group.Element, params BulletProofSetupParams, weight *big.Int) error {
+    if proof.Taux == nil || proof.Mu == nil || proof.Tprime == nil {
+        return errors.New("proof is incomplete")
+    }
+    grp := params.group()
+    order := grp.Order()
+    if params.H == nil || params.N <= 0 || params.N >= int64(order.BitLen()) {
+        return errors.New("invalid setup parameters")
+    }
+    err := e.setGroup(grp)
+    if err != nil {
+        return err
+    }
+    err = e.checkElements(append([]group.Element{proof.A, proof.S, proof.T1, proof.T2, params.H}, V...)...)
+    if err != nil {
+        return err
+    }
+    // the padding commitments up to mp values are the identity, they are implicit
+    m := int64(len(V))
+    if m == 0 {
+        return errors.New("no commitment to verify")
+    }
+    mp := nextPowerOfTwo(m)
+    nm := params.paddedN() * mp
+    if int64(len(params.Gg)) < nm || int64(len(params.Hh)) < nm {
+        return errors.New("not enough generators for the number of aggregated values")
+    }
+    params.Gg = params.Gg[:nm]
+    params.Hh = params.Hh[:nm]
+    err = e.checkElements(append(append([]group.Element{params.innerProductU()}, params.Gg...), params.Hh...)...)
+    if err != nil {
+        return err
+    }
+    e.useTables(params.tables)
+
+    // Recover x, y, z using Fiat-Shamir heuristic
+    transcript := newRangeProofTranscript(params, V)
+    transcript.AppendPoint("A", proof.A)
+    transcript.AppendPoint("S", proof.S)
+    y := transcript.ChallengeScalar("y")
+    z := transcript.ChallengeScalar("z")
+    transcript.AppendPoint("T1", proof.T1)
+    transcript.AppendPoint("T2", proof.T2)
+    x := transcript.ChallengeScalar("x")
+    transcript.AppendScalar("taux", proof.Taux)
+    transcript.AppendScalar("mu", proof.Mu)
+    transcript.AppendScalar("tprime", proof.Tprime)
+
+    // h' = h^(y^-i) is never computed, the exponents of h are scaled instead  // (64)
+    yinv := powerScalarVector(order, bn.ModInverse(y, order), nm).bigs()
+
+    // ////////////////////////////////////////////////////////////////////////////
+    // Check that tprime  = t(x) = t0 + t1x + t2x^2  ----------  Condition (65) //
+    // V^(z^2.z^m).g^(delta - tprime).h^(-taux).T1^x.T2^(x^2) = 1               //
+    // ////////////////////////////////////////////////////////////////////////////
+    c := bn.Mod(bn.Multiply(weight, randomWeight()), order)
+
+    zj := bn.Mod(bn.Multiply(z, z), order)
+    for j := int64(0); j < m; j++ {
+        e.add(V[j], bn.Multiply(c, zj))
+        zj = bn.Mod(bn.Multiply(zj, z), order)
+    }
+    delta := params.deltaAggregated(y, z, mp)
+    e.addBase(bn.Multiply(c, bn.Sub(delta, proof.Tprime)))
+    e.add(params.H, bn.Multiply(c, bn.Sub(order, proof.Taux)))
+    x2 := bn.Mod(bn.Multiply(x, x), order)
+    e.add(proof.T1, bn.Multiply(c, x))
+    e.add(proof.T2, bn.Multiply(c, x2))
+
+    // P = A.S^x.g^-z.h'^(z.y^nm + zeta)  ############### Condition (66) ##
+    e.add(proof.A, weight)
+    e.add(proof.S, bn.Multiply(weight, x))
+    wz := bn.Mod(bn.Multiply(weight, z), order)
+    mwz := bn.Sub(order, wz)
+    zeta := aggregatedZ2n(z, params.N, params.paddedN(), mp, order).bigs()
+    for i := int64(0); i < nm; i++ {
+        e.add(params.Gg[i], mwz)
+        // h'_i^(z.y^i + zeta_i) = h_i^(z + zeta_i.y^-i)
+        hexp := bn.Mod(bn.Multiply(zeta[i], yinv[i]), order)
+        e.add(params.Hh[i], bn.Add(wz, bn.Multiply(weight, hexp)))
+    }
+
+    // P.h^-mu is the commitment to l and r  ################ Condition (67) ##
+    // The Inner Product Proof shows that it is equal to g^l.h'^r, where
+    // tprime = < l, r >.
+    e.add(params.H, bn.Multiply(weight, bn.Sub(order, proof.Mu)))
+
+    ipParams, errIP := setupInnerProduct(params.H, params.innerProductU(), params.Gg, params.Hh, proof.Tprime, nm)
+    if errIP != nil {
+        return errIP
+    }
+    return proof.InnerProductProof.verificationTerms(e, ipParams, yinv, weight, transcript)
+}
+
+/*
+aggregatedZ2n computes the vector sum_j z^(1+j) . (0^((j-1).size) || 2^n || 0^((m-j).size)),
+for j from 1 to m, which replaces z^2.2^n in the aggregated proof. Each block 2^n
+is padded with zeros up to size.
+*/
+func aggregatedZ2n(z *big.Int, n, size, m int64, order *big.Int) scalarVector {
+    result := constantScalarVector(order, new(big.Int), 0)
+    p2n := bitWeights(n, size, order)
+    zj := bn.Mod(bn.Multiply(z, z), order)
+    for j := int64(0); j < m; j++ {
+        result = result.concat(p2n.scale(zj))
+        zj = bn.Mod(bn.Multiply(zj, z), order)
+    }
+    return result
+}
+
+/*
+deltaAggregated(y,z) = (z-z^2) . < 1^nm, y^nm > - sum_j z^(j+2) . < 1^n, 2^n >, where
+nm counts the padding bits as well.
+*/
+func (params *BulletProofSetupParams) deltaAggregated(y, z *big.Int, m int64) *big.Int {
+    nm := params.paddedN() * m
+    order := params.group().Order()
+    z2 := bn.Mod(bn.Multiply(z, z), order)
+
+    // < 1^nm, y^nm >
+    v1 := constantScalarVector(order, new(big.Int).SetInt64(1), nm)
+    vy := powerScalarVector(order, y, nm)
+    sp1y := v1.innerProduct(vy)
+
+    // < 1^n, 2^n >
+    p2n := powerScalarVector(order, new(big.Int).SetInt64(2), params.N)
+    sp12 := v1.slice(0, int(params.N)).innerProduct(p2n)
+
+    result := bn.Sub(z, z2)
+    result = bn.Mod(result, order)
+    result = bn.Multiply(result, sp1y)
+    result = bn.Mod(result, order)
+
+    zj := bn.Mod(bn.Multiply(z2, z), order)
+    for j := int64(0); j < m; j++ {
+        result = bn.Sub(result, bn.Multiply(zj, sp12))
+        result = bn.Mod(result, order)
+        zj = bn.Mod(bn.Multiply(zj, z), order)
+    }
+
+    return result
+}
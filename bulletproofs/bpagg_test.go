@@ -0,0 +1,254 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "encoding/json"
+    "math"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestAggregatedWithinRange(t *testing.T) {
+    secrets := []*big.Int{
+        new(big.Int).SetInt64(0),
+        new(big.Int).SetInt64(3),
+        new(big.Int).SetInt64(4294967295),
+        new(big.Int).SetInt64(1000),
+    }
+    if proveAndVerifyAggregated(t, secrets) != true {
+        t.Errorf("secrets within range should verify successfully")
+    }
+}
+
+func TestAggregatedNonPowerOfTwoBits(t *testing.T) {
+    params, err := SetupAggregatedBits(24, 2)
+    if err != nil {
+        t.Fatalf("Invalid setup: %s", err)
+    }
+    assert.Equal(t, int64(24), params.N)
+    assert.Equal(t, 64, len(params.Gg))
+    secrets := []*big.Int{
+        new(big.Int).SetInt64(16777215), // 2^24 - 1
+        new(big.Int).SetInt64(42),
+    }
+    proof, _ := ProveAggregated(secrets, params)
+    ok, _ := proof.Verify()
+    assert.True(t, ok, "secrets within 24-bit range should verify successfully")
+
+    secrets[1] = new(big.Int).SetInt64(16777216) // 2^24
+    proof, _ = ProveAggregated(secrets, params)
+    ok, _ = proof.Verify()
+    assert.False(t, ok, "secret equal to range end should not verify")
+}
+
+func TestAggregatedSingleValue(t *testing.T) {
+    secrets := []*big.Int{new(big.Int).SetInt64(18)}
+    if proveAndVerifyAggregated(t, secrets) != true {
+        t.Errorf("single secret within range should verify successfully")
+    }
+}
+
+func TestAggregatedOneOutOfRange(t *testing.T) {
+    secrets := []*big.Int{
+        new(big.Int).SetInt64(3),
+        new(big.Int).SetInt64(int64(math.Pow(2, 32))),
+    }
+    if proveAndVerifyAggregated(t, secrets) == true {
+        t.Errorf("secret equal to range end should not verify")
+    }
+}
+
+func TestAggregatedNegative(t *testing.T) {
+    secrets := []*big.Int{
+        new(big.Int).SetInt64(-1),
+        new(big.Int).SetInt64(3),
+    }
+    if proveAndVerifyAggregated(t, secrets) == true {
+        t.Errorf("secret lower than range start should not verify")
+    }
+}
+
+func TestAggregatedSwappedCommitments(t *testing.T) {
+    params, _ := SetupAggregated(1<<32, 2)
+    secrets := []*big.Int{new(big.Int).SetInt64(5), new(big.Int).SetInt64(7)}
+    proof, _ := ProveAggregated(secrets, params)
+    proof.V[0], proof.V[1] = proof.V[1], proof.V[0]
+    ok, _ := proof.Verify()
+    if ok {
+        t.Errorf("proof should be bound to the order of the commitments")
+    }
+}
+
+func TestAggregatedNotPowerOfTwo(t *testing.T) {
+    params, err := SetupAggregatedBits(32, 3)
+    assert.NoError(t, err)
+    assert.Equal(t, 128, len(params.Gg), "generators are padded to 4 values")
+    secrets := []*big.Int{
+        new(big.Int).SetInt64(1),
+        new(big.Int).SetInt64(2),
+        new(big.Int).SetInt64(3),
+    }
+    proof, err := ProveAggregated(secrets, params)
+    assert.NoError(t, err)
+    assert.Equal(t, 3, len(proof.V))
+    ok, err := VerifyAggregatedWithParams(proof, params, proof.V)
+    assert.NoError(t, err)
+    assert.True(t, ok, "3 aggregated values should verify successfully")
+
+    ok, _ = VerifyAggregatedWithParams(proof, params, proof.V[:2])
+    assert.False(t, ok, "proof should not verify without one of the commitments")
+    ok, _ = VerifyAggregatedWithParams(proof, params, append(proof.V, group.Secp256k1.Identity()))
+    assert.False(t, ok, "the padding commitments should not be accepted explicitly")
+
+    _, err = SetupAggregatedBits(32, 0)
+    assert.Error(t, err, "setup for no value should be rejected")
+}
+
+func TestAggregatedFiftyValues(t *testing.T) {
+    params, err := SetupAggregatedBits(16, 50)
+    assert.NoError(t, err)
+    assert.Equal(t, 16*64, len(params.Gg))
+    secrets := make([]*big.Int, 50)
+    for i := range secrets {
+        secrets[i] = new(big.Int).SetInt64(int64(1000 * i))
+    }
+    proof, err := ProveAggregated(secrets, params)
+    assert.NoError(t, err)
+    ok, err := VerifyAggregatedWithParams(proof, params, proof.V)
+    assert.NoError(t, err)
+    assert.True(t, ok, "50 aggregated values should verify successfully")
+
+    encoded, err := proof.MarshalBinary()
+    assert.NoError(t, err)
+    var decoded AggregatedBulletProof
+    assert.NoError(t, decoded.UnmarshalBinary(encoded))
+    ok, _ = VerifyAggregatedWithParams(decoded, params, decoded.V)
+    assert.True(t, ok, "decoded proof should verify")
+
+    secrets[49] = new(big.Int).SetInt64(65536)
+    proof, _ = ProveAggregated(secrets, params)
+    ok, _ = VerifyAggregatedWithParams(proof, params, proof.V)
+    assert.False(t, ok, "secret out of range should not verify")
+
+    registered, err := NewRegistry("").Params(16, 50, DefaultDST)
+    assert.NoError(t, err)
+    assert.Equal(t, params.Gg, registered.Gg)
+}
+
+func TestAggregatedNotEnoughGenerators(t *testing.T) {
+    params, _ := SetupAggregated(1<<32, 2)
+    secrets := []*big.Int{
+        new(big.Int).SetInt64(1),
+        new(big.Int).SetInt64(2),
+        new(big.Int).SetInt64(3),
+        new(big.Int).SetInt64(4),
+    }
+    _, err := ProveAggregated(secrets, params)
+    if err == nil {
+        t.Errorf("parameters for 2 values should not allow proving 4 values")
+    }
+}
+
+func TestAggregatedProofSize(t *testing.T) {
+    params, _ := SetupAggregated(1<<32, 8)
+    secrets := make([]*big.Int, 8)
+    for i := range secrets {
+        secrets[i] = new(big.Int).SetInt64(int64(i))
+    }
+    proof, _ := ProveAggregated(secrets, params)
+    single, _ := ProveAggregated(secrets[:1], params)
+    // 8 values only need log2(8) = 3 additional pairs (L, R)
+    assert.Equal(t, len(single.InnerProductProof.Ls)+3, len(proof.InnerProductProof.Ls))
+    assert.Equal(t, len(single.InnerProductProof.Rs)+3, len(proof.InnerProductProof.Rs))
+}
+
+func TestJsonEncodeDecodeAggregated(t *testing.T) {
+    params, _ := SetupAggregated(1<<32, 2)
+    secrets := []*big.Int{new(big.Int).SetInt64(18), new(big.Int).SetInt64(65)}
+    proof, _ := ProveAggregated(secrets, params)
+    jsonEncoded, err := json.Marshal(proof)
+    if err != nil {
+        t.Fatal("encode error:", err)
+    }
+
+    var decodedProof AggregatedBulletProof
+    err = json.Unmarshal(jsonEncoded, &decodedProof)
+    if err != nil {
+        t.Fatal("decode error:", err)
+    }
+
+    ok, err := decodedProof.Verify()
+    if err != nil {
+        t.Fatal("verify error:", err)
+    }
+    assert.True(t, ok, "should verify")
+}
+
+func proveAndVerifyAggregated(t *testing.T, secrets []*big.Int) bool {
+    params, err := SetupAggregated(1<<32, int64(len(secrets)))
+    if err != nil {
+        t.Errorf("Invalid parameters: %s", err)
+        t.FailNow()
+    }
+    proof, err := ProveAggregated(secrets, params)
+    if err != nil {
+        t.Errorf("Proof generation failed: %s", err)
+        t.FailNow()
+    }
+    ok, _ := proof.Verify()
+    return ok
+}
+
+func TestVerifyAggregatedWithParams(t *testing.T) {
+    params, _ := SetupAggregated(1<<32, 2)
+    secrets := []*big.Int{new(big.Int).SetInt64(5), new(big.Int).SetInt64(7)}
+    proof, _ := ProveAggregated(secrets, params)
+
+    verifierParams, _ := SetupAggregated(1<<32, 4)
+    ok, err := VerifyAggregatedWithParams(proof, verifierParams, proof.V)
+    assert.NoError(t, err)
+    assert.True(t, ok, "should verify with the parameters of the verifier")
+
+    other, _ := ProveAggregated(secrets, params)
+    ok, _ = VerifyAggregatedWithParams(proof, verifierParams, other.V)
+    assert.False(t, ok, "should not verify for other commitments")
+}
+
+func TestAggregatedWithGroups(t *testing.T) {
+    for _, g := range []group.Group{group.P256, group.BN256G1} {
+        params, err := SetupAggregatedBitsWithGroup(g, 16, 2, DefaultDSTForGroup(g))
+        assert.NoError(t, err)
+        secrets := []*big.Int{new(big.Int).SetInt64(18), new(big.Int).SetInt64(65)}
+        proof, err := ProveAggregated(secrets, params)
+        assert.NoError(t, err, g.Name())
+        ok, err := VerifyAggregatedWithParams(proof, params, proof.V)
+        assert.NoError(t, err, g.Name())
+        assert.True(t, ok, "secrets within range should verify successfully over "+g.Name())
+
+        encoded, err := proof.MarshalBinary()
+        assert.NoError(t, err, g.Name())
+        var decoded AggregatedBulletProof
+        assert.NoError(t, decoded.UnmarshalBinaryWithGroup(encoded, g), g.Name())
+        ok, _ = VerifyAggregatedWithParams(decoded, params, proof.V)
+        assert.True(t, ok, "decoded proof should verify over "+g.Name())
+    }
+}
@@ -18,7 +18,15 @@
 package bulletproofs
 
 import (
+    "crypto/rand"
+    "errors"
+    "fmt"
+    "io"
     "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
 )
 
 /*
@@ -26,16 +34,21 @@ bprp structure contains 2 BulletProofs in order to allow computation of
 generic Range Proofs, for any interval [A, B).
 */
 type bprp struct {
-    A   int64
-    B   int64
+    A   *big.Int
+    B   *big.Int
     BP1 BulletProofSetupParams
     BP2 BulletProofSetupParams
 }
 
 /*
-ProofBPRP stores the generic ZKRP.
+ProofBPRP stores the generic ZKRP. Both BulletProofs refer to the same Pedersen
+commitment V = g^x.h^gamma, which is shifted by the bounds of the interval
+[A, B) in order to obtain the commitments of each BulletProof.
 */
 type ProofBPRP struct {
+    V  group.Element
+    A  *big.Int
+    B  *big.Int
     P1 BulletProof
     P2 BulletProof
 }
@@ -45,44 +58,151 @@ SetupGeneric is responsible for calling the Setup algorithm for each
 BulletProof.
 */
 func SetupGeneric(a, b int64) (*bprp, error) {
-    params := new(bprp)
-    params.A = a
-    params.B = b
-    var errBp1, errBp2 error
-    params.BP1, errBp1 = Setup(MAX_RANGE_END)
-    if errBp1 != nil {
-        return nil, errBp1
+    return SetupGenericBig(new(big.Int).SetInt64(a), new(big.Int).SetInt64(b))
+}
+
+/*
+SetupGenericBig computes the parameters for the interval [a, b), where a and b
+are arbitrary big integers, possibly negative, such that a < b. The bit-length
+of both BulletProofs is the smallest one that is allowed by Setup such that
+2^N >= b - a.
+*/
+func SetupGenericBig(a, b *big.Int) (*bprp, error) {
+    return SetupGenericBigWithDST(a, b, DefaultDST)
+}
+
+/*
+SetupGenericBigWithDST computes the parameters for the interval [a, b), where
+every generator is hashed to the curve with the domain separation tag dst.
+*/
+func SetupGenericBigWithDST(a, b *big.Int, dst string) (*bprp, error) {
+    return SetupGenericBigWithGroup(group.Secp256k1, a, b, dst)
+}
+
+/*
+SetupGenericBigWithGroup computes the parameters for the interval [a, b), where
+every generator is hashed to the group g with the domain separation tag dst.
+*/
+func SetupGenericBigWithGroup(g group.Group, a, b *big.Int, dst string) (*bprp, error) {
+    if g == nil {
+        return nil, errors.New("group must be defined")
+    }
+    if a == nil || b == nil {
+        return nil, errors.New("range start and range end must be provided")
     }
-    params.BP2, errBp2 = Setup(MAX_RANGE_END)
-    if errBp2 != nil {
-        return nil, errBp2
+    if a.Cmp(b) >= 0 {
+        return nil, errors.New("range start must be lower than range end")
     }
+    n, err := genericBitLength(new(big.Int).Sub(b, a), g.Order())
+    if err != nil {
+        return nil, err
+    }
+    params := new(bprp)
+    params.A = new(big.Int).Set(a)
+    params.B = new(big.Int).Set(b)
+    params.BP1, err = SetupBitsWithGroup(g, n, dst)
+    if err != nil {
+        return nil, err
+    }
+    // both BulletProofs use the same generators
+    params.BP2 = params.BP1
     return params, nil
 }
 
+/*
+genericBitLength returns the smallest bit-length N accepted by SetupBits for a
+group of the given order such that the interval size fits into [0, 2^N]. The
+proofs of x - A and x - B + 2^N in [0, 2^N) modulo the order only imply that x
+is in [A, B) when they cannot wrap around the order, so that N must also
+satisfy 2^(N+1) <= order.
+*/
+func genericBitLength(size, order *big.Int) (int64, error) {
+    // 2^N >= size  <=>  size - 1 < 2^N
+    n := int64(new(big.Int).Sub(size, big.NewInt(1)).BitLen())
+    if n == 0 {
+        n = 1
+    }
+    if !genericBitLengthFits(n, order) {
+        return 0, fmt.Errorf("interval is too large, it requires a bit-length of %d", n)
+    }
+    return n, nil
+}
+
+/*
+genericBitLengthFits returns true if 2^(n+1) <= order, that is if n + 1 is lower
+than the bit-length of the order.
+*/
+func genericBitLengthFits(n int64, order *big.Int) bool {
+    return n+1 < int64(order.BitLen())
+}
+
 /*
 BulletProof only works for interval in the format [0, 2^N). In order to
 allow generic intervals in the format [A, B) it is necessary to use 2
 BulletProofs, as explained in Section 4.3 from the following paper:
 https://infoscience.epfl.ch/record/128718/files/CCS08.pdf
+Both BulletProofs use the same blinding factor, so that their commitments can
+be derived from a single commitment to the secret.
 */
 func ProveGeneric(secret *big.Int, params *bprp) (ProofBPRP, error) {
+    return ProveGenericWithRand(rand.Reader, secret, params)
+}
+
+/*
+ProveGenericWithRand computes the proof for the interval [A, B), reading every
+random value from random.
+*/
+func ProveGenericWithRand(random io.Reader, secret *big.Int, params *bprp) (ProofBPRP, error) {
+    gamma, err := RandomScalar(random, params.BP1.group().Order())
+    if err != nil {
+        return ProofBPRP{}, err
+    }
+    return proveGeneric(random, secret, gamma, params)
+}
+
+/*
+ProveGenericWithBlinding computes the proof for the existing commitment
+V = g^secret.h^gamma, where gamma is the blinding factor chosen by the caller.
+*/
+func ProveGenericWithBlinding(secret, gamma *big.Int, params *bprp) (ProofBPRP, error) {
+    return ProveGenericWithBlindingAndRand(rand.Reader, secret, gamma, params)
+}
+
+/*
+ProveGenericWithBlindingAndRand computes the proof for the existing commitment
+V = g^secret.h^gamma, reading every other random value from random.
+*/
+func ProveGenericWithBlindingAndRand(random io.Reader, secret, gamma *big.Int, params *bprp) (ProofBPRP, error) {
+    if secret == nil || gamma == nil {
+        return ProofBPRP{}, errors.New("secret and blinding factor must be defined")
+    }
+    return proveGeneric(random, secret, bn.Mod(gamma, params.BP1.group().Order()), params)
+}
+
+/*
+proveGeneric computes the proof for the commitment V = g^secret.h^gamma.
+*/
+func proveGeneric(random io.Reader, secret, gamma *big.Int, params *bprp) (ProofBPRP, error) {
     var proof ProofBPRP
 
+    proof.V = commit(params.BP1.group(), secret, gamma, params.BP1.H)
+    proof.A = new(big.Int).Set(params.A)
+    proof.B = new(big.Int).Set(params.B)
+
     // x - b + 2^N
-    p2 := new(big.Int).SetInt64(MAX_RANGE_END)
-    xb := new(big.Int).Sub(secret, new(big.Int).SetInt64(params.B))
+    p2 := new(big.Int).Lsh(big.NewInt(1), uint(params.BP1.N))
+    xb := new(big.Int).Sub(secret, params.B)
     xb.Add(xb, p2)
 
     var err1 error
-    proof.P1, err1 = Prove(xb, params.BP1)
+    proof.P1, err1 = prove(random, xb, gamma, params.BP1)
     if err1 != nil {
         return proof, err1
     }
 
-    xa := new(big.Int).Sub(secret, new(big.Int).SetInt64(params.A))
+    xa := new(big.Int).Sub(secret, params.A)
     var err2 error
-    proof.P2, err2 = Prove(xa, params.BP2)
+    proof.P2, err2 = prove(random, xa, gamma, params.BP2)
     if err2 != nil {
         return proof, err2
     }
@@ -91,17 +211,80 @@ func ProveGeneric(secret *big.Int, params *bprp) (ProofBPRP, error) {
 }
 
 /*
-Verify call the Verification algorithm for each BulletProof argument.
+Verify call the Verification algorithm for each BulletProof argument. The
+commitments of the BulletProofs are not taken from the proof, instead they are
+computed from V as V1 = V.g^(2^N - B) and V2 = V.g^(-A). The caller must check
+that the interval [A, B) stored in the proof is the expected one, or use
+VerifyGenericWithParams instead.
 */
 func (proof ProofBPRP) Verify() (bool, error) {
-    ok1, err1 := proof.P1.Verify()
-    if !ok1 {
-        return false, err1
+    params := &bprp{A: proof.A, B: proof.B, BP1: proof.P1.Params, BP2: proof.P2.Params}
+    return VerifyGenericWithParams(proof, params, proof.V)
+}
+
+/*
+VerifyGenericWithParams returns true if and only if the proof shows that the
+value committed in V lies in the interval of params. The interval, the setup
+parameters and the commitment stored in the proof are ignored.
+*/
+func VerifyGenericWithParams(proof ProofBPRP, params *bprp, V group.Element) (bool, error) {
+    e := newMultiExp()
+    err := proof.verificationTerms(e, params, V, big.NewInt(1))
+    if err != nil {
+        return false, err
+    }
+    return e.isIdentity(), nil
+}
+
+/*
+verificationTerms adds to e the terms of the verification equations of both
+BulletProofs, multiplied by weight and by an additional random weight for the
+second one, so that both are checked by a single multi-exponentiation.
+*/
+func (proof *ProofBPRP) verificationTerms(e *multiExp, params *bprp, V group.Element, weight *big.Int) error {
+    if params == nil || params.A == nil || params.B == nil {
+        return errors.New("interval is not defined")
+    }
+    if V == nil {
+        return errors.New("commitment is not defined")
+    }
+    if params.BP1.N != params.BP2.N {
+        return errors.New("both BulletProofs must have the same bit-length")
     }
-    ok2, err2 := proof.P2.Verify()
-    if !ok2 {
-        return false, err2
+    grp := params.BP1.group()
+    if params.BP2.group() != grp {
+        return errors.New("both BulletProofs must use the same group")
     }
+    if !genericBitLengthFits(params.BP1.N, grp.Order()) {
+        return errors.New("bit-length is too large for the order of the group")
+    }
+    if V.Group() != grp {
+        return errors.New("point does not belong to the group of the parameters")
+    }
+    V1, V2 := shiftCommitment(grp, V, params.A, params.B, params.BP1.N)
+    err := proof.P1.verificationTerms(e, V1, params.BP1, weight)
+    if err != nil {
+        return err
+    }
+    weight2 := bn.Mod(bn.Multiply(weight, randomWeight()), grp.Order())
+    return proof.P2.verificationTerms(e, V2, params.BP2, weight2)
+}
+
+/*
+shiftCommitment computes the commitments to x - b + 2^N and x - a, given the
+commitment V to x in the group g, namely V1 = V.g^(2^N - b) and V2 = V.g^(-a).
+*/
+func shiftCommitment(g group.Group, V group.Element, a, b *big.Int, N int64) (group.Element, group.Element) {
+    order := g.Order()
+    // 2^N - b
+    p2b := new(big.Int).Lsh(big.NewInt(1), uint(N))
+    p2b.Sub(p2b, b)
+    p2b = bn.Mod(p2b, order)
+    V1 := V.Add(g.Generator().ScalarMult(p2b))
+
+    // -a
+    ma := bn.Mod(new(big.Int).Neg(a), order)
+    V2 := V.Add(g.Generator().ScalarMult(ma))
 
-    return ok1 && ok2, nil
+    return V1, V2
 }
@@ -22,6 +22,8 @@ import (
     "math/big"
     "testing"
 
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/util/bn"
     "github.com/stretchr/testify/assert"
 )
 
@@ -75,6 +77,157 @@ func setupProveVerify18To200(t *testing.T, secret int) bool {
     return ok
 }
 
+func TestXWithinGenericRangeNegativeStart(t *testing.T) {
+    // balance in [-10^6, 10^12)
+    a := big.NewInt(-1000000)
+    b := big.NewInt(1000000000000)
+    assert.True(t, setupProveVerifyBig(t, a, b, big.NewInt(-1000000)), "range start should verify")
+    assert.True(t, setupProveVerifyBig(t, a, b, big.NewInt(-1)), "negative secret within range should verify")
+    assert.True(t, setupProveVerifyBig(t, a, b, big.NewInt(999999999999)), "secret just below range end should verify")
+    assert.False(t, setupProveVerifyBig(t, a, b, big.NewInt(-1000001)), "secret below range start should not verify")
+    assert.False(t, setupProveVerifyBig(t, a, b, big.NewInt(1000000000000)), "secret equal to range end should not verify")
+}
+
+func TestXWithinGenericRangeBeyondInt64(t *testing.T) {
+    a := new(big.Int).Lsh(big.NewInt(1), 70)
+    b := new(big.Int).Add(a, new(big.Int).Lsh(big.NewInt(1), 40))
+    secret := new(big.Int).Add(a, big.NewInt(12345))
+    assert.True(t, setupProveVerifyBig(t, a, b, secret), "secret within range should verify")
+    assert.False(t, setupProveVerifyBig(t, a, b, b), "secret equal to range end should not verify")
+}
+
+func TestSetupGenericBitLength(t *testing.T) {
+    params, err := SetupGeneric(18, 200)
+    assert.NoError(t, err)
+    assert.Equal(t, int64(8), params.BP1.N)
+    assert.Equal(t, int64(8), params.BP2.N)
+
+    params, err = SetupGeneric(0, 1)
+    assert.NoError(t, err)
+    assert.Equal(t, int64(1), params.BP1.N)
+
+    params, err = SetupGenericBig(big.NewInt(-1000000), big.NewInt(1000000000000))
+    assert.NoError(t, err)
+    assert.Equal(t, int64(40), params.BP1.N)
+}
+
+/*
+TestGenericBitLengthBoundary checks that the bit-length of an interval is only
+accepted when 2^(N+1) <= order, for the orders of secp256k1 and of bn256 G1.
+*/
+func TestGenericBitLengthBoundary(t *testing.T) {
+    for _, g := range []group.Group{group.Secp256k1, group.BN256G1} {
+        order := g.Order()
+        // the largest N such that 2^(N+1) <= order
+        largest := int64(order.BitLen() - 2)
+        size := new(big.Int).Lsh(big.NewInt(1), uint(largest))
+        n, err := genericBitLength(size, order)
+        assert.NoError(t, err)
+        assert.Equal(t, largest, n)
+
+        _, err = genericBitLength(new(big.Int).Add(size, big.NewInt(1)), order)
+        assert.Error(t, err, "interval of size 2^N + 1 should be rejected when 2^(N+2) > order")
+        _, err = SetupGenericBigWithGroup(g, big.NewInt(0), new(big.Int).Add(size, big.NewInt(1)), DefaultDST)
+        assert.Error(t, err, "interval that may wrap around the order should be rejected")
+    }
+
+    // a verifier does not accept the bit-length chosen by the prover beyond the bound
+    params, err := SetupGenericBig(big.NewInt(0), big.NewInt(1000))
+    assert.NoError(t, err)
+    proof, err := ProveGeneric(big.NewInt(10), params)
+    assert.NoError(t, err)
+    params.BP1.N = int64(ORDER.BitLen() - 1)
+    params.BP2.N = params.BP1.N
+    _, err = VerifyGenericWithParams(proof, params, proof.V)
+    assert.Error(t, err, "bit-length with 2^(N+1) > order should be rejected by the verifier")
+}
+
+func TestProveGenericWithBlinding(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    x := new(big.Int).SetInt64(40)
+    gamma := new(big.Int).SetInt64(-5)
+    V, _ := params.BP1.Commit(x, bn.Mod(gamma, ORDER))
+    proof, err := ProveGenericWithBlinding(x, gamma, params)
+    assert.NoError(t, err)
+    ok, _ := VerifyGenericWithParams(proof, params, V)
+    assert.True(t, ok, "proof for an existing commitment should verify successfully")
+
+    _, err = ProveGenericWithBlinding(nil, gamma, params)
+    assert.Error(t, err, "missing secret should be rejected")
+}
+
+func TestSetupGenericInvalidInterval(t *testing.T) {
+    _, err := SetupGeneric(200, 18)
+    assert.Error(t, err, "range start greater than range end should be rejected")
+    _, err = SetupGeneric(18, 18)
+    assert.Error(t, err, "empty range should be rejected")
+    _, err = SetupGenericBig(nil, big.NewInt(18))
+    assert.Error(t, err, "missing range start should be rejected")
+    _, err = SetupGenericBig(big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), 256))
+    assert.Error(t, err, "range larger than the group order allows should be rejected")
+}
+
+func setupProveVerifyBig(t *testing.T, a, b, secret *big.Int) bool {
+    params, errSetup := SetupGenericBig(a, b)
+    if errSetup != nil {
+        t.Fatal(errSetup.Error())
+    }
+    proof, errProve := ProveGeneric(secret, params)
+    if errProve != nil {
+        t.Fatal(errProve.Error())
+    }
+    ok, _ := proof.Verify()
+    return ok
+}
+
+func TestGenericProofsAboutDifferentSecrets(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    // 10 satisfies the upper bound and 300 satisfies the lower bound
+    low, _ := ProveGeneric(new(big.Int).SetInt64(10), params)
+    high, _ := ProveGeneric(new(big.Int).SetInt64(300), params)
+    forged := ProofBPRP{V: low.V, A: low.A, B: low.B, P1: low.P1, P2: high.P2}
+    ok, _ := forged.Verify()
+    assert.False(t, ok, "halves proving statements about different secrets should not verify")
+    forged.V = high.V
+    ok, _ = forged.Verify()
+    assert.False(t, ok, "halves proving statements about different secrets should not verify")
+}
+
+func TestGenericProofWrongCommitment(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+    other, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+    proof.V = other.V
+    ok, _ := proof.Verify()
+    assert.False(t, ok, "proof should not verify for another commitment")
+}
+
+func TestVerifyGenericWithParams(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+
+    verifierParams, _ := SetupGeneric(18, 200)
+    ok, err := VerifyGenericWithParams(proof, verifierParams, proof.V)
+    assert.NoError(t, err)
+    assert.True(t, ok, "should verify")
+
+    // the interval stored in the proof is ignored
+    proof.A = new(big.Int).SetInt64(0)
+    proof.B = new(big.Int).SetInt64(256)
+    ok, _ = VerifyGenericWithParams(proof, verifierParams, proof.V)
+    assert.True(t, ok, "should verify with the interval of the verifier")
+
+    // a different interval of the verifier
+    otherParams, _ := SetupGeneric(21, 200)
+    ok, _ = VerifyGenericWithParams(proof, otherParams, proof.V)
+    assert.False(t, ok, "should not verify for another interval")
+
+    // a different commitment expected by the verifier
+    other, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+    ok, _ = VerifyGenericWithParams(proof, verifierParams, other.V)
+    assert.False(t, ok, "should not verify for another commitment")
+}
+
 func TestJsonEncodeDecodeBPRP(t *testing.T) {
     // Set up the range, [18, 200) in this case.
     // We want to prove that we are over 18, and less than 200 years old.
@@ -92,6 +245,10 @@ func TestJsonEncodeDecodeBPRP(t *testing.T) {
         t.FailNow()
     }
 
+    // The commitment is published once, for instance on a ledger, independently
+    // of the proofs
+    ledger := group.EncodeText(proof.V)
+
     // Encode the proof to JSON
     jsonEncoded, err := json.Marshal(proof)
     if err != nil {
@@ -107,13 +264,45 @@ func TestJsonEncodeDecodeBPRP(t *testing.T) {
         t.Fatal("decode error:", err)
     }
 
-    assert.Equal(t, proof, decodedProof, "should be equal")
+    // the points are compared by their encodings, which do not depend on their coordinates
+    reencoded, _ := json.Marshal(decodedProof)
+    assert.Equal(t, string(jsonEncoded), string(reencoded), "should be equal")
 
-    // Verify the proof
-    ok, errVerify := decodedProof.Verify()
+    // Verify the proof with the parameters of the verifier and the commitment
+    // it reads from the ledger, instead of the ones stored in the proof
+    verifierParams, _ := SetupGeneric(18, 200)
+    commitment, err := group.DecodeText(ledger)
+    if err != nil {
+        t.Fatal("decode error:", err)
+    }
+    ok, errVerify := VerifyGenericWithParams(decodedProof, verifierParams, commitment)
     if errVerify != nil {
         t.Errorf(errVerify.Error())
         t.FailNow()
     }
     assert.True(t, ok, "should verify")
 }
+
+func TestGenericWithGroups(t *testing.T) {
+    for _, g := range []group.Group{group.P256, group.BN256G1} {
+        params, err := SetupGenericBigWithGroup(g, big.NewInt(18), big.NewInt(200), DefaultDSTForGroup(g))
+        assert.NoError(t, err)
+        proof, err := ProveGeneric(new(big.Int).SetInt64(40), params)
+        assert.NoError(t, err, g.Name())
+        ok, err := VerifyGenericWithParams(proof, params, proof.V)
+        assert.NoError(t, err, g.Name())
+        assert.True(t, ok, "x within range should verify successfully over "+g.Name())
+
+        encoded, err := proof.MarshalBinary()
+        assert.NoError(t, err, g.Name())
+        var decoded ProofBPRP
+        assert.NoError(t, decoded.UnmarshalBinaryWithGroup(encoded, g), g.Name())
+        ok, _ = VerifyGenericWithParams(decoded, params, proof.V)
+        assert.True(t, ok, "decoded proof should verify over "+g.Name())
+
+        other, _ := SetupGeneric(18, 200)
+        ok, err = VerifyGenericWithParams(proof, other, proof.V)
+        assert.Error(t, err, "proof must belong to the group of the parameters")
+        assert.False(t, ok)
+    }
+}
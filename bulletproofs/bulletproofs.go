@@ -25,10 +25,53 @@ Asiacrypt 2008
 package bulletproofs
 
 import (
+    "github.com/ing-bank/zkrp/crypto/group"
     "github.com/ing-bank/zkrp/crypto/p256"
 )
 
+/*
+ORDER is the order of secp256k1, the group of the parameters that do not set
+another one.
+*/
 var ORDER = p256.CURVE.N
+
+/*
+SEEDH was the seed of the generators H, Gg and Hh.
+
+Deprecated: the generators are computed with HashToCurve and DefaultDST.
+*/
 var SEEDH = "BulletproofsDoesNotNeedTrustedSetupH"
+
+/*
+DefaultDST is the domain separation tag used to hash the generators to the curve,
+following the naming convention of Section 3.1 of RFC 9380. Applications can use
+their own tag with the WithDST variants of the Setup functions.
+*/
+const DefaultDST = dstPrefix + "secp256k1_XMD:SHA-256_SSWU_RO_"
+
+// dstPrefix is the prefix of the default tag of every group
+const dstPrefix = "ZKRP-BULLETPROOFS-V01-CS01-with-"
+
+/*
+DefaultDSTForGroup returns the default domain separation tag of the group g,
+which ends with the identifier of its hash-to-curve suite. It is DefaultDST for
+group.Secp256k1.
+*/
+func DefaultDSTForGroup(g group.Group) string {
+    return dstPrefix + g.HashSuite()
+}
+
+/*
+MAX_RANGE_END was the end of the largest range, 2^32.
+
+Deprecated: it no longer limits anything, ranges and secrets are *big.Int values
+whose bit-length is only bounded by the order of the group.
+*/
 var MAX_RANGE_END int64 = 4294967296 // 2**32
-var MAX_RANGE_END_EXPONENT = 32      // 2**32
+
+/*
+MAX_RANGE_END_EXPONENT was the bit-length of MAX_RANGE_END.
+
+Deprecated: it no longer limits anything, see MAX_RANGE_END.
+*/
+var MAX_RANGE_END_EXPONENT = 32 // 2**32
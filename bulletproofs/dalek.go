@@ -0,0 +1,798 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "bytes"
+    "crypto/rand"
+    "encoding/binary"
+    "errors"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/merlin"
+    "github.com/ing-bank/zkrp/crypto/ristretto255"
+    . "github.com/ing-bank/zkrp/util"
+    "golang.org/x/crypto/sha3"
+)
+
+/*
+This file implements the range proofs of the Rust crate bulletproofs of
+dalek-cryptography (https://github.com/dalek-cryptography/bulletproofs) over
+Ristretto255. The generators, the Merlin transcript and the binary encoding are
+the ones of the crate, so that a proof computed by one implementation verifies
+with the other:
+
+    - B is the Ristretto255 basepoint and B_blinding is the point of the SHA3-512
+      digest of the encoding of B (PedersenGens::default);
+    - the generators of party j are read from SHAKE256("GeneratorsChain" || label),
+      64 bytes per point, where label is 'G' or 'H' followed by j as a 32-bit
+      little-endian integer (BulletproofGens);
+    - the transcript is a Merlin transcript supplied by the caller;
+    - the proof is encoded as A || S || T1 || T2 || t_x || t_x_blinding || e_blinding
+      || L_0 || R_0 || ... || L_k || R_k || a || b, with 32-byte points and 32-byte
+      little-endian scalars (RangeProof::to_bytes).
+
+A 64-bit proof is encoded with 672 bytes.
+*/
+
+const (
+    dalekScalarSize        = 32
+    dalekRangeProofLabel   = "rangeproof v1"
+    dalekInnerProductLabel = "ipp v1"
+)
+
+/*
+DalekParams contains the generators of the range proofs of dalek: the Pedersen
+generators B and BBlinding, and N generators G[j] and H[j] for each of the M parties.
+*/
+type DalekParams struct {
+    N         int64
+    M         int64
+    B         group.Element
+    BBlinding group.Element
+    G         [][]group.Element
+    H         [][]group.Element
+}
+
+/*
+DalekRangeProof is a range proof in the format of dalek, for one value or for the
+aggregation of several values. The commitments are not part of the proof.
+*/
+type DalekRangeProof struct {
+    A                 group.Element
+    S                 group.Element
+    T1                group.Element
+    T2                group.Element
+    Tx                *big.Int
+    TxBlinding        *big.Int
+    EBlinding         *big.Int
+    InnerProductProof InnerProductProof
+}
+
+/*
+SetupDalek computes the generators to prove that up to m values lie in the
+interval [0, 2^n). The bit-length n must be 8, 16, 32 or 64 and m a power of 2.
+*/
+func SetupDalek(n, m int64) (DalekParams, error) {
+    if n != 8 && n != 16 && n != 32 && n != 64 {
+        return DalekParams{}, errors.New("bit-length must be 8, 16, 32 or 64")
+    }
+    if !IsPowerOfTwo(m) {
+        return DalekParams{}, errors.New("number of parties is not a power of 2")
+    }
+    basepoint := ristretto255.NewGenerator()
+    digest := sha3.Sum512(basepoint.Marshal())
+    blinding, err := new(ristretto255.Point).FromUniformBytes(digest[:])
+    if err != nil {
+        return DalekParams{}, err
+    }
+    params := DalekParams{N: n, M: m, B: group.NewRistretto255Element(basepoint),
+        BBlinding: group.NewRistretto255Element(blinding)}
+    params.G = make([][]group.Element, m)
+    params.H = make([][]group.Element, m)
+    for j := int64(0); j < m; j++ {
+        params.G[j], err = dalekGeneratorsChain('G', uint32(j), n)
+        if err != nil {
+            return DalekParams{}, err
+        }
+        params.H[j], err = dalekGeneratorsChain('H', uint32(j), n)
+        if err != nil {
+            return DalekParams{}, err
+        }
+    }
+    return params, nil
+}
+
+/*
+dalekGeneratorsChain returns the first n generators of the chain of a party.
+*/
+func dalekGeneratorsChain(kind byte, party uint32, n int64) ([]group.Element, error) {
+    label := make([]byte, 5)
+    label[0] = kind
+    binary.LittleEndian.PutUint32(label[1:], party)
+    shake := sha3.NewShake256()
+    _, _ = shake.Write([]byte("GeneratorsChain"))
+    _, _ = shake.Write(label)
+    generators := make([]group.Element, n)
+    uniform := make([]byte, ristretto255.UniformLength)
+    for i := range generators {
+        _, _ = shake.Read(uniform)
+        p, err := new(ristretto255.Point).FromUniformBytes(uniform)
+        if err != nil {
+            return nil, err
+        }
+        generators[i] = group.NewRistretto255Element(p)
+    }
+    return generators, nil
+}
+
+/*
+Commit returns the Pedersen commitment v.B + gamma.BBlinding.
+*/
+func (params DalekParams) Commit(v, gamma *big.Int) (group.Element, error) {
+    if params.B == nil || params.BBlinding == nil {
+        return nil, errors.New("generators are not initialized")
+    }
+    return group.SecretMultiScalarMult(params.B.Group(), []group.Element{params.B, params.BBlinding}, []group.Scalar{v, gamma})
+}
+
+/*
+generators returns the concatenation of the first n generators of the first m parties.
+*/
+func (params DalekParams) generators(m int64) ([]group.Element, []group.Element) {
+    G := make([]group.Element, 0, params.N*m)
+    H := make([]group.Element, 0, params.N*m)
+    for j := int64(0); j < m; j++ {
+        G = append(G, params.G[j]...)
+        H = append(H, params.H[j]...)
+    }
+    return G, H
+}
+
+/*
+ProveDalek computes a range proof of the secrets, each lower than 2^N, with
+random blinding factors, and returns the openings of the commitments. The
+number of secrets must be a power of 2 no larger than M. The statement is
+appended to the transcript, which must be in the same state for the verifier.
+*/
+func ProveDalek(transcript *merlin.Transcript, secrets []*big.Int, params DalekParams) (DalekRangeProof, []Opening, error) {
+    return ProveDalekWithRand(rand.Reader, transcript, secrets, params)
+}
+
+/*
+ProveDalekWithRand is ProveDalek with the randomness read from random.
+*/
+func ProveDalekWithRand(random io.Reader, transcript *merlin.Transcript, secrets []*big.Int, params DalekParams) (DalekRangeProof, []Opening, error) {
+    if params.B == nil {
+        return DalekRangeProof{}, nil, errors.New("generators are not initialized")
+    }
+    order := params.B.Group().Order()
+    blindings := make([]*big.Int, len(secrets))
+    for j := range blindings {
+        var err error
+        blindings[j], err = RandomScalar(random, order)
+        if err != nil {
+            return DalekRangeProof{}, nil, err
+        }
+    }
+    proof, V, err := proveDalek(random, transcript, secrets, blindings, params)
+    if err != nil {
+        return DalekRangeProof{}, nil, err
+    }
+    openings := make([]Opening, len(V))
+    for j := range V {
+        openings[j] = Opening{V: V[j], Gamma: blindings[j]}
+    }
+    return proof, openings, nil
+}
+
+/*
+ProveDalekWithBlindings computes a range proof of the secrets committed with the
+given blinding factors, and returns the commitments.
+*/
+func ProveDalekWithBlindings(transcript *merlin.Transcript, secrets, blindings []*big.Int, params DalekParams) (DalekRangeProof, []group.Element, error) {
+    return proveDalek(rand.Reader, transcript, secrets, blindings, params)
+}
+
+func proveDalek(random io.Reader, transcript *merlin.Transcript, secrets, blindings []*big.Int, params DalekParams) (DalekRangeProof, []group.Element, error) {
+    var proof DalekRangeProof
+    m := int64(len(secrets))
+    n := params.N
+    if params.B == nil || params.BBlinding == nil {
+        return proof, nil, errors.New("generators are not initialized")
+    }
+    if !IsPowerOfTwo(m) || m > params.M {
+        return proof, nil, errors.New("number of values is not a power of 2 no larger than the number of parties")
+    }
+    if int64(len(blindings)) != m {
+        return proof, nil, errors.New("number of blinding factors does not match the number of values")
+    }
+    for j := range secrets {
+        if secrets[j] == nil || secrets[j].Sign() < 0 || blindings[j] == nil {
+            return proof, nil, errors.New("secrets must be non-negative and blinding factors defined")
+        }
+    }
+    grp := params.B.Group()
+    order := grp.Order()
+    nm := n * m
+    G, H := params.generators(m)
+
+    dalekRangeProofDomainSep(transcript, n, m)
+    V := make([]group.Element, m)
+    for j := range secrets {
+        var err error
+        V[j], err = params.Commit(secrets[j], blindings[j])
+        if err != nil {
+            return proof, nil, err
+        }
+        transcript.AppendMessage("V", V[j].Encode())
+    }
+
+    // A = alpha.B_blinding + sum_i (aL_i ? G_i : -H_i)
+    aL := make([]*big.Int, nm)
+    aR := make([]*big.Int, nm)
+    for j := int64(0); j < m; j++ {
+        for i := int64(0); i < n; i++ {
+            bit := int64(secrets[j].Bit(int(i)))
+            aL[j*n+i] = big.NewInt(bit)
+            aR[j*n+i] = new(big.Int).Mod(big.NewInt(bit-1), order)
+        }
+    }
+    alpha, err := RandomScalar(random, order)
+    if err != nil {
+        return proof, nil, err
+    }
+    proof.A, err = dalekVectorCommit(grp, params.BBlinding, alpha, G, H, aL, aR)
+    if err != nil {
+        return proof, nil, err
+    }
+
+    // S = rho.B_blinding + <sL, G> + <sR, H>
+    sL, err := sampleRandomVector(random, nm, order)
+    if err != nil {
+        return proof, nil, err
+    }
+    sR, err := sampleRandomVector(random, nm, order)
+    if err != nil {
+        return proof, nil, err
+    }
+    rho, err := RandomScalar(random, order)
+    if err != nil {
+        return proof, nil, err
+    }
+    proof.S, err = dalekVectorCommit(grp, params.BBlinding, rho, G, H, sL, sR)
+    if err != nil {
+        return proof, nil, err
+    }
+
+    transcript.AppendMessage("A", proof.A.Encode())
+    transcript.AppendMessage("S", proof.S.Encode())
+    y := dalekChallengeScalar(transcript, "y", order)
+    z := dalekChallengeScalar(transcript, "z", order)
+
+    // l(X) = l0 + l1.X and r(X) = r0 + r1.X, where for the bit i of party j
+    // l0 = aL - z, l1 = sL, r0 = y^(jn+i).(aR + z) + z^(2+j).2^i and r1 = y^(jn+i).sR
+    vz := constantScalarVector(order, z, nm)
+    vy := powerScalarVector(order, y, nm)
+    zeta := make([]*big.Int, 0, nm)
+    p2n := powerOf(big.NewInt(2), n, order)
+    zj := new(big.Int).Mul(z, z)
+    for j := int64(0); j < m; j++ {
+        zj2n, _ := vectorScalarMul(p2n, zj, order)
+        zeta = append(zeta, zj2n...)
+        zj = new(big.Int).Mod(new(big.Int).Mul(zj, z), order)
+    }
+    l0 := newScalarVector(order, aL).sub(vz)
+    l1 := newScalarVector(order, sL)
+    r0 := vy.mul(newScalarVector(order, aR).add(vz)).add(newScalarVector(order, zeta))
+    r1 := vy.mul(newScalarVector(order, sR))
+
+    // t1 = <l0, r1> + <l1, r0> and t2 = <l1, r1>
+    t1 := new(big.Int).Add(l0.innerProduct(r1), l1.innerProduct(r0))
+    t1.Mod(t1, order)
+    t2 := l1.innerProduct(r1)
+
+    tau1, err := RandomScalar(random, order)
+    if err != nil {
+        return proof, nil, err
+    }
+    tau2, err := RandomScalar(random, order)
+    if err != nil {
+        return proof, nil, err
+    }
+    proof.T1, err = params.Commit(t1, tau1)
+    if err != nil {
+        return proof, nil, err
+    }
+    proof.T2, err = params.Commit(t2, tau2)
+    if err != nil {
+        return proof, nil, err
+    }
+
+    transcript.AppendMessage("T_1", proof.T1.Encode())
+    transcript.AppendMessage("T_2", proof.T2.Encode())
+    x := dalekChallengeScalar(transcript, "x", order)
+
+    l := l0.add(l1.scale(x))
+    r := r0.add(r1.scale(x))
+    proof.Tx = l.innerProduct(r)
+
+    // t_x_blinding = tau2.x^2 + tau1.x + sum_j z^(2+j).gamma_j and e_blinding = alpha + rho.x
+    zj = new(big.Int).Mul(z, z)
+    factors := append([]*big.Int{tau2, tau1}, blindings...)
+    coefficients := []*big.Int{new(big.Int).Mul(x, x), x}
+    for range blindings {
+        coefficients = append(coefficients, zj)
+        zj = new(big.Int).Mod(new(big.Int).Mul(zj, z), order)
+    }
+    proof.TxBlinding = secretCombination(order, factors, coefficients)
+    proof.EBlinding = secretCombination(order, []*big.Int{alpha, rho}, []*big.Int{big.NewInt(1), x})
+
+    transcript.AppendMessage("t_x", dalekScalarBytes(proof.Tx))
+    transcript.AppendMessage("t_x_blinding", dalekScalarBytes(proof.TxBlinding))
+    transcript.AppendMessage("e_blinding", dalekScalarBytes(proof.EBlinding))
+    w := dalekChallengeScalar(transcript, "w", order)
+    Q := params.B.ScalarMult(w)
+
+    yInv := new(big.Int).ModInverse(y, order)
+    hFactors := powerOf(yInv, nm, order)
+    proof.InnerProductProof, err = proveDalekInnerProduct(transcript, Q, hFactors, G, H, l, r)
+    if err != nil {
+        return proof, nil, err
+    }
+    return proof, V, nil
+}
+
+/*
+dalekVectorCommit returns blinding.base + <a, G> + <b, H>.
+*/
+func dalekVectorCommit(grp group.Group, base group.Element, blinding *big.Int, G, H []group.Element, a, b []*big.Int) (group.Element, error) {
+    points := make([]group.Element, 0, 1+len(G)+len(H))
+    scalars := make([]group.Scalar, 0, 1+len(a)+len(b))
+    points = append(append(append(points, base), G...), H...)
+    scalars = append(scalars, blinding)
+    scalars = append(append(scalars, a...), b...)
+    return group.SecretMultiScalarMult(grp, points, scalars)
+}
+
+/*
+proveDalekInnerProduct proves that <a, b> is the scalar product committed in
+<a, G> + <b, H'> + <a, b>.Q, where H'_i = hFactors_i.H_i. The factors are only
+applied in the first round, when the vectors of generators are folded.
+*/
+func proveDalekInnerProduct(transcript *merlin.Transcript, Q group.Element, hFactors []*big.Int, G, H []group.Element, a, b scalarVector) (InnerProductProof, error) {
+    grp := Q.Group()
+    order := grp.Order()
+    n := len(G)
+    if n == 0 || !IsPowerOfTwo(int64(n)) || len(H) != n || a.size() != n || b.size() != n || len(hFactors) != n {
+        return InnerProductProof{}, errors.New("invalid lengths of the inner product vectors")
+    }
+    proof := InnerProductProof{N: int64(n)}
+    transcript.AppendMessage("dom-sep", []byte(dalekInnerProductLabel))
+    transcript.AppendUint64("n", uint64(n))
+
+    G = append([]group.Element{}, G...)
+    H = append([]group.Element{}, H...)
+    for n > 1 {
+        n /= 2
+        aL, aR, bL, bR := a.slice(0, n), a.slice(n, 2*n), b.slice(0, n), b.slice(n, 2*n)
+        h := newScalarVector(order, hFactors)
+        cL := aL.innerProduct(bR)
+        cR := aR.innerProduct(bL)
+
+        // L = <aL, G_R> + <bR, H'_L> + cL.Q and R = <aR, G_L> + <bL, H'_R> + cR.Q
+        pointsL := make([]group.Element, 0, 2*n+1)
+        pointsR := make([]group.Element, 0, 2*n+1)
+        scalarsL := make([]group.Scalar, 0, 2*n+1)
+        scalarsR := make([]group.Scalar, 0, 2*n+1)
+        pointsL = append(append(append(pointsL, G[n:2*n]...), H[:n]...), Q)
+        pointsR = append(append(append(pointsR, G[:n]...), H[n:2*n]...), Q)
+        for _, s := range append(aL.bigs(), bR.mul(h.slice(0, n)).bigs()...) {
+            scalarsL = append(scalarsL, s)
+        }
+        for _, s := range append(aR.bigs(), bL.mul(h.slice(n, 2*n)).bigs()...) {
+            scalarsR = append(scalarsR, s)
+        }
+        scalarsL = append(scalarsL, cL)
+        scalarsR = append(scalarsR, cR)
+        L, err := group.SecretMultiScalarMult(grp, pointsL, scalarsL)
+        if err != nil {
+            return InnerProductProof{}, err
+        }
+        R, err := group.SecretMultiScalarMult(grp, pointsR, scalarsR)
+        if err != nil {
+            return InnerProductProof{}, err
+        }
+        proof.Ls = append(proof.Ls, L)
+        proof.Rs = append(proof.Rs, R)
+
+        transcript.AppendMessage("L", L.Encode())
+        transcript.AppendMessage("R", R.Encode())
+        u := dalekChallengeScalar(transcript, "u", order)
+        uInv := new(big.Int).ModInverse(u, order)
+
+        // a' = u.aL + u^-1.aR, b' = u^-1.bL + u.bR, G' = u^-1.G_L + u.G_R and H' = u.H'_L + u^-1.H'_R
+        a = aL.scale(u).add(aR.scale(uInv))
+        b = bL.scale(uInv).add(bR.scale(u))
+        for i := 0; i < n; i++ {
+            G[i], err = grp.MultiScalarMult([]group.Element{G[i], G[n+i]}, []group.Scalar{uInv, u})
+            if err != nil {
+                return InnerProductProof{}, err
+            }
+            H[i], err = grp.MultiScalarMult([]group.Element{H[i], H[n+i]},
+                []group.Scalar{new(big.Int).Mul(u, hFactors[i]), new(big.Int).Mul(uInv, hFactors[n+i])})
+            if err != nil {
+                return InnerProductProof{}, err
+            }
+        }
+        G, H = G[:n], H[:n]
+        hFactors = powerOf(big.NewInt(1), int64(n), order)
+    }
+    proof.A = a.bigs()[0]
+    proof.B = b.bigs()[0]
+    return proof, nil
+}
+
+/*
+VerifyDalek verifies the range proof of the values committed in V, which must be
+appended to a transcript in the same state as the one of the prover. It returns
+an error when the proof is malformed.
+*/
+func VerifyDalek(transcript *merlin.Transcript, proof DalekRangeProof, params DalekParams, V []group.Element) (bool, error) {
+    m := int64(len(V))
+    n := params.N
+    if params.B == nil || params.BBlinding == nil {
+        return false, errors.New("generators are not initialized")
+    }
+    if !IsPowerOfTwo(m) || m > params.M {
+        return false, errors.New("number of values is not a power of 2 no larger than the number of parties")
+    }
+    ipp := proof.InnerProductProof
+    if proof.A == nil || proof.S == nil || proof.T1 == nil || proof.T2 == nil || proof.Tx == nil ||
+        proof.TxBlinding == nil || proof.EBlinding == nil || ipp.A == nil || ipp.B == nil {
+        return false, errors.New("proof is incomplete")
+    }
+    grp := params.B.Group()
+    order := grp.Order()
+    nm := n * m
+    lgN := len(ipp.Ls)
+    if lgN >= 32 || len(ipp.Rs) != lgN || nm != int64(1)<<uint(lgN) {
+        return false, errors.New("number of inner product rounds does not match the number of generators")
+    }
+    for _, e := range append(append([]group.Element{proof.A, proof.S, proof.T1, proof.T2}, ipp.Ls...), append(ipp.Rs, V...)...) {
+        if e == nil || e.Group() != grp {
+            return false, errors.New("point is not an element of the group of the generators")
+        }
+    }
+
+    dalekRangeProofDomainSep(transcript, n, m)
+    for j := range V {
+        transcript.AppendMessage("V", V[j].Encode())
+    }
+    err := dalekValidateAndAppend(transcript, "A", proof.A)
+    if err != nil {
+        return false, err
+    }
+    err = dalekValidateAndAppend(transcript, "S", proof.S)
+    if err != nil {
+        return false, err
+    }
+    y := dalekChallengeScalar(transcript, "y", order)
+    z := dalekChallengeScalar(transcript, "z", order)
+    err = dalekValidateAndAppend(transcript, "T_1", proof.T1)
+    if err != nil {
+        return false, err
+    }
+    err = dalekValidateAndAppend(transcript, "T_2", proof.T2)
+    if err != nil {
+        return false, err
+    }
+    x := dalekChallengeScalar(transcript, "x", order)
+    transcript.AppendMessage("t_x", dalekScalarBytes(proof.Tx))
+    transcript.AppendMessage("t_x_blinding", dalekScalarBytes(proof.TxBlinding))
+    transcript.AppendMessage("e_blinding", dalekScalarBytes(proof.EBlinding))
+    w := dalekChallengeScalar(transcript, "w", order)
+
+    // challenges u_k of the inner product proof and the scalars s_i of its final check
+    transcript.AppendMessage("dom-sep", []byte(dalekInnerProductLabel))
+    transcript.AppendUint64("n", uint64(nm))
+    uSq := make([]*big.Int, lgN)
+    uInvSq := make([]*big.Int, lgN)
+    s0 := big.NewInt(1)
+    for k := 0; k < lgN; k++ {
+        err = dalekValidateAndAppend(transcript, "L", ipp.Ls[k])
+        if err != nil {
+            return false, err
+        }
+        err = dalekValidateAndAppend(transcript, "R", ipp.Rs[k])
+        if err != nil {
+            return false, err
+        }
+        u := dalekChallengeScalar(transcript, "u", order)
+        uInv := new(big.Int).ModInverse(u, order)
+        uSq[k] = new(big.Int).Mod(new(big.Int).Mul(u, u), order)
+        uInvSq[k] = new(big.Int).Mod(new(big.Int).Mul(uInv, uInv), order)
+        s0.Mul(s0, uInv)
+        s0.Mod(s0, order)
+    }
+    s := make([]*big.Int, nm)
+    s[0] = s0
+    for i := int64(1); i < nm; i++ {
+        // the bit lgI of i is its highest bit, k = 2^lgI
+        lgI := 63
+        for i>>uint(lgI) == 0 {
+            lgI--
+        }
+        k := int64(1) << uint(lgI)
+        s[i] = new(big.Int).Mul(s[i-k], uSq[lgN-1-lgI])
+        s[i].Mod(s[i], order)
+    }
+
+    // every check is combined with the random factor c in a single multi-exponentiation
+    c, err := RandomScalar(rand.Reader, order)
+    if err != nil {
+        return false, err
+    }
+    zz := new(big.Int).Mod(new(big.Int).Mul(z, z), order)
+    minusZ := new(big.Int).Sub(order, z)
+    yInv := new(big.Int).ModInverse(y, order)
+    points := make([]group.Element, 0, 2*nm+int64(2*lgN)+m+6)
+    scalars := make([]group.Scalar, 0, cap(points))
+    points = append(points, proof.A, proof.S, proof.T1, proof.T2)
+    xx := new(big.Int).Mul(x, x)
+    scalars = append(scalars, big.NewInt(1), x, new(big.Int).Mul(c, x), new(big.Int).Mul(c, xx))
+    points = append(append(points, ipp.Ls...), ipp.Rs...)
+    for k := range uSq {
+        scalars = append(scalars, uSq[k])
+    }
+    for k := range uInvSq {
+        scalars = append(scalars, uInvSq[k])
+    }
+
+    // B_blinding: -e_blinding - c.t_x_blinding
+    bBlinding := new(big.Int).Mul(c, proof.TxBlinding)
+    bBlinding.Add(bBlinding, proof.EBlinding)
+    bBlinding.Neg(bBlinding)
+    points = append(points, params.BBlinding)
+    scalars = append(scalars, bBlinding)
+
+    // B: w.(t_x - a.b) + c.(delta(y, z) - t_x)
+    ab := new(big.Int).Mul(ipp.A, ipp.B)
+    basepoint := new(big.Int).Sub(proof.Tx, ab)
+    basepoint.Mul(basepoint, w)
+    deltaMinusTx := new(big.Int).Sub(dalekDelta(y, z, n, m, order), proof.Tx)
+    basepoint.Add(basepoint, deltaMinusTx.Mul(deltaMinusTx, c))
+    points = append(points, params.B)
+    scalars = append(scalars, basepoint)
+
+    // G_i: -z - a.s_i and H_i: z + y^-i.(z^(2+j).2^(i mod n) - b.s_(nm-1-i))
+    G, H := params.generators(m)
+    points = append(append(points, G...), H...)
+    for i := int64(0); i < nm; i++ {
+        g := new(big.Int).Mul(ipp.A, s[i])
+        scalars = append(scalars, g.Sub(minusZ, g))
+    }
+    yi := big.NewInt(1)
+    zj := new(big.Int).Set(zz)
+    for j := int64(0); j < m; j++ {
+        twoi := big.NewInt(1)
+        for i := int64(0); i < n; i++ {
+            k := j*n + i
+            h := new(big.Int).Mul(ipp.B, s[nm-1-k])
+            h.Sub(new(big.Int).Mul(zj, twoi), h)
+            h.Mul(h, yi)
+            h.Add(h, z)
+            scalars = append(scalars, h.Mod(h, order))
+            yi = new(big.Int).Mod(new(big.Int).Mul(yi, yInv), order)
+            twoi = new(big.Int).Lsh(twoi, 1)
+        }
+        zj = new(big.Int).Mod(new(big.Int).Mul(zj, z), order)
+    }
+
+    // V_j: c.z^(2+j)
+    points = append(points, V...)
+    zj = new(big.Int).Mul(c, zz)
+    for j := int64(0); j < m; j++ {
+        scalars = append(scalars, new(big.Int).Mod(zj, order))
+        zj = new(big.Int).Mod(new(big.Int).Mul(zj, z), order)
+    }
+
+    result, err := grp.MultiScalarMult(points, scalars)
+    if err != nil {
+        return false, err
+    }
+    return result.IsIdentity(), nil
+}
+
+/*
+dalekDelta returns delta(y, z) = (z - z^2).<1, y^nm> - sum_j z^(3+j).<1, 2^n>.
+*/
+func dalekDelta(y, z *big.Int, n, m int64, order *big.Int) *big.Int {
+    sumY := new(big.Int)
+    yi := big.NewInt(1)
+    for i := int64(0); i < n*m; i++ {
+        sumY.Add(sumY, yi)
+        yi = new(big.Int).Mod(new(big.Int).Mul(yi, y), order)
+    }
+    zz := new(big.Int).Mul(z, z)
+    result := new(big.Int).Sub(z, zz)
+    result.Mul(result, sumY)
+    sum2 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(n)), big.NewInt(1))
+    zj := new(big.Int).Mod(new(big.Int).Mul(zz, z), order)
+    for j := int64(0); j < m; j++ {
+        result.Sub(result, new(big.Int).Mul(zj, sum2))
+        zj = new(big.Int).Mod(new(big.Int).Mul(zj, z), order)
+    }
+    return result.Mod(result, order)
+}
+
+/*
+dalekRangeProofDomainSep appends the statement of the range proof to the transcript.
+*/
+func dalekRangeProofDomainSep(transcript *merlin.Transcript, n, m int64) {
+    transcript.AppendMessage("dom-sep", []byte(dalekRangeProofLabel))
+    transcript.AppendUint64("n", uint64(n))
+    transcript.AppendUint64("m", uint64(m))
+}
+
+/*
+dalekValidateAndAppend appends the point to the transcript, and fails if it is
+the identity.
+*/
+func dalekValidateAndAppend(transcript *merlin.Transcript, label string, p group.Element) error {
+    if p.IsIdentity() {
+        return errors.New("point " + label + " of the proof is the identity")
+    }
+    transcript.AppendMessage(label, p.Encode())
+    return nil
+}
+
+/*
+dalekChallengeScalar reads 64 bytes from the transcript and reduces them, as a
+little-endian integer, modulo the order.
+*/
+func dalekChallengeScalar(transcript *merlin.Transcript, label string, order *big.Int) *big.Int {
+    result := dalekScalar(transcript.ChallengeBytes(label, 64))
+    return result.Mod(result, order)
+}
+
+/*
+dalekScalarBytes returns the 32-byte little-endian encoding of a reduced scalar.
+*/
+func dalekScalarBytes(s *big.Int) []byte {
+    be := s.Bytes()
+    result := make([]byte, dalekScalarSize)
+    for i := range be {
+        result[i] = be[len(be)-1-i]
+    }
+    return result
+}
+
+/*
+dalekScalar returns the integer of a little-endian encoding.
+*/
+func dalekScalar(data []byte) *big.Int {
+    be := make([]byte, len(data))
+    for i := range data {
+        be[i] = data[len(data)-1-i]
+    }
+    return new(big.Int).SetBytes(be)
+}
+
+/*
+MarshalBinary encodes the proof like RangeProof::to_bytes of dalek.
+*/
+func (proof DalekRangeProof) MarshalBinary() ([]byte, error) {
+    ipp := proof.InnerProductProof
+    if proof.A == nil || proof.S == nil || proof.T1 == nil || proof.T2 == nil || proof.Tx == nil ||
+        proof.TxBlinding == nil || proof.EBlinding == nil || ipp.A == nil || ipp.B == nil ||
+        len(ipp.Ls) != len(ipp.Rs) {
+        return nil, errors.New("proof is incomplete")
+    }
+    order := ristretto255.Order
+    var buffer bytes.Buffer
+    for _, p := range []group.Element{proof.A, proof.S, proof.T1, proof.T2} {
+        buffer.Write(p.Encode())
+    }
+    for _, s := range []*big.Int{proof.Tx, proof.TxBlinding, proof.EBlinding} {
+        if s.Sign() < 0 || s.Cmp(order) >= 0 {
+            return nil, errors.New("scalar is not reduced")
+        }
+        buffer.Write(dalekScalarBytes(s))
+    }
+    for k := range ipp.Ls {
+        if ipp.Ls[k] == nil || ipp.Rs[k] == nil {
+            return nil, errors.New("proof is incomplete")
+        }
+        buffer.Write(ipp.Ls[k].Encode())
+        buffer.Write(ipp.Rs[k].Encode())
+    }
+    for _, s := range []*big.Int{ipp.A, ipp.B} {
+        if s.Sign() < 0 || s.Cmp(order) >= 0 {
+            return nil, errors.New("scalar is not reduced")
+        }
+        buffer.Write(dalekScalarBytes(s))
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes a proof encoded like RangeProof::to_bytes of dalek. The
+points must be canonical encodings of Ristretto255 elements and the scalars must
+be reduced.
+*/
+func (proof *DalekRangeProof) UnmarshalBinary(data []byte) error {
+    const size = ristretto255.EncodingLength
+    if len(data)%size != 0 || len(data) < 7*size {
+        return ErrInvalidEncodingLength
+    }
+    elements := len(data)/size - 7
+    if elements < 2 || elements%2 != 0 || (elements-2)/2 >= 32 {
+        return ErrInvalidEncodingLength
+    }
+    var (
+        result DalekRangeProof
+        err    error
+    )
+    readPoint := func() group.Element {
+        if err != nil {
+            return nil
+        }
+        var p group.Element
+        p, err = group.Ristretto255.DecodeElement(data[:size])
+        if err != nil {
+            err = ErrInvalidPointEncoding
+        }
+        data = data[size:]
+        return p
+    }
+    readScalar := func() *big.Int {
+        if err != nil {
+            return nil
+        }
+        s := dalekScalar(data[:size])
+        if s.Cmp(ristretto255.Order) >= 0 {
+            err = ErrInvalidScalarEncoding
+        }
+        data = data[size:]
+        return s
+    }
+    result.A = readPoint()
+    result.S = readPoint()
+    result.T1 = readPoint()
+    result.T2 = readPoint()
+    result.Tx = readScalar()
+    result.TxBlinding = readScalar()
+    result.EBlinding = readScalar()
+    rounds := (elements - 2) / 2
+    ipp := InnerProductProof{N: int64(1) << uint(rounds)}
+    for k := 0; k < rounds; k++ {
+        ipp.Ls = append(ipp.Ls, readPoint())
+        ipp.Rs = append(ipp.Rs, readPoint())
+    }
+    ipp.A = readScalar()
+    ipp.B = readScalar()
+    if err != nil {
+        return err
+    }
+    result.InnerProductProof = ipp
+    *proof = result
+    return nil
+}
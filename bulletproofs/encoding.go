@@ -0,0 +1,436 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/util/byteconversion"
+)
+
+/*
+The binary encoding contains only the data the verifier needs, the setup
+parameters are never encoded. Points are encoded with Element.Encode of their
+group, for secp256k1 with 33 bytes using the SEC1 compressed format, where the
+point at infinity is encoded as 33 zero bytes. Scalars are encoded in big-endian
+order with the number of bytes of the group order, 32 bytes for secp256k1, and
+must be lower than the order. The encoding is canonical: decoding fails on
+trailing data, scalars that are not reduced and points that are not on the curve.
+The group is not encoded either: UnmarshalBinary decodes proofs over secp256k1
+and UnmarshalBinaryWithGroup proofs over any other group.
+
+    InnerProductProof     = rounds (1 byte) || (L_i || R_i) for each round || a || b
+    BulletProof           = V || A || S || T1 || T2 || taux || mu || tprime || InnerProductProof
+    AggregatedBulletProof = m (2 bytes) || V_1 ... V_m || A || ... || InnerProductProof
+    ProofBPRP             = V || P1 without V || P2 without V
+
+A 64-bit BulletProof over secp256k1 is encoded with 722 bytes.
+*/
+const (
+    // pointSize and scalarSize are the sizes of the encodings for secp256k1
+    pointSize  = 33
+    scalarSize = 32
+)
+
+var (
+    ErrInvalidEncodingLength = errors.New("invalid encoding length")
+    ErrInvalidPointEncoding  = errors.New("invalid point encoding")
+    ErrInvalidScalarEncoding = errors.New("scalar is not canonical")
+)
+
+/*
+MarshalBinary encodes the inner product proof.
+*/
+func (proof InnerProductProof) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    g := group.Secp256k1
+    if len(proof.Ls) > 0 && proof.Ls[0] != nil {
+        g = proof.Ls[0].Group()
+    }
+    err := proof.writeTo(&buffer, g.Order())
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the inner product proof over secp256k1. The length N of
+the vectors is computed from the number of rounds.
+*/
+func (proof *InnerProductProof) UnmarshalBinary(data []byte) error {
+    return proof.UnmarshalBinaryWithGroup(data, group.Secp256k1)
+}
+
+/*
+UnmarshalBinaryWithGroup decodes the inner product proof over the group g.
+*/
+func (proof *InnerProductProof) UnmarshalBinaryWithGroup(data []byte, g group.Group) error {
+    r := newProofReader(data, g)
+    proof.readFrom(r)
+    return r.finish()
+}
+
+/*
+MarshalBinary encodes the BulletProof. The setup parameters are not encoded.
+*/
+func (proof BulletProof) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    err := writePoint(&buffer, proof.V)
+    if err != nil {
+        return nil, err
+    }
+    err = proof.writeTo(&buffer, proof.Params.group().Order())
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the BulletProof over secp256k1. Since the setup parameters
+are not part of the encoding, the proof must be checked using VerifyWithParams.
+*/
+func (proof *BulletProof) UnmarshalBinary(data []byte) error {
+    return proof.UnmarshalBinaryWithGroup(data, group.Secp256k1)
+}
+
+/*
+UnmarshalBinaryWithGroup decodes the BulletProof over the group g, which is
+stored in the otherwise empty parameters of the proof.
+*/
+func (proof *BulletProof) UnmarshalBinaryWithGroup(data []byte, g group.Group) error {
+    r := newProofReader(data, g)
+    *proof = BulletProof{Params: BulletProofSetupParams{Group: g}}
+    proof.V = r.point()
+    proof.readFrom(r)
+    return r.finish()
+}
+
+/*
+MarshalBinary encodes the aggregated BulletProof. The setup parameters are not encoded.
+*/
+func (proof AggregatedBulletProof) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    if len(proof.V) == 0 || len(proof.V) > 0xffff {
+        return nil, errors.New("invalid number of aggregated values")
+    }
+    m := make([]byte, 2)
+    binary.BigEndian.PutUint16(m, uint16(len(proof.V)))
+    buffer.Write(m)
+    for i := range proof.V {
+        err := writePoint(&buffer, proof.V[i])
+        if err != nil {
+            return nil, err
+        }
+    }
+    bp := BulletProof{A: proof.A, S: proof.S, T1: proof.T1, T2: proof.T2,
+        Taux: proof.Taux, Mu: proof.Mu, Tprime: proof.Tprime, InnerProductProof: proof.InnerProductProof}
+    err := bp.writeTo(&buffer, proof.Params.group().Order())
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the aggregated BulletProof over secp256k1. Since the setup
+parameters are not part of the encoding, the proof must be checked using
+VerifyAggregatedWithParams.
+*/
+func (proof *AggregatedBulletProof) UnmarshalBinary(data []byte) error {
+    return proof.UnmarshalBinaryWithGroup(data, group.Secp256k1)
+}
+
+/*
+UnmarshalBinaryWithGroup decodes the aggregated BulletProof over the group g.
+*/
+func (proof *AggregatedBulletProof) UnmarshalBinaryWithGroup(data []byte, g group.Group) error {
+    r := newProofReader(data, g)
+    *proof = AggregatedBulletProof{Params: BulletProofSetupParams{Group: g}}
+    m := r.next(2)
+    if r.err != nil {
+        return r.err
+    }
+    count := int(binary.BigEndian.Uint16(m))
+    if count == 0 {
+        return errors.New("invalid number of aggregated values")
+    }
+    proof.V = make([]group.Element, count)
+    for i := range proof.V {
+        proof.V[i] = r.point()
+    }
+    var bp BulletProof
+    bp.readFrom(r)
+    proof.A, proof.S, proof.T1, proof.T2 = bp.A, bp.S, bp.T1, bp.T2
+    proof.Taux, proof.Mu, proof.Tprime = bp.Taux, bp.Mu, bp.Tprime
+    proof.InnerProductProof = bp.InnerProductProof
+    return r.finish()
+}
+
+/*
+MarshalBinary encodes the generic range proof. The commitments of both
+BulletProofs are not encoded, since the verifier computes them from V. The
+interval and the setup parameters are not encoded either.
+*/
+func (proof ProofBPRP) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    err := writePoint(&buffer, proof.V)
+    if err != nil {
+        return nil, err
+    }
+    order := proof.P1.Params.group().Order()
+    err = proof.P1.writeTo(&buffer, order)
+    if err != nil {
+        return nil, err
+    }
+    err = proof.P2.writeTo(&buffer, order)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the generic range proof over secp256k1. Since the interval
+and the setup parameters are not part of the encoding, the proof must be checked
+using VerifyGenericWithParams.
+*/
+func (proof *ProofBPRP) UnmarshalBinary(data []byte) error {
+    return proof.UnmarshalBinaryWithGroup(data, group.Secp256k1)
+}
+
+/*
+UnmarshalBinaryWithGroup decodes the generic range proof over the group g.
+*/
+func (proof *ProofBPRP) UnmarshalBinaryWithGroup(data []byte, g group.Group) error {
+    r := newProofReader(data, g)
+    *proof = ProofBPRP{}
+    proof.P1.Params.Group = g
+    proof.P2.Params.Group = g
+    proof.V = r.point()
+    proof.P1.readFrom(r)
+    proof.P2.readFrom(r)
+    return r.finish()
+}
+
+/*
+writeTo writes every field of the BulletProof, except the commitment V. The
+scalars must be lower than order.
+*/
+func (proof *BulletProof) writeTo(buffer *bytes.Buffer, order *big.Int) error {
+    points := []group.Element{proof.A, proof.S, proof.T1, proof.T2}
+    for i := range points {
+        err := writePoint(buffer, points[i])
+        if err != nil {
+            return err
+        }
+    }
+    scalars := []*big.Int{proof.Taux, proof.Mu, proof.Tprime}
+    for i := range scalars {
+        err := writeScalar(buffer, scalars[i], order)
+        if err != nil {
+            return err
+        }
+    }
+    return proof.InnerProductProof.writeTo(buffer, order)
+}
+
+/*
+readFrom reads every field of the BulletProof, except the commitment V.
+*/
+func (proof *BulletProof) readFrom(r *proofReader) {
+    proof.A = r.point()
+    proof.S = r.point()
+    proof.T1 = r.point()
+    proof.T2 = r.point()
+    proof.Taux = r.scalar()
+    proof.Mu = r.scalar()
+    proof.Tprime = r.scalar()
+    proof.InnerProductProof.readFrom(r)
+}
+
+/*
+writeTo writes the number of rounds, the vectors L and R and the final scalars,
+which must be lower than order.
+*/
+func (proof *InnerProductProof) writeTo(buffer *bytes.Buffer, order *big.Int) error {
+    if len(proof.Ls) != len(proof.Rs) || len(proof.Ls) > 62 {
+        return errors.New("invalid number of rounds")
+    }
+    buffer.WriteByte(byte(len(proof.Ls)))
+    for i := range proof.Ls {
+        err := writePoint(buffer, proof.Ls[i])
+        if err != nil {
+            return err
+        }
+        err = writePoint(buffer, proof.Rs[i])
+        if err != nil {
+            return err
+        }
+    }
+    err := writeScalar(buffer, proof.A, order)
+    if err != nil {
+        return err
+    }
+    return writeScalar(buffer, proof.B, order)
+}
+
+/*
+readFrom reads the inner product proof.
+*/
+func (proof *InnerProductProof) readFrom(r *proofReader) {
+    rounds := r.next(1)
+    if r.err != nil {
+        return
+    }
+    logn := int(rounds[0])
+    if logn > 62 {
+        r.err = errors.New("invalid number of rounds")
+        return
+    }
+    proof.N = int64(1) << uint(logn)
+    proof.Ls = make([]group.Element, logn)
+    proof.Rs = make([]group.Element, logn)
+    for i := 0; i < logn; i++ {
+        proof.Ls[i] = r.point()
+        proof.Rs[i] = r.point()
+    }
+    proof.A = r.scalar()
+    proof.B = r.scalar()
+}
+
+/*
+writePoint writes the encoding of the point.
+*/
+func writePoint(buffer *bytes.Buffer, p group.Element) error {
+    if p == nil {
+        return errors.New("proof is incomplete")
+    }
+    buffer.Write(p.Encode())
+    return nil
+}
+
+/*
+scalarLength returns the number of bytes of the encoding of the scalars modulo order.
+*/
+func scalarLength(order *big.Int) int {
+    return (order.BitLen() + 7) / 8
+}
+
+/*
+writeScalar writes the encoding of the scalar, which must be reduced modulo order.
+*/
+func writeScalar(buffer *bytes.Buffer, s, order *big.Int) error {
+    if s == nil {
+        return errors.New("proof is incomplete")
+    }
+    if s.Sign() < 0 || s.Cmp(order) >= 0 {
+        return ErrInvalidScalarEncoding
+    }
+    b, err := byteconversion.ToFixedByteArray(s, scalarLength(order))
+    if err != nil {
+        return err
+    }
+    buffer.Write(b)
+    return nil
+}
+
+/*
+proofReader decodes a proof over a group field by field. After the first error
+every read returns nil, so that the error only needs to be checked at the end.
+*/
+type proofReader struct {
+    data  []byte
+    err   error
+    group group.Group
+    order *big.Int
+}
+
+/*
+newProofReader returns a reader of the proof over the group g encoded in data.
+*/
+func newProofReader(data []byte, g group.Group) *proofReader {
+    return &proofReader{data: data, group: g, order: g.Order()}
+}
+
+/*
+next returns the following n bytes.
+*/
+func (r *proofReader) next(n int) []byte {
+    if r.err != nil {
+        return nil
+    }
+    if len(r.data) < n {
+        r.err = ErrInvalidEncodingLength
+        return nil
+    }
+    b := r.data[:n]
+    r.data = r.data[n:]
+    return b
+}
+
+/*
+point decodes the following point.
+*/
+func (r *proofReader) point() group.Element {
+    b := r.next(r.group.ElementLength())
+    if r.err != nil {
+        return nil
+    }
+    p, err := r.group.DecodeElement(b)
+    if err != nil {
+        r.err = ErrInvalidPointEncoding
+        return nil
+    }
+    return p
+}
+
+/*
+scalar decodes the following scalar.
+*/
+func (r *proofReader) scalar() *big.Int {
+    b := r.next(scalarLength(r.order))
+    if r.err != nil {
+        return nil
+    }
+    s := new(big.Int).SetBytes(b)
+    if s.Cmp(r.order) >= 0 {
+        r.err = ErrInvalidScalarEncoding
+        return nil
+    }
+    return s
+}
+
+/*
+finish returns the first error found while decoding, or an error if there is
+data left.
+*/
+func (r *proofReader) finish() error {
+    if r.err != nil {
+        return r.err
+    }
+    if len(r.data) != 0 {
+        return ErrInvalidEncodingLength
+    }
+    return nil
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestBulletProofBinaryEncoding(t *testing.T) {
+    params, _ := SetupBits(64)
+    proof, errProve := Prove(new(big.Int).SetInt64(1234567), params)
+    assert.Nil(t, errProve)
+
+    data, err := proof.MarshalBinary()
+    assert.Nil(t, err)
+    assert.Equal(t, 722, len(data), "64-bit proof should be encoded with 722 bytes")
+
+    var decoded BulletProof
+    err = decoded.UnmarshalBinary(data)
+    assert.Nil(t, err)
+    ok, _ := VerifyWithParams(decoded, params, decoded.V)
+    assert.True(t, ok, "decoded proof should be valid")
+
+    reencoded, _ := decoded.MarshalBinary()
+    assert.Equal(t, data, reencoded, "encoding should be canonical")
+}
+
+func TestBulletProofBinaryEncodingInvalid(t *testing.T) {
+    params, _ := SetupBits(8)
+    proof, _ := Prove(new(big.Int).SetInt64(12), params)
+    data, _ := proof.MarshalBinary()
+    var decoded BulletProof
+
+    assert.Equal(t, ErrInvalidEncodingLength, decoded.UnmarshalBinary(data[:len(data)-1]))
+    assert.Equal(t, ErrInvalidEncodingLength, decoded.UnmarshalBinary(append(data, 0)))
+
+    // taux equal to ORDER is not canonical
+    nonCanonical := append([]byte{}, data...)
+    copy(nonCanonical[5*pointSize:], ORDER.Bytes())
+    assert.Equal(t, ErrInvalidScalarEncoding, decoded.UnmarshalBinary(nonCanonical))
+
+    // x = 5 is not the X coordinate of a point on the curve
+    invalidPoint := append([]byte{}, data...)
+    copy(invalidPoint[pointSize:2*pointSize], make([]byte, pointSize))
+    invalidPoint[pointSize] = 0x02
+    invalidPoint[2*pointSize-1] = 5
+    assert.Equal(t, ErrInvalidPointEncoding, decoded.UnmarshalBinary(invalidPoint))
+
+    // tampering with the encoding gives an invalid proof
+    tampered := append([]byte{}, data...)
+    tampered[6*pointSize] ^= 1
+    if decoded.UnmarshalBinary(tampered) == nil {
+        ok, _ := VerifyWithParams(decoded, params, decoded.V)
+        assert.False(t, ok, "tampered proof should be rejected")
+    }
+}
+
+func TestInnerProductProofBinaryEncoding(t *testing.T) {
+    params, _ := SetupBits(16)
+    proof, _ := Prove(new(big.Int).SetInt64(3), params)
+    data, err := proof.InnerProductProof.MarshalBinary()
+    assert.Nil(t, err)
+    assert.Equal(t, 1+2*4*pointSize+2*scalarSize, len(data))
+
+    var decoded InnerProductProof
+    assert.Nil(t, decoded.UnmarshalBinary(data))
+    assert.Equal(t, int64(16), decoded.N)
+    assert.Equal(t, proof.InnerProductProof.A, decoded.A)
+    assert.Equal(t, proof.InnerProductProof.B, decoded.B)
+}
+
+func TestAggregatedBulletProofBinaryEncoding(t *testing.T) {
+    params, _ := SetupAggregatedBits(32, 4)
+    secrets := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}
+    proof, errProve := ProveAggregated(secrets, params)
+    assert.Nil(t, errProve)
+
+    data, err := proof.MarshalBinary()
+    assert.Nil(t, err)
+
+    var decoded AggregatedBulletProof
+    assert.Nil(t, decoded.UnmarshalBinary(data))
+    ok, _ := VerifyAggregatedWithParams(decoded, params, decoded.V)
+    assert.True(t, ok, "decoded aggregated proof should be valid")
+}
+
+func TestProofBPRPBinaryEncoding(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, errProve := ProveGeneric(new(big.Int).SetInt64(40), params)
+    assert.Nil(t, errProve)
+
+    data, err := proof.MarshalBinary()
+    assert.Nil(t, err)
+
+    var decoded ProofBPRP
+    assert.Nil(t, decoded.UnmarshalBinary(data))
+    ok, _ := VerifyGenericWithParams(decoded, params, decoded.V)
+    assert.True(t, ok, "decoded generic proof should be valid")
+
+    otherParams, _ := SetupGeneric(41, 200)
+    ok, _ = VerifyGenericWithParams(decoded, otherParams, decoded.V)
+    assert.False(t, ok, "decoded generic proof should be rejected for another interval")
+}
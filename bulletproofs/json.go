@@ -0,0 +1,367 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "encoding/json"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+)
+
+/*
+The JSON encoding keeps the field names of the structures. Since the group
+elements are interfaces, they are encoded as strings using group.EncodeText,
+e.g. "secp256k1:02...", which carries the name of the group needed to decode
+them. The group of the parameters is encoded with its name. The decoders
+validate the proofs and parameters, see validate.go.
+*/
+
+/*
+jsonElement is the JSON encoding of a group element, which may be nil.
+*/
+type jsonElement struct {
+    element group.Element
+}
+
+func (e jsonElement) MarshalJSON() ([]byte, error) {
+    if e.element == nil {
+        return []byte("null"), nil
+    }
+    return json.Marshal(group.EncodeText(e.element))
+}
+
+func (e *jsonElement) UnmarshalJSON(data []byte) error {
+    if string(data) == "null" {
+        return nil
+    }
+    var text string
+    err := json.Unmarshal(data, &text)
+    if err != nil {
+        return err
+    }
+    e.element, err = group.DecodeText(text)
+    if err != nil {
+        return ErrInvalidPointEncoding
+    }
+    return nil
+}
+
+/*
+toJSONElements converts a vector of elements, keeping nil vectors nil.
+*/
+func toJSONElements(points []group.Element) []jsonElement {
+    if points == nil {
+        return nil
+    }
+    result := make([]jsonElement, len(points))
+    for i := range points {
+        result[i] = jsonElement{points[i]}
+    }
+    return result
+}
+
+/*
+fromJSONElements converts back the vector returned by toJSONElements.
+*/
+func fromJSONElements(points []jsonElement) []group.Element {
+    if points == nil {
+        return nil
+    }
+    result := make([]group.Element, len(points))
+    for i := range points {
+        result[i] = points[i].element
+    }
+    return result
+}
+
+/*
+The structures nested in a proof are decoded without being validated, and
+validated with the proof, so that a FieldError has the path of the field from
+the proof, e.g. "P1.InnerProductProof.Ls". Their encoding is unchanged.
+*/
+type (
+    nestedInnerProductParams struct{ InnerProductParams }
+    nestedInnerProductProof  struct{ InnerProductProof }
+    nestedSetupParams        struct{ BulletProofSetupParams }
+    nestedBulletProof        struct{ BulletProof }
+)
+
+func (n *nestedInnerProductParams) UnmarshalJSON(data []byte) error {
+    return n.decodeJSON(data)
+}
+
+func (n *nestedInnerProductProof) UnmarshalJSON(data []byte) error {
+    return n.decodeJSON(data)
+}
+
+func (n *nestedSetupParams) UnmarshalJSON(data []byte) error {
+    return n.decodeJSON(data)
+}
+
+func (n *nestedBulletProof) UnmarshalJSON(data []byte) error {
+    return n.decodeJSON(data)
+}
+
+type jsonSetupParams struct {
+    N                  int64
+    Group              string `json:",omitempty"`
+    G                  jsonElement
+    H                  jsonElement
+    Gg                 []jsonElement
+    Hh                 []jsonElement
+    U                  jsonElement
+    DST                string
+    InnerProductParams nestedInnerProductParams
+}
+
+func (params BulletProofSetupParams) MarshalJSON() ([]byte, error) {
+    j := jsonSetupParams{N: params.N, G: jsonElement{params.G}, H: jsonElement{params.H},
+        Gg: toJSONElements(params.Gg), Hh: toJSONElements(params.Hh), U: jsonElement{params.U},
+        DST: params.DST, InnerProductParams: nestedInnerProductParams{params.InnerProductParams}}
+    if params.Group != nil {
+        j.Group = params.Group.Name()
+    }
+    return json.Marshal(j)
+}
+
+func (params *BulletProofSetupParams) UnmarshalJSON(data []byte) error {
+    err := params.decodeJSON(data)
+    if err != nil {
+        return err
+    }
+    return params.validate()
+}
+
+/*
+decodeJSON decodes the parameters without validating them.
+*/
+func (params *BulletProofSetupParams) decodeJSON(data []byte) error {
+    var j jsonSetupParams
+    err := json.Unmarshal(data, &j)
+    if err != nil {
+        return err
+    }
+    var g group.Group
+    if j.Group != "" {
+        g, err = group.Lookup(j.Group)
+        if err != nil {
+            return err
+        }
+    }
+    *params = BulletProofSetupParams{N: j.N, Group: g, G: j.G.element, H: j.H.element,
+        Gg: fromJSONElements(j.Gg), Hh: fromJSONElements(j.Hh), U: j.U.element,
+        DST: j.DST, InnerProductParams: j.InnerProductParams.InnerProductParams}
+    return nil
+}
+
+type jsonInnerProductParams struct {
+    N  int64
+    Cc *big.Int
+    Uu jsonElement
+    H  jsonElement
+    Gg []jsonElement
+    Hh []jsonElement
+}
+
+func (params InnerProductParams) MarshalJSON() ([]byte, error) {
+    return json.Marshal(jsonInnerProductParams{N: params.N, Cc: params.Cc, Uu: jsonElement{params.Uu},
+        H: jsonElement{params.H}, Gg: toJSONElements(params.Gg), Hh: toJSONElements(params.Hh)})
+}
+
+func (params *InnerProductParams) UnmarshalJSON(data []byte) error {
+    err := params.decodeJSON(data)
+    if err != nil {
+        return err
+    }
+    return params.validate(groupOf(append([]group.Element{params.Uu, params.H}, params.Gg...)...))
+}
+
+/*
+decodeJSON decodes the parameters without validating them.
+*/
+func (params *InnerProductParams) decodeJSON(data []byte) error {
+    var j jsonInnerProductParams
+    err := json.Unmarshal(data, &j)
+    if err != nil {
+        return err
+    }
+    *params = InnerProductParams{N: j.N, Cc: j.Cc, Uu: j.Uu.element, H: j.H.element,
+        Gg: fromJSONElements(j.Gg), Hh: fromJSONElements(j.Hh)}
+    return nil
+}
+
+type jsonInnerProductProof struct {
+    N  int64
+    Ls []jsonElement
+    Rs []jsonElement
+    A  *big.Int
+    B  *big.Int
+}
+
+func (proof InnerProductProof) MarshalJSON() ([]byte, error) {
+    return json.Marshal(jsonInnerProductProof{N: proof.N, Ls: toJSONElements(proof.Ls),
+        Rs: toJSONElements(proof.Rs), A: proof.A, B: proof.B})
+}
+
+func (proof *InnerProductProof) UnmarshalJSON(data []byte) error {
+    err := proof.decodeJSON(data)
+    if err != nil {
+        return err
+    }
+    return proof.validate(groupOf(proof.Ls...))
+}
+
+/*
+decodeJSON decodes the proof without validating it.
+*/
+func (proof *InnerProductProof) decodeJSON(data []byte) error {
+    var j jsonInnerProductProof
+    err := json.Unmarshal(data, &j)
+    if err != nil {
+        return err
+    }
+    *proof = InnerProductProof{N: j.N, Ls: fromJSONElements(j.Ls), Rs: fromJSONElements(j.Rs), A: j.A, B: j.B}
+    return nil
+}
+
+type jsonBulletProof struct {
+    V                 jsonElement
+    A                 jsonElement
+    S                 jsonElement
+    T1                jsonElement
+    T2                jsonElement
+    Taux              *big.Int
+    Mu                *big.Int
+    Tprime            *big.Int
+    InnerProductProof nestedInnerProductProof
+    Params            nestedSetupParams
+}
+
+func (proof BulletProof) MarshalJSON() ([]byte, error) {
+    return json.Marshal(jsonBulletProof{V: jsonElement{proof.V}, A: jsonElement{proof.A},
+        S: jsonElement{proof.S}, T1: jsonElement{proof.T1}, T2: jsonElement{proof.T2},
+        Taux: proof.Taux, Mu: proof.Mu, Tprime: proof.Tprime,
+        InnerProductProof: nestedInnerProductProof{proof.InnerProductProof}, Params: nestedSetupParams{proof.Params}})
+}
+
+func (proof *BulletProof) UnmarshalJSON(data []byte) error {
+    err := proof.decodeJSON(data)
+    if err != nil {
+        return err
+    }
+    return proof.validate()
+}
+
+/*
+decodeJSON decodes the proof without validating it.
+*/
+func (proof *BulletProof) decodeJSON(data []byte) error {
+    var j jsonBulletProof
+    err := json.Unmarshal(data, &j)
+    if err != nil {
+        return err
+    }
+    *proof = BulletProof{V: j.V.element, A: j.A.element, S: j.S.element, T1: j.T1.element,
+        T2: j.T2.element, Taux: j.Taux, Mu: j.Mu, Tprime: j.Tprime,
+        InnerProductProof: j.InnerProductProof.InnerProductProof, Params: j.Params.BulletProofSetupParams}
+    return nil
+}
+
+type jsonAggregatedBulletProof struct {
+    V                 []jsonElement
+    A                 jsonElement
+    S                 jsonElement
+    T1                jsonElement
+    T2                jsonElement
+    Taux              *big.Int
+    Mu                *big.Int
+    Tprime            *big.Int
+    InnerProductProof nestedInnerProductProof
+    Params            nestedSetupParams
+}
+
+func (proof AggregatedBulletProof) MarshalJSON() ([]byte, error) {
+    return json.Marshal(jsonAggregatedBulletProof{V: toJSONElements(proof.V), A: jsonElement{proof.A},
+        S: jsonElement{proof.S}, T1: jsonElement{proof.T1}, T2: jsonElement{proof.T2},
+        Taux: proof.Taux, Mu: proof.Mu, Tprime: proof.Tprime,
+        InnerProductProof: nestedInnerProductProof{proof.InnerProductProof}, Params: nestedSetupParams{proof.Params}})
+}
+
+func (proof *AggregatedBulletProof) UnmarshalJSON(data []byte) error {
+    var j jsonAggregatedBulletProof
+    err := json.Unmarshal(data, &j)
+    if err != nil {
+        return err
+    }
+    *proof = AggregatedBulletProof{V: fromJSONElements(j.V), A: j.A.element, S: j.S.element,
+        T1: j.T1.element, T2: j.T2.element, Taux: j.Taux, Mu: j.Mu, Tprime: j.Tprime,
+        InnerProductProof: j.InnerProductProof.InnerProductProof, Params: j.Params.BulletProofSetupParams}
+    return proof.validate()
+}
+
+type jsonProofBPRP struct {
+    V  jsonElement
+    A  *big.Int
+    B  *big.Int
+    P1 nestedBulletProof
+    P2 nestedBulletProof
+}
+
+func (proof ProofBPRP) MarshalJSON() ([]byte, error) {
+    return json.Marshal(jsonProofBPRP{V: jsonElement{proof.V}, A: proof.A, B: proof.B,
+        P1: nestedBulletProof{proof.P1}, P2: nestedBulletProof{proof.P2}})
+}
+
+func (proof *ProofBPRP) UnmarshalJSON(data []byte) error {
+    var j jsonProofBPRP
+    err := json.Unmarshal(data, &j)
+    if err != nil {
+        return err
+    }
+    *proof = ProofBPRP{V: j.V.element, A: j.A, B: j.B, P1: j.P1.BulletProof, P2: j.P2.BulletProof}
+    return proof.validate()
+}
+
+type jsonDalekRangeProof struct {
+    A                 jsonElement
+    S                 jsonElement
+    T1                jsonElement
+    T2                jsonElement
+    Tx                *big.Int
+    TxBlinding        *big.Int
+    EBlinding         *big.Int
+    InnerProductProof nestedInnerProductProof
+}
+
+func (proof DalekRangeProof) MarshalJSON() ([]byte, error) {
+    return json.Marshal(jsonDalekRangeProof{A: jsonElement{proof.A}, S: jsonElement{proof.S},
+        T1: jsonElement{proof.T1}, T2: jsonElement{proof.T2}, Tx: proof.Tx, TxBlinding: proof.TxBlinding,
+        EBlinding: proof.EBlinding, InnerProductProof: nestedInnerProductProof{proof.InnerProductProof}})
+}
+
+func (proof *DalekRangeProof) UnmarshalJSON(data []byte) error {
+    var j jsonDalekRangeProof
+    err := json.Unmarshal(data, &j)
+    if err != nil {
+        return err
+    }
+    *proof = DalekRangeProof{A: j.A.element, S: j.S.element, T1: j.T1.element, T2: j.T2.element,
+        Tx: j.Tx, TxBlinding: j.TxBlinding, EBlinding: j.EBlinding, InnerProductProof: j.InnerProductProof.InnerProductProof}
+    return proof.validate()
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "crypto/rand"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+multiExp accumulates the terms of a verification equation of the form
+g^base . prod(points[i]^scalars[i]) = 1, so that it can be checked with a single
+multi-exponentiation. Adding the same point twice only adds up the exponents,
+hence the generators of the setup parameters appear once, even when several
+equations are merged. The group of the equation is set by the first proof whose
+terms are added.
+*/
+type multiExp struct {
+    group   group.Group
+    order   *big.Int
+    base    *big.Int
+    points  []group.Element
+    scalars []*big.Int
+    index   map[group.Element]int
+    tables  []*generatorTables
+}
+
+/*
+newMultiExp returns an empty verification equation.
+*/
+func newMultiExp() *multiExp {
+    return &multiExp{base: new(big.Int), index: make(map[group.Element]int)}
+}
+
+/*
+setGroup sets the group of the equation, or returns an error if the equation
+already has terms of another group.
+*/
+func (e *multiExp) setGroup(g group.Group) error {
+    if e.group == nil {
+        e.group = g
+        e.order = g.Order()
+        return nil
+    }
+    if e.group != g {
+        return errors.New("proofs of different groups cannot be verified together")
+    }
+    return nil
+}
+
+/*
+checkElements returns an error if one of the points is not defined or does not
+belong to the group of the equation.
+*/
+func (e *multiExp) checkElements(points ...group.Element) error {
+    for i := range points {
+        if points[i] == nil {
+            return errors.New("proof is incomplete")
+        }
+        if points[i].Group() != e.group {
+            return errors.New("point does not belong to the group of the parameters")
+        }
+    }
+    return nil
+}
+
+/*
+addBase adds s to the exponent of the generator g of the group.
+*/
+func (e *multiExp) addBase(s *big.Int) {
+    e.base = bn.Mod(bn.Add(e.base, s), e.order)
+}
+
+/*
+add adds the term p^s.
+*/
+func (e *multiExp) add(p group.Element, s *big.Int) {
+    i, ok := e.index[p]
+    if ok {
+        e.scalars[i] = bn.Mod(bn.Add(e.scalars[i], s), e.order)
+        return
+    }
+    e.index[p] = len(e.points)
+    e.points = append(e.points, p)
+    e.scalars = append(e.scalars, bn.Mod(s, e.order))
+}
+
+/*
+addVector adds the terms p[i]^s[i] for each i.
+*/
+func (e *multiExp) addVector(p []group.Element, s []*big.Int) {
+    for i := range p {
+        e.add(p[i], s[i])
+    }
+}
+
+/*
+merge adds every term of other to e, together with its fixed-base tables.
+*/
+func (e *multiExp) merge(other *multiExp) error {
+    if other.group == nil {
+        return nil
+    }
+    err := e.setGroup(other.group)
+    if err != nil {
+        return err
+    }
+    e.addBase(other.base)
+    e.addVector(other.points, other.scalars)
+    for i := range other.tables {
+        e.useTables(other.tables[i])
+    }
+    return nil
+}
+
+/*
+useTables registers the fixed-base tables of a set of parameters, which are used
+for the terms of their generators.
+*/
+func (e *multiExp) useTables(tables *generatorTables) {
+    if tables == nil {
+        return
+    }
+    for i := range e.tables {
+        if e.tables[i] == tables {
+            return
+        }
+    }
+    e.tables = append(e.tables, tables)
+}
+
+/*
+table returns the fixed-base table of p among the registered ones, or nil.
+*/
+func (e *multiExp) table(p group.Element) group.FixedBase {
+    for i := range e.tables {
+        if table := e.tables[i].table(p); table != nil {
+            return table
+        }
+    }
+    return nil
+}
+
+/*
+isIdentity returns true if and only if the product of all the terms is the
+identity of the group.
+*/
+func (e *multiExp) isIdentity() bool {
+    if e.group == nil {
+        return true
+    }
+    // the terms of points with a fixed-base table are computed separately
+    var (
+        tables       []group.FixedBase
+        tableScalars []*big.Int
+        points       []group.Element
+        scalars      []*big.Int
+    )
+    if len(e.tables) > 0 && e.tables[0].G != nil {
+        tables = append(tables, e.tables[0].G)
+        tableScalars = append(tableScalars, e.base)
+    } else {
+        points = append(points, e.group.Generator())
+        scalars = append(scalars, e.base)
+    }
+    for i := range e.points {
+        if table := e.table(e.points[i]); table != nil {
+            tables = append(tables, table)
+            tableScalars = append(tableScalars, e.scalars[i])
+        } else {
+            points = append(points, e.points[i])
+            scalars = append(scalars, e.scalars[i])
+        }
+    }
+    result, err := e.group.MultiScalarMult(points, scalars)
+    if err != nil {
+        return false
+    }
+    if len(tables) > 0 {
+        fixed, err := e.tables[0].group.FixedBaseMultiScalarMult(tables, tableScalars)
+        if err != nil {
+            return false
+        }
+        result = result.Add(fixed)
+    }
+    return result.IsIdentity()
+}
+
+/*
+randomWeight returns a random non-zero scalar of 128 bits, used by the verifier
+to merge several equations into a single one. It is lower than the order of
+every group.
+*/
+func randomWeight() *big.Int {
+    w, _ := rand.Int(rand.Reader, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1)))
+    return w.Add(w, big.NewInt(1))
+}
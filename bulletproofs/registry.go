@@ -0,0 +1,356 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "io/ioutil"
+    "math/big"
+    "os"
+    "path/filepath"
+    "sync"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+)
+
+// tablesMagic identifies the files written by a Registry.
+var tablesMagic = []byte("ZKRPGT01")
+
+/*
+generatorTables contains the fixed-base tables of the generators of a set of
+parameters, G being the generator of the group. The tables are indexed by the
+generators themselves, so that parameters whose generators were replaced or
+decoded from JSON simply fall back to the generic scalar multiplication. It is
+never modified once it is shared with parameters.
+*/
+type generatorTables struct {
+    group  group.FixedBaseGroup
+    G      group.FixedBase
+    points map[group.Element]group.FixedBase
+}
+
+/*
+table returns the fixed-base table of p, or nil if there is none.
+*/
+func (t *generatorTables) table(p group.Element) group.FixedBase {
+    if t == nil {
+        return nil
+    }
+    return t.points[p]
+}
+
+/*
+multiScalarMult returns prod(points[i]^scalars[i]) in the group g, using the
+fixed-base tables when every point has one.
+*/
+func (t *generatorTables) multiScalarMult(g group.Group, points []group.Element, scalars []*big.Int) group.Element {
+    tables := make([]group.FixedBase, len(points))
+    for i := range points {
+        tables[i] = t.table(points[i])
+        if tables[i] == nil {
+            result, _ := g.MultiScalarMult(points, scalars)
+            return result
+        }
+    }
+    result, _ := t.group.FixedBaseMultiScalarMult(tables, scalars)
+    return result
+}
+
+/*
+generatorSet contains the generators hashed with a domain separation tag and
+their tables. Gg and Hh grow when larger parameters are requested, the
+generators of smaller parameters are prefixes of them.
+*/
+type generatorSet struct {
+    G, H, U group.Element
+    Gg, Hh  []group.Element
+    tables  *generatorTables
+}
+
+/*
+Registry builds the setup parameters once per process, together with the
+fixed-base tables of G, H, Gg and Hh, which speed up Prove and Verify. When the
+registry has a directory, the tables are written there and loaded back by later
+processes instead of being recomputed. Only the groups that implement
+group.FixedBaseGroup have tables, the generators of the other groups are simply
+cached. It is safe for concurrent use.
+*/
+type Registry struct {
+    dir   string
+    mutex sync.Mutex
+    sets  map[string]*generatorSet
+}
+
+/*
+DefaultRegistry is the registry of the process. It keeps the tables in memory only.
+*/
+var DefaultRegistry = NewRegistry("")
+
+/*
+NewRegistry returns an empty registry. If dir is not empty, the tables are
+stored in files of that directory, one per group and domain separation tag. The generators
+are always hashed again and compared with the bases of the stored tables, and
+every multiple of a table is checked when it is decoded, so a modified file is
+rejected and recomputed. The SHA-256 at the end of the file only detects
+accidental corruption. A file that cannot be written is not an error: the
+tables are then kept in memory only.
+*/
+func NewRegistry(dir string) *Registry {
+    return &Registry{dir: dir, sets: make(map[string]*generatorSet)}
+}
+
+/*
+Params returns the parameters computed by SetupAggregatedBitsWithDST(n, m, dst),
+with the fixed-base tables of their generators. For m = 1 they are the parameters
+of SetupBitsWithDST(n, dst).
+*/
+func (r *Registry) Params(n, m int64, dst string) (BulletProofSetupParams, error) {
+    return r.ParamsWithGroup(group.Secp256k1, n, m, dst)
+}
+
+/*
+ParamsWithGroup returns the parameters computed by
+SetupAggregatedBitsWithGroup(g, n, m, dst), with the fixed-base tables of their
+generators if the group has any.
+*/
+func (r *Registry) ParamsWithGroup(g group.Group, n, m int64, dst string) (BulletProofSetupParams, error) {
+    if m <= 0 {
+        return BulletProofSetupParams{}, errors.New("number of aggregated values must be positive")
+    }
+    err := checkSetupBits(g, n, dst)
+    if err != nil {
+        return BulletProofSetupParams{}, err
+    }
+    size := nextPowerOfTwo(n) * nextPowerOfTwo(m)
+
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    set, err := r.generators(g, dst, size)
+    if err != nil {
+        return BulletProofSetupParams{}, err
+    }
+    params := BulletProofSetupParams{
+        N:      n,
+        Group:  g,
+        G:      set.G,
+        H:      set.H,
+        U:      set.U,
+        DST:    dst,
+        Gg:     append([]group.Element{}, set.Gg[:size]...),
+        Hh:     append([]group.Element{}, set.Hh[:size]...),
+        tables: set.tables,
+    }
+    return params, nil
+}
+
+/*
+GenericParams returns the parameters computed by SetupGenericBigWithDST(a, b, dst),
+with the fixed-base tables of their generators.
+*/
+func (r *Registry) GenericParams(a, b *big.Int, dst string) (*bprp, error) {
+    return r.GenericParamsWithGroup(group.Secp256k1, a, b, dst)
+}
+
+/*
+GenericParamsWithGroup returns the parameters computed by
+SetupGenericBigWithGroup(g, a, b, dst), with the fixed-base tables of their
+generators if the group has any.
+*/
+func (r *Registry) GenericParamsWithGroup(g group.Group, a, b *big.Int, dst string) (*bprp, error) {
+    if a == nil || b == nil {
+        return nil, errors.New("range start and range end must be provided")
+    }
+    if a.Cmp(b) >= 0 {
+        return nil, errors.New("range start must be lower than range end")
+    }
+    n, err := genericBitLength(new(big.Int).Sub(b, a), g.Order())
+    if err != nil {
+        return nil, err
+    }
+    params := new(bprp)
+    params.A = new(big.Int).Set(a)
+    params.B = new(big.Int).Set(b)
+    params.BP1, err = r.ParamsWithGroup(g, n, 1, dst)
+    if err != nil {
+        return nil, err
+    }
+    params.BP2 = params.BP1
+    return params, nil
+}
+
+/*
+generators returns the generator set of g and dst with at least size generators
+in Gg and Hh, extending it, and the file of the registry, if necessary.
+*/
+func (r *Registry) generators(g group.Group, dst string, size int64) (*generatorSet, error) {
+    key := g.Name() + ":" + dst
+    set, ok := r.sets[key]
+    if ok && int64(len(set.Gg)) >= size {
+        return set, nil
+    }
+    // the set is replaced rather than modified, since its tables are shared
+    next := new(generatorSet)
+    var previous *generatorTables
+    if ok {
+        *next = *set
+        previous = set.tables
+    } else {
+        next.G = g.Generator()
+        next.H = hashGenerator(g, dst, []byte("H"))
+        next.U = hashGenerator(g, dst, []byte("U"))
+    }
+    Gg, Hh := computeGeneratorRange(g, dst, int64(len(next.Gg)), size)
+    next.Gg = append(append([]group.Element{}, next.Gg...), Gg...)
+    next.Hh = append(append([]group.Element{}, next.Hh...), Hh...)
+
+    fixed, ok := g.(group.FixedBaseGroup)
+    if !ok {
+        r.sets[key] = next
+        return next, nil
+    }
+    // the tables of the file that match the generators are used as they are
+    stored := r.loadTables(fixed, dst)
+    points := next.points()
+    next.tables = &generatorTables{group: fixed, points: make(map[group.Element]group.FixedBase, len(points))}
+    computed := false
+    for i, p := range points {
+        table := previous.table(p)
+        if table == nil && i < len(stored) && stored[i].Base().Equal(p) {
+            table = stored[i]
+        }
+        if table == nil {
+            var err error
+            table, err = fixed.NewFixedBase(p)
+            if err != nil {
+                return nil, err
+            }
+            computed = true
+        }
+        next.tables.points[p] = table
+    }
+    next.tables.G = next.tables.table(next.G)
+    if computed {
+        // the file is only a cache: the tables in memory are used even if it
+        // cannot be written, for instance in a read-only directory
+        _ = r.storeTables(g, dst, next)
+    }
+    r.sets[key] = next
+    return next, nil
+}
+
+/*
+points returns the generators of the set in the order of the file: G, H, then Gg
+and Hh interleaved, so that the file of a smaller set is a prefix of the file of
+a larger one.
+*/
+func (set *generatorSet) points() []group.Element {
+    points := []group.Element{set.G, set.H}
+    for i := range set.Gg {
+        points = append(points, set.Gg[i], set.Hh[i])
+    }
+    return points
+}
+
+/*
+tablesFile returns the name of the file of the tables of g and dst.
+*/
+func (r *Registry) tablesFile(g group.Group, dst string) string {
+    digest := sha256.Sum256([]byte(g.Name() + ":" + dst))
+    return filepath.Join(r.dir, "bulletproofs-"+hex.EncodeToString(digest[:8])+".tables")
+}
+
+/*
+loadTables reads the tables of dst from the directory of the registry. A missing
+or invalid file is ignored, in which case the tables are computed again.
+*/
+func (r *Registry) loadTables(g group.FixedBaseGroup, dst string) []group.FixedBase {
+    if r.dir == "" {
+        return nil
+    }
+    data, err := ioutil.ReadFile(r.tablesFile(g, dst))
+    if err != nil || len(data) < len(tablesMagic)+4+sha256.Size {
+        return nil
+    }
+    body := data[:len(data)-sha256.Size]
+    digest := sha256.Sum256(body)
+    if !bytes.Equal(digest[:], data[len(body):]) || !bytes.Equal(body[:len(tablesMagic)], tablesMagic) {
+        return nil
+    }
+    body = body[len(tablesMagic):]
+    count := int(binary.BigEndian.Uint32(body))
+    body = body[4:]
+    if count == 0 || len(body)%count != 0 {
+        return nil
+    }
+    tableLength := len(body) / count
+    tables := make([]group.FixedBase, count)
+    for i := range tables {
+        tables[i], err = g.DecodeFixedBase(body[i*tableLength : (i+1)*tableLength])
+        if err != nil {
+            return nil
+        }
+    }
+    return tables
+}
+
+/*
+storeTables writes the tables of the set to the directory of the registry: the
+magic bytes, the number of tables, the tables and the SHA-256 of everything before.
+*/
+func (r *Registry) storeTables(g group.Group, dst string, set *generatorSet) error {
+    if r.dir == "" {
+        return nil
+    }
+    points := set.points()
+    var buffer bytes.Buffer
+    buffer.Write(tablesMagic)
+    count := make([]byte, 4)
+    binary.BigEndian.PutUint32(count, uint32(len(points)))
+    buffer.Write(count)
+    for _, p := range points {
+        data, err := set.tables.table(p).MarshalBinary()
+        if err != nil {
+            return err
+        }
+        buffer.Write(data)
+    }
+    digest := sha256.Sum256(buffer.Bytes())
+    buffer.Write(digest[:])
+
+    // write to a temporary file first, so that readers never see a partial file
+    tmp, err := ioutil.TempFile(r.dir, "bulletproofs-*.tmp")
+    if err != nil {
+        return err
+    }
+    _, err = tmp.Write(buffer.Bytes())
+    errClose := tmp.Close()
+    if err == nil {
+        err = errClose
+    }
+    if err == nil {
+        err = os.Rename(tmp.Name(), r.tablesFile(g, dst))
+    }
+    if err != nil {
+        _ = os.Remove(tmp.Name())
+    }
+    return err
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "crypto/sha256"
+    "io/ioutil"
+    "math/big"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/util"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestRegistryParams(t *testing.T) {
+    registry := NewRegistry("")
+    params, err := registry.Params(32, 1, DefaultDST)
+    assert.NoError(t, err)
+    setup, _ := SetupBits(32)
+    assert.Equal(t, setup.Gg, params.Gg)
+    assert.Equal(t, setup.Hh, params.Hh)
+    assert.Equal(t, setup.H, params.H)
+    assert.Equal(t, setup.U, params.U)
+
+    // the same parameters are returned by later calls, larger ones share the generators
+    again, _ := registry.Params(32, 1, DefaultDST)
+    assert.True(t, again.H == params.H && again.Gg[0] == params.Gg[0])
+    aggregated, err := registry.Params(32, 4, DefaultDST)
+    assert.NoError(t, err)
+    assert.Len(t, aggregated.Gg, 128)
+    assert.True(t, aggregated.Gg[31] == params.Gg[31])
+
+    padded, err := registry.Params(32, 3, DefaultDST)
+    assert.NoError(t, err)
+    assert.Len(t, padded.Gg, 128)
+    _, err = registry.Params(32, 0, DefaultDST)
+    assert.Error(t, err)
+    _, err = registry.Params(32, 1, "")
+    assert.Error(t, err)
+}
+
+func TestRegistryProveVerify(t *testing.T) {
+    params, err := DefaultRegistry.Params(40, 1, DefaultDST)
+    assert.NoError(t, err)
+    setup, _ := SetupBits(40)
+    secret := new(big.Int).SetInt64(1234567)
+    gamma := new(big.Int).SetInt64(42)
+
+    // the tables do not change the proof
+    seed := []byte("registry")
+    proof, _, err := ProveWithRand(util.NewDeterministicReader(seed), secret, params)
+    assert.NoError(t, err)
+    expected, _, _ := ProveWithRand(util.NewDeterministicReader(seed), secret, setup)
+    assert.True(t, expected.A.Equal(proof.A))
+    expectedEncoding, _ := expected.MarshalBinary()
+    encoding, _ := proof.MarshalBinary()
+    assert.Equal(t, expectedEncoding, encoding)
+
+    ok, err := VerifyWithParams(proof, params, proof.V)
+    assert.NoError(t, err)
+    assert.True(t, ok)
+    ok, _ = VerifyWithParams(proof, setup, proof.V)
+    assert.True(t, ok)
+    ok, _ = VerifyWithParams(expected, params, expected.V)
+    assert.True(t, ok)
+
+    other, _ := ProveWithBlinding(secret, gamma, params)
+    ok, _ = VerifyWithParams(other, params, proof.V)
+    assert.False(t, ok)
+
+    aggregatedParams, _ := DefaultRegistry.Params(16, 2, DefaultDST)
+    aggregated, err := ProveAggregated([]*big.Int{big.NewInt(7), big.NewInt(65535)}, aggregatedParams)
+    assert.NoError(t, err)
+    ok, _ = VerifyAggregatedWithParams(aggregated, aggregatedParams, aggregated.V)
+    assert.True(t, ok)
+
+    generic, err := DefaultRegistry.GenericParams(big.NewInt(18), big.NewInt(200), DefaultDST)
+    assert.NoError(t, err)
+    proofGeneric, _ := ProveGeneric(big.NewInt(40), generic)
+    ok, _ = VerifyGenericWithParams(proofGeneric, generic, proofGeneric.V)
+    assert.True(t, ok)
+}
+
+func TestRegistryTablesOnDisk(t *testing.T) {
+    dir, err := ioutil.TempDir("", "zkrp")
+    assert.NoError(t, err)
+    defer os.RemoveAll(dir)
+
+    params, err := NewRegistry(dir).Params(8, 1, "ZKRP-TEST-REGISTRY")
+    assert.NoError(t, err)
+    file := NewRegistry(dir).tablesFile(group.Secp256k1, "ZKRP-TEST-REGISTRY")
+    data, err := ioutil.ReadFile(file)
+    assert.NoError(t, err)
+
+    // a new registry loads the tables of the file
+    registry := NewRegistry(dir)
+    assert.Len(t, registry.loadTables(group.Secp256k1.(group.FixedBaseGroup), "ZKRP-TEST-REGISTRY"), 2+2*8)
+    loaded, err := registry.Params(8, 1, "ZKRP-TEST-REGISTRY")
+    assert.NoError(t, err)
+    proof, err := Prove(big.NewInt(200), loaded)
+    assert.NoError(t, err)
+    ok, _ := VerifyWithParams(proof, params, proof.V)
+    assert.True(t, ok)
+
+    // larger parameters extend the file
+    _, err = registry.Params(16, 1, "ZKRP-TEST-REGISTRY")
+    assert.NoError(t, err)
+    assert.Len(t, NewRegistry(dir).loadTables(group.Secp256k1.(group.FixedBaseGroup), "ZKRP-TEST-REGISTRY"), 2+2*16)
+
+    // a corrupted file is ignored and written again
+    data[len(data)/2] ^= 1
+    assert.NoError(t, ioutil.WriteFile(file, data, 0644))
+    assert.Nil(t, NewRegistry(dir).loadTables(group.Secp256k1.(group.FixedBaseGroup), "ZKRP-TEST-REGISTRY"))
+    _, err = NewRegistry(dir).Params(8, 1, "ZKRP-TEST-REGISTRY")
+    assert.NoError(t, err)
+    assert.Len(t, NewRegistry(dir).loadTables(group.Secp256k1.(group.FixedBaseGroup), "ZKRP-TEST-REGISTRY"), 2+2*8)
+
+    // a file whose checksum is recomputed after swapping 2H and 3H in the table
+    // of H is rejected as well
+    data, err = ioutil.ReadFile(file)
+    assert.NoError(t, err)
+    body := data[:len(data)-sha256.Size]
+    tableLength := (len(body) - len(tablesMagic) - 4) / (2 + 2*8)
+    multiples := body[len(tablesMagic)+4+tableLength+33:]
+    swapped := append([]byte{}, multiples[64:128]...)
+    copy(multiples[64:128], multiples[128:192])
+    copy(multiples[128:192], swapped)
+    digest := sha256.Sum256(body)
+    assert.NoError(t, ioutil.WriteFile(file, append(body, digest[:]...), 0644))
+    assert.Nil(t, NewRegistry(dir).loadTables(group.Secp256k1.(group.FixedBaseGroup), "ZKRP-TEST-REGISTRY"))
+}
+
+/*
+TestRegistryUnwritableDirectory checks that the registry keeps the tables in
+memory when its directory cannot be written. The directory is below a regular
+file, which cannot be written even by root.
+*/
+func TestRegistryUnwritableDirectory(t *testing.T) {
+    file, err := ioutil.TempFile("", "zkrp")
+    assert.NoError(t, err)
+    assert.NoError(t, file.Close())
+    defer os.Remove(file.Name())
+
+    registry := NewRegistry(filepath.Join(file.Name(), "tables"))
+    params, err := registry.Params(8, 1, "ZKRP-TEST-REGISTRY")
+    assert.NoError(t, err)
+    proof, err := Prove(big.NewInt(200), params)
+    assert.NoError(t, err)
+    ok, _ := VerifyWithParams(proof, params, proof.V)
+    assert.True(t, ok)
+
+    // the tables in memory are extended and used
+    extended, err := registry.Params(16, 1, "ZKRP-TEST-REGISTRY")
+    assert.NoError(t, err)
+    assert.NotNil(t, extended.tables)
+    proof, err = Prove(big.NewInt(40000), extended)
+    assert.NoError(t, err)
+    ok, _ = VerifyWithParams(proof, extended, proof.V)
+    assert.True(t, ok)
+}
+
+func BenchmarkRegistryProve64(b *testing.B) {
+    params, _ := DefaultRegistry.Params(64, 1, DefaultDST)
+    secret := new(big.Int).SetInt64(1234567)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = Prove(secret, params)
+    }
+}
+
+func BenchmarkRegistryVerify64(b *testing.B) {
+    params, _ := DefaultRegistry.Params(64, 1, DefaultDST)
+    proof, _ := Prove(new(big.Int).SetInt64(1234567), params)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = VerifyWithParams(proof, params, proof.V)
+    }
+}
+
+func TestRegistryParamsWithGroup(t *testing.T) {
+    registry := NewRegistry("")
+    params, err := registry.ParamsWithGroup(group.P256, 8, 1, DefaultDSTForGroup(group.P256))
+    assert.NoError(t, err)
+    assert.Equal(t, group.P256, params.H.Group())
+    again, _ := registry.ParamsWithGroup(group.P256, 8, 1, DefaultDSTForGroup(group.P256))
+    assert.True(t, again.H.Equal(params.H))
+    proof, err := Prove(big.NewInt(200), params)
+    assert.NoError(t, err)
+    ok, _ := VerifyWithParams(proof, again, proof.V)
+    assert.True(t, ok)
+
+    // the generators of the same tag in another group are different
+    secp, _ := registry.Params(8, 1, DefaultDSTForGroup(group.P256))
+    assert.Equal(t, group.Secp256k1, secp.H.Group())
+}
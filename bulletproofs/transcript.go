@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/merlin"
+    "github.com/ing-bank/zkrp/util/byteconversion"
+)
+
+/*
+Transcript implements the Fiat-Shamir heuristic with a Merlin transcript
+(https://merlin.cool) of the package crypto/merlin. Every public input and every
+message of the prover is absorbed in order, together with a label for domain
+separation. The challenges depend on everything that was absorbed before, and
+STROBE binds them to the state, so that the next challenges depend on them.
+*/
+type Transcript struct {
+    merlin *merlin.Transcript
+    // order is the modulus of the scalars, the order of the group of the proof
+    order *big.Int
+}
+
+/*
+NewTranscript returns a transcript for the protocol identified by label, whose
+scalars are reduced modulo ORDER.
+*/
+func NewTranscript(label string) *Transcript {
+    return newTranscript(label, ORDER)
+}
+
+/*
+newTranscript returns a transcript whose scalars are reduced modulo order.
+*/
+func newTranscript(label string, order *big.Int) *Transcript {
+    return &Transcript{merlin: merlin.NewTranscript(label), order: order}
+}
+
+/*
+AppendMessage absorbs the message into the transcript.
+*/
+func (t *Transcript) AppendMessage(label string, message []byte) {
+    t.merlin.AppendMessage(label, message)
+}
+
+/*
+AppendPoint absorbs the canonical encoding of the group element, which is the
+SEC1 compressed encoding for elliptic curve points.
+*/
+func (t *Transcript) AppendPoint(label string, p group.Element) {
+    t.AppendMessage(label, p.Encode())
+}
+
+/*
+AppendPoints absorbs a vector of group elements.
+*/
+func (t *Transcript) AppendPoints(label string, points []group.Element) {
+    t.AppendUint64(label, uint64(len(points)))
+    for i := range points {
+        t.AppendPoint(label, points[i])
+    }
+}
+
+/*
+AppendScalar absorbs the scalar reduced modulo the order, encoded with the
+number of bytes of the order, namely 32 bytes for secp256k1.
+*/
+func (t *Transcript) AppendScalar(label string, s *big.Int) {
+    b, _ := byteconversion.ToFixedByteArray(new(big.Int).Mod(s, t.order), scalarLength(t.order))
+    t.AppendMessage(label, b)
+}
+
+/*
+AppendUint64 absorbs an integer, encoded with 8 bytes in little-endian order as
+in Merlin.
+*/
+func (t *Transcript) AppendUint64(label string, n uint64) {
+    t.merlin.AppendUint64(label, n)
+}
+
+/*
+ChallengeScalar returns a non-zero challenge modulo the order. It is computed from
+64 challenge bytes, read as a big-endian integer, so the bias of the modular
+reduction is negligible.
+*/
+func (t *Transcript) ChallengeScalar(label string) *big.Int {
+    for {
+        c := new(big.Int).SetBytes(t.merlin.ChallengeBytes(label, 64))
+        c.Mod(c, t.order)
+        if c.Sign() != 0 {
+            return c
+        }
+    }
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestTranscriptDeterministic(t *testing.T) {
+    t1 := NewTranscript("test protocol")
+    t1.AppendMessage("some label", []byte("some data"))
+    t2 := NewTranscript("test protocol")
+    t2.AppendMessage("some label", []byte("some data"))
+    assert.Equal(t, t1.ChallengeScalar("challenge"), t2.ChallengeScalar("challenge"))
+}
+
+func TestTranscriptDomainSeparation(t *testing.T) {
+    base := NewTranscript("test protocol").ChallengeScalar("challenge")
+    assert.NotEqual(t, base, NewTranscript("other protocol").ChallengeScalar("challenge"))
+
+    t1 := NewTranscript("test protocol")
+    t1.AppendMessage("label", []byte("ab"))
+    t2 := NewTranscript("test protocol")
+    t2.AppendMessage("labela", []byte("b"))
+    assert.NotEqual(t, t1.ChallengeScalar("challenge"), t2.ChallengeScalar("challenge"),
+        "labels and messages must be separated")
+}
+
+func TestTranscriptOrder(t *testing.T) {
+    A := group.Secp256k1.Generator().ScalarMult(big.NewInt(3))
+    S := group.Secp256k1.Generator().ScalarMult(big.NewInt(5))
+    t1 := NewTranscript("test protocol")
+    t1.AppendPoint("A", A)
+    t1.AppendPoint("S", S)
+    t2 := NewTranscript("test protocol")
+    t2.AppendPoint("A", S)
+    t2.AppendPoint("S", A)
+    assert.NotEqual(t, t1.ChallengeScalar("y"), t2.ChallengeScalar("y"))
+}
+
+func TestTranscriptSuccessiveChallenges(t *testing.T) {
+    transcript := NewTranscript("test protocol")
+    transcript.AppendPoint("A", group.Secp256k1.Generator().ScalarMult(big.NewInt(3)))
+    y := transcript.ChallengeScalar("y")
+    z := transcript.ChallengeScalar("z")
+    assert.NotEqual(t, y, z, "successive challenges must be different")
+    assert.True(t, y.Cmp(ORDER) < 0 && y.Sign() > 0)
+    assert.True(t, z.Cmp(ORDER) < 0 && z.Sign() > 0)
+}
+
+func TestTranscriptScalarReduced(t *testing.T) {
+    t1 := NewTranscript("test protocol")
+    t1.AppendScalar("s", big.NewInt(7))
+    t2 := NewTranscript("test protocol")
+    t2.AppendScalar("s", new(big.Int).Add(ORDER, big.NewInt(7)))
+    assert.Equal(t, t1.ChallengeScalar("c"), t2.ChallengeScalar("c"))
+}
+
+func TestProofBoundToBitLength(t *testing.T) {
+    // a proof is bound to the generators and to the bit-length of the setup
+    params, _ := SetupBits(16)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    ok, _ := VerifyWithParams(proof, params, proof.V)
+    assert.True(t, ok, "should verify")
+
+    other := params
+    other.Hh = append([]group.Element{}, params.Hh...)
+    other.Hh[0], other.Hh[1] = params.Hh[1], params.Hh[0]
+    ok, _ = VerifyWithParams(proof, other, proof.V)
+    assert.False(t, ok, "should not verify with other generators")
+}
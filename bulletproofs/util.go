@@ -23,15 +23,16 @@ import (
     "errors"
     "math/big"
 
+    "github.com/ing-bank/zkrp/crypto/group"
     "github.com/ing-bank/zkrp/crypto/p256"
     "github.com/ing-bank/zkrp/util/bn"
     "github.com/ing-bank/zkrp/util/intconversion"
 )
 
 /*
-powerOf returns a vector composed by powers of x.
+powerOf returns a vector composed by powers of x modulo order.
 */
-func powerOf(x *big.Int, n int64) []*big.Int {
+func powerOf(x *big.Int, n int64, order *big.Int) []*big.Int {
     var (
         i      int64
         result []*big.Int
@@ -42,16 +43,22 @@ func powerOf(x *big.Int, n int64) []*big.Int {
     for i < n {
         result[i] = current
         current = bn.Multiply(current, x)
-        current = bn.Mod(current, ORDER)
+        current = bn.Mod(current, order)
         i = i + 1
     }
     return result
 }
 
 /*
-Hash is responsible for the computing a Zp element given elements from GT and G1.
+HashBP is responsible for the computing a Zp element given elements from GT and G1.
+The second output is the SHA-256 of the points followed by the first output. It
+used to hash the points only, because of a copy of the wrong buffer, so that the
+second output of earlier versions differs.
+
+Deprecated: the proofs no longer use it, they compute the challenges with a
+Transcript, which absorbs every public input and every message of the prover.
 */
-func HashBP(A, S *p256.P256) (*big.Int, *big.Int, error) {
+func HashBP(A, S *p256.Secp256k1Point) (*big.Int, *big.Int, error) {
 
     digest1 := sha256.New()
     var buffer bytes.Buffer
@@ -71,7 +78,7 @@ func HashBP(A, S *p256.P256) (*big.Int, *big.Int, error) {
     buffer2.WriteString(S.X.String())
     buffer2.WriteString(S.Y.String())
     buffer2.WriteString(result1.String())
-    digest2.Write(buffer.Bytes())
+    digest2.Write(buffer2.Bytes())
     output2 := digest2.Sum(nil)
     tmp2 := output2[0:]
     result2 := new(big.Int).SetBytes(tmp2)
@@ -80,31 +87,29 @@ func HashBP(A, S *p256.P256) (*big.Int, *big.Int, error) {
 }
 
 /*
-VectorExp computes Prod_i^n{a[i]^b[i]}.
+VectorExp computes Prod_i^n{a[i]^b[i]}. The vectors must not be empty.
 */
-func VectorExp(a []*p256.P256, b []*big.Int) (*p256.P256, error) {
-    var (
-        result  *p256.P256
-        i, n, m int64
-    )
-    n = int64(len(a))
-    m = int64(len(b))
-    if n != m {
+func VectorExp(a []group.Element, b []*big.Int) (group.Element, error) {
+    if len(a) != len(b) {
         return nil, errors.New("Size of first argument is different from size of second argument.")
     }
-    i = 0
-    result = new(p256.P256).SetInfinity()
-    for i < n {
-        result.Multiply(result, new(p256.P256).ScalarMult(a[i], b[i]))
-        i = i + 1
+    if len(a) == 0 || a[0] == nil {
+        return nil, errors.New("vectors must not be empty")
     }
-    return result, nil
+    return a[0].Group().MultiScalarMult(a, b)
 }
 
 /*
 ScalarProduct return the inner product between a and b.
 */
 func ScalarProduct(a, b []*big.Int) (*big.Int, error) {
+    return scalarProduct(a, b, ORDER)
+}
+
+/*
+scalarProduct returns the inner product between a and b modulo order.
+*/
+func scalarProduct(a, b []*big.Int, order *big.Int) (*big.Int, error) {
     var (
         result  *big.Int
         i, n, m int64
@@ -119,7 +124,7 @@ func ScalarProduct(a, b []*big.Int) (*big.Int, error) {
     for i < n {
         ab := bn.Multiply(a[i], b[i])
         result.Add(result, ab)
-        result = bn.Mod(result, ORDER)
+        result = bn.Mod(result, order)
         i = i + 1
     }
     return result, nil
@@ -132,3 +137,14 @@ https://stackoverflow.com/a/600306/844313
 func IsPowerOfTwo(x int64) bool {
     return (x != 0) && ((x & (x - 1)) == 0)
 }
+
+/*
+nextPowerOfTwo returns the smallest power of 2 that is greater than or equal to x.
+*/
+func nextPowerOfTwo(x int64) int64 {
+    result := int64(1)
+    for result < x {
+        result = result * 2
+    }
+    return result
+}
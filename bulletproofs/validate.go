@@ -0,0 +1,330 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "errors"
+    "fmt"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+)
+
+/*
+The JSON decoders validate every proof and its parameters before returning them,
+so that the verification arithmetic only starts on proofs with the expected
+structure: every point is defined and belongs to the group of the proof, every
+scalar is lower than the order of that group and the Inner Product Proof has
+log2(N) rounds. The points are checked to be on the curve by group.DecodeText,
+and the failures are reported with ErrInvalidPointEncoding. The other failures are
+reported with a FieldError, which names the field and wraps one of the errors
+below. The binary decoders perform the same checks while reading the proofs.
+*/
+
+var (
+    ErrMissingElement = errors.New("point or scalar is missing")
+    ErrInvalidGroup   = errors.New("point does not belong to the group of the proof")
+    ErrInvalidRounds  = errors.New("number of rounds does not match the size of the inner product")
+    ErrInvalidParams  = errors.New("invalid setup parameters")
+)
+
+/*
+FieldError is the error of a field of a proof or of its parameters, such as a
+point that does not belong to the group of the proof. Field is the path of the
+field in the JSON encoding, e.g. "P2.InnerProductProof.Ls", and Index is the
+index of the element in a vector field, such as Ls or Gg, and -1 in the other
+fields. Err is
+one of the errors of the package, which errors.Is finds through Unwrap.
+*/
+type FieldError struct {
+    Field string
+    Index int
+    Err   error
+}
+
+func (e *FieldError) Error() string {
+    if e.Index < 0 {
+        return e.Field + ": " + e.Err.Error()
+    }
+    return fmt.Sprintf("%s[%d]: %s", e.Field, e.Index, e.Err)
+}
+
+/*
+Unwrap returns the error of the package, so that errors.Is(err, ErrInvalidGroup)
+holds for a point of another group.
+*/
+func (e *FieldError) Unwrap() error {
+    return e.Err
+}
+
+/*
+inField prefixes the field of a FieldError with the field of the struct that
+contains it.
+*/
+func inField(field string, err error) error {
+    if e, ok := err.(*FieldError); ok {
+        return &FieldError{Field: field + "." + e.Field, Index: e.Index, Err: e.Err}
+    }
+    return err
+}
+
+/*
+firstError returns the first error that is not nil.
+*/
+func firstError(errs ...error) error {
+    for _, err := range errs {
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+/*
+checkPoint returns an error if the point of field is missing or does not belong
+to the group g.
+*/
+func checkPoint(g group.Group, field string, p group.Element) error {
+    return checkElement(g, field, -1, p)
+}
+
+/*
+checkOptionalPoint is checkPoint for a point that may be missing.
+*/
+func checkOptionalPoint(g group.Group, field string, p group.Element) error {
+    if p == nil {
+        return nil
+    }
+    return checkElement(g, field, -1, p)
+}
+
+/*
+checkPoints returns an error if one of the points of the vector field is missing
+or does not belong to the group g.
+*/
+func checkPoints(g group.Group, field string, points []group.Element) error {
+    for i := range points {
+        err := checkElement(g, field, i, points[i])
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+/*
+checkElement returns the error of the point at index of field, if any.
+*/
+func checkElement(g group.Group, field string, index int, p group.Element) error {
+    if p == nil {
+        return &FieldError{Field: field, Index: index, Err: ErrMissingElement}
+    }
+    if p.Group() != g {
+        return &FieldError{Field: field, Index: index, Err: ErrInvalidGroup}
+    }
+    return nil
+}
+
+/*
+checkScalar returns an error if the scalar of field is missing or does not lie
+in [0, order).
+*/
+func checkScalar(order *big.Int, field string, s *big.Int) error {
+    if s == nil {
+        return &FieldError{Field: field, Index: -1, Err: ErrMissingElement}
+    }
+    if s.Sign() < 0 || s.Cmp(order) >= 0 {
+        return &FieldError{Field: field, Index: -1, Err: ErrInvalidScalarEncoding}
+    }
+    return nil
+}
+
+/*
+groupOf returns the group of the first point that is defined, or group.Secp256k1
+if there is none, as for parameters without a group.
+*/
+func groupOf(points ...group.Element) group.Group {
+    for i := range points {
+        if points[i] != nil {
+            return points[i].Group()
+        }
+    }
+    return group.Secp256k1
+}
+
+/*
+validate checks the Inner Product Proof in the group g.
+*/
+func (proof *InnerProductProof) validate(g group.Group) error {
+    logn := len(proof.Ls)
+    if logn > 62 || proof.N != int64(1)<<uint(logn) {
+        return &FieldError{Field: "Ls", Index: -1, Err: ErrInvalidRounds}
+    }
+    if len(proof.Rs) != logn {
+        return &FieldError{Field: "Rs", Index: -1, Err: ErrInvalidRounds}
+    }
+    return firstError(
+        checkPoints(g, "Ls", proof.Ls),
+        checkPoints(g, "Rs", proof.Rs),
+        checkScalar(g.Order(), "A", proof.A),
+        checkScalar(g.Order(), "B", proof.B))
+}
+
+/*
+validate checks that the generators that are defined belong to the group g.
+*/
+func (params *InnerProductParams) validate(g group.Group) error {
+    err := firstError(
+        checkOptionalPoint(g, "Uu", params.Uu),
+        checkOptionalPoint(g, "H", params.H),
+        checkPoints(g, "Gg", params.Gg),
+        checkPoints(g, "Hh", params.Hh))
+    if err != nil {
+        return err
+    }
+    if params.Cc != nil {
+        return checkScalar(g.Order(), "Cc", params.Cc)
+    }
+    return nil
+}
+
+/*
+validate checks that the generators that are defined belong to the group of the
+parameters.
+*/
+func (params *BulletProofSetupParams) validate() error {
+    g := params.group()
+    if params.N < 0 {
+        return &FieldError{Field: "N", Index: -1, Err: ErrInvalidParams}
+    }
+    if len(params.Gg) != len(params.Hh) {
+        return &FieldError{Field: "Hh", Index: -1, Err: ErrInvalidParams}
+    }
+    err := firstError(
+        checkOptionalPoint(g, "G", params.G),
+        checkOptionalPoint(g, "H", params.H),
+        checkOptionalPoint(g, "U", params.U),
+        checkPoints(g, "Gg", params.Gg),
+        checkPoints(g, "Hh", params.Hh))
+    if err != nil {
+        return err
+    }
+    return inField("InnerProductParams", params.InnerProductParams.validate(g))
+}
+
+/*
+validate checks the BulletProof and its parameters. The commitment V may be
+missing, since VerifyWithParams takes it as an argument.
+*/
+func (proof *BulletProof) validate() error {
+    err := checkOptionalPoint(proof.Params.group(), "V", proof.V)
+    if err != nil {
+        return err
+    }
+    return proof.validateTerms(1)
+}
+
+/*
+validateTerms checks every field of a proof of m aggregated values, except the
+commitments. When the parameters have a bit-length, the Inner Product Proof must
+have log2(m.paddedN) rounds.
+*/
+func (proof *BulletProof) validateTerms(m int64) error {
+    err := proof.Params.validate()
+    if err != nil {
+        return inField("Params", err)
+    }
+    g := proof.Params.group()
+    err = firstError(
+        checkPoint(g, "A", proof.A),
+        checkPoint(g, "S", proof.S),
+        checkPoint(g, "T1", proof.T1),
+        checkPoint(g, "T2", proof.T2),
+        checkScalar(g.Order(), "Taux", proof.Taux),
+        checkScalar(g.Order(), "Mu", proof.Mu),
+        checkScalar(g.Order(), "Tprime", proof.Tprime),
+        inField("InnerProductProof", proof.InnerProductProof.validate(g)))
+    if err != nil {
+        return err
+    }
+    if proof.Params.N > 0 && proof.InnerProductProof.N != m*proof.Params.paddedN() {
+        return &FieldError{Field: "InnerProductProof.Ls", Index: -1, Err: ErrInvalidRounds}
+    }
+    return nil
+}
+
+/*
+validate checks the aggregated BulletProof and its parameters.
+*/
+func (proof *AggregatedBulletProof) validate() error {
+    if len(proof.V) == 0 {
+        return &FieldError{Field: "V", Index: -1, Err: ErrMissingElement}
+    }
+    err := checkPoints(proof.Params.group(), "V", proof.V)
+    if err != nil {
+        return err
+    }
+    bp := BulletProof{A: proof.A, S: proof.S, T1: proof.T1, T2: proof.T2, Taux: proof.Taux,
+        Mu: proof.Mu, Tprime: proof.Tprime, InnerProductProof: proof.InnerProductProof, Params: proof.Params}
+    return bp.validateTerms(int64(len(proof.V)))
+}
+
+/*
+validate checks the generic range proof and both of its BulletProofs, which must
+have the same group.
+*/
+func (proof *ProofBPRP) validate() error {
+    if proof.A == nil {
+        return &FieldError{Field: "A", Index: -1, Err: ErrMissingElement}
+    }
+    if proof.B == nil {
+        return &FieldError{Field: "B", Index: -1, Err: ErrMissingElement}
+    }
+    g := proof.P1.Params.group()
+    if proof.P2.Params.group() != g {
+        return &FieldError{Field: "P2", Index: -1, Err: ErrInvalidGroup}
+    }
+    err := checkPoint(g, "V", proof.V)
+    if err != nil {
+        return err
+    }
+    err = proof.P1.validate()
+    if err != nil {
+        return inField("P1", err)
+    }
+    return inField("P2", proof.P2.validate())
+}
+
+/*
+validate checks the range proof of dalek-cryptography, whose group is the group
+of A.
+*/
+func (proof *DalekRangeProof) validate() error {
+    if proof.A == nil {
+        return &FieldError{Field: "A", Index: -1, Err: ErrMissingElement}
+    }
+    g := proof.A.Group()
+    return firstError(
+        checkPoint(g, "S", proof.S),
+        checkPoint(g, "T1", proof.T1),
+        checkPoint(g, "T2", proof.T2),
+        checkScalar(g.Order(), "Tx", proof.Tx),
+        checkScalar(g.Order(), "TxBlinding", proof.TxBlinding),
+        checkScalar(g.Order(), "EBlinding", proof.EBlinding),
+        inField("InnerProductProof", proof.InnerProductProof.validate(g)))
+}
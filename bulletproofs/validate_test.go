@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "encoding/json"
+    "errors"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/merlin"
+    "github.com/stretchr/testify/assert"
+)
+
+/*
+decodeTampered encodes proof, applies tamper to a decoded copy and returns the
+error of decoding it again.
+*/
+func decodeTampered(t *testing.T, proof BulletProof, tamper func(p *BulletProof)) error {
+    data, _ := json.Marshal(proof)
+    var tampered BulletProof
+    assert.NoError(t, json.Unmarshal(data, &tampered))
+    tamper(&tampered)
+    data, err := json.Marshal(tampered)
+    assert.NoError(t, err)
+    var decoded BulletProof
+    return json.Unmarshal(data, &decoded)
+}
+
+/*
+assertFieldError checks that err is a FieldError of field and index, which
+errors.Is matches with target.
+*/
+func assertFieldError(t *testing.T, target error, field string, index int, err error) {
+    assert.True(t, errors.Is(err, target), "expected %v, actual: %v", target, err)
+    var fieldErr *FieldError
+    if assert.True(t, errors.As(err, &fieldErr), "expected a FieldError, actual: %v", err) {
+        assert.Equal(t, field, fieldErr.Field)
+        assert.Equal(t, index, fieldErr.Index)
+    }
+}
+
+func TestValidateBulletProof(t *testing.T) {
+    params, _ := SetupBits(16)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    order := params.group().Order()
+    other := group.P256.Generator()
+
+    assert.NoError(t, decodeTampered(t, proof, func(p *BulletProof) {}))
+    assert.NoError(t, decodeTampered(t, proof, func(p *BulletProof) { p.V = nil }))
+    assertFieldError(t, ErrMissingElement, "S", -1, decodeTampered(t, proof, func(p *BulletProof) { p.S = nil }))
+    assertFieldError(t, ErrMissingElement, "Mu", -1, decodeTampered(t, proof, func(p *BulletProof) { p.Mu = nil }))
+    assertFieldError(t, ErrInvalidGroup, "A", -1, decodeTampered(t, proof, func(p *BulletProof) { p.A = other }))
+    assertFieldError(t, ErrInvalidGroup, "V", -1, decodeTampered(t, proof, func(p *BulletProof) { p.V = other }))
+    assertFieldError(t, ErrInvalidGroup, "Params.Gg", 3, decodeTampered(t, proof, func(p *BulletProof) { p.Params.Gg[3] = other }))
+    assertFieldError(t, ErrInvalidScalarEncoding, "Taux", -1, decodeTampered(t, proof, func(p *BulletProof) { p.Taux = order }))
+    assertFieldError(t, ErrInvalidScalarEncoding, "Tprime", -1, decodeTampered(t, proof, func(p *BulletProof) { p.Tprime = big.NewInt(-1) }))
+    assertFieldError(t, ErrInvalidScalarEncoding, "InnerProductProof.B", -1, decodeTampered(t, proof, func(p *BulletProof) {
+        p.InnerProductProof.B = new(big.Int).Add(p.InnerProductProof.B, order)
+    }))
+    assertFieldError(t, ErrInvalidRounds, "InnerProductProof.Ls", -1, decodeTampered(t, proof, func(p *BulletProof) {
+        p.InnerProductProof.Ls = p.InnerProductProof.Ls[1:]
+    }))
+    assertFieldError(t, ErrInvalidRounds, "InnerProductProof.Ls", -1, decodeTampered(t, proof, func(p *BulletProof) {
+        p.InnerProductProof.Ls = p.InnerProductProof.Ls[1:]
+        p.InnerProductProof.Rs = p.InnerProductProof.Rs[1:]
+    }))
+    assertFieldError(t, ErrInvalidRounds, "InnerProductProof.Ls", -1, decodeTampered(t, proof, func(p *BulletProof) { p.Params.N = 32 }))
+    assertFieldError(t, ErrInvalidParams, "Params.Hh", -1, decodeTampered(t, proof, func(p *BulletProof) { p.Params.Hh = p.Params.Hh[1:] }))
+}
+
+func TestValidateInvalidPointEncoding(t *testing.T) {
+    params, _ := SetupBits(16)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    data, _ := json.Marshal(proof)
+    var fields map[string]json.RawMessage
+    assert.NoError(t, json.Unmarshal(data, &fields))
+
+    // x = 5 is not the X coordinate of any point of secp256k1
+    fields["T1"] = json.RawMessage(`"secp256k1:020000000000000000000000000000000000000000000000000000000000000005"`)
+    data, _ = json.Marshal(fields)
+    var decoded BulletProof
+    assert.Equal(t, ErrInvalidPointEncoding, json.Unmarshal(data, &decoded))
+
+    fields["T1"] = json.RawMessage(`"unknown:02"`)
+    data, _ = json.Marshal(fields)
+    assert.Equal(t, ErrInvalidPointEncoding, json.Unmarshal(data, &decoded))
+}
+
+func TestValidateAggregatedBulletProof(t *testing.T) {
+    params, _ := SetupAggregatedBits(8, 2)
+    proof, _ := ProveAggregated([]*big.Int{big.NewInt(3), big.NewInt(200)}, params)
+    data, _ := json.Marshal(proof)
+    var decoded, rejected AggregatedBulletProof
+    assert.NoError(t, json.Unmarshal(data, &decoded))
+
+    tampered := decoded
+    tampered.V = nil
+    data, _ = json.Marshal(tampered)
+    assertFieldError(t, ErrMissingElement, "V", -1, json.Unmarshal(data, &rejected))
+
+    tampered = decoded
+    tampered.V = tampered.V[:1]
+    data, _ = json.Marshal(tampered)
+    assertFieldError(t, ErrInvalidRounds, "InnerProductProof.Ls", -1, json.Unmarshal(data, &rejected))
+}
+
+func TestValidateProofBPRP(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+    data, _ := json.Marshal(proof)
+    var decoded, rejected ProofBPRP
+    assert.NoError(t, json.Unmarshal(data, &decoded))
+
+    tampered := decoded
+    tampered.V = nil
+    data, _ = json.Marshal(tampered)
+    assertFieldError(t, ErrMissingElement, "V", -1, json.Unmarshal(data, &rejected))
+
+    tampered = decoded
+    tampered.P2.Mu = new(big.Int).Set(params.BP2.group().Order())
+    data, _ = json.Marshal(tampered)
+    assertFieldError(t, ErrInvalidScalarEncoding, "P2.Mu", -1, json.Unmarshal(data, &rejected))
+
+    tampered = decoded
+    tampered.P1.InnerProductProof.Rs = tampered.P1.InnerProductProof.Rs[1:]
+    data, _ = json.Marshal(tampered)
+    assertFieldError(t, ErrInvalidRounds, "P1.InnerProductProof.Rs", -1, json.Unmarshal(data, &rejected))
+}
+
+func TestValidateDalekRangeProof(t *testing.T) {
+    params, _ := SetupDalek(8, 1)
+    proof, _, _ := ProveDalek(merlin.NewTranscript("test"), []*big.Int{big.NewInt(42)}, params)
+    order := params.B.Group().Order()
+
+    tampered := proof
+    tampered.Tx = new(big.Int).Add(proof.Tx, order)
+    data, _ := json.Marshal(tampered)
+    var decoded DalekRangeProof
+    assertFieldError(t, ErrInvalidScalarEncoding, "Tx", -1, json.Unmarshal(data, &decoded))
+
+    tampered = proof
+    tampered.T2 = group.Secp256k1.Generator()
+    data, _ = json.Marshal(tampered)
+    assertFieldError(t, ErrInvalidGroup, "T2", -1, json.Unmarshal(data, &decoded))
+
+    tampered = proof
+    tampered.InnerProductProof.Ls = append([]group.Element{}, proof.InnerProductProof.Ls...)
+    tampered.InnerProductProof.Ls[1] = group.Secp256k1.Generator()
+    data, _ = json.Marshal(tampered)
+    err := json.Unmarshal(data, &decoded)
+    assertFieldError(t, ErrInvalidGroup, "InnerProductProof.Ls", 1, err)
+    assert.Equal(t, "InnerProductProof.Ls[1]: "+ErrInvalidGroup.Error(), err.Error())
+}
@@ -19,9 +19,13 @@ package bulletproofs
 
 import (
     "errors"
+    "io"
     "math/big"
+    "sync"
 
+    "github.com/ing-bank/zkrp/crypto/group"
     "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/internal/arith"
     "github.com/ing-bank/zkrp/util/bn"
 )
 
@@ -63,6 +67,13 @@ func VectorConvertToBig(a []int64, n int64) ([]*big.Int, error) {
 VectorAdd computes vector addition componentwisely.
 */
 func VectorAdd(a, b []*big.Int) ([]*big.Int, error) {
+    return vectorAdd(a, b, ORDER)
+}
+
+/*
+vectorAdd computes the vector addition modulo order.
+*/
+func vectorAdd(a, b []*big.Int, order *big.Int) ([]*big.Int, error) {
     var (
         result  []*big.Int
         i, n, m int64
@@ -76,7 +87,7 @@ func VectorAdd(a, b []*big.Int) ([]*big.Int, error) {
     result = make([]*big.Int, n)
     for i < n {
         result[i] = bn.Add(a[i], b[i])
-        result[i] = bn.Mod(result[i], ORDER)
+        result[i] = bn.Mod(result[i], order)
         i = i + 1
     }
     return result, nil
@@ -86,6 +97,13 @@ func VectorAdd(a, b []*big.Int) ([]*big.Int, error) {
 VectorSub computes vector addition componentwisely.
 */
 func VectorSub(a, b []*big.Int) ([]*big.Int, error) {
+    return vectorSub(a, b, ORDER)
+}
+
+/*
+vectorSub computes the vector subtraction modulo order.
+*/
+func vectorSub(a, b []*big.Int, order *big.Int) ([]*big.Int, error) {
     var (
         result  []*big.Int
         i, n, m int64
@@ -99,7 +117,7 @@ func VectorSub(a, b []*big.Int) ([]*big.Int, error) {
     result = make([]*big.Int, n)
     for i < n {
         result[i] = bn.Sub(a[i], b[i])
-        result[i] = bn.Mod(result[i], ORDER)
+        result[i] = bn.Mod(result[i], order)
         i = i + 1
     }
     return result, nil
@@ -109,6 +127,13 @@ func VectorSub(a, b []*big.Int) ([]*big.Int, error) {
 VectorScalarMul computes vector scalar multiplication componentwisely.
 */
 func VectorScalarMul(a []*big.Int, b *big.Int) ([]*big.Int, error) {
+    return vectorScalarMul(a, b, ORDER)
+}
+
+/*
+vectorScalarMul computes the vector scalar multiplication modulo order.
+*/
+func vectorScalarMul(a []*big.Int, b *big.Int, order *big.Int) ([]*big.Int, error) {
     var (
         result []*big.Int
         i, n   int64
@@ -118,7 +143,7 @@ func VectorScalarMul(a []*big.Int, b *big.Int) ([]*big.Int, error) {
     result = make([]*big.Int, n)
     for i < n {
         result[i] = bn.Multiply(a[i], b)
-        result[i] = bn.Mod(result[i], ORDER)
+        result[i] = bn.Mod(result[i], order)
         i = i + 1
     }
     return result, nil
@@ -128,6 +153,13 @@ func VectorScalarMul(a []*big.Int, b *big.Int) ([]*big.Int, error) {
 VectorMul computes vector multiplication componentwisely.
 */
 func VectorMul(a, b []*big.Int) ([]*big.Int, error) {
+    return vectorMul(a, b, ORDER)
+}
+
+/*
+vectorMul computes the componentwise vector multiplication modulo order.
+*/
+func vectorMul(a, b []*big.Int, order *big.Int) ([]*big.Int, error) {
     var (
         result  []*big.Int
         i, n, m int64
@@ -141,7 +173,7 @@ func VectorMul(a, b []*big.Int) ([]*big.Int, error) {
     result = make([]*big.Int, n)
     for i < n {
         result[i] = bn.Multiply(a[i], b[i])
-        result[i] = bn.Mod(result[i], ORDER)
+        result[i] = bn.Mod(result[i], order)
         i = i + 1
     }
     return result, nil
@@ -150,9 +182,9 @@ func VectorMul(a, b []*big.Int) ([]*big.Int, error) {
 /*
 VectorECMul computes vector EC addition componentwisely.
 */
-func VectorECAdd(a, b []*p256.P256) ([]*p256.P256, error) {
+func VectorECAdd(a, b []group.Element) ([]group.Element, error) {
     var (
-        result  []*p256.P256
+        result  []group.Element
         i, n, m int64
     )
     n = int64(len(a))
@@ -160,11 +192,377 @@ func VectorECAdd(a, b []*p256.P256) ([]*p256.P256, error) {
     if n != m {
         return nil, errors.New("Size of first argument is different from size of second argument.")
     }
-    result = make([]*p256.P256, n)
+    result = make([]group.Element, n)
     i = 0
     for i < n {
-        result[i] = new(p256.P256).Multiply(a[i], b[i])
+        result[i] = a[i].Add(b[i])
         i = i + 1
     }
     return result, nil
 }
+
+/*
+scalarVector is a vector of scalars modulo the order of a group, on which the
+provers compute. Modulo the order of secp256k1 the entries are the fixed-width
+scalars of p256 and modulo the other orders of at most 256 bits they are the
+residues of internal/arith, so that the arithmetic on secrets never goes through
+big.Int; only an order that does not fit in 4 limbs falls back to big.Int. The operands of an operation must have the same
+order and the same size. The scalars are converted from big.Int when they enter
+the prover, as bits, random values or challenges, and back to big.Int by bigs
+when they leave it, in commitments and in the proof.
+*/
+type scalarVector interface {
+    // size returns the number of entries.
+    size() int
+    // slice returns the entries from i to j - 1, which share the storage.
+    slice(i, j int) scalarVector
+    // concat returns the entries followed by the entries of b.
+    concat(b scalarVector) scalarVector
+    // add returns the componentwise sum.
+    add(b scalarVector) scalarVector
+    // sub returns the componentwise difference.
+    sub(b scalarVector) scalarVector
+    // mul returns the componentwise product.
+    mul(b scalarVector) scalarVector
+    // scale returns the entries multiplied by k.
+    scale(k *big.Int) scalarVector
+    // innerProduct returns the sum of the products of the entries with those of b.
+    innerProduct(b scalarVector) *big.Int
+    // bigs returns the entries as big.Int, reduced modulo the order.
+    bigs() []*big.Int
+}
+
+/*
+newScalarVector returns the vector of the integers a modulo order.
+*/
+func newScalarVector(order *big.Int, a []*big.Int) scalarVector {
+    if m := residueModulus(order); m != nil {
+        v := residueVector{v: make([]arith.Residue, len(a)), m: m}
+        for i := range a {
+            m.SetBig(&v.v[i], a[i])
+        }
+        return v
+    }
+    if order.Cmp(p256.CURVE.N) != 0 {
+        v := make([]*big.Int, len(a))
+        for i := range a {
+            v[i] = bn.Mod(a[i], order)
+        }
+        return bigVector{v: v, order: order}
+    }
+    v := make(secp256k1Vector, len(a))
+    for i := range a {
+        v[i].SetBig(a[i])
+    }
+    return v
+}
+
+/*
+int64ScalarVector returns the vector of the integers a modulo order, such as the
+bits of a secret.
+*/
+func int64ScalarVector(order *big.Int, a []int64) scalarVector {
+    if m := residueModulus(order); m != nil {
+        v := residueVector{v: make([]arith.Residue, len(a)), m: m}
+        for i := range a {
+            if a[i] < 0 {
+                m.Neg(&v.v[i], m.SetUint64(&v.v[i], uint64(-a[i])))
+            } else {
+                m.SetUint64(&v.v[i], uint64(a[i]))
+            }
+        }
+        return v
+    }
+    if order.Cmp(p256.CURVE.N) != 0 {
+        v, _ := VectorConvertToBig(a, int64(len(a)))
+        return newScalarVector(order, v)
+    }
+    v := make(secp256k1Vector, len(a))
+    for i := range a {
+        if a[i] < 0 {
+            v[i].Neg(v[i].SetUint64(uint64(-a[i])))
+        } else {
+            v[i].SetUint64(uint64(a[i]))
+        }
+    }
+    return v
+}
+
+/*
+constantScalarVector returns the vector of size n whose entries are all x.
+*/
+func constantScalarVector(order, x *big.Int, n int64) scalarVector {
+    v, _ := VectorCopy(x, n)
+    return newScalarVector(order, v)
+}
+
+/*
+powerScalarVector returns the vector of the powers x^i modulo order, for i from
+0 to n - 1.
+*/
+func powerScalarVector(order, x *big.Int, n int64) scalarVector {
+    if m := residueModulus(order); m != nil {
+        v := residueVector{v: make([]arith.Residue, n), m: m}
+        var s, current arith.Residue
+        m.SetBig(&s, x)
+        m.One(&current)
+        for i := range v.v {
+            v.v[i] = current
+            m.Mul(&current, &current, &s)
+        }
+        return v
+    }
+    if order.Cmp(p256.CURVE.N) != 0 {
+        return bigVector{v: powerOf(x, n, order), order: order}
+    }
+    v := make(secp256k1Vector, n)
+    var s, current p256.Scalar
+    s.SetBig(x)
+    current.SetUint64(1)
+    for i := range v {
+        v[i] = current
+        current.Mul(&current, &s)
+    }
+    return v
+}
+
+/*
+secretCombination returns the sum of the products coefficients[i].secrets[i]
+modulo order, such as the combinations of the blinding factors in a proof, on
+the fixed-width scalars of a scalarVector.
+*/
+func secretCombination(order *big.Int, secrets, coefficients []*big.Int) *big.Int {
+    return newScalarVector(order, secrets).innerProduct(newScalarVector(order, coefficients))
+}
+
+/*
+randomScalarVector returns a vector of n scalars modulo order read from random.
+*/
+func randomScalarVector(random io.Reader, n int64, order *big.Int) (scalarVector, error) {
+    v, err := sampleRandomVector(random, n, order)
+    if err != nil {
+        return nil, err
+    }
+    return newScalarVector(order, v), nil
+}
+
+/*
+secp256k1Vector is a scalarVector modulo the order of secp256k1.
+*/
+type secp256k1Vector []p256.Scalar
+
+func (a secp256k1Vector) size() int {
+    return len(a)
+}
+
+func (a secp256k1Vector) slice(i, j int) scalarVector {
+    return a[i:j]
+}
+
+func (a secp256k1Vector) concat(b scalarVector) scalarVector {
+    result := make(secp256k1Vector, 0, len(a)+b.size())
+    return append(append(result, a...), b.(secp256k1Vector)...)
+}
+
+func (a secp256k1Vector) add(b scalarVector) scalarVector {
+    return a.apply(b.(secp256k1Vector), (*p256.Scalar).Add)
+}
+
+func (a secp256k1Vector) sub(b scalarVector) scalarVector {
+    return a.apply(b.(secp256k1Vector), (*p256.Scalar).Sub)
+}
+
+func (a secp256k1Vector) mul(b scalarVector) scalarVector {
+    return a.apply(b.(secp256k1Vector), (*p256.Scalar).Mul)
+}
+
+/*
+apply returns the vector op(a[i], b[i]).
+*/
+func (a secp256k1Vector) apply(b secp256k1Vector, op func(z, x, y *p256.Scalar) *p256.Scalar) secp256k1Vector {
+    b = b[:len(a)]
+    result := make(secp256k1Vector, len(a))
+    for i := range a {
+        op(&result[i], &a[i], &b[i])
+    }
+    return result
+}
+
+func (a secp256k1Vector) scale(k *big.Int) scalarVector {
+    var s p256.Scalar
+    s.SetBig(k)
+    result := make(secp256k1Vector, len(a))
+    for i := range a {
+        result[i].Mul(&a[i], &s)
+    }
+    return result
+}
+
+func (a secp256k1Vector) innerProduct(b scalarVector) *big.Int {
+    c := b.(secp256k1Vector)[:len(a)]
+    var sum, product p256.Scalar
+    for i := range a {
+        sum.Add(&sum, product.Mul(&a[i], &c[i]))
+    }
+    return sum.Big()
+}
+
+func (a secp256k1Vector) bigs() []*big.Int {
+    result := make([]*big.Int, len(a))
+    for i := range a {
+        result[i] = a[i].Big()
+    }
+    return result
+}
+
+/*
+moduli caches the arith.Modulus of the orders, which are few.
+*/
+var moduli sync.Map
+
+/*
+residueModulus returns the modulus of the residues of the vectors modulo order,
+or nil when order is the one of secp256k1, which has its own scalars, or does not
+fit in 4 limbs.
+*/
+func residueModulus(order *big.Int) *arith.Modulus {
+    if order.Cmp(p256.CURVE.N) == 0 {
+        return nil
+    }
+    key := order.String()
+    if m, ok := moduli.Load(key); ok {
+        return m.(*arith.Modulus)
+    }
+    m, err := arith.NewModulus(order)
+    if err != nil {
+        return nil
+    }
+    moduli.Store(key, m)
+    return m
+}
+
+/*
+residueVector is a scalarVector of residues modulo an odd order of at most 256
+bits.
+*/
+type residueVector struct {
+    v []arith.Residue
+    m *arith.Modulus
+}
+
+func (a residueVector) size() int {
+    return len(a.v)
+}
+
+func (a residueVector) slice(i, j int) scalarVector {
+    return residueVector{v: a.v[i:j], m: a.m}
+}
+
+func (a residueVector) concat(b scalarVector) scalarVector {
+    v := make([]arith.Residue, 0, len(a.v)+b.size())
+    v = append(append(v, a.v...), b.(residueVector).v...)
+    return residueVector{v: v, m: a.m}
+}
+
+func (a residueVector) add(b scalarVector) scalarVector {
+    return a.apply(b.(residueVector), a.m.Add)
+}
+
+func (a residueVector) sub(b scalarVector) scalarVector {
+    return a.apply(b.(residueVector), a.m.Sub)
+}
+
+func (a residueVector) mul(b scalarVector) scalarVector {
+    return a.apply(b.(residueVector), a.m.Mul)
+}
+
+/*
+apply returns the vector op(a[i], b[i]).
+*/
+func (a residueVector) apply(b residueVector, op func(z, x, y *arith.Residue) *arith.Residue) residueVector {
+    c := b.v[:len(a.v)]
+    result := residueVector{v: make([]arith.Residue, len(a.v)), m: a.m}
+    for i := range a.v {
+        op(&result.v[i], &a.v[i], &c[i])
+    }
+    return result
+}
+
+func (a residueVector) scale(k *big.Int) scalarVector {
+    var s arith.Residue
+    a.m.SetBig(&s, k)
+    result := residueVector{v: make([]arith.Residue, len(a.v)), m: a.m}
+    for i := range a.v {
+        a.m.Mul(&result.v[i], &a.v[i], &s)
+    }
+    return result
+}
+
+func (a residueVector) innerProduct(b scalarVector) *big.Int {
+    c := b.(residueVector).v[:len(a.v)]
+    var sum, product arith.Residue
+    for i := range a.v {
+        a.m.Add(&sum, &sum, a.m.Mul(&product, &a.v[i], &c[i]))
+    }
+    return a.m.ToBig(&sum)
+}
+
+func (a residueVector) bigs() []*big.Int {
+    result := make([]*big.Int, len(a.v))
+    for i := range a.v {
+        result[i] = a.m.ToBig(&a.v[i])
+    }
+    return result
+}
+
+/*
+bigVector is a scalarVector of reduced big.Int modulo an order that does not fit
+in 4 limbs.
+*/
+type bigVector struct {
+    v     []*big.Int
+    order *big.Int
+}
+
+func (a bigVector) size() int {
+    return len(a.v)
+}
+
+func (a bigVector) slice(i, j int) scalarVector {
+    return bigVector{v: a.v[i:j], order: a.order}
+}
+
+func (a bigVector) concat(b scalarVector) scalarVector {
+    v := make([]*big.Int, 0, len(a.v)+b.size())
+    v = append(append(v, a.v...), b.(bigVector).v...)
+    return bigVector{v: v, order: a.order}
+}
+
+func (a bigVector) add(b scalarVector) scalarVector {
+    v, _ := vectorAdd(a.v, b.(bigVector).v, a.order)
+    return bigVector{v: v, order: a.order}
+}
+
+func (a bigVector) sub(b scalarVector) scalarVector {
+    v, _ := vectorSub(a.v, b.(bigVector).v, a.order)
+    return bigVector{v: v, order: a.order}
+}
+
+func (a bigVector) mul(b scalarVector) scalarVector {
+    v, _ := vectorMul(a.v, b.(bigVector).v, a.order)
+    return bigVector{v: v, order: a.order}
+}
+
+func (a bigVector) scale(k *big.Int) scalarVector {
+    v, _ := vectorScalarMul(a.v, k, a.order)
+    return bigVector{v: v, order: a.order}
+}
+
+func (a bigVector) innerProduct(b scalarVector) *big.Int {
+    result, _ := scalarProduct(a.v, b.(bigVector).v, a.order)
+    return result
+}
+
+func (a bigVector) bigs() []*big.Int {
+    return a.v
+}
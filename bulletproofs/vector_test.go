@@ -18,9 +18,11 @@
 package bulletproofs
 
 import (
+    "crypto/rand"
     "math/big"
     "testing"
 
+    "github.com/ing-bank/zkrp/crypto/group"
     "github.com/ing-bank/zkrp/util/intconversion"
 )
 
@@ -133,3 +135,55 @@ func TestVectorMul(t *testing.T) {
         t.Errorf("Assert failure: expected true, actual: %t", ok)
     }
 }
+
+/*
+TestVectorOrders compares the scalar vectors modulo the order of secp256k1, which
+use the scalars of p256, and modulo the orders of bn256 G1 and ristretto255,
+which use the residues of internal/arith, with the same operations computed by
+math/big. The inputs may be negative or larger than the order.
+*/
+func TestVectorOrders(t *testing.T) {
+    bound := new(big.Int).Lsh(big.NewInt(1), 300)
+    for _, order := range []*big.Int{ORDER, group.BN256G1.Order(), group.Ristretto255.Order()} {
+        a := make([]*big.Int, 8)
+        b := make([]*big.Int, 8)
+        for i := range a {
+            a[i], _ = rand.Int(rand.Reader, bound)
+            b[i], _ = rand.Int(rand.Reader, order)
+        }
+        a[0].Neg(a[0])
+        mod := func(x *big.Int) *big.Int { return x.Mod(x, order) }
+        va, vb := newScalarVector(order, a), newScalarVector(order, b)
+        sum := va.add(vb).bigs()
+        diff := va.sub(vb).bigs()
+        prod := va.mul(vb).bigs()
+        scaled := va.scale(b[1]).bigs()
+        powers := powerScalarVector(order, b[2], 8).bigs()
+        bits := int64ScalarVector(order, []int64{-1, 0, 1, -1, 1, 0, 2, -2}).bigs()
+        halves := va.slice(4, 8).concat(va.slice(0, 4)).bigs()
+        expectedProduct := new(big.Int)
+        for i := range a {
+            ok := sum[i].Cmp(mod(new(big.Int).Add(a[i], b[i]))) == 0
+            ok = ok && diff[i].Cmp(mod(new(big.Int).Sub(a[i], b[i]))) == 0
+            ok = ok && prod[i].Cmp(mod(new(big.Int).Mul(a[i], b[i]))) == 0
+            ok = ok && scaled[i].Cmp(mod(new(big.Int).Mul(a[i], b[1]))) == 0
+            ok = ok && powers[i].Cmp(new(big.Int).Exp(b[2], big.NewInt(int64(i)), order)) == 0
+            ok = ok && halves[i].Cmp(mod(new(big.Int).Set(a[(i+4)%8]))) == 0
+            if !ok {
+                t.Errorf("Assert failure: vector operations modulo %s differ at index %d", order, i)
+            }
+            expectedProduct.Add(expectedProduct, new(big.Int).Mul(a[i], b[i]))
+        }
+        if bits[0].Cmp(new(big.Int).Sub(order, big.NewInt(1))) != 0 || bits[6].Int64() != 2 || bits[7].Cmp(new(big.Int).Sub(order, big.NewInt(2))) != 0 {
+            t.Errorf("Assert failure: small integers modulo %s, actual: %s", order, bits)
+        }
+        product := va.innerProduct(vb)
+        if product.Cmp(mod(expectedProduct)) != 0 {
+            t.Errorf("Assert failure: scalar product modulo %s, expected %s, actual: %s", order, expectedProduct, product)
+        }
+        combination := secretCombination(order, a, b)
+        if combination.Cmp(product) != 0 {
+            t.Errorf("Assert failure: secret combination modulo %s, expected %s, actual: %s", order, product, combination)
+        }
+    }
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "encoding/hex"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/internal/golden"
+    "github.com/stretchr/testify/assert"
+)
+
+const vectorsFile = "testdata/vectors.json"
+
+/*
+knownAnswer is a proof computed with a DeterministicReader instantiated with Seed
+and Data. For range proofs Bits is the bit-length, for generic proofs the
+interval is [Start, End). Secrets are decimal, every other field is hex.
+*/
+type knownAnswer struct {
+    Name        string   `json:"name"`
+    Seed        string   `json:"seed"`
+    Data        string   `json:"data"`
+    Bits        int64    `json:"bits,omitempty"`
+    Start       string   `json:"start,omitempty"`
+    End         string   `json:"end,omitempty"`
+    Secrets     []string `json:"secrets"`
+    Commitments []string `json:"commitments"`
+    Proof       string   `json:"proof"`
+}
+
+type knownAnswers struct {
+    RangeProofs      []knownAnswer `json:"range_proofs"`
+    AggregatedProofs []knownAnswer `json:"aggregated_proofs"`
+    GenericProofs    []knownAnswer `json:"generic_proofs"`
+}
+
+func knownAnswerCases() knownAnswers {
+    return knownAnswers{
+        RangeProofs: []knownAnswer{
+            {Name: "8 bits, zero", Seed: "00", Data: "00", Bits: 8, Secrets: []string{"0"}},
+            {Name: "32 bits", Seed: "0102030405060708", Data: "2a", Bits: 32, Secrets: []string{"42"}},
+            {Name: "40 bits, maximum", Seed: "ffff", Data: "ffffffffff", Bits: 40, Secrets: []string{"1099511627775"}},
+            {Name: "64 bits", Seed: "7365656421", Data: "0de0b6b3a7640000", Bits: 64, Secrets: []string{"1000000000000000000"}},
+        },
+        AggregatedProofs: []knownAnswer{
+            {Name: "2 x 16 bits", Seed: "aa", Data: "0102", Bits: 16, Secrets: []string{"1", "65535"}},
+            {Name: "4 x 8 bits", Seed: "bb", Data: "01020304", Bits: 8, Secrets: []string{"1", "2", "3", "4"}},
+        },
+        GenericProofs: []knownAnswer{
+            {Name: "[18, 200)", Seed: "cc", Data: "28", Start: "18", End: "200", Secrets: []string{"40"}},
+        },
+    }
+}
+
+/*
+computeKnownAnswer fills the commitments and the proof of the vector.
+*/
+func computeKnownAnswer(t *testing.T, kind string, vector knownAnswer) knownAnswer {
+    random := golden.Reader(vector.Seed, vector.Data)
+    secrets := make([]*big.Int, len(vector.Secrets))
+    for i := range vector.Secrets {
+        secrets[i], _ = new(big.Int).SetString(vector.Secrets[i], 10)
+    }
+
+    var (
+        V       []group.Element
+        encoded []byte
+    )
+    switch kind {
+    case "range":
+        params, err := SetupBits(vector.Bits)
+        assert.NoError(t, err)
+        proof, _, err := ProveWithRand(random, secrets[0], params)
+        assert.NoError(t, err)
+        V = []group.Element{proof.V}
+        encoded, _ = proof.MarshalBinary()
+    case "aggregated":
+        params, err := SetupAggregatedBits(vector.Bits, int64(len(secrets)))
+        assert.NoError(t, err)
+        proof, err := ProveAggregatedWithRand(random, secrets, params)
+        assert.NoError(t, err)
+        V = proof.V
+        encoded, _ = proof.MarshalBinary()
+    case "generic":
+        start, _ := new(big.Int).SetString(vector.Start, 10)
+        end, _ := new(big.Int).SetString(vector.End, 10)
+        params, err := SetupGenericBig(start, end)
+        assert.NoError(t, err)
+        proof, err := ProveGenericWithRand(random, secrets[0], params)
+        assert.NoError(t, err)
+        V = []group.Element{proof.V}
+        encoded, _ = proof.MarshalBinary()
+    }
+    vector.Commitments = make([]string, len(V))
+    for i := range V {
+        vector.Commitments[i] = hex.EncodeToString(V[i].Encode())
+    }
+    vector.Proof = hex.EncodeToString(encoded)
+    return vector
+}
+
+/*
+TestKnownAnswers recomputes every proof of testdata/vectors.json byte for byte.
+Run the tests with -update to rewrite the file.
+*/
+func TestKnownAnswers(t *testing.T) {
+    golden.Update(t, vectorsFile, func() interface{} {
+        vectors := knownAnswerCases()
+        for i := range vectors.RangeProofs {
+            vectors.RangeProofs[i] = computeKnownAnswer(t, "range", vectors.RangeProofs[i])
+        }
+        for i := range vectors.AggregatedProofs {
+            vectors.AggregatedProofs[i] = computeKnownAnswer(t, "aggregated", vectors.AggregatedProofs[i])
+        }
+        for i := range vectors.GenericProofs {
+            vectors.GenericProofs[i] = computeKnownAnswer(t, "generic", vectors.GenericProofs[i])
+        }
+        return vectors
+    })
+
+    var vectors knownAnswers
+    golden.Read(t, vectorsFile, &vectors)
+    assert.NotEmpty(t, vectors.RangeProofs)
+
+    for _, vector := range vectors.RangeProofs {
+        assert.Equal(t, vector, computeKnownAnswer(t, "range", vector), vector.Name)
+        encoded, _ := hex.DecodeString(vector.Proof)
+        var proof BulletProof
+        assert.NoError(t, proof.UnmarshalBinary(encoded), vector.Name)
+        params, _ := SetupBits(vector.Bits)
+        V := decodeCommitment(t, vector.Commitments[0])
+        ok, _ := VerifyWithParams(proof, params, V)
+        assert.True(t, ok, vector.Name)
+    }
+    for _, vector := range vectors.AggregatedProofs {
+        assert.Equal(t, vector, computeKnownAnswer(t, "aggregated", vector), vector.Name)
+        encoded, _ := hex.DecodeString(vector.Proof)
+        var proof AggregatedBulletProof
+        assert.NoError(t, proof.UnmarshalBinary(encoded), vector.Name)
+        params, _ := SetupAggregatedBits(vector.Bits, int64(len(vector.Secrets)))
+        ok, _ := VerifyAggregatedWithParams(proof, params, proof.V)
+        assert.True(t, ok, vector.Name)
+    }
+    for _, vector := range vectors.GenericProofs {
+        assert.Equal(t, vector, computeKnownAnswer(t, "generic", vector), vector.Name)
+        encoded, _ := hex.DecodeString(vector.Proof)
+        var proof ProofBPRP
+        assert.NoError(t, proof.UnmarshalBinary(encoded), vector.Name)
+        start, _ := new(big.Int).SetString(vector.Start, 10)
+        end, _ := new(big.Int).SetString(vector.End, 10)
+        params, _ := SetupGenericBig(start, end)
+        V := decodeCommitment(t, vector.Commitments[0])
+        ok, _ := VerifyGenericWithParams(proof, params, V)
+        assert.True(t, ok, vector.Name)
+    }
+}
+
+func decodeCommitment(t *testing.T, s string) group.Element {
+    data, _ := hex.DecodeString(s)
+    V, err := group.Secp256k1.DecodeElement(data)
+    assert.NoError(t, err)
+    return V
+}
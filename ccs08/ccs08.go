@@ -28,6 +28,7 @@ import (
     "bytes"
     "crypto/rand"
     "errors"
+    "io"
     "math"
     "math/big"
     "strconv"
@@ -98,11 +99,23 @@ type proofUL struct {
 SetupSet generates the signature for the elements in the set.
 */
 func SetupSet(s []int64) (paramsSet, error) {
+    return SetupSetWithRand(rand.Reader, s)
+}
+
+/*
+SetupSetWithRand generates the signature for the elements in the set, reading the
+key of the signature scheme from random.
+*/
+func SetupSetWithRand(random io.Reader, s []int64) (paramsSet, error) {
     var (
-        i int
-        p paramsSet
+        i   int
+        p   paramsSet
+        err error
     )
-    p.kp, _ = bbsignatures.Keygen()
+    p.kp, err = bbsignatures.KeygenWithRand(random)
+    if err != nil {
+        return p, err
+    }
 
     p.signatures = make(map[int64]*bn256.G2)
     for i = 0; i < len(s); i++ {
@@ -121,11 +134,23 @@ The value of u should be roughly b/log(b), but we can choose smaller values in
 order to get smaller parameters, at the cost of having worse performance.
 */
 func SetupUL(u, l int64) (paramsUL, error) {
+    return SetupULWithRand(rand.Reader, u, l)
+}
+
+/*
+SetupULWithRand generates the signature for the interval [0,u^l), reading the key
+of the signature scheme from random.
+*/
+func SetupULWithRand(random io.Reader, u, l int64) (paramsUL, error) {
     var (
-        i int64
-        p paramsUL
+        i   int64
+        p   paramsUL
+        err error
     )
-    p.kp, _ = bbsignatures.Keygen()
+    p.kp, err = bbsignatures.KeygenWithRand(random)
+    if err != nil {
+        return p, err
+    }
 
     p.signatures = make(map[string]*bn256.G2)
     for i = 0; i < u; i++ {
@@ -144,17 +169,32 @@ func SetupUL(u, l int64) (paramsUL, error) {
 ProveSet method is used to produce the ZK Set Membership proof.
 */
 func ProveSet(x int64, r *big.Int, p paramsSet) (proofSet, error) {
+    return ProveSetWithRand(rand.Reader, x, r, p)
+}
+
+/*
+ProveSetWithRand produces the ZK Set Membership proof, reading every random value
+from random.
+*/
+func ProveSetWithRand(random io.Reader, x int64, r *big.Int, p paramsSet) (proofSet, error) {
     var (
         v         *big.Int
         proof_out proofSet
+        err       error
     )
 
     // Initialize variables
     proof_out.D = new(bn256.G2)
     proof_out.D.SetInfinity()
-    proof_out.m, _ = rand.Int(rand.Reader, bn256.Order)
+    proof_out.m, err = RandomScalar(random, bn256.Order)
+    if err != nil {
+        return proof_out, err
+    }
 
-    v, _ = rand.Int(rand.Reader, bn256.Order)
+    v, err = RandomScalar(random, bn256.Order)
+    if err != nil {
+        return proof_out, err
+    }
     A, ok := p.signatures[x]
 
     if !ok {
@@ -163,12 +203,18 @@ func ProveSet(x int64, r *big.Int, p paramsSet) (proofSet, error) {
 
     // D = g^s.H^m
     D := new(bn256.G2).ScalarMult(p.H, proof_out.m)
-    proof_out.s, _ = rand.Int(rand.Reader, bn256.Order)
+    proof_out.s, err = RandomScalar(random, bn256.Order)
+    if err != nil {
+        return proof_out, err
+    }
     aux := new(bn256.G2).ScalarBaseMult(proof_out.s)
     D.Add(D, aux)
 
     proof_out.V = new(bn256.G2).ScalarMult(A, v)
-    proof_out.t, _ = rand.Int(rand.Reader, bn256.Order)
+    proof_out.t, err = RandomScalar(random, bn256.Order)
+    if err != nil {
+        return proof_out, err
+    }
     proof_out.a = bn256.Pair(G1, proof_out.V)
     proof_out.a.ScalarMult(proof_out.a, proof_out.s)
     proof_out.a.Invert(proof_out.a)
@@ -195,10 +241,19 @@ func ProveSet(x int64, r *big.Int, p paramsSet) (proofSet, error) {
 ProveUL method is used to produce the ZKRP proof that secret x belongs to the interval [0,U^L].
 */
 func ProveUL(x, r *big.Int, p paramsUL) (proofUL, error) {
+    return ProveULWithRand(rand.Reader, x, r, p)
+}
+
+/*
+ProveULWithRand produces the ZKRP proof that secret x belongs to the interval
+[0,U^L], reading every random value from random.
+*/
+func ProveULWithRand(random io.Reader, x, r *big.Int, p paramsUL) (proofUL, error) {
     var (
         i         int64
         v         []*big.Int
         proof_out proofUL
+        err       error
     )
     decx, _ := Decompose(x, p.u, p.l)
 
@@ -212,17 +267,29 @@ func ProveUL(x, r *big.Int, p paramsUL) (proofUL, error) {
     proof_out.zv = make([]*big.Int, p.l)
     proof_out.D = new(bn256.G2)
     proof_out.D.SetInfinity()
-    proof_out.m, _ = rand.Int(rand.Reader, bn256.Order)
+    proof_out.m, err = RandomScalar(random, bn256.Order)
+    if err != nil {
+        return proof_out, err
+    }
 
     // D = H^m
     D := new(bn256.G2).ScalarMult(p.H, proof_out.m)
     for i = 0; i < p.l; i++ {
-        v[i], _ = rand.Int(rand.Reader, bn256.Order)
+        v[i], err = RandomScalar(random, bn256.Order)
+        if err != nil {
+            return proof_out, err
+        }
         A, ok := p.signatures[strconv.FormatInt(decx[i], 10)]
         if ok {
             proof_out.V[i] = new(bn256.G2).ScalarMult(A, v[i])
-            proof_out.s[i], _ = rand.Int(rand.Reader, bn256.Order)
-            proof_out.t[i], _ = rand.Int(rand.Reader, bn256.Order)
+            proof_out.s[i], err = RandomScalar(random, bn256.Order)
+            if err != nil {
+                return proof_out, err
+            }
+            proof_out.t[i], err = RandomScalar(random, bn256.Order)
+            if err != nil {
+                return proof_out, err
+            }
             proof_out.a[i] = bn256.Pair(G1, proof_out.V[i])
             proof_out.a[i].ScalarMult(proof_out.a[i], proof_out.s[i])
             proof_out.a[i].Invert(proof_out.a[i])
@@ -361,6 +428,14 @@ type ccs08 struct {
 SetupInnerProduct receives integers a and b, and configures the parameters for the rangeproof scheme.
 */
 func (zkrp *ccs08) Setup(a, b int64) error {
+    return zkrp.SetupWithRand(rand.Reader, a, b)
+}
+
+/*
+SetupWithRand computes the parameters like Setup, reading the key of the signature
+scheme from random.
+*/
+func (zkrp *ccs08) SetupWithRand(random io.Reader, a, b int64) error {
     // Compute optimal values for u and l
     var (
         u, l int64
@@ -381,7 +456,7 @@ func (zkrp *ccs08) Setup(a, b int64) error {
             for i := b; i > 0; i = i / u {
                 l = l + 1
             }
-            params_out, e := SetupUL(u, l)
+            params_out, e := SetupULWithRand(random, u, l)
             p.p = &params_out
             p.a = a
             p.b = b
@@ -401,16 +476,29 @@ func (zkrp *ccs08) Setup(a, b int64) error {
 Prove method is responsible for generating the zero knowledge proof.
 */
 func (zkrp *ccs08) Prove() error {
+    return zkrp.ProveWithRand(rand.Reader)
+}
+
+/*
+ProveWithRand generates the zero knowledge proof, reading every random value from random.
+*/
+func (zkrp *ccs08) ProveWithRand(random io.Reader) error {
     ul := new(big.Int).Exp(new(big.Int).SetInt64(zkrp.p.p.u), new(big.Int).SetInt64(zkrp.p.p.l), nil)
 
     // x - b + ul
     xb := new(big.Int).Sub(zkrp.x, new(big.Int).SetInt64(zkrp.p.b))
     xb.Add(xb, ul)
-    first, _ := ProveUL(xb, zkrp.r, *zkrp.p.p)
+    first, err := ProveULWithRand(random, xb, zkrp.r, *zkrp.p.p)
+    if err != nil {
+        return err
+    }
 
     // x - a
     xa := new(big.Int).Sub(zkrp.x, new(big.Int).SetInt64(zkrp.p.a))
-    second, _ := ProveUL(xa, zkrp.r, *zkrp.p.p)
+    second, err := ProveULWithRand(random, xa, zkrp.r, *zkrp.p.p)
+    if err != nil {
+        return err
+    }
 
     zkrp.proof_out.p1 = first
     zkrp.proof_out.p2 = second
@@ -0,0 +1,123 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ccs08
+
+import (
+    "encoding/hex"
+    "fmt"
+    "math/big"
+    "reflect"
+    "testing"
+
+    "github.com/ing-bank/zkrp/internal/golden"
+)
+
+const vectorsFile = "testdata/vectors.json"
+
+/*
+knownAnswer is a setup and a proof computed with a single DeterministicReader
+instantiated with Seed and Data. A vector either proves that X belongs to Set, or
+that X belongs to [0, U^L). X is decimal, every other field is hex.
+*/
+type knownAnswer struct {
+    Seed       string  `json:"seed"`
+    Data       string  `json:"data"`
+    Set        []int64 `json:"set,omitempty"`
+    U          int64   `json:"u,omitempty"`
+    L          int64   `json:"l,omitempty"`
+    X          string  `json:"x"`
+    R          string  `json:"r"`
+    PublicKey  string  `json:"public_key"`
+    Commitment string  `json:"commitment"`
+    D          string  `json:"d"`
+    C          string  `json:"c"`
+    Zr         string  `json:"zr"`
+}
+
+func computeKnownAnswer(t *testing.T, vector knownAnswer) knownAnswer {
+    random := golden.Reader(vector.Seed, vector.Data)
+    x, _ := new(big.Int).SetString(vector.X, 10)
+    r, _ := new(big.Int).SetString(vector.R, 16)
+
+    if vector.Set != nil {
+        p, err := SetupSetWithRand(random, vector.Set)
+        if err != nil {
+            t.Fatalf("Could not compute the setup: %s", err)
+        }
+        proof, err := ProveSetWithRand(random, x.Int64(), r, p)
+        if err != nil {
+            t.Fatalf("Could not compute the proof: %s", err)
+        }
+        if ok, _ := VerifySet(&proof, &p); !ok {
+            t.Errorf("Assert failure: set membership proof of %s does not verify", vector.X)
+        }
+        vector.PublicKey = hex.EncodeToString(p.kp.Pubk.Marshal())
+        vector.Commitment = hex.EncodeToString(proof.C.Marshal())
+        vector.D = hex.EncodeToString(proof.D.Marshal())
+        vector.C = fmt.Sprintf("%064x", proof.c)
+        vector.Zr = fmt.Sprintf("%064x", proof.zr)
+        return vector
+    }
+
+    p, err := SetupULWithRand(random, vector.U, vector.L)
+    if err != nil {
+        t.Fatalf("Could not compute the setup: %s", err)
+    }
+    proof, err := ProveULWithRand(random, x, r, p)
+    if err != nil {
+        t.Fatalf("Could not compute the proof: %s", err)
+    }
+    if ok, _ := VerifyUL(&proof, &p); !ok {
+        t.Errorf("Assert failure: range proof of %s does not verify", vector.X)
+    }
+    vector.PublicKey = hex.EncodeToString(p.kp.Pubk.Marshal())
+    vector.Commitment = hex.EncodeToString(proof.C.Marshal())
+    vector.D = hex.EncodeToString(proof.D.Marshal())
+    vector.C = fmt.Sprintf("%064x", proof.c)
+    vector.Zr = fmt.Sprintf("%064x", proof.zr)
+    return vector
+}
+
+/*
+TestKnownAnswers recomputes every proof of testdata/vectors.json. Run the tests
+with -update to rewrite the file.
+*/
+func TestKnownAnswers(t *testing.T) {
+    golden.Update(t, vectorsFile, func() interface{} {
+        vectors := []knownAnswer{
+            {Seed: "00", Data: "0c", Set: []int64{12, 42, 61, 71}, X: "12", R: "0b"},
+            {Seed: "0102030405060708", Data: "a4c0", U: 10, L: 5, X: "42176", R: "2a"},
+        }
+        for i := range vectors {
+            vectors[i] = computeKnownAnswer(t, vectors[i])
+        }
+        return vectors
+    })
+
+    var vectors []knownAnswer
+    golden.Read(t, vectorsFile, &vectors)
+    if len(vectors) == 0 {
+        t.Fatalf("Could not decode the vectors")
+    }
+    for _, vector := range vectors {
+        actual := computeKnownAnswer(t, vector)
+        if !reflect.DeepEqual(vector, actual) {
+            t.Errorf("Assert failure: expected %+v, actual: %+v", vector, actual)
+        }
+    }
+}
@@ -27,9 +27,11 @@ Eurocrypt 2004
 import (
     "crypto/rand"
     "errors"
+    "io"
     "math/big"
 
     "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/util"
     "github.com/ing-bank/zkrp/util/bn"
 )
 
@@ -42,17 +44,25 @@ type Keypair struct {
 keygen is responsible for the key generation.
 */
 func Keygen() (Keypair, error) {
+    return KeygenWithRand(rand.Reader)
+}
+
+/*
+KeygenWithRand is responsible for the key generation, reading the private key
+from the given source of randomness.
+*/
+func KeygenWithRand(random io.Reader) (Keypair, error) {
     var (
         kp  Keypair
         e   error
         res bool
     )
-    kp.Privk, e = rand.Int(rand.Reader, bn256.Order)
+    kp.Privk, e = util.RandomScalar(random, bn256.Order)
     if e != nil {
         return kp, e
     }
     kp.Pubk, res = new(bn256.G1).Unmarshal(new(bn256.G1).ScalarBaseMult(kp.Privk).Marshal())
-    if res {
+    if !res {
         return kp, errors.New("Could not compute scalar multiplication.")
     }
     return kp, e
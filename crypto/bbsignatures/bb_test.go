@@ -18,8 +18,11 @@
 package bbsignatures
 
 import (
+    "bytes"
     "math/big"
     "testing"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
 )
 
 func TestKeyGen(t *testing.T) {
@@ -31,3 +34,18 @@ func TestKeyGen(t *testing.T) {
         t.Fail()
     }
 }
+
+/*
+TestKeyGenNoError checks that the key generation only fails when the public key
+cannot be computed: it used to return an error whenever the public key was
+decoded successfully.
+*/
+func TestKeyGenNoError(t *testing.T) {
+    kp, err := Keygen()
+    if err != nil {
+        t.Errorf("Assert failure: expected no error, actual: %s", err)
+    }
+    if kp.Pubk == nil || !bytes.Equal(kp.Pubk.Marshal(), new(bn256.G1).ScalarBaseMult(kp.Privk).Marshal()) {
+        t.Errorf("Assert failure: public key is not g^privk")
+    }
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bbsignatures
+
+import (
+    "encoding/hex"
+    "fmt"
+    "math/big"
+    "reflect"
+    "testing"
+
+    "github.com/ing-bank/zkrp/internal/golden"
+)
+
+const vectorsFile = "testdata/vectors.json"
+
+/*
+knownAnswer is a key pair generated with a DeterministicReader instantiated with
+Seed and Data, and the signature of Message. Message is decimal, every other
+field is hex.
+*/
+type knownAnswer struct {
+    Seed       string `json:"seed"`
+    Data       string `json:"data"`
+    PrivateKey string `json:"private_key"`
+    PublicKey  string `json:"public_key"`
+    Message    string `json:"message"`
+    Signature  string `json:"signature"`
+}
+
+func computeKnownAnswer(t *testing.T, vector knownAnswer) knownAnswer {
+    kp, err := KeygenWithRand(golden.Reader(vector.Seed, vector.Data))
+    if err != nil {
+        t.Fatalf("Could not generate the key pair: %s", err)
+    }
+    m, _ := new(big.Int).SetString(vector.Message, 10)
+    signature, _ := Sign(m, kp.Privk)
+    vector.PrivateKey = fmt.Sprintf("%064x", kp.Privk)
+    vector.PublicKey = hex.EncodeToString(kp.Pubk.Marshal())
+    vector.Signature = hex.EncodeToString(signature.Marshal())
+    return vector
+}
+
+/*
+TestKnownAnswers recomputes every key pair and signature of testdata/vectors.json.
+Run the tests with -update to rewrite the file.
+*/
+func TestKnownAnswers(t *testing.T) {
+    golden.Update(t, vectorsFile, func() interface{} {
+        vectors := []knownAnswer{
+            {Seed: "00", Data: "", Message: "0"},
+            {Seed: "0102030405060708", Data: "6b6579", Message: "42"},
+            {Seed: "ffffffffffffffffffffffffffffffff", Data: "00", Message: "1000000007"},
+        }
+        for i := range vectors {
+            vectors[i] = computeKnownAnswer(t, vectors[i])
+        }
+        return vectors
+    })
+
+    var vectors []knownAnswer
+    golden.Read(t, vectorsFile, &vectors)
+    if len(vectors) == 0 {
+        t.Fatalf("Could not decode the vectors")
+    }
+    for _, vector := range vectors {
+        actual := computeKnownAnswer(t, vector)
+        if !reflect.DeepEqual(vector, actual) {
+            t.Errorf("Assert failure: expected %+v, actual: %+v", vector, actual)
+        }
+    }
+}
@@ -315,3 +315,36 @@ func BenchmarkPairing(b *testing.B) {
         Pair(&G1{curveGen}, &G2{twistGen})
     }
 }
+
+func TestG1SecretMultiScalarMult(t *testing.T) {
+    var points []*G1
+    var scalars []*big.Int
+    for i := 0; i < 4; i++ {
+        _, p, _ := RandomG1(rand.Reader)
+        k, _ := rand.Int(rand.Reader, Order)
+        points = append(points, p)
+        scalars = append(scalars, k)
+    }
+    // equal points, the point at infinity and edge scalars
+    points = append(points, points[0], new(G1).SetInfinity(), points[1], points[2], points[3])
+    scalars = append(scalars, scalars[0], big.NewInt(5), big.NewInt(0), new(big.Int).Sub(Order, big.NewInt(1)), new(big.Int).Neg(Order))
+
+    expected := new(G1).SetInfinity()
+    for i := range points {
+        term := new(G1).ScalarMult(points[i], scalars[i])
+        if !bytes.Equal(term.Marshal(), new(G1).SecretScalarMult(points[i], scalars[i]).Marshal()) {
+            t.Errorf("SecretScalarMult differs from ScalarMult for the term %d", i)
+        }
+        expected.Add(expected, term)
+    }
+    actual, err := new(G1).SecretMultiScalarMult(points, scalars)
+    if err != nil {
+        t.Fatalf("Unexpected error: %s", err)
+    }
+    if !bytes.Equal(expected.Marshal(), actual.Marshal()) {
+        t.Errorf("SecretMultiScalarMult differs from the sum of the terms")
+    }
+    if _, err := new(G1).SecretMultiScalarMult(points, scalars[1:]); err == nil {
+        t.Errorf("different lengths accepted")
+    }
+}
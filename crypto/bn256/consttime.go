@@ -0,0 +1,233 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bn256
+
+import (
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/internal/arith"
+)
+
+// This file contains the multiplications of points of G₁ by secret scalars, in
+// a time that depends on the number of points, but not on the scalars. The
+// field elements are the fixed-width residues of internal/arith instead of
+// big.Int, the points are in homogeneous projective coordinates, whose complete
+// formulas have no exceptional case, the scalars are processed by fixed windows
+// of 4 bits and the multiples are read by scanning the whole table.
+
+const (
+    // secretWindow is the bit-length of the unsigned digits of the secret scalars
+    secretWindow = 4
+    // secretTableSize is the number of multiples 0.P to 15.P of every point
+    secretTableSize = 1 << secretWindow
+)
+
+var (
+    // fieldP is the modulus of the arithmetic of GF(p)
+    fieldP, _ = arith.NewModulus(P)
+    // curveB3 is 3.b = 9, the constant of the complete formulas
+    curveB3 = *fieldP.SetUint64(new(arith.Residue), 9)
+)
+
+// secretPoint represents the point (x/z, y/z) of G₁. The point at infinity is
+// (0, 1, 0).
+type secretPoint struct {
+    x, y, z arith.Residue
+}
+
+func (p *secretPoint) setInfinity() *secretPoint {
+    *p = secretPoint{}
+    fieldP.One(&p.y)
+    return p
+}
+
+// setCurvePoint sets p to the public point a.
+func (p *secretPoint) setCurvePoint(a *curvePoint) *secretPoint {
+    affine := newCurvePoint(nil)
+    affine.Set(a)
+    affine.MakeAffine(nil)
+    if affine.IsInfinity() {
+        return p.setInfinity()
+    }
+    fieldP.SetBig(&p.x, affine.x)
+    fieldP.SetBig(&p.y, affine.y)
+    fieldP.One(&p.z)
+    return p
+}
+
+// add sets p = a + b with the complete addition formulas of Algorithm 7 of
+// Renes, Costello and Batina, "Complete addition formulas for prime order
+// elliptic curves", for curves with a = 0.
+func (p *secretPoint) add(a, b *secretPoint) *secretPoint {
+    f := fieldP
+    var t0, t1, t2, t3, t4, x3, y3, z3 arith.Residue
+    f.Mul(&t0, &a.x, &b.x)
+    f.Mul(&t1, &a.y, &b.y)
+    f.Mul(&t2, &a.z, &b.z)
+    f.Add(&t3, &a.x, &a.y)
+    f.Add(&t4, &b.x, &b.y)
+    f.Mul(&t3, &t3, &t4)
+    f.Add(&t4, &t0, &t1)
+    f.Sub(&t3, &t3, &t4)
+    f.Add(&t4, &a.y, &a.z)
+    f.Add(&x3, &b.y, &b.z)
+    f.Mul(&t4, &t4, &x3)
+    f.Add(&x3, &t1, &t2)
+    f.Sub(&t4, &t4, &x3)
+    f.Add(&x3, &a.x, &a.z)
+    f.Add(&y3, &b.x, &b.z)
+    f.Mul(&x3, &x3, &y3)
+    f.Add(&y3, &t0, &t2)
+    f.Sub(&y3, &x3, &y3)
+    f.Add(&x3, &t0, &t0)
+    f.Add(&t0, &x3, &t0)
+    f.Mul(&t2, &curveB3, &t2)
+    f.Add(&z3, &t1, &t2)
+    f.Sub(&t1, &t1, &t2)
+    f.Mul(&y3, &curveB3, &y3)
+    f.Mul(&x3, &t4, &y3)
+    f.Mul(&t2, &t3, &t1)
+    f.Sub(&x3, &t2, &x3)
+    f.Mul(&y3, &y3, &t0)
+    f.Mul(&t1, &t1, &z3)
+    f.Add(&y3, &t1, &y3)
+    f.Mul(&t0, &t0, &t3)
+    f.Mul(&z3, &z3, &t4)
+    f.Add(&z3, &z3, &t0)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+// double sets p = 2.a with the complete doubling formulas of Algorithm 9 of the
+// same paper.
+func (p *secretPoint) double(a *secretPoint) *secretPoint {
+    f := fieldP
+    var t0, t1, t2, x3, y3, z3 arith.Residue
+    f.Square(&t0, &a.y)
+    f.Add(&z3, &t0, &t0)
+    f.Add(&z3, &z3, &z3)
+    f.Add(&z3, &z3, &z3)
+    f.Mul(&t1, &a.y, &a.z)
+    f.Square(&t2, &a.z)
+    f.Mul(&t2, &curveB3, &t2)
+    f.Mul(&x3, &t2, &z3)
+    f.Add(&y3, &t0, &t2)
+    f.Mul(&z3, &t1, &z3)
+    f.Add(&t1, &t2, &t2)
+    f.Add(&t2, &t1, &t2)
+    f.Sub(&t0, &t0, &t2)
+    f.Mul(&y3, &t0, &y3)
+    f.Add(&y3, &x3, &y3)
+    f.Mul(&t1, &a.x, &a.y)
+    f.Mul(&x3, &t0, &t1)
+    f.Add(&x3, &x3, &x3)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+// lookup sets p = table[d] without branching on d nor accessing memory at an
+// address that depends on d.
+func (p *secretPoint) lookup(table []secretPoint, d uint64) *secretPoint {
+    p.setInfinity()
+    for i := range table {
+        // equal is 1 if and only if i = d
+        v := uint64(i) ^ d
+        equal := ((v | -v) >> 63) ^ 1
+        p.x.Select(&table[i].x, &p.x, equal)
+        p.y.Select(&table[i].y, &p.y, equal)
+        p.z.Select(&table[i].z, &p.z, equal)
+    }
+    return p
+}
+
+// curvePoint returns p in affine coordinates. The inverse of z is computed
+// with a public exponent, in a time that does not depend on z.
+func (p *secretPoint) curvePoint() *curvePoint {
+    c := newCurvePoint(nil)
+    if p.z.IsZero() == 1 {
+        c.SetInfinity()
+        return c
+    }
+    var zInv, x, y arith.Residue
+    fieldP.Invert(&zInv, &p.z)
+    c.x.Set(fieldP.ToBig(fieldP.Mul(&x, &p.x, &zInv)))
+    c.y.Set(fieldP.ToBig(fieldP.Mul(&y, &p.y, &zInv)))
+    c.z.SetInt64(1)
+    c.t.SetInt64(1)
+    return c
+}
+
+// secretScalar returns the limbs of k modulo the order, whose conversion only
+// depends on the number of words of k.
+func secretScalar(k *big.Int) [4]uint64 {
+    if k.Sign() < 0 || k.BitLen() > 256 {
+        k = new(big.Int).Mod(k, Order)
+    }
+    const wordBits = 32 << (^uint(0) >> 63)
+    var l [4]uint64
+    for i, w := range k.Bits() {
+        l[i*wordBits/64] |= uint64(w) << uint(i*wordBits%64)
+    }
+    return l
+}
+
+// SecretMultiScalarMult sets e to the sum of scalars[i]*points[i] and returns
+// e, in a time that does not depend on the scalars, which may be secret. The
+// points are public.
+func (e *G1) SecretMultiScalarMult(points []*G1, scalars []*big.Int) (*G1, error) {
+    if len(points) != len(scalars) {
+        return nil, errors.New("number of points is different from the number of scalars")
+    }
+    tables := make([]secretPoint, len(points)*secretTableSize)
+    ks := make([][4]uint64, len(scalars))
+    for i := range points {
+        if points[i] == nil || points[i].p == nil || scalars[i] == nil {
+            return nil, errors.New("points and scalars must be defined")
+        }
+        table := tables[i*secretTableSize : (i+1)*secretTableSize]
+        table[0].setInfinity()
+        table[1].setCurvePoint(points[i].p)
+        for d := 2; d < secretTableSize; d++ {
+            table[d].add(&table[d-1], &table[1])
+        }
+        ks[i] = secretScalar(scalars[i])
+    }
+
+    var term secretPoint
+    result := new(secretPoint).setInfinity()
+    for w := 256/secretWindow - 1; w >= 0; w-- {
+        for j := 0; j < secretWindow; j++ {
+            result.double(result)
+        }
+        for i := range points {
+            d := (ks[i][w*secretWindow/64] >> uint(w*secretWindow%64)) & (secretTableSize - 1)
+            term.lookup(tables[i*secretTableSize:(i+1)*secretTableSize], d)
+            result.add(result, &term)
+        }
+    }
+    e.p = result.curvePoint()
+    return e, nil
+}
+
+// SecretScalarMult sets e to a*k and returns e, in a time that does not depend
+// on k.
+func (e *G1) SecretScalarMult(a *G1, k *big.Int) *G1 {
+    result, _ := e.SecretMultiScalarMult([]*G1{a}, []*big.Int{k})
+    return result
+}
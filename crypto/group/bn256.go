@@ -0,0 +1,219 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package group
+
+import (
+    "bytes"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+/*
+BN256G1 is the group G1 of the pairing-friendly curve bn256, y^2 = x^3 + 3,
+implemented by the package crypto/bn256. Elements are encoded with 64 bytes, the
+coordinates x || y, and the identity is encoded as 64 zero bytes.
+*/
+var BN256G1 = mustRegister(bn256G1{})
+
+const (
+    bn256ElementLength = 64
+    // bn256FieldLength is the number of uniform bytes reduced to a field element
+    bn256FieldLength = 48
+)
+
+var bn256B = big.NewInt(3)
+
+type bn256G1 struct{}
+
+/*
+bn256Element is an element of BN256G1. The point is always in affine
+coordinates, so that it is never modified by Marshal.
+*/
+type bn256Element struct {
+    p       *bn256.G1
+    encoded []byte
+}
+
+func (bn256G1) Name() string {
+    return "bn256-G1"
+}
+
+/*
+HashSuite identifies the try-and-increment hash of HashToElement. There is no
+RFC 9380 suite for this curve.
+*/
+func (bn256G1) HashSuite() string {
+    return "BN256G1_XMD:SHA-256_TAI_RO_"
+}
+
+func (bn256G1) Order() *big.Int {
+    return new(big.Int).Set(bn256.Order)
+}
+
+func (bn256G1) Generator() Element {
+    return newBN256Element(new(bn256.G1).ScalarBaseMult(big.NewInt(1)))
+}
+
+func (bn256G1) Identity() Element {
+    return newBN256Element(new(bn256.G1).SetInfinity())
+}
+
+/*
+HashToElement hashes msg || i to a field element x with expand_message_xmd, for
+i = 0, 1, ..., until x^3 + 3 is a square, and returns the point (x, y) whose y
+is even. The cofactor of G1 is 1.
+*/
+func (g bn256G1) HashToElement(msg, dst []byte) (Element, error) {
+    input := append(append([]byte{}, msg...), 0)
+    for i := 0; i < 256; i++ {
+        input[len(msg)] = byte(i)
+        uniform, err := p256.ExpandMessageXMD(input, dst, bn256FieldLength)
+        if err != nil {
+            return nil, err
+        }
+        x := new(big.Int).SetBytes(uniform)
+        x.Mod(x, bn256.P)
+        y := new(big.Int).ModSqrt(bn256F(x), bn256.P)
+        if y == nil {
+            continue
+        }
+        if y.Bit(0) != 0 {
+            y.Sub(bn256.P, y)
+        }
+        return g.DecodeElement(bn256Encode(x, y))
+    }
+    return nil, errors.New("no point found for the message")
+}
+
+func (g bn256G1) MultiScalarMult(points []Element, scalars []Scalar) (Element, error) {
+    return naiveMultiScalarMult(g, points, scalars)
+}
+
+func (bn256G1) SecretMultiScalarMult(points []Element, scalars []Scalar) (Element, error) {
+    err := checkTerms(points, scalars)
+    if err != nil {
+        return nil, err
+    }
+    ps := make([]*bn256.G1, len(points))
+    for i := range points {
+        ps[i] = points[i].(*bn256Element).p
+    }
+    p, err := new(bn256.G1).SecretMultiScalarMult(ps, scalars)
+    if err != nil {
+        return nil, err
+    }
+    return newBN256Element(p), nil
+}
+
+func (bn256G1) ElementLength() int {
+    return bn256ElementLength
+}
+
+func (bn256G1) DecodeElement(data []byte) (Element, error) {
+    if len(data) != bn256ElementLength {
+        return nil, errors.New("invalid length of the bn256 G1 element")
+    }
+    if bytes.Equal(data, make([]byte, bn256ElementLength)) {
+        return BN256G1.Identity(), nil
+    }
+    x := new(big.Int).SetBytes(data[:32])
+    y := new(big.Int).SetBytes(data[32:])
+    // Unmarshal accepts the encodings that are not reduced, and (0, 1) for the identity
+    if x.Cmp(bn256.P) >= 0 || y.Cmp(bn256.P) >= 0 || (x.Sign() == 0 && y.Cmp(big.NewInt(1)) == 0) {
+        return nil, errors.New("invalid encoding of the bn256 G1 element")
+    }
+    p, ok := new(bn256.G1).Unmarshal(data)
+    if !ok {
+        return nil, errors.New("point is not on the curve")
+    }
+    return newBN256Element(p), nil
+}
+
+func (e *bn256Element) Group() Group {
+    return BN256G1
+}
+
+func (e *bn256Element) Add(b Element) Element {
+    return newBN256Element(new(bn256.G1).Add(e.p, b.(*bn256Element).p))
+}
+
+func (e *bn256Element) Neg() Element {
+    return newBN256Element(new(bn256.G1).Neg(e.p))
+}
+
+func (e *bn256Element) ScalarMult(k Scalar) Element {
+    return newBN256Element(new(bn256.G1).ScalarMult(e.p, new(big.Int).Mod(k, bn256.Order)))
+}
+
+func (e *bn256Element) Equal(b Element) bool {
+    other, ok := b.(*bn256Element)
+    return ok && bytes.Equal(e.encoded, other.encoded)
+}
+
+func (e *bn256Element) IsIdentity() bool {
+    return e.p.IsZero()
+}
+
+func (e *bn256Element) Encode() []byte {
+    return append([]byte{}, e.encoded...)
+}
+
+func (e *bn256Element) String() string {
+    if e.IsIdentity() {
+        return "bn256-G1(infinity)"
+    }
+    return "bn256-G1(" + new(big.Int).SetBytes(e.encoded[:32]).String() + "," +
+        new(big.Int).SetBytes(e.encoded[32:]).String() + ")"
+}
+
+/*
+newBN256Element returns the element of p, which is converted to affine coordinates.
+*/
+func newBN256Element(p *bn256.G1) *bn256Element {
+    e := &bn256Element{p: p}
+    if p.IsZero() {
+        e.encoded = make([]byte, bn256ElementLength)
+    } else {
+        e.encoded = p.Marshal()
+    }
+    return e
+}
+
+/*
+bn256F returns x^3 + 3.
+*/
+func bn256F(x *big.Int) *big.Int {
+    result := new(big.Int).Mul(x, x)
+    result.Mul(result, x)
+    result.Add(result, bn256B)
+    return result.Mod(result, bn256.P)
+}
+
+/*
+bn256Encode returns the 64 bytes encoding of the coordinates.
+*/
+func bn256Encode(x, y *big.Int) []byte {
+    result := make([]byte, bn256ElementLength)
+    xBytes, yBytes := x.Bytes(), y.Bytes()
+    copy(result[32-len(xBytes):32], xBytes)
+    copy(result[64-len(yBytes):], yBytes)
+    return result
+}
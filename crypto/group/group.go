@@ -0,0 +1,259 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package group defines the prime-order groups used by the zero knowledge proofs,
+so that the same protocol can run over different elliptic curves. It contains
+the backends for secp256k1, NIST P-256, the group G1 of bn256 and Ristretto255.
+*/
+package group
+
+import (
+    "encoding/hex"
+    "errors"
+    "math/big"
+    "strings"
+    "sync"
+)
+
+/*
+Scalar is an integer modulo the order of a group. Scalars are represented by big
+integers, so that they can be used with the functions of util/bn. Every
+operation of a group accepts any integer and reduces it modulo Order().
+*/
+type Scalar = *big.Int
+
+/*
+Group is a cyclic group of prime order, in which the discrete logarithm problem
+is hard. The operations are written additively. The elements given to a group
+must belong to it, mixing elements of different groups panics.
+*/
+type Group interface {
+    // Name returns the name of the group, for instance "secp256k1".
+    Name() string
+    // HashSuite returns the identifier of the hash-to-curve suite of HashToElement.
+    HashSuite() string
+    // Order returns the order of the group.
+    Order() *big.Int
+    // Generator returns the standard generator of the group.
+    Generator() Element
+    // Identity returns the neutral element.
+    Identity() Element
+    // HashToElement hashes the message to an element whose discrete logarithm is
+    // unknown, using the domain separation tag dst, which must not be empty.
+    HashToElement(msg, dst []byte) (Element, error)
+    // MultiScalarMult returns the sum of scalars[i].points[i].
+    MultiScalarMult(points []Element, scalars []Scalar) (Element, error)
+    // ElementLength returns the length of the encoding of every element.
+    ElementLength() int
+    // DecodeElement returns the element encoded by Element.Encode. It fails on
+    // every encoding that is not canonical.
+    DecodeElement(data []byte) (Element, error)
+}
+
+/*
+Element is an element of a Group. Elements are immutable: every operation
+returns a new element.
+*/
+type Element interface {
+    // Group returns the group of the element.
+    Group() Group
+    // Add returns the sum of the element and b.
+    Add(b Element) Element
+    // Neg returns the inverse of the element.
+    Neg() Element
+    // ScalarMult returns k times the element.
+    ScalarMult(k Scalar) Element
+    // Equal returns true if and only if the element is equal to b.
+    Equal(b Element) bool
+    // IsIdentity returns true if and only if the element is the neutral element.
+    IsIdentity() bool
+    // Encode returns the canonical encoding of the element, of ElementLength bytes.
+    Encode() []byte
+    // String returns a readable representation of the element.
+    String() string
+}
+
+/*
+FixedBaseGroup is implemented by the groups that can precompute tables for
+fixed bases, such as generators, in order to speed up scalar multiplications.
+*/
+type FixedBaseGroup interface {
+    Group
+    // NewFixedBase computes the table of the element p.
+    NewFixedBase(p Element) (FixedBase, error)
+    // DecodeFixedBase decodes a table encoded by FixedBase.MarshalBinary. It
+    // rejects the tables whose entries are not the multiples of their base.
+    DecodeFixedBase(data []byte) (FixedBase, error)
+    // FixedBaseMultiScalarMult returns the sum of scalars[i] times the base of tables[i].
+    FixedBaseMultiScalarMult(tables []FixedBase, scalars []Scalar) (Element, error)
+}
+
+/*
+ConstantTimeGroup is implemented by the groups whose multi-scalar multiplication
+can run in a time that does not depend on the scalars, which is needed when the
+scalars are secret, such as the openings of commitments.
+*/
+type ConstantTimeGroup interface {
+    Group
+    // SecretMultiScalarMult returns the sum of scalars[i].points[i], in a time
+    // that does not depend on the scalars.
+    SecretMultiScalarMult(points []Element, scalars []Scalar) (Element, error)
+}
+
+/*
+FixedBase is the precomputed table of an element of a FixedBaseGroup.
+*/
+type FixedBase interface {
+    // Base returns the element of the table.
+    Base() Element
+    // ScalarMult returns k times the base.
+    ScalarMult(k Scalar) Element
+    // MarshalBinary encodes the table.
+    MarshalBinary() ([]byte, error)
+}
+
+var (
+    // ErrNotConstantTime is returned by SecretMultiScalarMult for the groups that
+    // do not implement ConstantTimeGroup
+    ErrNotConstantTime = errors.New("group has no constant-time multi-scalar multiplication")
+)
+
+var (
+    groupsMutex sync.RWMutex
+    groups      = make(map[string]Group)
+)
+
+/*
+Register makes the group available to Lookup under its name, which must be unique.
+*/
+func Register(g Group) error {
+    groupsMutex.Lock()
+    defer groupsMutex.Unlock()
+    if g == nil || g.Name() == "" || strings.Contains(g.Name(), ":") {
+        return errors.New("invalid group name")
+    }
+    if _, ok := groups[g.Name()]; ok {
+        return errors.New("group is already registered: " + g.Name())
+    }
+    groups[g.Name()] = g
+    return nil
+}
+
+/*
+Lookup returns the registered group with the given name.
+*/
+func Lookup(name string) (Group, error) {
+    groupsMutex.RLock()
+    defer groupsMutex.RUnlock()
+    g, ok := groups[name]
+    if !ok {
+        return nil, errors.New("unknown group: " + name)
+    }
+    return g, nil
+}
+
+/*
+EncodeText returns the text encoding of the element: the name of its group, a
+colon and the hex encoding of the element, e.g. "secp256k1:02...".
+*/
+func EncodeText(e Element) string {
+    return e.Group().Name() + ":" + hex.EncodeToString(e.Encode())
+}
+
+/*
+DecodeText returns the element encoded by EncodeText, whose group must be registered.
+*/
+func DecodeText(s string) (Element, error) {
+    i := strings.LastIndex(s, ":")
+    if i < 0 {
+        return nil, errors.New("missing group name")
+    }
+    g, err := Lookup(s[:i])
+    if err != nil {
+        return nil, err
+    }
+    data, err := hex.DecodeString(s[i+1:])
+    if err != nil {
+        return nil, err
+    }
+    return g.DecodeElement(data)
+}
+
+/*
+SecretMultiScalarMult returns the sum of scalars[i].points[i], where the scalars
+are secret, in a time that does not depend on the scalars. Every built-in group
+implements ConstantTimeGroup; for the other groups it returns
+ErrNotConstantTime, rather than computing with secrets in variable time.
+*/
+func SecretMultiScalarMult(g Group, points []Element, scalars []Scalar) (Element, error) {
+    ct, ok := g.(ConstantTimeGroup)
+    if !ok {
+        return nil, ErrNotConstantTime
+    }
+    return ct.SecretMultiScalarMult(points, scalars)
+}
+
+/*
+SecretScalarMult returns k.p, where k is secret, as SecretMultiScalarMult.
+*/
+func SecretScalarMult(p Element, k Scalar) (Element, error) {
+    return SecretMultiScalarMult(p.Group(), []Element{p}, []Scalar{k})
+}
+
+/*
+mustRegister registers the built-in groups.
+*/
+func mustRegister(g Group) Group {
+    err := Register(g)
+    if err != nil {
+        panic(err)
+    }
+    return g
+}
+
+/*
+checkTerms returns an error if the vectors of a multi-scalar multiplication have
+different sizes or undefined entries.
+*/
+func checkTerms(points []Element, scalars []Scalar) error {
+    if len(points) != len(scalars) {
+        return errors.New("number of points is different from the number of scalars")
+    }
+    for i := range points {
+        if points[i] == nil || scalars[i] == nil {
+            return errors.New("points and scalars must be defined")
+        }
+    }
+    return nil
+}
+
+/*
+naiveMultiScalarMult computes the sum of scalars[i].points[i] term by term, for
+the groups that have no faster algorithm.
+*/
+func naiveMultiScalarMult(g Group, points []Element, scalars []Scalar) (Element, error) {
+    err := checkTerms(points, scalars)
+    if err != nil {
+        return nil, err
+    }
+    result := g.Identity()
+    for i := range points {
+        result = result.Add(points[i].ScalarMult(scalars[i]))
+    }
+    return result, nil
+}
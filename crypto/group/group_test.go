@@ -0,0 +1,223 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package group
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "flag"
+    "math"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/internal/timing"
+    "github.com/stretchr/testify/assert"
+)
+
+var timingTest = flag.Bool("timing", false, "run the statistical test of the running time of SecretMultiScalarMult")
+
+var testGroups = []Group{Secp256k1, P256, BN256G1, Ristretto255}
+
+func TestGroupArithmetic(t *testing.T) {
+    for _, g := range testGroups {
+        gen := g.Generator()
+        a, _ := rand.Int(rand.Reader, g.Order())
+        b, _ := rand.Int(rand.Reader, g.Order())
+        A := gen.ScalarMult(a)
+        B := gen.ScalarMult(b)
+        sum := gen.ScalarMult(new(big.Int).Add(a, b))
+
+        assert.True(t, A.Add(B).Equal(sum), g.Name())
+        assert.True(t, A.Add(A).Equal(gen.ScalarMult(new(big.Int).Lsh(a, 1))), g.Name())
+        assert.True(t, A.Add(A.Neg()).IsIdentity(), g.Name())
+        assert.True(t, A.Add(g.Identity()).Equal(A), g.Name())
+        assert.True(t, g.Identity().Add(A).Equal(A), g.Name())
+        assert.True(t, gen.ScalarMult(g.Order()).IsIdentity(), g.Name())
+        assert.True(t, gen.ScalarMult(big.NewInt(-1)).Equal(gen.Neg()), g.Name())
+        assert.True(t, g.Identity().Neg().IsIdentity(), g.Name())
+        assert.False(t, A.Equal(B), g.Name())
+        assert.Equal(t, g, A.Group())
+    }
+}
+
+func TestGroupMultiScalarMult(t *testing.T) {
+    for _, g := range testGroups {
+        points := make([]Element, 5)
+        scalars := make([]Scalar, 5)
+        expected := g.Identity()
+        for i := range points {
+            k, _ := rand.Int(rand.Reader, g.Order())
+            points[i] = g.Generator().ScalarMult(k)
+            scalars[i], _ = rand.Int(rand.Reader, g.Order())
+            expected = expected.Add(points[i].ScalarMult(scalars[i]))
+        }
+        result, err := g.MultiScalarMult(points, scalars)
+        assert.Nil(t, err, g.Name())
+        assert.True(t, result.Equal(expected), g.Name())
+
+        _, err = g.MultiScalarMult(points, scalars[1:])
+        assert.NotNil(t, err, g.Name())
+
+        result, err = SecretMultiScalarMult(g, points, scalars)
+        assert.Nil(t, err, g.Name())
+        assert.True(t, result.Equal(expected), g.Name())
+        result, err = SecretScalarMult(points[0], scalars[0])
+        assert.Nil(t, err, g.Name())
+        assert.True(t, result.Equal(points[0].ScalarMult(scalars[0])), g.Name())
+
+        _, err = SecretMultiScalarMult(g, points, scalars[1:])
+        assert.NotNil(t, err, g.Name())
+    }
+}
+
+func TestGroupEncoding(t *testing.T) {
+    for _, g := range testGroups {
+        k, _ := rand.Int(rand.Reader, g.Order())
+        elements := []Element{g.Identity(), g.Generator(), g.Generator().ScalarMult(k)}
+        for _, e := range elements {
+            data := e.Encode()
+            assert.Equal(t, g.ElementLength(), len(data), g.Name())
+            decoded, err := g.DecodeElement(data)
+            assert.Nil(t, err, g.Name())
+            assert.True(t, decoded.Equal(e), g.Name())
+
+            text := EncodeText(e)
+            decoded, err = DecodeText(text)
+            assert.Nil(t, err, g.Name())
+            assert.True(t, decoded.Equal(e), g.Name())
+        }
+        assert.Equal(t, make([]byte, g.ElementLength()), g.Identity().Encode(), g.Name())
+
+        _, err := g.DecodeElement(g.Generator().Encode()[1:])
+        assert.NotNil(t, err, g.Name())
+        invalid := make([]byte, g.ElementLength())
+        for i := range invalid {
+            invalid[i] = 0xff
+        }
+        _, err = g.DecodeElement(invalid)
+        assert.NotNil(t, err, g.Name())
+    }
+    _, err := DecodeText("unknown:00")
+    assert.NotNil(t, err)
+}
+
+func TestGroupHashToElement(t *testing.T) {
+    for _, g := range testGroups {
+        dst := []byte("ZKRP-TEST-with-" + g.HashSuite())
+        a, err := g.HashToElement([]byte("a"), dst)
+        assert.Nil(t, err, g.Name())
+        again, _ := g.HashToElement([]byte("a"), dst)
+        b, _ := g.HashToElement([]byte("b"), dst)
+        assert.True(t, a.Equal(again), g.Name())
+        assert.False(t, a.Equal(b), g.Name())
+        assert.False(t, a.IsIdentity(), g.Name())
+        decoded, err := g.DecodeElement(a.Encode())
+        assert.Nil(t, err, g.Name())
+        assert.True(t, decoded.Equal(a), g.Name())
+
+        _, err = g.HashToElement([]byte("a"), nil)
+        assert.NotNil(t, err, g.Name())
+    }
+}
+
+/*
+Test vectors of Appendix J.1.1 of RFC 9380.
+*/
+func TestP256HashToElement(t *testing.T) {
+    dst := []byte("QUUX-V01-CS02-with-P256_XMD:SHA-256_SSWU_RO_")
+    vectors := []struct {
+        msg, x, y string
+    }{
+        {"", "2c15230b26dbc6fc9a37051158c95b79656e17a1a920b11394ca91c44247d3e4",
+            "8a7a74985cc5c776cdfe4b1f19884970453912e9d31528c060be9ab5c43e8415"},
+        {"abc", "0bb8b87485551aa43ed54f009230450b492fead5f1cc91658775dac4a3388a0f",
+            "5c41b3d0731a27a7b14bc0bf0ccded2d8751f83493404c84a88e71ffd424212e"},
+    }
+    for _, v := range vectors {
+        e, err := P256.HashToElement([]byte(v.msg), dst)
+        assert.Nil(t, err)
+        p := e.(*NistP256Element).Point()
+        assert.Equal(t, v.x, hex.EncodeToString(p.X.Bytes()))
+        assert.Equal(t, v.y, hex.EncodeToString(p.Y.Bytes()))
+    }
+}
+
+func TestSecp256k1FixedBase(t *testing.T) {
+    g := Secp256k1.(FixedBaseGroup)
+    base, _ := g.HashToElement([]byte("base"), []byte("ZKRP-TEST"))
+    table, err := g.NewFixedBase(base)
+    assert.Nil(t, err)
+    k, _ := rand.Int(rand.Reader, g.Order())
+    assert.True(t, table.ScalarMult(k).Equal(base.ScalarMult(k)))
+    assert.True(t, table.Base().Equal(base))
+
+    data, err := table.MarshalBinary()
+    assert.Nil(t, err)
+    decoded, err := g.DecodeFixedBase(data)
+    assert.Nil(t, err)
+    result, err := g.FixedBaseMultiScalarMult([]FixedBase{decoded, table}, []Scalar{k, big.NewInt(1)})
+    assert.Nil(t, err)
+    assert.True(t, result.Equal(base.ScalarMult(new(big.Int).Add(k, big.NewInt(1)))))
+}
+
+/*
+variableTimeGroup hides the constant-time multi-scalar multiplication of its group.
+*/
+type variableTimeGroup struct {
+    Group
+}
+
+func TestSecretMultiScalarMultNotConstantTime(t *testing.T) {
+    g := variableTimeGroup{Secp256k1}
+    _, err := SecretMultiScalarMult(g, []Element{g.Generator()}, []Scalar{big.NewInt(42)})
+    assert.Equal(t, ErrNotConstantTime, err)
+}
+
+/*
+TestSecretMultiScalarMultTiming compares the running time of
+SecretMultiScalarMult for the scalar 1 and for random scalars, in every group,
+as the timing test of crypto/p256. The measurements depend on the load of the
+machine, so the test only runs with -timing.
+*/
+func TestSecretMultiScalarMultTiming(t *testing.T) {
+    if !*timingTest {
+        t.Skip("timing test runs with -timing")
+    }
+    const measurements = 2000
+    for _, g := range testGroups {
+        k, _ := rand.Int(rand.Reader, g.Order())
+        points := []Element{g.Generator().ScalarMult(k), g.Generator()}
+        r, _ := rand.Int(rand.Reader, g.Order())
+        statistic := timing.TStatistic(measurements, g.Order(), big.NewInt(1), func(k *big.Int) {
+            SecretMultiScalarMult(g, points, []Scalar{k, r})
+        })
+        t.Logf("t-statistic of SecretMultiScalarMult in %s: %.2f", g.Name(), statistic)
+        if math.Abs(statistic) > 10 {
+            t.Errorf("the running time of SecretMultiScalarMult in %s depends on the scalar, t = %.2f", g.Name(), statistic)
+        }
+    }
+}
+
+func TestRegister(t *testing.T) {
+    assert.NotNil(t, Register(Secp256k1))
+    g, err := Lookup("P-256")
+    assert.Nil(t, err)
+    assert.Equal(t, P256, g)
+    _, err = Lookup("unknown")
+    assert.NotNil(t, err)
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package group
+
+import (
+    "bytes"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+/*
+P256 is the group of the points of NIST P-256, implemented by the type
+NistP256Point of the package crypto/p256. Elements are encoded with 33 bytes,
+using the SEC1 compressed format, and the identity is encoded as 33 zero bytes.
+*/
+var P256 = mustRegister(nistP256{})
+
+const nistP256ElementLength = 33
+
+type nistP256 struct{}
+
+/*
+NistP256Element is an element of P256.
+*/
+type NistP256Element struct {
+    p *p256.NistP256Point
+}
+
+/*
+NewNistP256Element returns the element of the point p, which must be on the curve.
+*/
+func NewNistP256Element(p *p256.NistP256Point) *NistP256Element {
+    if p == nil {
+        return &NistP256Element{p: new(p256.NistP256Point).SetInfinity()}
+    }
+    return &NistP256Element{p: new(p256.NistP256Point).Set(p)}
+}
+
+func (nistP256) Name() string {
+    return "P-256"
+}
+
+func (nistP256) HashSuite() string {
+    return "P256_XMD:SHA-256_SSWU_RO_"
+}
+
+func (nistP256) Order() *big.Int {
+    return new(big.Int).Set(p256.NistP256N)
+}
+
+func (nistP256) Generator() Element {
+    return &NistP256Element{p: new(p256.NistP256Point).ScalarBaseMult(big.NewInt(1))}
+}
+
+func (nistP256) Identity() Element {
+    return &NistP256Element{p: new(p256.NistP256Point).SetInfinity()}
+}
+
+/*
+HashToElement implements the suite P256_XMD:SHA-256_SSWU_RO_ of RFC 9380.
+*/
+func (nistP256) HashToElement(msg, dst []byte) (Element, error) {
+    p, err := p256.HashToNistP256(msg, dst)
+    if err != nil {
+        return nil, err
+    }
+    return &NistP256Element{p: p}, nil
+}
+
+func (g nistP256) MultiScalarMult(points []Element, scalars []Scalar) (Element, error) {
+    return naiveMultiScalarMult(g, points, scalars)
+}
+
+func (nistP256) SecretMultiScalarMult(points []Element, scalars []Scalar) (Element, error) {
+    err := checkTerms(points, scalars)
+    if err != nil {
+        return nil, err
+    }
+    ps := make([]*p256.NistP256Point, len(points))
+    for i := range points {
+        ps[i] = nistP256Point(points[i])
+    }
+    p, err := new(p256.NistP256Point).SecretMultiScalarMult(ps, scalars)
+    if err != nil {
+        return nil, err
+    }
+    return &NistP256Element{p: p}, nil
+}
+
+func (nistP256) ElementLength() int {
+    return nistP256ElementLength
+}
+
+func (nistP256) DecodeElement(data []byte) (Element, error) {
+    if len(data) != nistP256ElementLength {
+        return nil, errors.New("invalid length of the P-256 element")
+    }
+    if bytes.Equal(data, make([]byte, nistP256ElementLength)) {
+        return P256.Identity(), nil
+    }
+    p, err := new(p256.NistP256Point).Unmarshal(data)
+    if err != nil {
+        return nil, err
+    }
+    return &NistP256Element{p: p}, nil
+}
+
+/*
+Point returns a copy of the point of the element.
+*/
+func (e *NistP256Element) Point() *p256.NistP256Point {
+    return new(p256.NistP256Point).Set(e.p)
+}
+
+func (e *NistP256Element) Group() Group {
+    return P256
+}
+
+func (e *NistP256Element) Add(b Element) Element {
+    return &NistP256Element{p: new(p256.NistP256Point).Add(e.p, nistP256Point(b))}
+}
+
+func (e *NistP256Element) Neg() Element {
+    return &NistP256Element{p: new(p256.NistP256Point).Neg(e.p)}
+}
+
+func (e *NistP256Element) ScalarMult(k Scalar) Element {
+    return &NistP256Element{p: new(p256.NistP256Point).ScalarMult(e.p, k)}
+}
+
+func (e *NistP256Element) Equal(b Element) bool {
+    other, ok := b.(*NistP256Element)
+    if !ok {
+        return false
+    }
+    if e.p.IsZero() || other.p.IsZero() {
+        return e.p.IsZero() && other.p.IsZero()
+    }
+    return e.p.X.Cmp(other.p.X) == 0 && e.p.Y.Cmp(other.p.Y) == 0
+}
+
+func (e *NistP256Element) IsIdentity() bool {
+    return e.p.IsZero()
+}
+
+func (e *NistP256Element) Encode() []byte {
+    if e.p.IsZero() {
+        return make([]byte, nistP256ElementLength)
+    }
+    return e.p.Marshal()
+}
+
+func (e *NistP256Element) String() string {
+    if e.p.IsZero() {
+        return "P-256(infinity)"
+    }
+    return "P-256(" + e.p.X.String() + "," + e.p.Y.String() + ")"
+}
+
+/*
+nistP256Point returns the point of an element of P256.
+*/
+func nistP256Point(e Element) *p256.NistP256Point {
+    return e.(*NistP256Element).p
+}
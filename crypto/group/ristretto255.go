@@ -0,0 +1,180 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package group
+
+import (
+    "crypto/sha512"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/ristretto255"
+)
+
+/*
+Ristretto255 is the prime-order group of RFC 9496, implemented by the package
+crypto/ristretto255. Elements are encoded with 32 bytes, and the identity is
+encoded as 32 zero bytes.
+*/
+var Ristretto255 = mustRegister(ristretto255Group{})
+
+type ristretto255Group struct{}
+
+/*
+Ristretto255Element is an element of Ristretto255.
+*/
+type Ristretto255Element struct {
+    p *ristretto255.Point
+}
+
+/*
+NewRistretto255Element returns the element of the point p.
+*/
+func NewRistretto255Element(p *ristretto255.Point) *Ristretto255Element {
+    return &Ristretto255Element{p: new(ristretto255.Point).Set(p)}
+}
+
+func (ristretto255Group) Name() string {
+    return "ristretto255"
+}
+
+func (ristretto255Group) HashSuite() string {
+    return "ristretto255_XMD:SHA-512_R255MAP_RO_"
+}
+
+func (ristretto255Group) Order() *big.Int {
+    return new(big.Int).Set(ristretto255.Order)
+}
+
+func (ristretto255Group) Generator() Element {
+    return &Ristretto255Element{p: ristretto255.NewGenerator()}
+}
+
+func (ristretto255Group) Identity() Element {
+    return &Ristretto255Element{p: ristretto255.NewIdentity()}
+}
+
+/*
+HashToElement implements the suite ristretto255_XMD:SHA-512_R255MAP_RO_ of RFC
+9496: 64 bytes of expand_message_xmd with SHA-512 are mapped to the group.
+*/
+func (ristretto255Group) HashToElement(msg, dst []byte) (Element, error) {
+    uniform, err := p256.ExpandMessageXMDWithHash(sha512.New, msg, dst, ristretto255.UniformLength)
+    if err != nil {
+        return nil, err
+    }
+    p, err := new(ristretto255.Point).FromUniformBytes(uniform)
+    if err != nil {
+        return nil, err
+    }
+    return &Ristretto255Element{p: p}, nil
+}
+
+func (ristretto255Group) MultiScalarMult(points []Element, scalars []Scalar) (Element, error) {
+    err := checkTerms(points, scalars)
+    if err != nil {
+        return nil, err
+    }
+    ps := make([]*ristretto255.Point, len(points))
+    for i := range points {
+        ps[i] = ristretto255Point(points[i])
+    }
+    p, err := new(ristretto255.Point).MultiScalarMult(ps, scalars)
+    if err != nil {
+        return nil, err
+    }
+    return &Ristretto255Element{p: p}, nil
+}
+
+func (ristretto255Group) SecretMultiScalarMult(points []Element, scalars []Scalar) (Element, error) {
+    err := checkTerms(points, scalars)
+    if err != nil {
+        return nil, err
+    }
+    ps := make([]*ristretto255.Point, len(points))
+    for i := range points {
+        ps[i] = ristretto255Point(points[i])
+    }
+    p, err := new(ristretto255.Point).SecretMultiScalarMult(ps, scalars)
+    if err != nil {
+        return nil, err
+    }
+    return &Ristretto255Element{p: p}, nil
+}
+
+func (ristretto255Group) ElementLength() int {
+    return ristretto255.EncodingLength
+}
+
+func (ristretto255Group) DecodeElement(data []byte) (Element, error) {
+    if len(data) != ristretto255.EncodingLength {
+        return nil, errors.New("invalid length of the ristretto255 element")
+    }
+    p, err := new(ristretto255.Point).Unmarshal(data)
+    if err != nil {
+        return nil, err
+    }
+    return &Ristretto255Element{p: p}, nil
+}
+
+/*
+Point returns a copy of the point of the element.
+*/
+func (e *Ristretto255Element) Point() *ristretto255.Point {
+    return new(ristretto255.Point).Set(e.p)
+}
+
+func (e *Ristretto255Element) Group() Group {
+    return Ristretto255
+}
+
+func (e *Ristretto255Element) Add(b Element) Element {
+    return &Ristretto255Element{p: new(ristretto255.Point).Add(e.p, ristretto255Point(b))}
+}
+
+func (e *Ristretto255Element) Neg() Element {
+    return &Ristretto255Element{p: new(ristretto255.Point).Neg(e.p)}
+}
+
+func (e *Ristretto255Element) ScalarMult(k Scalar) Element {
+    return &Ristretto255Element{p: new(ristretto255.Point).ScalarMult(e.p, k)}
+}
+
+func (e *Ristretto255Element) Equal(b Element) bool {
+    other, ok := b.(*Ristretto255Element)
+    return ok && e.p.Equal(other.p)
+}
+
+func (e *Ristretto255Element) IsIdentity() bool {
+    return e.p.IsIdentity()
+}
+
+func (e *Ristretto255Element) Encode() []byte {
+    return e.p.Marshal()
+}
+
+func (e *Ristretto255Element) String() string {
+    return e.p.String()
+}
+
+/*
+ristretto255Point returns the point of an element of Ristretto255.
+*/
+func ristretto255Point(e Element) *ristretto255.Point {
+    return e.(*Ristretto255Element).p
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package group
+
+import (
+    "bytes"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+/*
+Secp256k1 is the group of the points of secp256k1, implemented by the package
+crypto/p256. Elements are encoded with 33 bytes, using the SEC1 compressed format,
+and the identity is encoded as 33 zero bytes.
+*/
+var Secp256k1 = mustRegister(secp256k1{})
+
+const secp256k1ElementLength = 33
+
+type secp256k1 struct{}
+
+/*
+Secp256k1Element is an element of Secp256k1. The point is kept in Jacobian
+coordinates, so that sums of elements compute no modular inversion; it is only
+normalized to affine coordinates by Encode, Point and String.
+*/
+type Secp256k1Element struct {
+    p *p256.JacobianPoint
+}
+
+/*
+NewSecp256k1Element returns the element of the point p, which must be on the curve.
+*/
+func NewSecp256k1Element(p *p256.Secp256k1Point) *Secp256k1Element {
+    if p == nil {
+        return &Secp256k1Element{p: new(p256.JacobianPoint).SetInfinity()}
+    }
+    return &Secp256k1Element{p: p256.NewJacobianPoint(p)}
+}
+
+func (secp256k1) Name() string {
+    return "secp256k1"
+}
+
+func (secp256k1) HashSuite() string {
+    return "secp256k1_XMD:SHA-256_SSWU_RO_"
+}
+
+func (secp256k1) Order() *big.Int {
+    return new(big.Int).Set(p256.CURVE.N)
+}
+
+func (secp256k1) Generator() Element {
+    return NewSecp256k1Element(new(p256.Secp256k1Point).ScalarBaseMult(big.NewInt(1)))
+}
+
+func (secp256k1) Identity() Element {
+    return &Secp256k1Element{p: new(p256.JacobianPoint).SetInfinity()}
+}
+
+func (secp256k1) HashToElement(msg, dst []byte) (Element, error) {
+    p, err := p256.HashToCurve(msg, dst)
+    if err != nil {
+        return nil, err
+    }
+    return NewSecp256k1Element(p), nil
+}
+
+func (secp256k1) MultiScalarMult(points []Element, scalars []Scalar) (Element, error) {
+    err := checkTerms(points, scalars)
+    if err != nil {
+        return nil, err
+    }
+    ps := make([]*p256.JacobianPoint, len(points))
+    for i := range points {
+        ps[i] = secp256k1Point(points[i])
+    }
+    p, err := new(p256.JacobianPoint).MultiScalarMult(ps, scalars)
+    if err != nil {
+        return nil, err
+    }
+    return &Secp256k1Element{p: p}, nil
+}
+
+func (secp256k1) SecretMultiScalarMult(points []Element, scalars []Scalar) (Element, error) {
+    err := checkTerms(points, scalars)
+    if err != nil {
+        return nil, err
+    }
+    ps := make([]*p256.JacobianPoint, len(points))
+    for i := range points {
+        ps[i] = secp256k1Point(points[i])
+    }
+    p, err := new(p256.JacobianPoint).SecretMultiScalarMult(ps, scalars)
+    if err != nil {
+        return nil, err
+    }
+    return &Secp256k1Element{p: p}, nil
+}
+
+func (secp256k1) ElementLength() int {
+    return secp256k1ElementLength
+}
+
+func (secp256k1) DecodeElement(data []byte) (Element, error) {
+    if len(data) != secp256k1ElementLength {
+        return nil, errors.New("invalid length of the secp256k1 element")
+    }
+    if bytes.Equal(data, make([]byte, secp256k1ElementLength)) {
+        return Secp256k1.Identity(), nil
+    }
+    p, err := new(p256.Secp256k1Point).Unmarshal(data)
+    if err != nil {
+        return nil, err
+    }
+    return NewSecp256k1Element(p), nil
+}
+
+func (secp256k1) NewFixedBase(p Element) (FixedBase, error) {
+    table, err := p256.NewFixedBaseTable(p.(*Secp256k1Element).Point())
+    if err != nil {
+        return nil, err
+    }
+    return secp256k1FixedBase{table}, nil
+}
+
+func (secp256k1) DecodeFixedBase(data []byte) (FixedBase, error) {
+    table := new(p256.FixedBaseTable)
+    err := table.UnmarshalBinary(data)
+    if err != nil {
+        return nil, err
+    }
+    return secp256k1FixedBase{table}, nil
+}
+
+func (secp256k1) FixedBaseMultiScalarMult(tables []FixedBase, scalars []Scalar) (Element, error) {
+    ts := make([]*p256.FixedBaseTable, len(tables))
+    for i := range tables {
+        if tables[i] == nil {
+            return nil, errors.New("tables and scalars must be defined")
+        }
+        ts[i] = tables[i].(secp256k1FixedBase).table
+    }
+    p, err := p256.FixedBaseMultiScalarMult(ts, scalars)
+    if err != nil {
+        return nil, err
+    }
+    return NewSecp256k1Element(p), nil
+}
+
+/*
+Point returns a copy of the point of the element.
+*/
+func (e *Secp256k1Element) Point() *p256.Secp256k1Point {
+    return e.p.Affine()
+}
+
+func (e *Secp256k1Element) Group() Group {
+    return Secp256k1
+}
+
+func (e *Secp256k1Element) Add(b Element) Element {
+    return &Secp256k1Element{p: new(p256.JacobianPoint).Add(e.p, secp256k1Point(b))}
+}
+
+func (e *Secp256k1Element) Neg() Element {
+    return &Secp256k1Element{p: new(p256.JacobianPoint).Neg(e.p)}
+}
+
+func (e *Secp256k1Element) ScalarMult(k Scalar) Element {
+    return &Secp256k1Element{p: new(p256.JacobianPoint).ScalarMult(e.p, k)}
+}
+
+func (e *Secp256k1Element) Equal(b Element) bool {
+    other, ok := b.(*Secp256k1Element)
+    return ok && e.p.Equal(other.p)
+}
+
+func (e *Secp256k1Element) IsIdentity() bool {
+    return e.p.IsZero()
+}
+
+func (e *Secp256k1Element) Encode() []byte {
+    if e.p.IsZero() {
+        return make([]byte, secp256k1ElementLength)
+    }
+    return e.p.Affine().Marshal()
+}
+
+func (e *Secp256k1Element) String() string {
+    if e.p.IsZero() {
+        return "secp256k1(infinity)"
+    }
+    p := e.p.Affine()
+    return "secp256k1(" + p.X.String() + "," + p.Y.String() + ")"
+}
+
+/*
+secp256k1FixedBase is the fixed-base table of an element of Secp256k1.
+*/
+type secp256k1FixedBase struct {
+    table *p256.FixedBaseTable
+}
+
+func (t secp256k1FixedBase) Base() Element {
+    return NewSecp256k1Element(t.table.Base())
+}
+
+func (t secp256k1FixedBase) ScalarMult(k Scalar) Element {
+    return NewSecp256k1Element(t.table.ScalarMult(k))
+}
+
+func (t secp256k1FixedBase) MarshalBinary() ([]byte, error) {
+    return t.table.MarshalBinary()
+}
+
+/*
+secp256k1Point returns the point of an element of Secp256k1.
+*/
+func secp256k1Point(e Element) *p256.JacobianPoint {
+    return e.(*Secp256k1Element).p
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package merlin implements Merlin transcripts (https://merlin.cool), the STROBE
+based Fiat-Shamir transcripts of the Rust crate merlin, which are used by the
+proofs of dalek-cryptography. Like the Go port github.com/gtank/merlin, it uses
+the STROBE-128 of github.com/mimoo/StrobeGo.
+*/
+package merlin
+
+import (
+    "encoding/binary"
+
+    "github.com/mimoo/StrobeGo/strobe"
+)
+
+const protocolLabel = "Merlin v1.0"
+
+/*
+Transcript is a Merlin transcript. It is compatible with version 2 and 3 of the
+Rust crate: the same operations give the same challenges.
+*/
+type Transcript struct {
+    strobe *strobe.Strobe
+}
+
+/*
+NewTranscript returns a transcript for the protocol identified by label.
+*/
+func NewTranscript(label string) *Transcript {
+    s := strobe.InitStrobe(protocolLabel, 128)
+    t := &Transcript{strobe: &s}
+    t.AppendMessage("dom-sep", []byte(label))
+    return t
+}
+
+/*
+AppendMessage absorbs the message into the transcript.
+*/
+func (t *Transcript) AppendMessage(label string, message []byte) {
+    t.strobe.AD(true, labelAndLength(label, len(message)))
+    t.strobe.AD(false, message)
+}
+
+/*
+AppendUint64 absorbs an integer, encoded with 8 bytes in little-endian order.
+*/
+func (t *Transcript) AppendUint64(label string, x uint64) {
+    b := make([]byte, 8)
+    binary.LittleEndian.PutUint64(b, x)
+    t.AppendMessage(label, b)
+}
+
+/*
+ChallengeBytes returns length bytes that depend on everything absorbed before.
+*/
+func (t *Transcript) ChallengeBytes(label string, length int) []byte {
+    t.strobe.AD(true, labelAndLength(label, length))
+    return t.strobe.PRF(length)
+}
+
+/*
+Clone returns an independent copy of the transcript.
+*/
+func (t *Transcript) Clone() *Transcript {
+    return &Transcript{strobe: t.strobe.Clone()}
+}
+
+/*
+labelAndLength returns the label followed by the length encoded with 4 bytes in
+little-endian order. StrobeGo has no continued operations, so Merlin's meta-AD
+of the label and its continuation with the length are a single meta-AD.
+*/
+func labelAndLength(label string, n int) []byte {
+    if uint64(n) > 0xffffffff {
+        panic("message is too long for a Merlin transcript")
+    }
+    b := make([]byte, len(label)+4)
+    copy(b, label)
+    binary.LittleEndian.PutUint32(b[len(label):], uint32(n))
+    return b
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package merlin
+
+import (
+    "bytes"
+    "encoding/hex"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+/*
+Test vector of the Rust crate merlin, test equivalence_simple.
+*/
+func TestTranscriptSimple(t *testing.T) {
+    transcript := NewTranscript("test protocol")
+    transcript.AppendMessage("some label", []byte("some data"))
+    challenge := transcript.ChallengeBytes("challenge", 32)
+    assert.Equal(t, "d5a21972d0d5fe320c0d263fac7fffb8145aa640af6e9bca177c03c7efcf0615", hex.EncodeToString(challenge))
+}
+
+/*
+Test vector of the Go port github.com/gtank/merlin, with challenges fed back
+into the transcript and messages longer than the rate of STROBE.
+*/
+func TestTranscriptComplex(t *testing.T) {
+    transcript := NewTranscript("test protocol")
+    transcript.AppendMessage("step1", []byte("some data"))
+    data := bytes.Repeat([]byte{99}, 1024)
+    var challenge []byte
+    for i := 0; i < 32; i++ {
+        challenge = transcript.ChallengeBytes("challenge", 32)
+        transcript.AppendMessage("bigdata", data)
+        transcript.AppendMessage("challengedata", challenge)
+    }
+    assert.Equal(t, "a8c933f54fae76e3f9bea93648c1308e7dfa2152dd51674ff3ca438351cf003c", hex.EncodeToString(challenge))
+}
+
+func TestTranscriptClone(t *testing.T) {
+    transcript := NewTranscript("test protocol")
+    transcript.AppendUint64("n", 64)
+    clone := transcript.Clone()
+    assert.Equal(t, transcript.ChallengeBytes("c", 64), clone.ChallengeBytes("c", 64))
+    clone.AppendMessage("data", []byte("other"))
+    assert.NotEqual(t, transcript.ChallengeBytes("c", 64), clone.ChallengeBytes("c", 64))
+}
+
+func TestTranscriptLongMessages(t *testing.T) {
+    // messages longer than the rate of STROBE
+    t1 := NewTranscript("test protocol")
+    t1.AppendMessage("data", make([]byte, 1000))
+    t2 := NewTranscript("test protocol")
+    t2.AppendMessage("data", make([]byte, 1001))
+    assert.NotEqual(t, t1.ChallengeBytes("c", 200), t2.ChallengeBytes("c", 200))
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "errors"
+    "math/big"
+)
+
+/*
+This file contains the scalar multiplications by secret scalars, such as the
+values, the bits and the blinding factors of a prover. They run in a time that
+depends on the number of points, but not on the scalars: the points are in
+homogeneous projective coordinates, whose complete addition and doubling
+formulas have no exceptional case, the scalars are processed by fixed windows
+of 4 bits, the multiples are read by scanning the whole table, and the result
+is normalized with invertConstantTime. The big.Int scalars of the API are
+converted to Scalar first, which only depends on the number of words of the
+big.Int.
+*/
+
+const (
+    // secretWindow is the bit-length of the unsigned digits of the secret scalars
+    secretWindow = 4
+    // secretTableSize is the number of multiples 0.P to 15.P of every point
+    secretTableSize = 1 << secretWindow
+)
+
+var (
+    // curveB3 is 3.b = 21, the constant of the complete formulas
+    curveB3 = fieldElement{21, 0, 0, 0}
+)
+
+/*
+projectivePoint represents the elliptic curve point (x/z, y/z). The point at
+infinity is (0, 1, 0).
+*/
+type projectivePoint struct {
+    x, y, z fieldElement
+}
+
+/*
+setInfinity sets p to the point at infinity.
+*/
+func (p *projectivePoint) setInfinity() *projectivePoint {
+    *p = projectivePoint{}
+    p.y[0] = 1
+    return p
+}
+
+/*
+setAffine sets p to the affine point a, which is public.
+*/
+func (p *projectivePoint) setAffine(a *affinePoint) *projectivePoint {
+    if a.infinity {
+        return p.setInfinity()
+    }
+    p.x = a.x
+    p.y = a.y
+    p.z = fieldElement{1, 0, 0, 0}
+    return p
+}
+
+/*
+add sets p = a + b with the complete addition formulas of Algorithm 7 of Renes,
+Costello and Batina, "Complete addition formulas for prime order elliptic
+curves", for curves with a = 0. They are valid for any points a and b,
+including equal points and the point at infinity.
+*/
+func (p *projectivePoint) add(a, b *projectivePoint) *projectivePoint {
+    var t0, t1, t2, t3, t4, x3, y3, z3 fieldElement
+    t0.mul(&a.x, &b.x)
+    t1.mul(&a.y, &b.y)
+    t2.mul(&a.z, &b.z)
+    t3.add(&a.x, &a.y)
+    t4.add(&b.x, &b.y)
+    t3.mul(&t3, &t4)
+    t4.add(&t0, &t1)
+    t3.sub(&t3, &t4)
+    t4.add(&a.y, &a.z)
+    x3.add(&b.y, &b.z)
+    t4.mul(&t4, &x3)
+    x3.add(&t1, &t2)
+    t4.sub(&t4, &x3)
+    x3.add(&a.x, &a.z)
+    y3.add(&b.x, &b.z)
+    x3.mul(&x3, &y3)
+    y3.add(&t0, &t2)
+    y3.sub(&x3, &y3)
+    x3.add(&t0, &t0)
+    t0.add(&x3, &t0)
+    t2.mul(&curveB3, &t2)
+    z3.add(&t1, &t2)
+    t1.sub(&t1, &t2)
+    y3.mul(&curveB3, &y3)
+    x3.mul(&t4, &y3)
+    t2.mul(&t3, &t1)
+    x3.sub(&t2, &x3)
+    y3.mul(&y3, &t0)
+    t1.mul(&t1, &z3)
+    y3.add(&t1, &y3)
+    t0.mul(&t0, &t3)
+    z3.mul(&z3, &t4)
+    z3.add(&z3, &t0)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+double sets p = 2.a with the complete doubling formulas of Algorithm 9 of the
+same paper.
+*/
+func (p *projectivePoint) double(a *projectivePoint) *projectivePoint {
+    var t0, t1, t2, x3, y3, z3 fieldElement
+    t0.square(&a.y)
+    z3.add(&t0, &t0)
+    z3.add(&z3, &z3)
+    z3.add(&z3, &z3)
+    t1.mul(&a.y, &a.z)
+    t2.square(&a.z)
+    t2.mul(&curveB3, &t2)
+    x3.mul(&t2, &z3)
+    y3.add(&t0, &t2)
+    z3.mul(&t1, &z3)
+    t1.add(&t2, &t2)
+    t2.add(&t1, &t2)
+    t0.sub(&t0, &t2)
+    y3.mul(&t0, &y3)
+    y3.add(&x3, &y3)
+    t1.mul(&a.x, &a.y)
+    x3.mul(&t0, &t1)
+    x3.add(&x3, &x3)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+lookup sets p = table[d] without branching on d nor accessing memory at an
+address that depends on d.
+*/
+func (p *projectivePoint) lookup(table []projectivePoint, d uint64) *projectivePoint {
+    p.setInfinity()
+    for i := range table {
+        // mask is all ones if and only if i = d
+        v := uint64(i) ^ d
+        mask := ((v | -v) >> 63) - 1
+        p.x.cmov(&table[i].x, mask)
+        p.y.cmov(&table[i].y, mask)
+        p.z.cmov(&table[i].z, mask)
+    }
+    return p
+}
+
+/*
+toAffine returns the affine representation of p, using invertConstantTime.
+*/
+func (p *projectivePoint) toAffine() *affinePoint {
+    var zinv fieldElement
+    a := new(affinePoint)
+    zinv.invertConstantTime(&p.z)
+    a.x.mul(&p.x, &zinv)
+    a.y.mul(&p.y, &zinv)
+    a.infinity = p.z.isZero()
+    return a
+}
+
+/*
+secretMultiScalarMult returns the sum of scalars[i].points[i], in a time that
+only depends on the number of points. The digit d of a window is added as
+table[d], where table[0] is the point at infinity.
+*/
+func secretMultiScalarMult(points []affinePoint, scalars []Scalar) *affinePoint {
+    tables := make([]projectivePoint, len(points)*secretTableSize)
+    for i := range points {
+        table := tables[i*secretTableSize : (i+1)*secretTableSize]
+        table[0].setInfinity()
+        table[1].setAffine(&points[i])
+        for d := 2; d < secretTableSize; d++ {
+            table[d].add(&table[d-1], &table[1])
+        }
+    }
+
+    var term projectivePoint
+    result := new(projectivePoint).setInfinity()
+    for w := 256/secretWindow - 1; w >= 0; w-- {
+        for j := 0; j < secretWindow; j++ {
+            result.double(result)
+        }
+        for i := range points {
+            d := (scalars[i].l[w*secretWindow/64] >> uint(w*secretWindow%64)) & (secretTableSize - 1)
+            term.lookup(tables[i*secretTableSize:(i+1)*secretTableSize], d)
+            result.add(result, &term)
+        }
+    }
+    return result.toAffine()
+}
+
+/*
+secretTerms converts the points and the scalars of a secret multi-scalar
+multiplication.
+*/
+func secretTerms(points []*JacobianPoint, scalars []*big.Int) ([]affinePoint, []Scalar, error) {
+    if len(points) != len(scalars) {
+        return nil, nil, errors.New("number of points is different from the number of scalars")
+    }
+    jacobian := make([]jacobianPoint, len(points))
+    ks := make([]Scalar, len(scalars))
+    for i := range points {
+        if points[i] == nil || scalars[i] == nil {
+            return nil, nil, errors.New("points and scalars must be defined")
+        }
+        jacobian[i] = points[i].p
+        ks[i].SetBig(scalars[i])
+    }
+    return batchToAffine(jacobian), ks, nil
+}
+
+/*
+SecretMultiScalarMult sets p to the sum of scalars[i].points[i], like
+MultiScalarMult, in a time that does not depend on the scalars, which may be
+secret. The points are public. It is about 2 times slower than MultiScalarMult
+for a single point, and 2.5 times for a hundred points. The result is normalized.
+*/
+func (p *JacobianPoint) SecretMultiScalarMult(points []*JacobianPoint, scalars []*big.Int) (*JacobianPoint, error) {
+    ps, ks, err := secretTerms(points, scalars)
+    if err != nil {
+        return nil, err
+    }
+    p.p.setAffine(secretMultiScalarMult(ps, ks))
+    return p, nil
+}
+
+/*
+SecretScalarMult sets p = k.a, in a time that does not depend on k.
+*/
+func (p *JacobianPoint) SecretScalarMult(a *JacobianPoint, k *big.Int) *JacobianPoint {
+    result, _ := p.SecretMultiScalarMult([]*JacobianPoint{a}, []*big.Int{k})
+    return result
+}
+
+/*
+SecretScalarMult sets p = k.a, in a time that does not depend on k.
+*/
+func (p *Secp256k1Point) SecretScalarMult(a *Secp256k1Point, k *big.Int) *Secp256k1Point {
+    r := new(JacobianPoint).SetAffine(a)
+    return p.setAffine(r.SecretScalarMult(r, k).Affine())
+}
+
+/*
+SecretScalarBaseMult sets p = k.G, where G is the generator, in a time that does
+not depend on k.
+*/
+func (p *Secp256k1Point) SecretScalarBaseMult(k *big.Int) *Secp256k1Point {
+    return p.SecretScalarMult(&Secp256k1Point{X: CURVE.Gx, Y: CURVE.Gy}, k)
+}
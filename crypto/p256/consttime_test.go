@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "flag"
+    "math"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/internal/timing"
+)
+
+var timingTest = flag.Bool("timing", false, "run the statistical test of the running time of SecretScalarMult")
+
+func TestSecretScalarMult(t *testing.T) {
+    points, scalars := randomTerms(6)
+    nm1 := new(big.Int).Sub(CURVE.N, big.NewInt(1))
+    // equal points, the point at infinity and edge scalars
+    points = append(points, points[0], new(Secp256k1Point).SetInfinity(), points[1], points[2], points[3])
+    scalars = append(scalars, scalars[0], big.NewInt(5), big.NewInt(0), nm1, new(big.Int).Neg(CURVE.N))
+    jacobian := make([]*JacobianPoint, len(points))
+    for i := range points {
+        jacobian[i] = NewJacobianPoint(points[i])
+        assertSamePoint(t, new(Secp256k1Point).ScalarMult(points[i], scalars[i]), new(Secp256k1Point).SecretScalarMult(points[i], scalars[i]))
+    }
+    actual, err := new(JacobianPoint).SecretMultiScalarMult(jacobian, scalars)
+    if err != nil {
+        t.Fatalf("Unexpected error: %s", err)
+    }
+    assertSamePoint(t, naiveMultiScalarMult(points, scalars), actual.Affine())
+
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    assertSamePoint(t, new(Secp256k1Point).ScalarBaseMult(k), new(Secp256k1Point).SecretScalarBaseMult(k))
+    if !new(Secp256k1Point).SecretScalarBaseMult(CURVE.N).IsZero() {
+        t.Errorf("Assert failure: N.G is not the point at infinity")
+    }
+    if _, err := new(JacobianPoint).SecretMultiScalarMult(jacobian, scalars[1:]); err == nil {
+        t.Errorf("Assert failure: different lengths accepted")
+    }
+}
+
+func TestProjectiveFormulas(t *testing.T) {
+    points, _ := randomTerms(2)
+    var a, b affinePoint
+    var p, q, infinity projectivePoint
+    p.setAffine(a.setPoint(points[0]))
+    q.setAffine(b.setPoint(points[1]))
+    infinity.setInfinity()
+    assertSamePoint(t, new(Secp256k1Point).Multiply(points[0], points[1]), new(projectivePoint).add(&p, &q).toAffine().toPoint())
+    assertSamePoint(t, new(Secp256k1Point).Double(points[0]), new(projectivePoint).add(&p, &p).toAffine().toPoint())
+    assertSamePoint(t, new(Secp256k1Point).Double(points[0]), new(projectivePoint).double(&p).toAffine().toPoint())
+    assertSamePoint(t, points[0], new(projectivePoint).add(&p, &infinity).toAffine().toPoint())
+    assertSamePoint(t, points[0], new(projectivePoint).add(&infinity, &p).toAffine().toPoint())
+    if !new(projectivePoint).double(&infinity).toAffine().infinity {
+        t.Errorf("Assert failure: 2.O is not the point at infinity")
+    }
+    var na affinePoint
+    na.neg(&a)
+    if !new(projectivePoint).add(&p, new(projectivePoint).setAffine(&na)).toAffine().infinity {
+        t.Errorf("Assert failure: P + (-P) is not the point at infinity")
+    }
+}
+
+/*
+TestSecretScalarMultTiming compares the running time of SecretScalarMult for the
+scalar 1, which has a single non-zero digit, and for random scalars. It also
+checks that the same measurement detects the difference for ScalarMult, whose
+running time depends on the scalar. The measurements depend on the load of the machine, so the test only
+runs with -timing.
+*/
+func TestSecretScalarMultTiming(t *testing.T) {
+    if !*timingTest {
+        t.Skip("timing test runs with -timing")
+    }
+    points, _ := randomTerms(1)
+    P := points[0]
+    const measurements = 4000
+    one := big.NewInt(1)
+
+    secret := timing.TStatistic(measurements, CURVE.N, one, func(k *big.Int) { new(Secp256k1Point).SecretScalarMult(P, k) })
+    t.Logf("t-statistic of SecretScalarMult: %.2f", secret)
+    if math.Abs(secret) > 10 {
+        t.Errorf("Assert failure: the running time of SecretScalarMult depends on the scalar, t = %.2f", secret)
+    }
+    public := timing.TStatistic(measurements, CURVE.N, one, func(k *big.Int) { new(Secp256k1Point).ScalarMult(P, k) })
+    t.Logf("t-statistic of ScalarMult: %.2f", public)
+    if math.Abs(public) < 10 {
+        t.Errorf("Assert failure: the timing test does not detect the variable time of ScalarMult, t = %.2f", public)
+    }
+}
+
+func BenchmarkSecretScalarMult(b *testing.B) {
+    points, scalars := randomTerms(1)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _ = new(Secp256k1Point).SecretScalarMult(points[0], scalars[0])
+    }
+}
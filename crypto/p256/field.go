@@ -0,0 +1,326 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "encoding/binary"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/internal/arith"
+)
+
+const (
+    // fieldC is 2^32 + 977, such that the field prime is P = 2^256 - fieldC
+    fieldC = uint64(4294968273)
+)
+
+/*
+fieldElement is an element of the base field of secp256k1, represented by 4
+limbs of 64 bits in little-endian order. It is always fully reduced, i.e. lower
+than P, so that equal elements have equal limbs. The arithmetic selects its
+results with masks instead of branches, so that add, sub, mul and
+invertConstantTime run in a time that does not depend on the values.
+*/
+type fieldElement [4]uint64
+
+/*
+setBig sets z to b mod P.
+*/
+func (z *fieldElement) setBig(b *big.Int) *fieldElement {
+    bytes := make([]byte, 32)
+    v := b
+    if b.Sign() < 0 || b.Cmp(CURVE.P) >= 0 {
+        v = new(big.Int).Mod(b, CURVE.P)
+    }
+    vb := v.Bytes()
+    copy(bytes[32-len(vb):], vb)
+    for i := 0; i < 4; i++ {
+        z[i] = binary.BigEndian.Uint64(bytes[24-8*i:])
+    }
+    return z
+}
+
+/*
+setBytes sets z to the 32-byte big-endian integer b. It returns false if b is not
+lower than P, in which case z is left unchanged.
+*/
+func (z *fieldElement) setBytes(b []byte) bool {
+    if len(b) != 32 {
+        return false
+    }
+    var v fieldElement
+    for i := 0; i < 4; i++ {
+        v[i] = binary.BigEndian.Uint64(b[24-8*i:])
+    }
+    // P = 2^256 - fieldC, hence v >= P iff the three top limbs are all ones and
+    // the lowest one is at least 2^64 - fieldC
+    if v[3]&v[2]&v[1] == ^uint64(0) && v[0] > ^fieldC {
+        return false
+    }
+    *z = v
+    return true
+}
+
+/*
+putBytes writes z to b as a 32-byte big-endian integer.
+*/
+func (z *fieldElement) putBytes(b []byte) {
+    for i := 0; i < 4; i++ {
+        binary.BigEndian.PutUint64(b[24-8*i:], z[i])
+    }
+}
+
+/*
+big returns z as a big.Int.
+*/
+func (z *fieldElement) big() *big.Int {
+    bytes := make([]byte, 32)
+    for i := 0; i < 4; i++ {
+        binary.BigEndian.PutUint64(bytes[24-8*i:], z[i])
+    }
+    return new(big.Int).SetBytes(bytes)
+}
+
+/*
+isZero returns true if and only if z is 0.
+*/
+func (z *fieldElement) isZero() bool {
+    return z[0]|z[1]|z[2]|z[3] == 0
+}
+
+/*
+add sets z = x + y mod P.
+*/
+func (z *fieldElement) add(x, y *fieldElement) *fieldElement {
+    var s, t fieldElement
+    var c, c2 uint64
+    s[0], c = arith.Add64(x[0], y[0], 0)
+    s[1], c = arith.Add64(x[1], y[1], c)
+    s[2], c = arith.Add64(x[2], y[2], c)
+    s[3], c = arith.Add64(x[3], y[3], c)
+    // s - P = s + fieldC - 2^256
+    t[0], c2 = arith.Add64(s[0], fieldC, 0)
+    t[1], c2 = arith.Add64(s[1], 0, c2)
+    t[2], c2 = arith.Add64(s[2], 0, c2)
+    t[3], c2 = arith.Add64(s[3], 0, c2)
+    *z = s
+    return z.cmov(&t, -(c | c2))
+}
+
+/*
+sub sets z = x - y mod P.
+*/
+func (z *fieldElement) sub(x, y *fieldElement) *fieldElement {
+    var d fieldElement
+    var b uint64
+    d[0], b = arith.Sub64(x[0], y[0], 0)
+    d[1], b = arith.Sub64(x[1], y[1], b)
+    d[2], b = arith.Sub64(x[2], y[2], b)
+    d[3], b = arith.Sub64(x[3], y[3], b)
+    // d + P = d - fieldC mod 2^256, if the subtraction borrowed
+    var b2 uint64
+    fix := fieldC & -b
+    d[0], b2 = arith.Sub64(d[0], fix, 0)
+    d[1], b2 = arith.Sub64(d[1], 0, b2)
+    d[2], b2 = arith.Sub64(d[2], 0, b2)
+    d[3], _ = arith.Sub64(d[3], 0, b2)
+    *z = d
+    return z
+}
+
+/*
+neg sets z = -x mod P.
+*/
+func (z *fieldElement) neg(x *fieldElement) *fieldElement {
+    var zero fieldElement
+    return z.sub(&zero, x)
+}
+
+/*
+madd returns the 128-bit result of x.y + a + b, which cannot overflow.
+*/
+func madd(x, y, a, b uint64) (hi, lo uint64) {
+    var c uint64
+    hi, lo = arith.Mul64(x, y)
+    lo, c = arith.Add64(lo, a, 0)
+    hi += c
+    lo, c = arith.Add64(lo, b, 0)
+    return hi + c, lo
+}
+
+/*
+mulWide returns the 512-bit product of x and y, in little-endian limbs.
+*/
+func mulWide(x, y *[4]uint64) (t [8]uint64) {
+    for i := 0; i < 4; i++ {
+        var c uint64
+        c, t[i] = madd(x[i], y[0], t[i], 0)
+        c, t[i+1] = madd(x[i], y[1], t[i+1], c)
+        c, t[i+2] = madd(x[i], y[2], t[i+2], c)
+        c, t[i+3] = madd(x[i], y[3], t[i+3], c)
+        t[i+4] = c
+    }
+    return t
+}
+
+/*
+mul sets z = x.y mod P. The product of 512 bits is reduced using the special form
+of P: since 2^256 = fieldC mod P, the upper half is multiplied by fieldC and
+added to the lower half, twice.
+*/
+func (z *fieldElement) mul(x, y *fieldElement) *fieldElement {
+    t := mulWide((*[4]uint64)(x), (*[4]uint64)(y))
+
+    // r = t[0:4] + t[4:8].fieldC, which has at most 290 bits
+    var r fieldElement
+    var carry uint64
+    carry, r[0] = madd(t[4], fieldC, t[0], 0)
+    carry, r[1] = madd(t[5], fieldC, t[1], carry)
+    carry, r[2] = madd(t[6], fieldC, t[2], carry)
+    carry, r[3] = madd(t[7], fieldC, t[3], carry)
+    // fold the remaining carry, lower than 2^34
+    hi, lo := arith.Mul64(carry, fieldC)
+    var c uint64
+    r[0], c = arith.Add64(r[0], lo, 0)
+    r[1], c = arith.Add64(r[1], hi, c)
+    r[2], c = arith.Add64(r[2], 0, c)
+    r[3], c = arith.Add64(r[3], 0, c)
+    // if the sum wrapped around 2^256, r is small and adding fieldC cannot overflow
+    fix := fieldC & -c
+    r[0], c = arith.Add64(r[0], fix, 0)
+    r[1], c = arith.Add64(r[1], 0, c)
+    r[2], c = arith.Add64(r[2], 0, c)
+    r[3], _ = arith.Add64(r[3], 0, c)
+    // subtract P if r >= P, i.e. if r + fieldC >= 2^256
+    var s fieldElement
+    s[0], c = arith.Add64(r[0], fieldC, 0)
+    s[1], c = arith.Add64(r[1], 0, c)
+    s[2], c = arith.Add64(r[2], 0, c)
+    s[3], c = arith.Add64(r[3], 0, c)
+    *z = r
+    return z.cmov(&s, -c)
+}
+
+/*
+cmov sets z = x if mask is all ones and leaves z unchanged if mask is 0, in
+constant time.
+*/
+func (z *fieldElement) cmov(x *fieldElement, mask uint64) *fieldElement {
+    z[0] ^= (z[0] ^ x[0]) & mask
+    z[1] ^= (z[1] ^ x[1]) & mask
+    z[2] ^= (z[2] ^ x[2]) & mask
+    z[3] ^= (z[3] ^ x[3]) & mask
+    return z
+}
+
+/*
+square sets z = x^2 mod P.
+*/
+func (z *fieldElement) square(x *fieldElement) *fieldElement {
+    return z.mul(x, x)
+}
+
+/*
+squareN sets z = x^(2^n) mod P.
+*/
+func (z *fieldElement) squareN(x *fieldElement, n int) *fieldElement {
+    *z = *x
+    for i := 0; i < n; i++ {
+        z.square(z)
+    }
+    return z
+}
+
+/*
+powerChain returns x^(2^223 - 1), x^(2^22 - 1) and x^3, the common prefix of the
+addition chains of invertConstantTime and sqrt, which are those of libsecp256k1.
+The name xk stands for x^(2^k - 1).
+*/
+func powerChain(x *fieldElement) (x223, x22, x2 fieldElement) {
+    var x3, x6, x9, x11, x44, x88, x176, x220 fieldElement
+    x2.square(x)
+    x2.mul(&x2, x)
+    x3.square(&x2)
+    x3.mul(&x3, x)
+    x6.squareN(&x3, 3)
+    x6.mul(&x6, &x3)
+    x9.squareN(&x6, 3)
+    x9.mul(&x9, &x3)
+    x11.squareN(&x9, 2)
+    x11.mul(&x11, &x2)
+    x22.squareN(&x11, 11)
+    x22.mul(&x22, &x11)
+    x44.squareN(&x22, 22)
+    x44.mul(&x44, &x22)
+    x88.squareN(&x44, 44)
+    x88.mul(&x88, &x44)
+    x176.squareN(&x88, 88)
+    x176.mul(&x176, &x88)
+    x220.squareN(&x176, 44)
+    x220.mul(&x220, &x44)
+    x223.squareN(&x220, 3)
+    x223.mul(&x223, &x3)
+    return x223, x22, x2
+}
+
+/*
+invert sets z = x^-1 mod P, for x different from 0. The extended Euclidean
+algorithm of math/big is faster than invertConstantTime, but its running time
+depends on x, so it is only used on public values.
+*/
+func (z *fieldElement) invert(x *fieldElement) *fieldElement {
+    return z.setBig(new(big.Int).ModInverse(x.big(), CURVE.P))
+}
+
+/*
+invertConstantTime sets z = x^-1 = x^(P - 2) mod P, and z = 0 for x = 0, in
+constant time. The exponent P - 2 = 2^256 - 2^32 - 979 costs 255 squarings and
+15 multiplications.
+*/
+func (z *fieldElement) invertConstantTime(x *fieldElement) *fieldElement {
+    x223, x22, x2 := powerChain(x)
+    var t fieldElement
+    t.squareN(&x223, 23)
+    t.mul(&t, &x22)
+    t.squareN(&t, 5)
+    t.mul(&t, x)
+    t.squareN(&t, 3)
+    t.mul(&t, &x2)
+    t.squareN(&t, 2)
+    *z = *t.mul(&t, x)
+    return z
+}
+
+/*
+sqrt sets z to a square root of x, x^((P + 1) / 4) since P = 3 mod 4, and
+returns true if and only if x is a square. Otherwise z is left unchanged.
+*/
+func (z *fieldElement) sqrt(x *fieldElement) bool {
+    x223, x22, x2 := powerChain(x)
+    var r, check fieldElement
+    r.squareN(&x223, 23)
+    r.mul(&r, &x22)
+    r.squareN(&r, 6)
+    r.mul(&r, &x2)
+    r.squareN(&r, 2)
+    if *check.square(&r) != *x {
+        return false
+    }
+    *z = r
+    return true
+}
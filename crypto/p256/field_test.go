@@ -0,0 +1,135 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "math/big"
+    "testing"
+)
+
+func TestFieldArithmetic(t *testing.T) {
+    pm1 := new(big.Int).Sub(CURVE.P, big.NewInt(1))
+    values := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), pm1, new(big.Int).Rsh(CURVE.P, 1)}
+    for i := 0; i < 64; i++ {
+        v, _ := rand.Int(rand.Reader, CURVE.P)
+        values = append(values, v)
+    }
+    for _, a := range values {
+        for _, b := range values[:8] {
+            var x, y, z fieldElement
+            x.setBig(a)
+            y.setBig(b)
+
+            expected := new(big.Int).Add(a, b)
+            expected.Mod(expected, CURVE.P)
+            if z.add(&x, &y).big().Cmp(expected) != 0 {
+                t.Errorf("Assert failure: %s + %s, expected %s, actual: %s", a, b, expected, z.big())
+            }
+            expected = new(big.Int).Sub(a, b)
+            expected.Mod(expected, CURVE.P)
+            if z.sub(&x, &y).big().Cmp(expected) != 0 {
+                t.Errorf("Assert failure: %s - %s, expected %s, actual: %s", a, b, expected, z.big())
+            }
+            expected = new(big.Int).Mul(a, b)
+            expected.Mod(expected, CURVE.P)
+            if z.mul(&x, &y).big().Cmp(expected) != 0 {
+                t.Errorf("Assert failure: %s * %s, expected %s, actual: %s", a, b, expected, z.big())
+            }
+        }
+    }
+}
+
+func TestFieldInvertSqrt(t *testing.T) {
+    pm1 := new(big.Int).Sub(CURVE.P, big.NewInt(1))
+    values := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(7), pm1}
+    for i := 0; i < 32; i++ {
+        v, _ := rand.Int(rand.Reader, CURVE.P)
+        values = append(values, v)
+    }
+    for _, a := range values {
+        var x, z fieldElement
+        x.setBig(a)
+        expected := new(big.Int).ModInverse(a, CURVE.P)
+        if z.invert(&x).big().Cmp(expected) != 0 {
+            t.Errorf("Assert failure: 1 / %s, expected %s, actual: %s", a, expected, z.big())
+        }
+        if z.invertConstantTime(&x).big().Cmp(expected) != 0 {
+            t.Errorf("Assert failure: 1 / %s in constant time, expected %s, actual: %s", a, expected, z.big())
+        }
+        expected = new(big.Int).ModSqrt(a, CURVE.P)
+        ok := z.sqrt(&x)
+        if ok != (expected != nil) {
+            t.Errorf("Assert failure: sqrt(%s) returned %v", a, ok)
+        }
+        if ok && new(big.Int).Exp(z.big(), big.NewInt(2), CURVE.P).Cmp(a) != 0 {
+            t.Errorf("Assert failure: %s is not a square root of %s", z.big(), a)
+        }
+    }
+    var zero, z fieldElement
+    if !z.invertConstantTime(&zero).isZero() || !z.sqrt(&zero) || !z.isZero() {
+        t.Errorf("Assert failure: invert or sqrt of 0 is not 0")
+    }
+}
+
+func TestFieldSetBytes(t *testing.T) {
+    pm1 := new(big.Int).Sub(CURVE.P, big.NewInt(1))
+    pow := new(big.Int).Lsh(big.NewInt(1), 224)
+    max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+    for _, v := range []*big.Int{big.NewInt(0), pow, pm1, CURVE.P, max} {
+        data := make([]byte, 32)
+        copy(data[32-len(v.Bytes()):], v.Bytes())
+        var z fieldElement
+        ok := z.setBytes(data)
+        if ok != (v.Cmp(CURVE.P) < 0) {
+            t.Errorf("Assert failure: setBytes(%s) returned %v", v, ok)
+        }
+        if ok && z.big().Cmp(v) != 0 {
+            t.Errorf("Assert failure: expected %s, actual: %s", v, z.big())
+        }
+    }
+}
+
+func TestJacobianArithmetic(t *testing.T) {
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    P := new(Secp256k1Point).ScalarBaseMult(k)
+    Q := new(Secp256k1Point).ScalarBaseMult(big.NewInt(3))
+    var a, b affinePoint
+    a.setPoint(P)
+    b.setPoint(Q)
+    jp := new(jacobianPoint).setAffine(&a)
+    jq := new(jacobianPoint).setAffine(&b)
+
+    // use a point with z different from 1
+    jp.double(jp)
+    P = new(Secp256k1Point).Double(P)
+    assertSamePoint(t, P, jp.toAffine().toPoint())
+
+    expected := new(Secp256k1Point).Multiply(P, Q)
+    assertSamePoint(t, expected, new(jacobianPoint).add(jp, jq).toAffine().toPoint())
+    assertSamePoint(t, expected, new(jacobianPoint).addMixed(jp, &b).toAffine().toPoint())
+
+    // doubling through the addition formulas and adding the inverse
+    expected = new(Secp256k1Point).Double(P)
+    assertSamePoint(t, expected, new(jacobianPoint).add(jp, jp).toAffine().toPoint())
+    var na affinePoint
+    na.neg(jp.toAffine())
+    if !new(jacobianPoint).addMixed(jp, &na).isInfinity() {
+        t.Errorf("Assert failure: P + (-P) should be the point at infinity")
+    }
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "errors"
+    "math/big"
+)
+
+const (
+    // fixedBaseWindow is the bit-length of the signed digits of a fixed-base table
+    fixedBaseWindow = 5
+    // fixedBaseBits is the bit-length of the scalars after replacing k by N - k
+    fixedBaseBits = 255
+    // fixedBaseSize is the number of multiples stored for every window
+    fixedBaseSize = 1 << (fixedBaseWindow - 1)
+    // fixedBaseWindows is the number of signed digits of a scalar
+    fixedBaseWindows = (fixedBaseBits+fixedBaseWindow-1)/fixedBaseWindow + 1
+    // fixedBasePointLength is the length of an encoded multiple, x || y
+    fixedBasePointLength = 64
+)
+
+/*
+FixedBaseTable contains the multiples d.2^(w.i).P, for every window i and every
+digit d in [1, 2^(w-1)], of a point P that is used in many scalar multiplications,
+such as a generator. A scalar multiplication with the table only needs one mixed
+addition per window and no doubling.
+*/
+type FixedBaseTable struct {
+    base      Secp256k1Point
+    multiples []affinePoint
+}
+
+/*
+NewFixedBaseTable computes the table of the point p, which must not be the point
+at infinity.
+*/
+func NewFixedBaseTable(p *Secp256k1Point) (*FixedBaseTable, error) {
+    if p == nil || p.IsZero() || !p.IsOnCurve() {
+        return nil, errors.New("invalid base point")
+    }
+    var base affinePoint
+    base.setPoint(p)
+
+    multiples := make([]jacobianPoint, fixedBaseWindows*fixedBaseSize)
+    var current jacobianPoint
+    current.setAffine(&base)
+    for w := 0; w < fixedBaseWindows; w++ {
+        // multiples[w][d-1] = d.current, where current = 2^(w.c).P
+        row := multiples[w*fixedBaseSize : (w+1)*fixedBaseSize]
+        row[0] = current
+        for d := 1; d < fixedBaseSize; d++ {
+            row[d].add(&row[d-1], &current)
+        }
+        // 2^c.current = 2.(2^(c-1).current)
+        current.double(&row[fixedBaseSize-1])
+    }
+    return &FixedBaseTable{
+        base:      Secp256k1Point{X: new(big.Int).Set(p.X), Y: new(big.Int).Set(p.Y)},
+        multiples: batchToAffine(multiples),
+    }, nil
+}
+
+/*
+Base returns the point P of the table.
+*/
+func (t *FixedBaseTable) Base() *Secp256k1Point {
+    return &Secp256k1Point{X: new(big.Int).Set(t.base.X), Y: new(big.Int).Set(t.base.Y)}
+}
+
+/*
+ScalarMult returns k.P, where P is the point of the table.
+*/
+func (t *FixedBaseTable) ScalarMult(k *big.Int) *Secp256k1Point {
+    result := new(jacobianPoint).setInfinity()
+    t.accumulate(result, k)
+    return result.toAffine().toPoint()
+}
+
+/*
+FixedBaseMultiScalarMult returns the sum of scalars[i].P_i, where P_i is the
+point of tables[i]. The scalars are not secret: the running time depends on
+their values.
+*/
+func FixedBaseMultiScalarMult(tables []*FixedBaseTable, scalars []*big.Int) (*Secp256k1Point, error) {
+    if len(tables) != len(scalars) {
+        return nil, errors.New("number of tables is different from the number of scalars")
+    }
+    result := new(jacobianPoint).setInfinity()
+    for i := range tables {
+        if tables[i] == nil || scalars[i] == nil {
+            return nil, errors.New("tables and scalars must be defined")
+        }
+        tables[i].accumulate(result, scalars[i])
+    }
+    return result.toAffine().toPoint(), nil
+}
+
+/*
+accumulate adds k.P to result. When k is larger than N/2 it adds (N - k).(-P),
+so that every scalar has at most fixedBaseBits bits.
+*/
+func (t *FixedBaseTable) accumulate(result *jacobianPoint, k *big.Int) {
+    var s Scalar
+    s.SetBig(k)
+    negate := s.isHigh()
+    if negate {
+        s.Neg(&s)
+    }
+    digits := signedDigits(&s, fixedBaseWindow, fixedBaseBits)
+    var term affinePoint
+    for w, d := range digits {
+        if d == 0 {
+            continue
+        }
+        // -d.Q is the negation of d.Q
+        if d > 0 {
+            term = t.multiples[w*fixedBaseSize+d-1]
+        } else {
+            term.neg(&t.multiples[w*fixedBaseSize-d-1])
+        }
+        if negate {
+            term.neg(&term)
+        }
+        result.addMixed(result, &term)
+    }
+}
+
+/*
+MarshalBinary encodes the table as the compressed base point followed by every
+multiple as x || y, 64 bytes each.
+*/
+func (t *FixedBaseTable) MarshalBinary() ([]byte, error) {
+    data := make([]byte, 33+len(t.multiples)*fixedBasePointLength)
+    copy(data, t.base.Marshal())
+    for i := range t.multiples {
+        point := data[33+i*fixedBasePointLength:]
+        t.multiples[i].x.putBytes(point[:32])
+        t.multiples[i].y.putBytes(point[32:64])
+    }
+    return data, nil
+}
+
+/*
+UnmarshalBinary decodes a table encoded by MarshalBinary. It checks that every
+multiple lies on the curve and is the right multiple of the base point, so that
+a table read from untrusted storage is either correct or rejected.
+*/
+func (t *FixedBaseTable) UnmarshalBinary(data []byte) error {
+    count := fixedBaseWindows * fixedBaseSize
+    if len(data) != 33+count*fixedBasePointLength {
+        return errors.New("invalid length of the fixed-base table")
+    }
+    base, err := new(Secp256k1Point).Unmarshal(data[:33])
+    if err != nil || base.IsZero() {
+        return errors.New("invalid base point of the fixed-base table")
+    }
+    multiples := make([]affinePoint, count)
+    for i := range multiples {
+        point := data[33+i*fixedBasePointLength:]
+        if !multiples[i].x.setBytes(point[:32]) || !multiples[i].y.setBytes(point[32:64]) ||
+            !multiples[i].isOnCurve() {
+            return errors.New("invalid point in the fixed-base table")
+        }
+    }
+    // the first multiple is the base point itself
+    if multiples[0].x.big().Cmp(base.X) != 0 || multiples[0].y.big().Cmp(base.Y) != 0 {
+        return errors.New("fixed-base table does not match its base point")
+    }
+    if !checkMultiples(multiples) {
+        return errors.New("invalid multiple in the fixed-base table")
+    }
+    t.base = *base
+    t.multiples = multiples
+    return nil
+}
+
+/*
+checkMultiples returns true if and only if the multiples are those computed by
+NewFixedBaseTable for the first one, that is multiples[w][d] = multiples[w][d-1]
++ multiples[w][0] in every window and multiples[w+1][0] = 2.multiples[w][last].
+The multiples must lie on the curve.
+*/
+func checkMultiples(multiples []affinePoint) bool {
+    var sum jacobianPoint
+    for w := 0; w < fixedBaseWindows; w++ {
+        row := multiples[w*fixedBaseSize : (w+1)*fixedBaseSize]
+        for d := 1; d < fixedBaseSize; d++ {
+            if !isChordSum(&row[d], &row[d-1], &row[0]) {
+                sum.setAffine(&row[d-1])
+                if !sum.addMixed(&sum, &row[0]).equalAffine(&row[d]) {
+                    return false
+                }
+            }
+        }
+        if w+1 < fixedBaseWindows {
+            sum.setAffine(&row[fixedBaseSize-1])
+            if !sum.double(&sum).equalAffine(&multiples[(w+1)*fixedBaseSize]) {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+/*
+isChordSum returns true if r = a + b, where a and b have different X coordinates,
+without inversion: with dx = xa - xb and dy = ya - yb, the slope is dy/dx, so
+(xr + xa + xb).dx^2 = dy^2 and (yr + ya).dx = dy.(xa - xr). It returns false if
+a and b have the same X coordinate, in which case the caller must check the sum
+otherwise.
+*/
+func isChordSum(r, a, b *affinePoint) bool {
+    var dx, dy, dx2, lhs, rhs fieldElement
+    dx.sub(&a.x, &b.x)
+    if dx.isZero() {
+        return false
+    }
+    dy.sub(&a.y, &b.y)
+    dx2.square(&dx)
+    lhs.add(&r.x, &a.x)
+    lhs.add(&lhs, &b.x)
+    lhs.mul(&lhs, &dx2)
+    if lhs != *rhs.square(&dy) {
+        return false
+    }
+    lhs.add(&r.y, &a.y)
+    lhs.mul(&lhs, &dx)
+    rhs.sub(&a.x, &r.x)
+    rhs.mul(&rhs, &dy)
+    return lhs == rhs
+}
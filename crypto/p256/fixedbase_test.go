@@ -0,0 +1,133 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "math/big"
+    "testing"
+)
+
+func TestFixedBaseScalarMult(t *testing.T) {
+    points, scalars := randomTerms(4)
+    scalars[1] = big.NewInt(-3)
+    scalars[2] = new(big.Int).Sub(CURVE.N, big.NewInt(1))
+    scalars[3] = big.NewInt(0)
+    for i := range points {
+        table, err := NewFixedBaseTable(points[i])
+        if err != nil {
+            t.Fatalf("Unexpected error: %s", err)
+        }
+        assertSamePoint(t, points[i], table.Base())
+        assertSamePoint(t, new(Secp256k1Point).ScalarMult(points[i], scalars[i]), table.ScalarMult(scalars[i]))
+    }
+
+    _, err := NewFixedBaseTable(new(Secp256k1Point).SetInfinity())
+    if err == nil {
+        t.Errorf("Assert failure: point at infinity should be rejected")
+    }
+}
+
+func TestFixedBaseMultiScalarMult(t *testing.T) {
+    points, scalars := randomTerms(5)
+    points[1] = points[0]
+    tables := make([]*FixedBaseTable, len(points))
+    for i := range points {
+        tables[i], _ = NewFixedBaseTable(points[i])
+    }
+    actual, err := FixedBaseMultiScalarMult(tables, scalars)
+    if err != nil {
+        t.Fatalf("Unexpected error: %s", err)
+    }
+    assertSamePoint(t, naiveMultiScalarMult(points, scalars), actual)
+
+    _, err = FixedBaseMultiScalarMult(tables, scalars[:2])
+    if err == nil {
+        t.Errorf("Assert failure: vectors of different sizes should be rejected")
+    }
+}
+
+func TestFixedBaseTableMarshal(t *testing.T) {
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    table, _ := NewFixedBaseTable(new(Secp256k1Point).ScalarBaseMult(k))
+    data, err := table.MarshalBinary()
+    if err != nil {
+        t.Fatalf("Unexpected error: %s", err)
+    }
+    var decoded FixedBaseTable
+    if err := decoded.UnmarshalBinary(data); err != nil {
+        t.Fatalf("Unexpected error: %s", err)
+    }
+    assertSamePoint(t, table.ScalarMult(k), decoded.ScalarMult(k))
+
+    // multiples that lie on the curve but are swapped, within a window and
+    // between the last multiple of a window and the first of the next one
+    for _, pair := range [][2]int{{1, 2}, {fixedBaseSize - 1, fixedBaseSize}, {5, fixedBaseSize*3 + 5}} {
+        swapped := append([]byte{}, data...)
+        i := swapped[33+pair[0]*fixedBasePointLength : 33+(pair[0]+1)*fixedBasePointLength]
+        j := swapped[33+pair[1]*fixedBasePointLength : 33+(pair[1]+1)*fixedBasePointLength]
+        tmp := append([]byte{}, i...)
+        copy(i, j)
+        copy(j, tmp)
+        if decoded.UnmarshalBinary(swapped) == nil {
+            t.Errorf("Assert failure: table with swapped multiples %v should be rejected", pair)
+        }
+    }
+
+    // a point that is not on the curve
+    data[len(data)-1] ^= 1
+    if decoded.UnmarshalBinary(data) == nil {
+        t.Errorf("Assert failure: corrupted table should be rejected")
+    }
+    if decoded.UnmarshalBinary(data[:100]) == nil {
+        t.Errorf("Assert failure: truncated table should be rejected")
+    }
+}
+
+func BenchmarkFixedBaseTable(b *testing.B) {
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    point := new(Secp256k1Point).ScalarBaseMult(k)
+    table, _ := NewFixedBaseTable(point)
+    data, _ := table.MarshalBinary()
+    b.Run("new", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            _, _ = NewFixedBaseTable(point)
+        }
+    })
+    b.Run("unmarshal", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            _ = new(FixedBaseTable).UnmarshalBinary(data)
+        }
+    })
+}
+
+func BenchmarkFixedBaseScalarMult(b *testing.B) {
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    point := new(Secp256k1Point).ScalarBaseMult(k)
+    table, _ := NewFixedBaseTable(point)
+    b.Run("table", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            _ = table.ScalarMult(k)
+        }
+    })
+    b.Run("scalarmult", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            _ = new(Secp256k1Point).ScalarMult(point, k)
+        }
+    })
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "github.com/ing-bank/zkrp/internal/arith"
+)
+
+/*
+This file contains the endomorphism of secp256k1 used by the method of
+Gallant, Lambert and Vanstone (GLV). Since P = 1 mod 3, there is a cube root of
+unity beta modulo P, and phi(x, y) = (beta.x, y) is a point of the curve, equal
+to lambda.(x, y) for a cube root of unity lambda modulo N. A scalar k is split
+into k1 + k2.lambda, where k1 and k2 have about 128 bits, so that k.Q is the
+multi-scalar multiplication k1.Q + k2.phi(Q) with half as many doublings. The
+constants are those of libsecp256k1.
+*/
+
+var (
+    // glvBeta is the cube root of unity modulo P of the endomorphism
+    glvBeta = fieldElement{0xC1396C28719501EE, 0x9CF0497512F58995, 0x6E64479EAC3434E9, 0x7AE96A2B657C0710}
+    // glvLambda is the cube root of unity modulo N such that phi(Q) = lambda.Q
+    glvLambda = Scalar{[4]uint64{0xDF02967C1B23BD72, 0x122E22EA20816678, 0xA5261C028812645A, 0x5363AD4CC05C30E0}}
+    // glvG1 and glvG2 are round(2^384.b2 / N) and round(-2^384.b1 / N), for the
+    // short basis (a1, b1), (a2, b2) of the lattice of the pairs (k1, k2) such
+    // that k1 + k2.lambda = 0 mod N
+    glvG1 = [4]uint64{0xE893209A45DBB031, 0x3DAA8A1471E8CA7F, 0xE86C90E49284EB15, 0x3086D221A7D46BCD}
+    glvG2 = [4]uint64{0x1571B4AE8AC47F71, 0x221208AC9DF506C6, 0x6F547FA90ABFE4C4, 0xE4437ED6010E8828}
+    // glvMinusB1 is -b1 and glvMinusB2 is -b2 = -a1 mod N
+    glvMinusB1 = Scalar{[4]uint64{0x6F547FA90ABFE4C3, 0xE4437ED6010E8828, 0, 0}}
+    glvMinusB2 = Scalar{[4]uint64{0xD765CDA83DB1562C, 0x8A280AC50774346D, 0xFFFFFFFFFFFFFFFE, 0xFFFFFFFFFFFFFFFF}}
+)
+
+/*
+splitLambda returns k1 and k2 such that k = k1 + k2.lambda mod N, where k1 and
+k2, or their negations, have at most 129 bits. The closest vector of the lattice
+is found with the rounding c1 = round(k.b2 / N) and c2 = round(-k.b1 / N), then
+k2 = -(c1.b1 + c2.b2) and k1 = k - k2.lambda.
+*/
+func (k *Scalar) splitLambda() (k1, k2 Scalar) {
+    var c1, c2 Scalar
+    c1.l = mulShift384(&k.l, &glvG1)
+    c2.l = mulShift384(&k.l, &glvG2)
+    c1.Mul(&c1, &glvMinusB1)
+    c2.Mul(&c2, &glvMinusB2)
+    k2.Add(&c1, &c2)
+    k1.Mul(&k2, &glvLambda)
+    k1.Sub(k, &k1)
+    return k1, k2
+}
+
+/*
+mulShift384 returns round(x.y / 2^384), for x and y lower than 2^256.
+*/
+func mulShift384(x, y *[4]uint64) [4]uint64 {
+    t := mulWide(x, y)
+    var c uint64
+    var r [4]uint64
+    // add the bit 383 to round to the nearest integer
+    r[0], c = arith.Add64(t[6], t[5]>>63, 0)
+    r[1], _ = arith.Add64(t[7], 0, c)
+    return r
+}
+
+/*
+endomorphism sets p = phi(a) = (beta.x, y), which is lambda.a.
+*/
+func (p *affinePoint) endomorphism(a *affinePoint) *affinePoint {
+    p.x.mul(&a.x, &glvBeta)
+    p.y = a.y
+    p.infinity = a.infinity
+    return p
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "math/big"
+    "testing"
+)
+
+func TestEndomorphism(t *testing.T) {
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    P := new(Secp256k1Point).ScalarBaseMult(k)
+    var a, phi affinePoint
+    phi.endomorphism(a.setPoint(P))
+    if !phi.isOnCurve() {
+        t.Errorf("Assert failure: phi(P) is not on the curve")
+    }
+    expected := naiveMultiScalarMult([]*Secp256k1Point{P}, []*big.Int{glvLambda.Big()})
+    assertSamePoint(t, expected, phi.toPoint())
+}
+
+func TestSplitLambda(t *testing.T) {
+    nm1 := new(big.Int).Sub(CURVE.N, big.NewInt(1))
+    values := []*big.Int{big.NewInt(0), big.NewInt(1), nm1, new(big.Int).Rsh(CURVE.N, 1), glvLambda.Big()}
+    for i := 0; i < 256; i++ {
+        v, _ := rand.Int(rand.Reader, CURVE.N)
+        values = append(values, v)
+    }
+    for _, v := range values {
+        var k, sum Scalar
+        k.SetBig(v)
+        k1, k2 := k.splitLambda()
+        sum.Mul(&k2, &glvLambda)
+        sum.Add(&sum, &k1)
+        if !sum.Equal(&k) {
+            t.Errorf("Assert failure: k1 + k2.lambda is not %s", v)
+        }
+        for _, half := range []Scalar{k1, k2} {
+            if half.isHigh() {
+                half.Neg(&half)
+            }
+            if half.bitLen() > 129 {
+                t.Errorf("Assert failure: %s is split into a half of %d bits", v, half.bitLen())
+            }
+        }
+    }
+}
+
+func BenchmarkScalarMult(b *testing.B) {
+    points, scalars := randomTerms(1)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _ = new(Secp256k1Point).ScalarMult(points[0], scalars[0])
+    }
+}
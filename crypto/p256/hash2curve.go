@@ -0,0 +1,268 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/sha256"
+    "errors"
+    "hash"
+    "math/big"
+)
+
+/*
+This file contains the hash-to-curve suite secp256k1_XMD:SHA-256_SSWU_RO_ of
+RFC 9380: Hashing to Elliptic Curves
+https://www.rfc-editor.org/rfc/rfc9380.html
+The message is hashed to two field elements, which are mapped with the
+simplified SWU map to the 3-isogenous curve E' and then to secp256k1.
+*/
+
+// Parameters of the suite, Section 8.7 of RFC 9380.
+var (
+    // isoA and isoB are the coefficients of E': y^2 = x^3 + A'.x + B'
+    isoA = fromHex("3f8731abdd661adca08a5558f0f5d272e953d363cb6f0e5d405447c01a444533")
+    isoB = big.NewInt(1771)
+    // sswuZ is the constant Z = -11
+    sswuZ = fromHex("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc24")
+
+    // Constants of the 3-isogeny map from E' to secp256k1, Appendix E.1.
+    isoXNum = []*big.Int{
+        fromHex("8e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38daaaaa8c7"),
+        fromHex("07d3d4c80bc321d5b9f315cea7fd44c5d595d2fc0bf63b92dfff1044f17c6581"),
+        fromHex("534c328d23f234e6e2a413deca25caece4506144037c40314ecbd0b53d9dd262"),
+        fromHex("8e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38daaaaa88c"),
+    }
+    isoXDen = []*big.Int{
+        fromHex("d35771193d94918a9ca34ccbb7b640dd86cd409542f8487d9fe6b745781eb49b"),
+        fromHex("edadc6f64383dc1df7c4b2d51b54225406d36b641f5e41bbc52a56612a8c6d14"),
+        big.NewInt(1),
+    }
+    isoYNum = []*big.Int{
+        fromHex("4bda12f684bda12f684bda12f684bda12f684bda12f684bda12f684b8e38e23c"),
+        fromHex("c75e0c32d5cb7c0fa9d0a54b12a0a6d5647ab046d686da6fdffc90fc201d71a3"),
+        fromHex("29a6194691f91a73715209ef6512e576722830a201be2018a765e85a9ecee931"),
+        fromHex("2f684bda12f684bda12f684bda12f684bda12f684bda12f684bda12f38e38d84"),
+    }
+    isoYDen = []*big.Int{
+        fromHex("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffff93b"),
+        fromHex("7a06534bb8bdb49fd5e9e6632722c2989467c1bfc8e8d978dfb425d2685c2573"),
+        fromHex("6484aa716545ca2cf3a70c3fa8fe337e0a3d21162f0d6299a7bf8192bfd2a76f"),
+        big.NewInt(1),
+    }
+)
+
+const (
+    // hashToFieldLength is L = ceil((ceil(log2(p)) + k) / 8), with k = 128
+    hashToFieldLength = 48
+    // maxDSTLength is the largest DST used as it is by expand_message_xmd
+    maxDSTLength = 255
+)
+
+/*
+HashToCurve hashes the message msg to a point of secp256k1, using the suite
+secp256k1_XMD:SHA-256_SSWU_RO_ of RFC 9380 with the domain separation tag dst.
+Different tags give independent hash functions, so every application or tenant
+should use its own tag. The tag must not be empty.
+*/
+func HashToCurve(msg, dst []byte) (*Secp256k1Point, error) {
+    u, err := hashToField(msg, dst, 2, CURVE.P)
+    if err != nil {
+        return nil, err
+    }
+    var q0, q1, sum jacobianPoint
+    q0.setAffine(new(affinePoint).setPoint(mapToCurve(u[0])))
+    q1.setAffine(new(affinePoint).setPoint(mapToCurve(u[1])))
+    // the cofactor of secp256k1 is 1
+    return sum.add(&q0, &q1).toAffine().toPoint(), nil
+}
+
+/*
+hashToField hashes msg to count elements of the base field of prime p, Section
+5.2. The length L is the same for secp256k1 and NIST P-256.
+*/
+func hashToField(msg, dst []byte, count int, p *big.Int) ([]*big.Int, error) {
+    uniform, err := ExpandMessageXMD(msg, dst, count*hashToFieldLength)
+    if err != nil {
+        return nil, err
+    }
+    u := make([]*big.Int, count)
+    for i := 0; i < count; i++ {
+        u[i] = new(big.Int).SetBytes(uniform[i*hashToFieldLength : (i+1)*hashToFieldLength])
+        u[i].Mod(u[i], p)
+    }
+    return u, nil
+}
+
+/*
+ExpandMessageXMD is expand_message_xmd of Section 5.3.1 with SHA-256.
+*/
+func ExpandMessageXMD(msg, dst []byte, length int) ([]byte, error) {
+    return ExpandMessageXMDWithHash(sha256.New, msg, dst, length)
+}
+
+/*
+ExpandMessageXMDWithHash is expand_message_xmd of Section 5.3.1 with the hash
+function newHash, for instance sha512.New for the suites of Ristretto255.
+*/
+func ExpandMessageXMDWithHash(newHash func() hash.Hash, msg, dst []byte, length int) ([]byte, error) {
+    if len(dst) == 0 {
+        return nil, errors.New("domain separation tag must not be empty")
+    }
+    if len(dst) > maxDSTLength {
+        // Section 5.3.3
+        h := newHash()
+        h.Write([]byte("H2C-OVERSIZE-DST-"))
+        h.Write(dst)
+        dst = h.Sum(nil)
+    }
+    h := newHash()
+    ell := (length + h.Size() - 1) / h.Size()
+    if ell > 255 || length > 65535 {
+        return nil, errors.New("requested length is too large")
+    }
+    dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+    // b_0 = H(Z_pad || msg || l_i_b_str || I2OSP(0, 1) || DST_prime)
+    h.Write(make([]byte, h.BlockSize()))
+    h.Write(msg)
+    h.Write([]byte{byte(length >> 8), byte(length), 0})
+    h.Write(dstPrime)
+    b0 := h.Sum(nil)
+
+    // b_1 = H(b_0 || I2OSP(1, 1) || DST_prime)
+    h.Reset()
+    h.Write(b0)
+    h.Write([]byte{1})
+    h.Write(dstPrime)
+    bi := h.Sum(nil)
+    uniform := append([]byte{}, bi...)
+
+    // b_i = H(strxor(b_0, b_(i - 1)) || I2OSP(i, 1) || DST_prime)
+    for i := 2; i <= ell; i++ {
+        xored := make([]byte, len(b0))
+        for j := range xored {
+            xored[j] = b0[j] ^ bi[j]
+        }
+        h.Reset()
+        h.Write(xored)
+        h.Write([]byte{byte(i)})
+        h.Write(dstPrime)
+        bi = h.Sum(nil)
+        uniform = append(uniform, bi...)
+    }
+    return uniform[:length], nil
+}
+
+/*
+mapToCurve maps the field element u to secp256k1, using the simplified SWU map
+of Section 6.6.2 to E' and the 3-isogeny of Section 6.6.3.
+*/
+func mapToCurve(u *big.Int) *Secp256k1Point {
+    x, y := mapToIsogenous(u)
+    return isogenyMap(x, y)
+}
+
+/*
+mapToIsogenous is the simplified SWU map to E', Section 6.6.2.
+*/
+func mapToIsogenous(u *big.Int) (*big.Int, *big.Int) {
+    p := CURVE.P
+    // tv1 = inv0(Z^2 * u^4 + Z * u^2)
+    zu2 := new(big.Int).Mul(u, u)
+    zu2.Mul(zu2, sswuZ).Mod(zu2, p)
+    tv1 := new(big.Int).Mul(zu2, zu2)
+    tv1.Add(tv1, zu2).Mod(tv1, p)
+    if tv1.Sign() != 0 {
+        tv1.ModInverse(tv1, p)
+    }
+
+    // x1 = (-B / A) * (1 + tv1), or B / (Z * A) if tv1 = 0
+    x1 := new(big.Int)
+    if tv1.Sign() == 0 {
+        x1.Mul(sswuZ, isoA)
+        x1.ModInverse(x1.Mod(x1, p), p)
+        x1.Mul(x1, isoB)
+    } else {
+        x1.ModInverse(isoA, p)
+        x1.Mul(x1, isoB).Neg(x1)
+        x1.Mul(x1, new(big.Int).Add(tv1, big.NewInt(1)))
+    }
+    x1.Mod(x1, p)
+
+    x := x1
+    y := new(big.Int).ModSqrt(isogenousF(x1), p)
+    if y == nil {
+        // x2 = Z * u^2 * x1, then g(x2) is a square
+        x = new(big.Int).Mul(zu2, x1)
+        x.Mod(x, p)
+        y = new(big.Int).ModSqrt(isogenousF(x), p)
+    }
+    if u.Bit(0) != y.Bit(0) {
+        y.Sub(p, y).Mod(y, p)
+    }
+    return x, y
+}
+
+/*
+isogenousF returns x^3 + A'.x + B' mod P.
+*/
+func isogenousF(x *big.Int) *big.Int {
+    p := CURVE.P
+    gx := new(big.Int).Mul(x, x)
+    gx.Add(gx, isoA).Mod(gx, p)
+    gx.Mul(gx, x).Add(gx, isoB)
+    return gx.Mod(gx, p)
+}
+
+/*
+isogenyMap maps the point (x, y) of E' to secp256k1:
+x = x_num / x_den, y = y * y_num / y_den.
+*/
+func isogenyMap(x, y *big.Int) *Secp256k1Point {
+    p := CURVE.P
+    xNum := evaluatePolynomial(isoXNum, x)
+    xDen := evaluatePolynomial(isoXDen, x)
+    yNum := evaluatePolynomial(isoYNum, x)
+    yDen := evaluatePolynomial(isoYDen, x)
+    if xDen.Sign() == 0 || yDen.Sign() == 0 {
+        // the exceptional points are mapped to the identity
+        return new(Secp256k1Point).SetInfinity()
+    }
+    rx := xNum.Mul(xNum, xDen.ModInverse(xDen, p))
+    ry := yNum.Mul(yNum, yDen.ModInverse(yDen, p))
+    ry.Mul(ry, y)
+    return &Secp256k1Point{X: rx.Mod(rx, p), Y: ry.Mod(ry, p)}
+}
+
+/*
+evaluatePolynomial returns sum_i k_i.x^i mod P, using Horner's rule.
+*/
+func evaluatePolynomial(k []*big.Int, x *big.Int) *big.Int {
+    result := new(big.Int)
+    for i := len(k) - 1; i >= 0; i-- {
+        result.Mul(result, x)
+        result.Add(result, k[i])
+        result.Mod(result, CURVE.P)
+    }
+    return result
+}
+
+func fromHex(s string) *big.Int {
+    result, _ := new(big.Int).SetString(s, 16)
+    return result
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/sha512"
+    "encoding/hex"
+    "math/big"
+    "testing"
+)
+
+/*
+Test vectors of RFC 9380, Section K.1: expand_message_xmd with SHA-256.
+*/
+func TestExpandMessageXMD(t *testing.T) {
+    dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+    vectors := []struct {
+        msg      string
+        length   int
+        expected string
+    }{
+        {"", 0x20, "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235"},
+    }
+    for _, vector := range vectors {
+        actual, err := ExpandMessageXMD([]byte(vector.msg), dst, vector.length)
+        if err != nil || hex.EncodeToString(actual) != vector.expected {
+            t.Errorf("Assert failure: msg %q expected %s, actual: %x", vector.msg, vector.expected, actual)
+        }
+    }
+}
+
+/*
+Test vectors of RFC 9380, Section K.2: expand_message_xmd with SHA-512.
+*/
+func TestExpandMessageXMDSHA512(t *testing.T) {
+    dst := []byte("QUUX-V01-CS02-with-expander-SHA512-256")
+    vectors := []struct {
+        msg      string
+        length   int
+        expected string
+    }{
+        {"", 0x20, "6b9a7312411d92f921c6f68ca0b6380730a1a4d982c507211a90964c394179ba"},
+        {"abc", 0x20, "0da749f12fbe5483eb066a5f595055679b976e93abe9be6f0f6318bce7aca8dc"},
+    }
+    for _, vector := range vectors {
+        actual, err := ExpandMessageXMDWithHash(sha512.New, []byte(vector.msg), dst, vector.length)
+        if err != nil || hex.EncodeToString(actual) != vector.expected {
+            t.Errorf("Assert failure: msg %q expected %s, actual: %x", vector.msg, vector.expected, actual)
+        }
+    }
+}
+
+/*
+Test vectors of RFC 9380, Section J.8.1: secp256k1_XMD:SHA-256_SSWU_RO_.
+*/
+func TestHashToCurve(t *testing.T) {
+    dst := []byte("QUUX-V01-CS02-with-secp256k1_XMD:SHA-256_SSWU_RO_")
+    vectors := []struct {
+        msg  string
+        x, y string
+    }{
+        {"", "c1cae290e291aee617ebaef1be6d73861479c48b841eaba9b7b5852ddfeb1346", "64fa678e07ae116126f08b022a94af6de15985c996c3a91b64c406a960e51067"},
+        {"abc", "3377e01eab42db296b512293120c6cee72b6ecf9f9205760bd9ff11fb3cb2c4b", "7f95890f33efebd1044d382a01b1bee0900fb6116f94688d487c6c7b9c8371f6"},
+    }
+    for _, vector := range vectors {
+        p, err := HashToCurve([]byte(vector.msg), dst)
+        if err != nil {
+            t.Fatalf("Could not hash to curve: %s", err)
+        }
+        if p.X.Cmp(fromHex(vector.x)) != 0 || p.Y.Cmp(fromHex(vector.y)) != 0 {
+            t.Errorf("Assert failure: msg %q expected (%s, %s), actual: (%x, %x)", vector.msg, vector.x, vector.y, p.X, p.Y)
+        }
+    }
+}
+
+func TestMapToCurveOnCurve(t *testing.T) {
+    for i := int64(0); i < 50; i++ {
+        p := mapToCurve(big.NewInt(i))
+        if !p.IsOnCurve() {
+            t.Errorf("Assert failure: map of %d is not on the curve", i)
+        }
+    }
+    _, err := HashToCurve([]byte("msg"), nil)
+    if err == nil {
+        t.Errorf("Assert failure: empty domain separation tag should be rejected")
+    }
+}
@@ -0,0 +1,329 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+/*
+affinePoint is the elliptic curve point (x, y) with coordinates in fieldElement.
+*/
+type affinePoint struct {
+    x, y     fieldElement
+    infinity bool
+}
+
+/*
+jacobianPoint represents the elliptic curve point (x/z^2, y/z^3). The point at
+infinity is represented by z = 0. It avoids a modular inversion on every
+addition, which is only computed when converting back to affine coordinates.
+*/
+type jacobianPoint struct {
+    x, y, z fieldElement
+}
+
+/*
+setPoint sets p to the point a.
+*/
+func (p *affinePoint) setPoint(a *Secp256k1Point) *affinePoint {
+    if a.IsZero() {
+        p.infinity = true
+        return p
+    }
+    p.x.setBig(a.X)
+    p.y.setBig(a.Y)
+    p.infinity = false
+    return p
+}
+
+/*
+toPoint returns p as a Secp256k1Point.
+*/
+func (p *affinePoint) toPoint() *Secp256k1Point {
+    if p.infinity {
+        return new(Secp256k1Point).SetInfinity()
+    }
+    return &Secp256k1Point{X: p.x.big(), Y: p.y.big()}
+}
+
+/*
+isOnCurve returns true if and only if p is the point at infinity or satisfies
+y^2 = x^3 + 7.
+*/
+func (p *affinePoint) isOnCurve() bool {
+    if p.infinity {
+        return true
+    }
+    var y2, fx fieldElement
+    return *y2.square(&p.y) == *curveF(&fx, &p.x)
+}
+
+/*
+neg sets p to -a.
+*/
+func (p *affinePoint) neg(a *affinePoint) *affinePoint {
+    p.x = a.x
+    p.y.neg(&a.y)
+    p.infinity = a.infinity
+    return p
+}
+
+/*
+setInfinity sets p to the point at infinity.
+*/
+func (p *jacobianPoint) setInfinity() *jacobianPoint {
+    *p = jacobianPoint{}
+    p.x[0] = 1
+    p.y[0] = 1
+    return p
+}
+
+/*
+setAffine sets p to the affine point a.
+*/
+func (p *jacobianPoint) setAffine(a *affinePoint) *jacobianPoint {
+    if a.infinity {
+        return p.setInfinity()
+    }
+    p.x = a.x
+    p.y = a.y
+    p.z = fieldElement{1, 0, 0, 0}
+    return p
+}
+
+/*
+isInfinity returns true if and only if p is the point at infinity.
+*/
+func (p *jacobianPoint) isInfinity() bool {
+    return p.z.isZero()
+}
+
+/*
+toAffine returns the affine representation of p.
+*/
+func (p *jacobianPoint) toAffine() *affinePoint {
+    a := new(affinePoint)
+    if p.isInfinity() {
+        a.infinity = true
+        return a
+    }
+    var zinv, zinv2 fieldElement
+    zinv.invert(&p.z)
+    zinv2.square(&zinv)
+    a.x.mul(&p.x, &zinv2)
+    zinv2.mul(&zinv2, &zinv)
+    a.y.mul(&p.y, &zinv2)
+    return a
+}
+
+/*
+double sets p to 2a, using the formulas dbl-2009-l for curves with a = 0:
+http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#doubling-dbl-2009-l
+*/
+func (p *jacobianPoint) double(a *jacobianPoint) *jacobianPoint {
+    if a.isInfinity() || a.y.isZero() {
+        return p.setInfinity()
+    }
+    var A, B, C, D, E, F, t, x3, y3, z3 fieldElement
+    A.square(&a.x)
+    B.square(&a.y)
+    C.square(&B)
+    // D = 2.((X1 + B)^2 - A - C)
+    t.add(&a.x, &B)
+    D.square(&t)
+    D.sub(&D, &A)
+    D.sub(&D, &C)
+    D.add(&D, &D)
+    // E = 3.A, F = E^2
+    E.add(&A, &A)
+    E.add(&E, &A)
+    F.square(&E)
+    // X3 = F - 2.D
+    t.add(&D, &D)
+    x3.sub(&F, &t)
+    // Y3 = E.(D - X3) - 8.C
+    t.sub(&D, &x3)
+    y3.mul(&E, &t)
+    C.add(&C, &C)
+    C.add(&C, &C)
+    C.add(&C, &C)
+    y3.sub(&y3, &C)
+    // Z3 = 2.Y1.Z1
+    z3.mul(&a.y, &a.z)
+    z3.add(&z3, &z3)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+add sets p to a + b, using the formulas add-2007-bl:
+http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-add-2007-bl
+*/
+func (p *jacobianPoint) add(a, b *jacobianPoint) *jacobianPoint {
+    if a.isInfinity() {
+        *p = *b
+        return p
+    }
+    if b.isInfinity() {
+        *p = *a
+        return p
+    }
+    var z1z1, z2z2, u1, u2, s1, s2, h, r, i, j, v, t, x3, y3, z3 fieldElement
+    z1z1.square(&a.z)
+    z2z2.square(&b.z)
+    u1.mul(&a.x, &z2z2)
+    u2.mul(&b.x, &z1z1)
+    s1.mul(&a.y, &b.z)
+    s1.mul(&s1, &z2z2)
+    s2.mul(&b.y, &a.z)
+    s2.mul(&s2, &z1z1)
+    h.sub(&u2, &u1)
+    r.sub(&s2, &s1)
+    if h.isZero() {
+        if r.isZero() {
+            return p.double(a)
+        }
+        return p.setInfinity()
+    }
+    // I = (2.H)^2, J = H.I, r = 2.(S2 - S1), V = U1.I
+    i.add(&h, &h)
+    i.square(&i)
+    j.mul(&h, &i)
+    r.add(&r, &r)
+    v.mul(&u1, &i)
+    // X3 = r^2 - J - 2.V
+    x3.square(&r)
+    x3.sub(&x3, &j)
+    x3.sub(&x3, &v)
+    x3.sub(&x3, &v)
+    // Y3 = r.(V - X3) - 2.S1.J
+    t.sub(&v, &x3)
+    y3.mul(&r, &t)
+    t.mul(&s1, &j)
+    y3.sub(&y3, &t)
+    y3.sub(&y3, &t)
+    // Z3 = ((Z1 + Z2)^2 - Z1Z1 - Z2Z2).H
+    t.add(&a.z, &b.z)
+    z3.square(&t)
+    z3.sub(&z3, &z1z1)
+    z3.sub(&z3, &z2z2)
+    z3.mul(&z3, &h)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+equalAffine returns true if and only if p and a are the same point, that is
+X = x.Z^2 and Y = y.Z^3.
+*/
+func (p *jacobianPoint) equalAffine(a *affinePoint) bool {
+    if p.isInfinity() || a.infinity {
+        return p.isInfinity() == a.infinity
+    }
+    var zz, t fieldElement
+    zz.square(&p.z)
+    if *t.mul(&a.x, &zz) != p.x {
+        return false
+    }
+    zz.mul(&zz, &p.z)
+    return *t.mul(&a.y, &zz) == p.y
+}
+
+/*
+addMixed sets p to a + b, where b is given in affine coordinates, using the
+formulas madd-2007-bl:
+http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-madd-2007-bl
+*/
+func (p *jacobianPoint) addMixed(a *jacobianPoint, b *affinePoint) *jacobianPoint {
+    if b.infinity {
+        *p = *a
+        return p
+    }
+    if a.isInfinity() {
+        return p.setAffine(b)
+    }
+    var z1z1, u2, s2, h, hh, r, i, j, v, t, x3, y3, z3 fieldElement
+    z1z1.square(&a.z)
+    u2.mul(&b.x, &z1z1)
+    s2.mul(&b.y, &a.z)
+    s2.mul(&s2, &z1z1)
+    h.sub(&u2, &a.x)
+    r.sub(&s2, &a.y)
+    if h.isZero() {
+        if r.isZero() {
+            return p.double(a)
+        }
+        return p.setInfinity()
+    }
+    // HH = H^2, I = 4.HH, J = H.I, r = 2.(S2 - Y1), V = X1.I
+    hh.square(&h)
+    i.add(&hh, &hh)
+    i.add(&i, &i)
+    j.mul(&h, &i)
+    r.add(&r, &r)
+    v.mul(&a.x, &i)
+    // X3 = r^2 - J - 2.V
+    x3.square(&r)
+    x3.sub(&x3, &j)
+    x3.sub(&x3, &v)
+    x3.sub(&x3, &v)
+    // Y3 = r.(V - X3) - 2.Y1.J
+    t.sub(&v, &x3)
+    y3.mul(&r, &t)
+    t.mul(&a.y, &j)
+    y3.sub(&y3, &t)
+    y3.sub(&y3, &t)
+    // Z3 = (Z1 + H)^2 - Z1Z1 - HH
+    t.add(&a.z, &h)
+    z3.square(&t)
+    z3.sub(&z3, &z1z1)
+    z3.sub(&z3, &hh)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+batchToAffine converts every point to affine coordinates using a single modular
+inversion (Montgomery's trick).
+*/
+func batchToAffine(points []jacobianPoint) []affinePoint {
+    n := len(points)
+    result := make([]affinePoint, n)
+    // prefix[i] is the product of the z coordinates of the first i finite points
+    prefix := make([]fieldElement, n+1)
+    prefix[0] = fieldElement{1, 0, 0, 0}
+    for i := 0; i < n; i++ {
+        prefix[i+1] = prefix[i]
+        if !points[i].isInfinity() {
+            prefix[i+1].mul(&prefix[i], &points[i].z)
+        }
+    }
+    var inv, zinv, zinv2 fieldElement
+    inv.invert(&prefix[n])
+    for i := n - 1; i >= 0; i-- {
+        if points[i].isInfinity() {
+            result[i].infinity = true
+            continue
+        }
+        // 1/z_i = inv . prefix[i], then inv becomes the inverse of prefix[i]
+        zinv.mul(&inv, &prefix[i])
+        inv.mul(&inv, &points[i].z)
+        zinv2.square(&zinv)
+        result[i].x.mul(&points[i].x, &zinv2)
+        zinv2.mul(&zinv2, &zinv)
+        result[i].y.mul(&points[i].y, &zinv2)
+    }
+    return result
+}
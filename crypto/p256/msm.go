@@ -0,0 +1,228 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "errors"
+    "math/big"
+)
+
+const (
+    // strausWindow is the bit-length of the signed digits used by Straus
+    strausWindow = 4
+    // pippengerThreshold is the number of terms, after the split of every scalar by
+    // the endomorphism, from which Pippenger is faster than Straus
+    pippengerThreshold = 64
+)
+
+/*
+MultiScalarMult sets p to the sum of scalars[i].points[i], for every i. It uses
+the interleaved window method of Straus for few terms and the bucket method of
+Pippenger for many terms, after splitting every scalar into two halves with the
+GLV endomorphism. The scalars are not secret: the running time depends
+on their values.
+*/
+func (p *Secp256k1Point) MultiScalarMult(points []*Secp256k1Point, scalars []*big.Int) (*Secp256k1Point, error) {
+    if len(points) != len(scalars) {
+        return nil, errors.New("number of points is different from the number of scalars")
+    }
+    affine := make([]affinePoint, len(points))
+    for i := range points {
+        if points[i] == nil {
+            return nil, errors.New("points and scalars must be defined")
+        }
+        affine[i].setPoint(points[i])
+    }
+    result, err := multiScalarMult(affine, scalars)
+    if err != nil {
+        return nil, err
+    }
+    r := result.toAffine().toPoint()
+    p.X = r.X
+    p.Y = r.Y
+    return p, nil
+}
+
+/*
+multiScalarMult returns the sum of scalars[i].points[i] in Jacobian coordinates.
+Every term k.P is split with the endomorphism into k1.P + k2.phi(P), where k1 and
+k2 have about 128 bits, which halves the number of doublings.
+*/
+func multiScalarMult(points []affinePoint, scalars []*big.Int) (*jacobianPoint, error) {
+    ps := make([]affinePoint, 0, 2*len(points))
+    ks := make([]Scalar, 0, 2*len(points))
+    bits := 0
+    // Drop the terms that are zero and use (-k).(-P) whenever -k is shorter than k
+    appendTerm := func(point affinePoint, k Scalar) {
+        if k.IsZero() {
+            return
+        }
+        if k.isHigh() {
+            k.Neg(&k)
+            point.neg(&point)
+        }
+        if k.bitLen() > bits {
+            bits = k.bitLen()
+        }
+        ps = append(ps, point)
+        ks = append(ks, k)
+    }
+    for i := range points {
+        if scalars[i] == nil {
+            return nil, errors.New("points and scalars must be defined")
+        }
+        if points[i].infinity {
+            continue
+        }
+        var k Scalar
+        var phi affinePoint
+        k1, k2 := k.SetBig(scalars[i]).splitLambda()
+        appendTerm(points[i], k1)
+        appendTerm(*phi.endomorphism(&points[i]), k2)
+    }
+
+    switch {
+    case len(ps) == 0:
+        return new(jacobianPoint).setInfinity(), nil
+    case len(ps) < pippengerThreshold:
+        return straus(ps, ks, bits), nil
+    default:
+        return pippenger(ps, ks, bits), nil
+    }
+}
+
+/*
+signedDigits returns the digits of k in base 2^c, least significant first, such
+that every digit lies in [-2^(c-1), 2^(c-1)). The number of digits is enough for
+any scalar of the given bit-length, including the final carry.
+*/
+func signedDigits(k *Scalar, c uint, bits int) []int {
+    windows := (bits+int(c)-1)/int(c) + 1
+    digits := make([]int, windows)
+    radix := 1 << c
+    carry := 0
+    for w := 0; w < windows; w++ {
+        d := carry
+        for b := uint(0); b < c; b++ {
+            d += int(k.bit(w*int(c)+int(b))) << b
+        }
+        carry = 0
+        if d >= radix/2 {
+            d -= radix
+            carry = 1
+        }
+        digits[w] = d
+    }
+    return digits
+}
+
+/*
+straus computes the multi-scalar multiplication using signed digits of
+strausWindow bits and a table with the multiples 1.P to 2^(strausWindow-1).P of
+each point, which is converted to affine coordinates in order to use mixed additions.
+*/
+func straus(points []affinePoint, scalars []Scalar, bits int) *jacobianPoint {
+    n := len(points)
+    size := 1 << (strausWindow - 1)
+    multiples := make([]jacobianPoint, n*size)
+    digits := make([][]int, n)
+    for i := 0; i < n; i++ {
+        multiples[i*size].setAffine(&points[i])
+        for j := 1; j < size; j++ {
+            multiples[i*size+j].addMixed(&multiples[i*size+j-1], &points[i])
+        }
+        digits[i] = signedDigits(&scalars[i], strausWindow, bits)
+    }
+    table := batchToAffine(multiples)
+
+    var negated affinePoint
+    result := new(jacobianPoint).setInfinity()
+    for w := len(digits[0]) - 1; w >= 0; w-- {
+        for j := 0; j < strausWindow && !result.isInfinity(); j++ {
+            result.double(result)
+        }
+        for i := 0; i < n; i++ {
+            d := digits[i][w]
+            if d > 0 {
+                result.addMixed(result, &table[i*size+d-1])
+            } else if d < 0 {
+                result.addMixed(result, negated.neg(&table[i*size-d-1]))
+            }
+        }
+    }
+    return result
+}
+
+/*
+pippengerWindow returns the bit-length c of the digits that minimizes the
+number of additions of Pippenger, namely (bits/c).(n + 2^c).
+*/
+func pippengerWindow(n, bits int) uint {
+    best, bestCost := uint(2), -1
+    for c := uint(2); c <= 16; c++ {
+        cost := ((bits+int(c)-1)/int(c) + 1) * (n + (1 << c))
+        if bestCost < 0 || cost < bestCost {
+            best, bestCost = c, cost
+        }
+    }
+    return best
+}
+
+/*
+pippenger computes the multi-scalar multiplication using the bucket method. For
+each window of c bits, every point is added to the bucket of its digit, and the
+buckets are combined with a running sum, so that the j-th bucket is counted j times.
+*/
+func pippenger(points []affinePoint, scalars []Scalar, bits int) *jacobianPoint {
+    n := len(points)
+    c := pippengerWindow(n, bits)
+    digits := make([][]int, n)
+    negated := make([]affinePoint, n)
+    for i := 0; i < n; i++ {
+        digits[i] = signedDigits(&scalars[i], c, bits)
+        negated[i].neg(&points[i])
+    }
+    buckets := make([]jacobianPoint, 1<<(c-1))
+    var sum, acc jacobianPoint
+
+    result := new(jacobianPoint).setInfinity()
+    for w := len(digits[0]) - 1; w >= 0; w-- {
+        for j := uint(0); j < c && !result.isInfinity(); j++ {
+            result.double(result)
+        }
+        for b := range buckets {
+            buckets[b].setInfinity()
+        }
+        for i := 0; i < n; i++ {
+            d := digits[i][w]
+            if d > 0 {
+                buckets[d-1].addMixed(&buckets[d-1], &points[i])
+            } else if d < 0 {
+                buckets[-d-1].addMixed(&buckets[-d-1], &negated[i])
+            }
+        }
+        sum.setInfinity()
+        acc.setInfinity()
+        for b := len(buckets) - 1; b >= 0; b-- {
+            sum.add(&sum, &buckets[b])
+            acc.add(&acc, &sum)
+        }
+        result.add(result, &acc)
+    }
+    return result
+}
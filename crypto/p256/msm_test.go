@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "fmt"
+    "math/big"
+    "testing"
+)
+
+func randomTerms(n int) ([]*Secp256k1Point, []*big.Int) {
+    points := make([]*Secp256k1Point, n)
+    scalars := make([]*big.Int, n)
+    for i := 0; i < n; i++ {
+        k, _ := rand.Int(rand.Reader, CURVE.N)
+        points[i] = new(Secp256k1Point).ScalarBaseMult(k)
+        scalars[i], _ = rand.Int(rand.Reader, CURVE.N)
+    }
+    return points, scalars
+}
+
+func naiveMultiScalarMult(points []*Secp256k1Point, scalars []*big.Int) *Secp256k1Point {
+    result := new(Secp256k1Point).SetInfinity()
+    for i := range points {
+        result.Multiply(result, new(Secp256k1Point).ScalarMult(points[i], scalars[i]))
+    }
+    return result
+}
+
+func assertSamePoint(t *testing.T, expected, actual *Secp256k1Point) {
+    if expected.IsZero() != actual.IsZero() {
+        t.Fatalf("Assert failure: expected %s, actual: %s", expected, actual)
+    }
+    if !expected.IsZero() && (expected.X.Cmp(actual.X) != 0 || expected.Y.Cmp(actual.Y) != 0) {
+        t.Errorf("Assert failure: expected %s, actual: %s", expected, actual)
+    }
+}
+
+func TestMultiScalarMult(t *testing.T) {
+    for _, n := range []int{0, 1, 2, 7, pippengerThreshold - 1, pippengerThreshold, 130} {
+        points, scalars := randomTerms(n)
+        actual, err := new(Secp256k1Point).MultiScalarMult(points, scalars)
+        if err != nil {
+            t.Fatalf("Unexpected error: %s", err)
+        }
+        assertSamePoint(t, naiveMultiScalarMult(points, scalars), actual)
+    }
+}
+
+func TestMultiScalarMultEdgeCases(t *testing.T) {
+    for _, n := range []int{8, pippengerThreshold + 8} {
+        points, scalars := randomTerms(n)
+        // small, negative and unreduced scalars
+        scalars[0] = big.NewInt(1)
+        scalars[1] = big.NewInt(-1)
+        scalars[2] = new(big.Int).Sub(CURVE.N, big.NewInt(1))
+        scalars[3] = new(big.Int).Add(CURVE.N, big.NewInt(5))
+        scalars[4] = big.NewInt(0)
+        // the point at infinity, a repeated point and its inverse
+        points[5] = new(Secp256k1Point).SetInfinity()
+        points[6] = points[7]
+        points[1] = points[0]
+        actual, err := new(Secp256k1Point).MultiScalarMult(points, scalars)
+        if err != nil {
+            t.Fatalf("Unexpected error: %s", err)
+        }
+        assertSamePoint(t, naiveMultiScalarMult(points, scalars), actual)
+    }
+
+    // terms that cancel each other
+    points, _ := randomTerms(3)
+    scalars := []*big.Int{big.NewInt(2), big.NewInt(-1), big.NewInt(-1)}
+    points[1] = points[0]
+    points[2] = points[0]
+    actual, _ := new(Secp256k1Point).MultiScalarMult(points, scalars)
+    if !actual.IsZero() {
+        t.Errorf("Assert failure: expected point at infinity, actual: %s", actual)
+    }
+
+    _, err := new(Secp256k1Point).MultiScalarMult(points, scalars[:2])
+    if err == nil {
+        t.Errorf("Assert failure: vectors of different sizes should be rejected")
+    }
+}
+
+func TestSignedDigits(t *testing.T) {
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    for c := uint(2); c <= 8; c++ {
+        digits := signedDigits(new(Scalar).SetBig(k), c, k.BitLen())
+        sum := new(big.Int)
+        for w := len(digits) - 1; w >= 0; w-- {
+            if digits[w] < -(1<<(c-1)) || digits[w] >= 1<<(c-1) {
+                t.Errorf("Assert failure: digit %d out of range for c = %d", digits[w], c)
+            }
+            sum.Lsh(sum, c)
+            sum.Add(sum, big.NewInt(int64(digits[w])))
+        }
+        if sum.Cmp(k) != 0 {
+            t.Errorf("Assert failure: digits of %s sum up to %s for c = %d", k, sum, c)
+        }
+    }
+}
+
+func BenchmarkMultiScalarMult(b *testing.B) {
+    for _, n := range []int{8, 32, 128, 512} {
+        points, scalars := randomTerms(n)
+        b.Run(fmt.Sprintf("msm-%d", n), func(b *testing.B) {
+            for i := 0; i < b.N; i++ {
+                _, _ = new(Secp256k1Point).MultiScalarMult(points, scalars)
+            }
+        })
+        b.Run(fmt.Sprintf("naive-%d", n), func(b *testing.B) {
+            for i := 0; i < b.N; i++ {
+                _ = naiveMultiScalarMult(points, scalars)
+            }
+        })
+    }
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/internal/arith"
+)
+
+/*
+This file contains the scalar multiplications of NIST P-256 by secret scalars,
+in the same way as those of secp256k1 in consttime.go: the field elements are
+the fixed-width residues of internal/arith, the points are in homogeneous
+projective coordinates with the complete formulas for a = -3, the scalars are
+processed by fixed windows of 4 bits and the multiples are read by scanning the
+whole table.
+*/
+
+var (
+    // nistField is the modulus of the arithmetic of the base field of NIST P-256
+    nistField, _ = arith.NewModulus(NistP256P)
+    // nistB is the coefficient b of the curve
+    nistB = *nistField.SetBig(new(arith.Residue), nistCurve.Params().B)
+)
+
+/*
+nistProjectivePoint represents the point (x/z, y/z) of NIST P-256. The point at
+infinity is (0, 1, 0).
+*/
+type nistProjectivePoint struct {
+    x, y, z arith.Residue
+}
+
+/*
+setInfinity sets p to the point at infinity.
+*/
+func (p *nistProjectivePoint) setInfinity() *nistProjectivePoint {
+    *p = nistProjectivePoint{}
+    nistField.One(&p.y)
+    return p
+}
+
+/*
+setAffine sets p to the public point a.
+*/
+func (p *nistProjectivePoint) setAffine(a *NistP256Point) *nistProjectivePoint {
+    if a.IsZero() {
+        return p.setInfinity()
+    }
+    nistField.SetBig(&p.x, a.X)
+    nistField.SetBig(&p.y, a.Y)
+    nistField.One(&p.z)
+    return p
+}
+
+/*
+add sets p = a + b with the complete addition formulas of Algorithm 4 of Renes,
+Costello and Batina, "Complete addition formulas for prime order elliptic
+curves", for curves with a = -3.
+*/
+func (p *nistProjectivePoint) add(a, b *nistProjectivePoint) *nistProjectivePoint {
+    f := nistField
+    var t0, t1, t2, t3, t4, x3, y3, z3 arith.Residue
+    f.Mul(&t0, &a.x, &b.x)
+    f.Mul(&t1, &a.y, &b.y)
+    f.Mul(&t2, &a.z, &b.z)
+    f.Add(&t3, &a.x, &a.y)
+    f.Add(&t4, &b.x, &b.y)
+    f.Mul(&t3, &t3, &t4)
+    f.Add(&t4, &t0, &t1)
+    f.Sub(&t3, &t3, &t4)
+    f.Add(&t4, &a.y, &a.z)
+    f.Add(&x3, &b.y, &b.z)
+    f.Mul(&t4, &t4, &x3)
+    f.Add(&x3, &t1, &t2)
+    f.Sub(&t4, &t4, &x3)
+    f.Add(&x3, &a.x, &a.z)
+    f.Add(&y3, &b.x, &b.z)
+    f.Mul(&x3, &x3, &y3)
+    f.Add(&y3, &t0, &t2)
+    f.Sub(&y3, &x3, &y3)
+    f.Mul(&z3, &nistB, &t2)
+    f.Sub(&x3, &y3, &z3)
+    f.Add(&z3, &x3, &x3)
+    f.Add(&x3, &x3, &z3)
+    f.Sub(&z3, &t1, &x3)
+    f.Add(&x3, &t1, &x3)
+    f.Mul(&y3, &nistB, &y3)
+    f.Add(&t1, &t2, &t2)
+    f.Add(&t2, &t1, &t2)
+    f.Sub(&y3, &y3, &t2)
+    f.Sub(&y3, &y3, &t0)
+    f.Add(&t1, &y3, &y3)
+    f.Add(&y3, &t1, &y3)
+    f.Add(&t1, &t0, &t0)
+    f.Add(&t0, &t1, &t0)
+    f.Sub(&t0, &t0, &t2)
+    f.Mul(&t1, &t4, &y3)
+    f.Mul(&t2, &t0, &y3)
+    f.Mul(&y3, &x3, &z3)
+    f.Add(&y3, &y3, &t2)
+    f.Mul(&x3, &t3, &x3)
+    f.Sub(&x3, &x3, &t1)
+    f.Mul(&z3, &t4, &z3)
+    f.Mul(&t1, &t3, &t0)
+    f.Add(&z3, &z3, &t1)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+double sets p = 2.a with the complete doubling formulas of Algorithm 6 of the
+same paper.
+*/
+func (p *nistProjectivePoint) double(a *nistProjectivePoint) *nistProjectivePoint {
+    f := nistField
+    var t0, t1, t2, t3, x3, y3, z3 arith.Residue
+    f.Square(&t0, &a.x)
+    f.Square(&t1, &a.y)
+    f.Square(&t2, &a.z)
+    f.Mul(&t3, &a.x, &a.y)
+    f.Add(&t3, &t3, &t3)
+    f.Mul(&z3, &a.x, &a.z)
+    f.Add(&z3, &z3, &z3)
+    f.Mul(&y3, &nistB, &t2)
+    f.Sub(&y3, &y3, &z3)
+    f.Add(&x3, &y3, &y3)
+    f.Add(&y3, &x3, &y3)
+    f.Sub(&x3, &t1, &y3)
+    f.Add(&y3, &t1, &y3)
+    f.Mul(&y3, &x3, &y3)
+    f.Mul(&x3, &x3, &t3)
+    f.Add(&t3, &t2, &t2)
+    f.Add(&t2, &t2, &t3)
+    f.Mul(&z3, &nistB, &z3)
+    f.Sub(&z3, &z3, &t2)
+    f.Sub(&z3, &z3, &t0)
+    f.Add(&t3, &z3, &z3)
+    f.Add(&z3, &z3, &t3)
+    f.Add(&t3, &t0, &t0)
+    f.Add(&t0, &t3, &t0)
+    f.Sub(&t0, &t0, &t2)
+    f.Mul(&t0, &t0, &z3)
+    f.Add(&y3, &y3, &t0)
+    f.Mul(&t0, &a.y, &a.z)
+    f.Add(&t0, &t0, &t0)
+    f.Mul(&z3, &t0, &z3)
+    f.Sub(&x3, &x3, &z3)
+    f.Mul(&z3, &t0, &t1)
+    f.Add(&z3, &z3, &z3)
+    f.Add(&z3, &z3, &z3)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+lookup sets p = table[d] without branching on d nor accessing memory at an
+address that depends on d.
+*/
+func (p *nistProjectivePoint) lookup(table []nistProjectivePoint, d uint64) *nistProjectivePoint {
+    p.setInfinity()
+    for i := range table {
+        // equal is 1 if and only if i = d
+        v := uint64(i) ^ d
+        equal := ((v | -v) >> 63) ^ 1
+        p.x.Select(&table[i].x, &p.x, equal)
+        p.y.Select(&table[i].y, &p.y, equal)
+        p.z.Select(&table[i].z, &p.z, equal)
+    }
+    return p
+}
+
+/*
+toAffine sets a to p. The inverse of z is computed with a public exponent, in a
+time that does not depend on z.
+*/
+func (p *nistProjectivePoint) toAffine(a *NistP256Point) *NistP256Point {
+    if p.z.IsZero() == 1 {
+        return a.SetInfinity()
+    }
+    var zInv, x, y arith.Residue
+    nistField.Invert(&zInv, &p.z)
+    a.X = nistField.ToBig(nistField.Mul(&x, &p.x, &zInv))
+    a.Y = nistField.ToBig(nistField.Mul(&y, &p.y, &zInv))
+    return a
+}
+
+/*
+SecretMultiScalarMult sets p to the sum of scalars[i].points[i], in a time that
+does not depend on the scalars, which may be secret. The points are public.
+*/
+func (p *NistP256Point) SecretMultiScalarMult(points []*NistP256Point, scalars []*big.Int) (*NistP256Point, error) {
+    if len(points) != len(scalars) {
+        return nil, errors.New("number of points is different from the number of scalars")
+    }
+    tables := make([]nistProjectivePoint, len(points)*secretTableSize)
+    ks := make([][4]uint64, len(scalars))
+    for i := range points {
+        if points[i] == nil || scalars[i] == nil {
+            return nil, errors.New("points and scalars must be defined")
+        }
+        table := tables[i*secretTableSize : (i+1)*secretTableSize]
+        table[0].setInfinity()
+        table[1].setAffine(points[i])
+        for d := 2; d < secretTableSize; d++ {
+            table[d].add(&table[d-1], &table[1])
+        }
+        k := scalars[i]
+        if k.Sign() < 0 || k.BitLen() > 256 {
+            k = new(big.Int).Mod(k, NistP256N)
+        }
+        for j, w := range k.Bits() {
+            ks[i][j*wordBits/64] |= uint64(w) << uint(j*wordBits%64)
+        }
+    }
+
+    var term nistProjectivePoint
+    result := new(nistProjectivePoint).setInfinity()
+    for w := 256/secretWindow - 1; w >= 0; w-- {
+        for j := 0; j < secretWindow; j++ {
+            result.double(result)
+        }
+        for i := range points {
+            d := (ks[i][w*secretWindow/64] >> uint(w*secretWindow%64)) & (secretTableSize - 1)
+            term.lookup(tables[i*secretTableSize:(i+1)*secretTableSize], d)
+            result.add(result, &term)
+        }
+    }
+    return result.toAffine(p), nil
+}
+
+/*
+SecretScalarMult sets p = k.a, in a time that does not depend on k.
+*/
+func (p *NistP256Point) SecretScalarMult(a *NistP256Point, k *big.Int) *NistP256Point {
+    result, _ := p.SecretMultiScalarMult([]*NistP256Point{a}, []*big.Int{k})
+    return result
+}
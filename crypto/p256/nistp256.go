@@ -0,0 +1,267 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/elliptic"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/util/byteconversion"
+)
+
+/*
+This file contains the curve NIST P-256 of FIPS 186-4, y^2 = x^3 - 3x + b, and
+its hash-to-curve suite P256_XMD:SHA-256_SSWU_RO_ of RFC 9380. Only the
+parameters of the curve come from crypto/elliptic: the group operations use the
+complete formulas of nistconsttime.go, which are defined for any input, while
+the operations of crypto/elliptic are deprecated and panic on points that are
+not on the curve. The encodings are the ones of Secp256k1Point: 33 bytes SEC1
+compressed, and 0x00 for the point at infinity.
+*/
+
+var (
+    nistCurve = elliptic.P256()
+    // NistP256N is the order of the group of NIST P-256.
+    NistP256N = new(big.Int).Set(nistCurve.Params().N)
+    // NistP256P is the prime of the base field of NIST P-256.
+    NistP256P = new(big.Int).Set(nistCurve.Params().P)
+    // nistA is the coefficient a = -3 of the curve
+    nistA = new(big.Int).Sub(nistCurve.Params().P, big.NewInt(3))
+    // nistZ is the constant Z = -10 of the simplified SWU map, Section 8.2 of RFC 9380
+    nistZ = new(big.Int).Sub(nistCurve.Params().P, big.NewInt(10))
+    // nistG is the base point of the curve
+    nistG = &NistP256Point{X: nistCurve.Params().Gx, Y: nistCurve.Params().Gy}
+)
+
+/*
+NistP256Point is a point of NIST P-256 in affine coordinates. The point at
+infinity has nil coordinates.
+*/
+type NistP256Point struct {
+    X, Y *big.Int
+}
+
+/*
+IsZero returns true if and only if p is the point at infinity.
+*/
+func (p *NistP256Point) IsZero() bool {
+    return p.X == nil || p.Y == nil || (p.X.Sign() == 0 && p.Y.Sign() == 0)
+}
+
+/*
+SetInfinity sets p to the point at infinity.
+*/
+func (p *NistP256Point) SetInfinity() *NistP256Point {
+    p.X = nil
+    p.Y = nil
+    return p
+}
+
+/*
+Neg sets p = -a.
+*/
+func (p *NistP256Point) Neg(a *NistP256Point) *NistP256Point {
+    if a.IsZero() {
+        return p.SetInfinity()
+    }
+    y := new(big.Int).Sub(NistP256P, a.Y)
+    p.X = new(big.Int).Set(a.X)
+    p.Y = y.Mod(y, NistP256P)
+    return p
+}
+
+/*
+Add sets p = a + b, for any points a and b. The formulas are complete: they need
+no special case for a = b, a = -b or the point at infinity, and do not panic on
+points that are not on the curve, whose result is then meaningless.
+*/
+func (p *NistP256Point) Add(a, b *NistP256Point) *NistP256Point {
+    var pa, pb nistProjectivePoint
+    pa.setAffine(a)
+    pb.setAffine(b)
+    return pa.add(&pa, &pb).toAffine(p)
+}
+
+/*
+Double sets p = 2.a.
+*/
+func (p *NistP256Point) Double(a *NistP256Point) *NistP256Point {
+    var pa nistProjectivePoint
+    pa.setAffine(a)
+    return pa.double(&pa).toAffine(p)
+}
+
+/*
+ScalarMult sets p = n.a, for any integer n.
+*/
+func (p *NistP256Point) ScalarMult(a *NistP256Point, n *big.Int) *NistP256Point {
+    return p.SecretScalarMult(a, n)
+}
+
+/*
+ScalarBaseMult sets p = n.G, where G is the base point of the curve.
+*/
+func (p *NistP256Point) ScalarBaseMult(n *big.Int) *NistP256Point {
+    return p.SecretScalarMult(nistG, n)
+}
+
+/*
+Set sets p to a copy of a.
+*/
+func (p *NistP256Point) Set(a *NistP256Point) *NistP256Point {
+    if a.IsZero() {
+        return p.SetInfinity()
+    }
+    p.X = new(big.Int).Set(a.X)
+    p.Y = new(big.Int).Set(a.Y)
+    return p
+}
+
+/*
+IsOnCurve returns true if and only if the coordinates of p are reduced and
+satisfy y^2 = x^3 - 3x + b.
+*/
+func (p *NistP256Point) IsOnCurve() bool {
+    if p.IsZero() || p.X.Sign() < 0 || p.Y.Sign() < 0 || p.X.Cmp(NistP256P) >= 0 || p.Y.Cmp(NistP256P) >= 0 {
+        return false
+    }
+    y2 := new(big.Int).Mul(p.Y, p.Y)
+    return y2.Mod(y2, NistP256P).Cmp(nistF(p.X)) == 0
+}
+
+/*
+String returns the coordinates of p.
+*/
+func (p *NistP256Point) String() string {
+    if p.IsZero() {
+        return "NistP256(infinity)"
+    }
+    return "NistP256(" + p.X.String() + "," + p.Y.String() + ")"
+}
+
+/*
+Marshal returns the SEC1 compressed encoding of p, or the single byte 0x00 for
+the point at infinity.
+*/
+func (p *NistP256Point) Marshal() []byte {
+    if p.IsZero() {
+        return []byte{0x00}
+    }
+    x, _ := byteconversion.ToFixedByteArray(p.X, 32)
+    result := make([]byte, 33)
+    result[0] = 0x02 | byte(p.Y.Bit(0))
+    copy(result[1:], x)
+    return result
+}
+
+/*
+Unmarshal sets p to the point of the encoding returned by Marshal. It returns an
+error if the encoding is not canonical or if there is no point with the given X
+coordinate.
+*/
+func (p *NistP256Point) Unmarshal(data []byte) (*NistP256Point, error) {
+    if len(data) == 1 && data[0] == 0x00 {
+        return p.SetInfinity(), nil
+    }
+    if len(data) != 33 || (data[0] != 0x02 && data[0] != 0x03) {
+        return nil, errors.New("invalid compressed point encoding")
+    }
+    x := new(big.Int).SetBytes(data[1:])
+    if x.Cmp(NistP256P) >= 0 {
+        return nil, errors.New("X coordinate is not lower than the field prime")
+    }
+    y := new(big.Int).ModSqrt(nistF(x), NistP256P)
+    if y == nil {
+        return nil, errors.New("X coordinate is not on the curve")
+    }
+    if y.Bit(0) != uint(data[0]&1) {
+        y.Sub(NistP256P, y)
+    }
+    p.X = x
+    p.Y = y.Mod(y, NistP256P)
+    return p, nil
+}
+
+/*
+HashToNistP256 hashes the message msg to a point of NIST P-256, using the suite
+P256_XMD:SHA-256_SSWU_RO_ of RFC 9380 with the domain separation tag dst, which
+must not be empty.
+*/
+func HashToNistP256(msg, dst []byte) (*NistP256Point, error) {
+    u, err := hashToField(msg, dst, 2, NistP256P)
+    if err != nil {
+        return nil, err
+    }
+    // the cofactor of NIST P-256 is 1
+    return new(NistP256Point).Add(nistMapToCurve(u[0]), nistMapToCurve(u[1])), nil
+}
+
+/*
+nistF returns x^3 - 3x + b mod P.
+*/
+func nistF(x *big.Int) *big.Int {
+    result := new(big.Int).Mul(x, x)
+    result.Add(result, nistA)
+    result.Mul(result, x)
+    result.Add(result, nistCurve.Params().B)
+    return result.Mod(result, NistP256P)
+}
+
+/*
+nistMapToCurve is the simplified SWU map of Section 6.6.2 of RFC 9380. The
+curve has a != 0 and b != 0, so no isogeny is needed.
+*/
+func nistMapToCurve(u *big.Int) *NistP256Point {
+    p := NistP256P
+    b := nistCurve.Params().B
+    // tv1 = inv0(Z^2 * u^4 + Z * u^2)
+    zu2 := new(big.Int).Mul(u, u)
+    zu2.Mul(zu2, nistZ).Mod(zu2, p)
+    tv1 := new(big.Int).Mul(zu2, zu2)
+    tv1.Add(tv1, zu2).Mod(tv1, p)
+    if tv1.Sign() != 0 {
+        tv1.ModInverse(tv1, p)
+    }
+
+    // x1 = (-B / A) * (1 + tv1), or B / (Z * A) if tv1 = 0
+    x1 := new(big.Int)
+    if tv1.Sign() == 0 {
+        x1.Mul(nistZ, nistA)
+        x1.ModInverse(x1.Mod(x1, p), p)
+        x1.Mul(x1, b)
+    } else {
+        x1.ModInverse(nistA, p)
+        x1.Mul(x1, b).Neg(x1)
+        x1.Mul(x1, new(big.Int).Add(tv1, big.NewInt(1)))
+    }
+    x1.Mod(x1, p)
+
+    x := x1
+    y := new(big.Int).ModSqrt(nistF(x1), p)
+    if y == nil {
+        // x2 = Z * u^2 * x1, then f(x2) is a square
+        x = new(big.Int).Mul(zu2, x1)
+        x.Mod(x, p)
+        y = new(big.Int).ModSqrt(nistF(x), p)
+    }
+    if u.Bit(0) != y.Bit(0) {
+        y.Sub(p, y).Mod(y, p)
+    }
+    return &NistP256Point{X: x, Y: y}
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "bytes"
+    "crypto/rand"
+    "encoding/hex"
+    "math/big"
+    "testing"
+)
+
+func TestNistP256Arithmetic(t *testing.T) {
+    G := new(NistP256Point).ScalarBaseMult(big.NewInt(1))
+    if !G.IsOnCurve() {
+        t.Errorf("Assert failure: the base point is not on the curve")
+    }
+    for i := 0; i < 20; i++ {
+        a, _ := rand.Int(rand.Reader, NistP256N)
+        b, _ := rand.Int(rand.Reader, NistP256N)
+        A := new(NistP256Point).ScalarBaseMult(a)
+        B := new(NistP256Point).ScalarMult(G, b)
+        sum := new(NistP256Point).ScalarBaseMult(new(big.Int).Add(a, b))
+        if !equalNist(new(NistP256Point).Add(A, B), sum) {
+            t.Errorf("Assert failure: a.G + b.G is not (a + b).G")
+        }
+        if !equalNist(new(NistP256Point).Add(A, A), new(NistP256Point).Double(A)) {
+            t.Errorf("Assert failure: A + A is not 2.A")
+        }
+        if !new(NistP256Point).Add(A, new(NistP256Point).Neg(A)).IsZero() {
+            t.Errorf("Assert failure: A - A is not the point at infinity")
+        }
+        infinity := new(NistP256Point).SetInfinity()
+        if !equalNist(new(NistP256Point).Add(A, infinity), A) || !equalNist(new(NistP256Point).Add(infinity, A), A) {
+            t.Errorf("Assert failure: A + O is not A")
+        }
+        if !new(NistP256Point).ScalarMult(A, NistP256N).IsZero() {
+            t.Errorf("Assert failure: N.A is not the point at infinity")
+        }
+        if !A.IsOnCurve() {
+            t.Errorf("Assert failure: A is not on the curve")
+        }
+    }
+}
+
+func TestNistP256SecretMultiScalarMult(t *testing.T) {
+    var points []*NistP256Point
+    var scalars []*big.Int
+    for i := 0; i < 4; i++ {
+        a, _ := rand.Int(rand.Reader, NistP256N)
+        k, _ := rand.Int(rand.Reader, NistP256N)
+        points = append(points, new(NistP256Point).ScalarBaseMult(a))
+        scalars = append(scalars, k)
+    }
+    // equal points, the point at infinity and edge scalars
+    nm1 := new(big.Int).Sub(NistP256N, big.NewInt(1))
+    points = append(points, points[0], new(NistP256Point).SetInfinity(), points[1], points[2], points[3])
+    scalars = append(scalars, scalars[0], big.NewInt(5), big.NewInt(0), nm1, new(big.Int).Neg(NistP256N))
+
+    expected := new(NistP256Point).SetInfinity()
+    for i := range points {
+        term := new(NistP256Point).ScalarMult(points[i], scalars[i])
+        if !equalNist(term, new(NistP256Point).SecretScalarMult(points[i], scalars[i])) {
+            t.Errorf("Assert failure: SecretScalarMult differs from ScalarMult for the term %d", i)
+        }
+        expected.Add(expected, term)
+    }
+    actual, err := new(NistP256Point).SecretMultiScalarMult(points, scalars)
+    if err != nil {
+        t.Fatalf("Unexpected error: %s", err)
+    }
+    if !equalNist(expected, actual) {
+        t.Errorf("Assert failure: SecretMultiScalarMult differs from the sum of the terms")
+    }
+    if _, err := new(NistP256Point).SecretMultiScalarMult(points, scalars[1:]); err == nil {
+        t.Errorf("Assert failure: different lengths accepted")
+    }
+}
+
+/*
+TestNistP256Multiples checks 2.G and 3.G against their coordinates, computed by
+the deprecated operations of crypto/elliptic.
+*/
+func TestNistP256Multiples(t *testing.T) {
+    expected := map[int64][2]string{
+        2: {"7cf27b188d034f7e8a52380304b51ac3c08969e277f21b35a60b48fc47669978", "07775510db8ed040293d9ac69f7430dbba7dade63ce982299e04b79d227873d1"},
+        3: {"5ecbe4d1a6330a44c8f7ef951d4bf165e6c6b721efada985fb41661bc6e7fd6c", "8734640c4998ff7e374b06ce1a64a2ecd82ab036384fb83d9a79b127a27d5032"},
+    }
+    G := new(NistP256Point).ScalarBaseMult(big.NewInt(1))
+    G2 := new(NistP256Point).Double(G)
+    G3 := new(NistP256Point).Add(G2, G)
+    for k, P := range map[int64]*NistP256Point{2: G2, 3: G3} {
+        x, _ := new(big.Int).SetString(expected[k][0], 16)
+        y, _ := new(big.Int).SetString(expected[k][1], 16)
+        if !equalNist(P, &NistP256Point{X: x, Y: y}) || !equalNist(P, new(NistP256Point).ScalarBaseMult(big.NewInt(k))) {
+            t.Errorf("Assert failure: %d.G, actual: %s", k, P)
+        }
+    }
+}
+
+/*
+TestNistP256InvalidPoints checks that the operations do not panic on points that
+are not on the curve, as those of crypto/elliptic do.
+*/
+func TestNistP256InvalidPoints(t *testing.T) {
+    G := new(NistP256Point).ScalarBaseMult(big.NewInt(1))
+    invalid := []*NistP256Point{
+        {X: big.NewInt(5), Y: big.NewInt(5)},
+        {X: new(big.Int).Add(G.X, NistP256P), Y: G.Y},
+        {X: new(big.Int).Lsh(big.NewInt(1), 300), Y: big.NewInt(-1)},
+    }
+    for _, P := range invalid {
+        new(NistP256Point).Add(P, G)
+        new(NistP256Point).Add(P, P)
+        new(NistP256Point).Double(P)
+        new(NistP256Point).ScalarMult(P, big.NewInt(12345))
+    }
+}
+
+/*
+TestNistP256IsOnCurve checks the equation y^2 = x^3 - 3x + b, which the base
+point of secp256k1 does not satisfy.
+*/
+func TestNistP256IsOnCurve(t *testing.T) {
+    secp := &NistP256Point{X: CURVE.Gx, Y: CURVE.Gy}
+    if secp.IsOnCurve() {
+        t.Errorf("Assert failure: the base point of secp256k1 is on NIST P-256")
+    }
+    G := new(NistP256Point).ScalarBaseMult(big.NewInt(1))
+    shifted := &NistP256Point{X: new(big.Int).Add(G.X, NistP256P), Y: G.Y}
+    if shifted.IsOnCurve() {
+        t.Errorf("Assert failure: coordinates that are not reduced are on the curve")
+    }
+    if new(NistP256Point).SetInfinity().IsOnCurve() {
+        t.Errorf("Assert failure: the point at infinity is on the curve")
+    }
+}
+
+func TestNistP256Marshal(t *testing.T) {
+    G := new(NistP256Point).ScalarBaseMult(big.NewInt(1))
+    expected := "036b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296"
+    if hex.EncodeToString(G.Marshal()) != expected {
+        t.Errorf("Assert failure: expected %s, actual: %x", expected, G.Marshal())
+    }
+    for i := 0; i < 20; i++ {
+        k, _ := rand.Int(rand.Reader, NistP256N)
+        p := new(NistP256Point).ScalarBaseMult(k)
+        decoded, err := new(NistP256Point).Unmarshal(p.Marshal())
+        if err != nil || !equalNist(decoded, p) {
+            t.Errorf("Assert failure: decoding of %s failed", p)
+        }
+    }
+    decoded, err := new(NistP256Point).Unmarshal([]byte{0x00})
+    if err != nil || !decoded.IsZero() {
+        t.Errorf("Assert failure: decoding of the point at infinity failed")
+    }
+
+    invalid := G.Marshal()
+    invalid[0] = 0x04
+    if _, err := new(NistP256Point).Unmarshal(invalid); err == nil {
+        t.Errorf("Assert failure: invalid prefix accepted")
+    }
+    invalid = append([]byte{0x02}, NistP256P.Bytes()...)
+    if _, err := new(NistP256Point).Unmarshal(invalid); err == nil {
+        t.Errorf("Assert failure: X coordinate larger than P accepted")
+    }
+}
+
+/*
+Test vectors of Appendix J.1.1 of RFC 9380.
+*/
+func TestHashToNistP256(t *testing.T) {
+    dst := []byte("QUUX-V01-CS02-with-P256_XMD:SHA-256_SSWU_RO_")
+    vectors := []struct {
+        msg, x, y string
+    }{
+        {"", "2c15230b26dbc6fc9a37051158c95b79656e17a1a920b11394ca91c44247d3e4",
+            "8a7a74985cc5c776cdfe4b1f19884970453912e9d31528c060be9ab5c43e8415"},
+        {"abc", "0bb8b87485551aa43ed54f009230450b492fead5f1cc91658775dac4a3388a0f",
+            "5c41b3d0731a27a7b14bc0bf0ccded2d8751f83493404c84a88e71ffd424212e"},
+    }
+    for _, v := range vectors {
+        p, err := HashToNistP256([]byte(v.msg), dst)
+        if err != nil {
+            t.Fatalf("Hash to curve failed: %s", err)
+        }
+        x, _ := hex.DecodeString(v.x)
+        y, _ := hex.DecodeString(v.y)
+        if !bytes.Equal(p.X.Bytes(), x) || !bytes.Equal(p.Y.Bytes(), y) {
+            t.Errorf("Assert failure: expected (%s, %s), actual: %s", v.x, v.y, p)
+        }
+        if !p.IsOnCurve() {
+            t.Errorf("Assert failure: hashed point is not on the curve")
+        }
+    }
+}
+
+func equalNist(a, b *NistP256Point) bool {
+    if a.IsZero() || b.IsZero() {
+        return a.IsZero() && b.IsZero()
+    }
+    return a.X.Cmp(b.X) == 0 && a.Y.Cmp(b.Y) == 0
+}
@@ -16,16 +16,22 @@
  */
 
 /*
-Package p256 Encapsulates secP256k1 elliptic curve.
+Package p256 encapsulates two elliptic curves. Despite its name, the curve of
+CURVE, S256() and Secp256k1Point is secp256k1, y^2 = x^3 + 7, the curve used by
+the proofs of this module. The curve NIST P-256, y^2 = x^3 - 3x + b, is
+implemented by NistP256Point.
 */
 package p256
 
 import (
     "bytes"
     "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
     "errors"
     "math/big"
     "strconv"
+    "sync"
 
     "github.com/ing-bank/zkrp/util/bn"
     "github.com/ing-bank/zkrp/util/byteconversion"
@@ -33,19 +39,31 @@ import (
 
 var (
     CURVE = S256()
+
+    generatorTableOnce  sync.Once
+    generatorTableValue *FixedBaseTable
 )
 
 /*
-Elliptic Curve Point struct.
+Secp256k1Point is a point of secp256k1 in affine coordinates. The point at
+infinity has nil coordinates.
 */
-type P256 struct {
+type Secp256k1Point struct {
     X, Y *big.Int
 }
 
+/*
+P256 is the former name of Secp256k1Point.
+
+Deprecated: the curve of P256 is secp256k1, not NIST P-256. Use Secp256k1Point,
+or NistP256Point for NIST P-256.
+*/
+type P256 = Secp256k1Point
+
 /*
 IsZero returns true if and only if the elliptic curve point is the point at infinity.
 */
-func (p *P256) IsZero() bool {
+func (p *Secp256k1Point) IsZero() bool {
     c1 := p.X == nil || p.Y == nil
     if !c1 {
         z := new(big.Int).SetInt64(0)
@@ -55,119 +73,127 @@ func (p *P256) IsZero() bool {
 }
 
 /*
-Neg returns the inverse of the given elliptic curve point.
+Equal returns true if and only if p and b are the same point.
 */
-func (p *P256) Neg(a *P256) *P256 {
-    // (X, Y) -> (X, X + Y)
+func (p *Secp256k1Point) Equal(b *Secp256k1Point) bool {
+    if p.IsZero() || b.IsZero() {
+        return p.IsZero() == b.IsZero()
+    }
+    return p.X.Cmp(b.X) == 0 && p.Y.Cmp(b.Y) == 0
+}
+
+/*
+Set sets p to a copy of a.
+*/
+func (p *Secp256k1Point) Set(a *Secp256k1Point) *Secp256k1Point {
     if a.IsZero() {
         return p.SetInfinity()
     }
-    one := new(big.Int).SetInt64(1)
-    mone := new(big.Int).Sub(CURVE.N, one)
-    p.ScalarMult(p, mone)
+    p.X = new(big.Int).Set(a.X)
+    p.Y = new(big.Int).Set(a.Y)
     return p
 }
 
 /*
-Input points must be distinct
+Neg sets p = -a, that is (X, P - Y).
 */
-func (p *P256) Add(a, b *P256) *P256 {
+func (p *Secp256k1Point) Neg(a *Secp256k1Point) *Secp256k1Point {
     if a.IsZero() {
-        p.X = b.X
-        p.Y = b.Y
-        return p
-    } else if b.IsZero() {
-        p.X = b.X
-        p.Y = b.Y
-        return p
-
+        return p.SetInfinity()
     }
-    resx, resy := CURVE.Add(a.X, a.Y, b.X, b.Y)
-    p.X = resx
-    p.Y = resy
+    y := new(big.Int).Sub(CURVE.P, a.Y)
+    p.X = new(big.Int).Set(a.X)
+    p.Y = y.Mod(y, CURVE.P)
     return p
 }
 
+/*
+Add sets p = a + b, for any points a and b: equal points are doubled and opposite
+points give the point at infinity. The sum is computed in Jacobian coordinates,
+so that the only modular inversion is the one of the conversion back to affine
+coordinates.
+*/
+func (p *Secp256k1Point) Add(a, b *Secp256k1Point) *Secp256k1Point {
+    if a.IsZero() {
+        return p.Set(b)
+    } else if b.IsZero() {
+        return p.Set(a)
+    }
+    r := new(JacobianPoint).SetAffine(a)
+    return p.setAffine(r.AddAffine(r, b).Affine())
+}
+
+/*
+Sub sets p = a - b.
+*/
+func (p *Secp256k1Point) Sub(a, b *Secp256k1Point) *Secp256k1Point {
+    return p.Add(a, new(Secp256k1Point).Neg(b))
+}
+
 /*
 Double returns 2*P, where P is the given elliptic curve point.
 */
-func (p *P256) Double(a *P256) *P256 {
+func (p *Secp256k1Point) Double(a *Secp256k1Point) *Secp256k1Point {
     if a.IsZero() {
         return p.SetInfinity()
     }
-    resx, resy := CURVE.Double(a.X, a.Y)
-    p.X = resx
-    p.Y = resy
-    return p
+    r := new(JacobianPoint).SetAffine(a)
+    return p.setAffine(r.Double(r).Affine())
 }
 
 /*
 ScalarMul encapsulates the scalar Multiplication Algorithm from secP256k1.
+The multiple is computed in Jacobian coordinates as k1.a + k2.phi(a), where
+k = k1 + k2.lambda is the GLV decomposition of the scalar reduced modulo N.
 */
-func (p *P256) ScalarMult(a *P256, n *big.Int) *P256 {
+func (p *Secp256k1Point) ScalarMult(a *Secp256k1Point, n *big.Int) *Secp256k1Point {
     if a.IsZero() {
         return p.SetInfinity()
     }
-    cmp := n.Cmp(big.NewInt(0))
-    if cmp == 0 {
-        return p.SetInfinity()
-    }
-    n = bn.Mod(n, CURVE.N)
-    bns := n.Bytes()
-    resx, resy := CURVE.ScalarMult(a.X, a.Y, bns)
-    p.X = resx
-    p.Y = resy
-    return p
+    r := new(JacobianPoint).SetAffine(a)
+    return p.setAffine(r.ScalarMult(r, n).Affine())
 }
 
 /*
-ScalarBaseMult returns the Scalar Multiplication by the base generator.
+ScalarBaseMult returns the Scalar Multiplication by the base generator, using a
+FixedBaseTable of the generator that is computed on the first call.
 */
-func (p *P256) ScalarBaseMult(n *big.Int) *P256 {
-    cmp := n.Cmp(big.NewInt(0))
-    if cmp == 0 {
-        return p.SetInfinity()
-    }
-    n = bn.Mod(n, CURVE.N)
-    bns := n.Bytes()
-    resx, resy := CURVE.ScalarBaseMult(bns)
-    p.X = resx
-    p.Y = resy
-    return p
+func (p *Secp256k1Point) ScalarBaseMult(n *big.Int) *Secp256k1Point {
+    return p.setAffine(generatorTable().ScalarMult(n))
+}
+
+/*
+generatorTable returns the FixedBaseTable of the generator of secp256k1.
+*/
+func generatorTable() *FixedBaseTable {
+    generatorTableOnce.Do(func() {
+        generatorTableValue, _ = NewFixedBaseTable(&Secp256k1Point{X: CURVE.Gx, Y: CURVE.Gy})
+    })
+    return generatorTableValue
 }
 
 /*
 Multiply actually is reponsible for the addition of elliptic curve points.
 The name here is to maintain compatibility with bn256 interface.
-This algorithm verifies if the given elliptic curve points are equal, in which case it
-returns the result of Double function, otherwise it returns the result of Add function.
+It is the same as Add.
 */
-func (p *P256) Multiply(a, b *P256) *P256 {
-    if a.IsZero() {
-        p.X = b.X
-        p.Y = b.Y
-        return p
-    } else if b.IsZero() {
-        p.X = a.X
-        p.Y = a.Y
-        return p
-    }
-    if a.X.Cmp(b.X) == 0 && a.Y.Cmp(b.Y) == 0 {
-        resx, resy := CURVE.Double(a.X, a.Y)
-        p.X = resx
-        p.Y = resy
-        return p
-    }
-    resx, resy := CURVE.Add(a.X, a.Y, b.X, b.Y)
-    p.X = resx
-    p.Y = resy
+func (p *Secp256k1Point) Multiply(a, b *Secp256k1Point) *Secp256k1Point {
+    return p.Add(a, b)
+}
+
+/*
+setAffine sets p to the coordinates of a.
+*/
+func (p *Secp256k1Point) setAffine(a *Secp256k1Point) *Secp256k1Point {
+    p.X = a.X
+    p.Y = a.Y
     return p
 }
 
 /*
 SetInfinity sets the given elliptic curve point to the point at infinity.
 */
-func (p *P256) SetInfinity() *P256 {
+func (p *Secp256k1Point) SetInfinity() *Secp256k1Point {
     p.X = nil
     p.Y = nil
     return p
@@ -177,10 +203,142 @@ func (p *P256) SetInfinity() *P256 {
 String returns the readable representation of the given elliptic curve point, i.e.
 the tuple formed by X and Y coordinates.
 */
-func (p *P256) String() string {
+func (p *Secp256k1Point) String() string {
     return "P256(" + p.X.String() + "," + p.Y.String() + ")"
 }
 
+/*
+Marshal returns the SEC1 compressed encoding of the elliptic curve point, namely
+a prefix byte 0x02 or 0x03, depending on the parity of Y, followed by the 32 bytes
+of the X coordinate. The point at infinity is encoded as the single byte 0x00.
+*/
+func (p *Secp256k1Point) Marshal() []byte {
+    if p.IsZero() {
+        return []byte{0x00}
+    }
+    x, _ := byteconversion.ToFixedByteArray(p.X, 32)
+    result := make([]byte, 33)
+    result[0] = 0x02 | byte(p.Y.Bit(0))
+    copy(result[1:], x)
+    return result
+}
+
+/*
+MarshalUncompressed returns the SEC1 uncompressed encoding of the elliptic curve
+point, namely the prefix byte 0x04 followed by the 32 bytes of X and the 32 bytes
+of Y. The point at infinity is encoded as the single byte 0x00.
+*/
+func (p *Secp256k1Point) MarshalUncompressed() []byte {
+    if p.IsZero() {
+        return []byte{0x00}
+    }
+    x, _ := byteconversion.ToFixedByteArray(p.X, 32)
+    y, _ := byteconversion.ToFixedByteArray(p.Y, 32)
+    result := make([]byte, 65)
+    result[0] = 0x04
+    copy(result[1:], x)
+    copy(result[33:], y)
+    return result
+}
+
+/*
+Unmarshal sets p to the elliptic curve point given by its SEC1 encoding, either
+compressed, as returned by Marshal, or uncompressed, as returned by
+MarshalUncompressed. The single byte 0x00 is the point at infinity. It returns an
+error if the encoding is not canonical or if the point is not on the curve.
+*/
+func (p *Secp256k1Point) Unmarshal(data []byte) (*Secp256k1Point, error) {
+    return p.unmarshal(data, true)
+}
+
+/*
+UnmarshalFinite is Unmarshal, but it also returns an error for the point at infinity.
+*/
+func (p *Secp256k1Point) UnmarshalFinite(data []byte) (*Secp256k1Point, error) {
+    return p.unmarshal(data, false)
+}
+
+/*
+unmarshal decodes a SEC1 encoding, accepting the point at infinity only if
+allowInfinity is true.
+*/
+func (p *Secp256k1Point) unmarshal(data []byte, allowInfinity bool) (*Secp256k1Point, error) {
+    if len(data) == 1 && data[0] == 0x00 {
+        if !allowInfinity {
+            return nil, errors.New("point at infinity is not allowed")
+        }
+        return p.SetInfinity(), nil
+    }
+    var x, y, fx, y2 fieldElement
+    switch {
+    case len(data) == 33 && (data[0] == 0x02 || data[0] == 0x03):
+        if !x.setBytes(data[1:]) {
+            return nil, errors.New("X coordinate is not lower than the field prime")
+        }
+        if !y.sqrt(curveF(&fx, &x)) {
+            return nil, errors.New("X coordinate is not on the curve")
+        }
+        if y[0]&1 != uint64(data[0]&1) {
+            y.neg(&y)
+        }
+    case len(data) == 65 && data[0] == 0x04:
+        if !x.setBytes(data[1:33]) {
+            return nil, errors.New("X coordinate is not lower than the field prime")
+        }
+        if !y.setBytes(data[33:]) {
+            return nil, errors.New("Y coordinate is not lower than the field prime")
+        }
+        if *y2.square(&y) != *curveF(&fx, &x) {
+            return nil, errors.New("point is not on the curve")
+        }
+    default:
+        return nil, errors.New("invalid point encoding")
+    }
+    p.X = x.big()
+    p.Y = y.big()
+    return p, nil
+}
+
+/*
+MarshalText returns the hex encoding of the SEC1 compressed encoding of the point.
+*/
+func (p *Secp256k1Point) MarshalText() ([]byte, error) {
+    return []byte(hex.EncodeToString(p.Marshal())), nil
+}
+
+/*
+UnmarshalText sets p to the point of the hex encoding of one of its SEC1 encodings.
+*/
+func (p *Secp256k1Point) UnmarshalText(text []byte) error {
+    data, err := hex.DecodeString(string(text))
+    if err != nil {
+        return err
+    }
+    _, err = p.Unmarshal(data)
+    return err
+}
+
+/*
+MarshalJSON encodes the point as a JSON string, the hex encoding of its SEC1
+compressed encoding.
+*/
+func (p *Secp256k1Point) MarshalJSON() ([]byte, error) {
+    text, _ := p.MarshalText()
+    return json.Marshal(string(text))
+}
+
+/*
+UnmarshalJSON decodes a point encoded by MarshalJSON.
+*/
+func (p *Secp256k1Point) UnmarshalJSON(data []byte) error {
+    var text string
+    err := json.Unmarshal(data, &text)
+    if err != nil {
+        return err
+    }
+    return p.UnmarshalText([]byte(text))
+}
+
 /*
 MapToGroup is a hash function that returns a valid elliptic curve point given as
 input a string. It is also known as hash-to-point and is used to obtain a generator
@@ -190,8 +348,11 @@ This implementation is based on the paper:
 Short signatures from the Weil pairing
 Boneh, Lynn and Shacham
 Journal of Cryptology, September 2004, Volume 17, Issue 4, pp 297–319
+
+Deprecated: the try-and-increment loop is neither constant-time nor interoperable,
+use HashToCurve instead.
 */
-func MapToGroup(m string) (*P256, error) {
+func MapToGroup(m string) (*Secp256k1Point, error) {
     var (
         i      int
         buffer bytes.Buffer
@@ -207,7 +368,7 @@ func MapToGroup(m string) (*P256, error) {
         fx = bn.Mod(fx, CURVE.P)
         y := fx.ModSqrt(fx, CURVE.P)
         if y != nil {
-            p := &P256{X: x, Y: y}
+            p := &Secp256k1Point{X: x, Y: y}
             if p.IsOnCurve() && !p.IsZero() {
                 return p, nil
             }
@@ -221,16 +382,18 @@ func MapToGroup(m string) (*P256, error) {
 F receives a big integer x as input and return x^3 + 7 mod ORDER.
 */
 func F(x *big.Int) (*big.Int, error) {
-    // Compute x^2
-    x3p7 := bn.Multiply(x, x)
-    x3p7 = bn.Mod(x3p7, CURVE.P)
-    // Compute x^3
-    x3p7 = bn.Multiply(x3p7, x)
-    x3p7 = bn.Mod(x3p7, CURVE.P)
-    // Compute X^3 + 7
-    x3p7 = bn.Add(x3p7, new(big.Int).SetInt64(7))
-    x3p7 = bn.Mod(x3p7, CURVE.P)
-    return x3p7, nil
+    var fx, v fieldElement
+    return curveF(&fx, v.setBig(x)).big(), nil
+}
+
+/*
+curveF sets z = x^3 + 7 mod P and returns z.
+*/
+func curveF(z, x *fieldElement) *fieldElement {
+    var x3 fieldElement
+    x3.square(x)
+    x3.mul(&x3, x)
+    return z.add(&x3, &fieldElement{7, 0, 0, 0})
 }
 
 /*
@@ -248,16 +411,10 @@ func HashToInt(b bytes.Buffer) (*big.Int, error) {
 IsOnCurve returns TRUE if and only if p has coordinates X and Y that satisfy the
 Elliptic Curve equation: y^2 = x^3 + 7.
 */
-func (p *P256) IsOnCurve() bool {
+func (p *Secp256k1Point) IsOnCurve() bool {
     // y² = x³ + 7
-    y2 := new(big.Int).Mul(p.Y, p.Y)
-    y2.Mod(y2, CURVE.P)
-
-    x3 := new(big.Int).Mul(p.X, p.X)
-    x3.Mul(x3, p.X)
-
-    x3.Add(x3, new(big.Int).SetInt64(7))
-    x3.Mod(x3, CURVE.P)
-
-    return x3.Cmp(y2) == 0
+    var x, y, y2, fx fieldElement
+    x.setBig(p.X)
+    y.setBig(p.Y)
+    return *y2.square(&y) == *curveF(&fx, &x)
 }
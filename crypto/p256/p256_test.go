@@ -19,6 +19,8 @@ package p256
 
 import (
     "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
     "math/big"
     "testing"
 )
@@ -29,7 +31,7 @@ func TestIsZero(t *testing.T) {
     curve := S256()
     a := curve.N.Bytes()
     Ax, Ay := curve.ScalarBaseMult(a)
-    p1 := P256{X: Ax, Y: Ay}
+    p1 := Secp256k1Point{X: Ax, Y: Ay}
     res := p1.IsZero()
     if res != true {
         t.Errorf("Assert failure: expected true, actual: %t", res)
@@ -40,14 +42,14 @@ func TestAdd(t *testing.T) {
     curve := S256()
     a1 := new(big.Int).SetInt64(71).Bytes()
     A1x, A1y := curve.ScalarBaseMult(a1)
-    p1 := &P256{X: A1x, Y: A1y}
+    p1 := &Secp256k1Point{X: A1x, Y: A1y}
     a2 := new(big.Int).SetInt64(17).Bytes()
     A2x, A2y := curve.ScalarBaseMult(a2)
-    p2 := &P256{X: A2x, Y: A2y}
+    p2 := &Secp256k1Point{X: A2x, Y: A2y}
     p3 := p1.Add(p1, p2)
-    sa := new(big.Int).SetInt64(-88).Bytes()
+    sa := new(big.Int).Sub(curve.N, big.NewInt(88)).Bytes()
     sAx, sAy := curve.ScalarBaseMult(sa)
-    sp := &P256{X: sAx, Y: sAy}
+    sp := &Secp256k1Point{X: sAx, Y: sAy}
     p4 := p3.Add(p3, sp)
     res := p4.IsZero()
     if res != true {
@@ -55,11 +57,67 @@ func TestAdd(t *testing.T) {
     }
 }
 
+func TestAddEdgeCases(t *testing.T) {
+    p := new(Secp256k1Point).ScalarBaseMult(big.NewInt(5))
+    inf := new(Secp256k1Point).SetInfinity()
+    if r := new(Secp256k1Point).Add(p, inf); !r.Equal(p) {
+        t.Errorf("Assert failure: expected %s, actual: %s", p, r)
+    }
+    if r := new(Secp256k1Point).Add(inf, p); !r.Equal(p) {
+        t.Errorf("Assert failure: expected %s, actual: %s", p, r)
+    }
+    expected := new(Secp256k1Point).ScalarBaseMult(big.NewInt(10))
+    if r := new(Secp256k1Point).Add(p, p); !r.Equal(expected) {
+        t.Errorf("Assert failure: expected %s, actual: %s", expected, r)
+    }
+    if r := new(Secp256k1Point).Add(p, new(Secp256k1Point).Neg(p)); !r.IsZero() {
+        t.Errorf("Assert failure: expected point at infinity, actual: %s", r)
+    }
+}
+
+func TestNegSub(t *testing.T) {
+    for i := 0; i < 16; i++ {
+        a, _ := rand.Int(rand.Reader, CURVE.N)
+        b, _ := rand.Int(rand.Reader, CURVE.N)
+        p := new(Secp256k1Point).ScalarBaseMult(a)
+        q := new(Secp256k1Point).ScalarBaseMult(b)
+        neg := new(Secp256k1Point).Neg(p)
+        expected := new(Secp256k1Point).ScalarBaseMult(new(big.Int).Sub(CURVE.N, a))
+        if !neg.Equal(expected) || !neg.IsOnCurve() {
+            t.Errorf("Assert failure: expected %s, actual: %s", expected, neg)
+        }
+        diff := new(Secp256k1Point).Sub(p, q)
+        expected = new(Secp256k1Point).ScalarBaseMult(new(big.Int).Sub(a, b))
+        if !diff.Equal(expected) {
+            t.Errorf("Assert failure: expected %s, actual: %s", expected, diff)
+        }
+    }
+    inf := new(Secp256k1Point).SetInfinity()
+    if !new(Secp256k1Point).Neg(inf).IsZero() || !new(Secp256k1Point).Sub(inf, inf).IsZero() {
+        t.Errorf("Assert failure: expected point at infinity")
+    }
+}
+
+func TestEqualSet(t *testing.T) {
+    p := new(Secp256k1Point).ScalarBaseMult(big.NewInt(7))
+    q := new(Secp256k1Point).Set(p)
+    if !q.Equal(p) || q.X == p.X || q.Y == p.Y {
+        t.Errorf("Assert failure: Set should copy the coordinates")
+    }
+    inf := new(Secp256k1Point).SetInfinity()
+    if p.Equal(inf) || inf.Equal(p) || !inf.Equal(new(Secp256k1Point).Set(inf)) {
+        t.Errorf("Assert failure: wrong comparison with the point at infinity")
+    }
+    if p.Equal(new(Secp256k1Point).Neg(p)) {
+        t.Errorf("Assert failure: a point is not equal to its inverse")
+    }
+}
+
 func TestScalarMultP256(t *testing.T) {
     curve := S256()
     a1 := new(big.Int).SetInt64(71).Bytes()
     Ax, Ay := curve.ScalarBaseMult(a1)
-    p1 := &P256{X: Ax, Y: Ay}
+    p1 := &Secp256k1Point{X: Ax, Y: Ay}
     pr := p1.ScalarMult(p1, curve.N)
     res := pr.IsZero()
     if res != true {
@@ -69,7 +127,7 @@ func TestScalarMultP256(t *testing.T) {
 
 func TestScalarBaseMult(t *testing.T) {
     a1 := new(big.Int).SetInt64(71)
-    p1 := new(P256).ScalarBaseMult(a1)
+    p1 := new(Secp256k1Point).ScalarBaseMult(a1)
     res := p1.IsZero()
     if res != false {
         t.Errorf("Assert failure: expected false, actual: %t", res)
@@ -83,11 +141,154 @@ func TestMapToGroup(t *testing.T) {
     p.ScalarMult(p, curve.N)
 }
 
+func TestMarshal(t *testing.T) {
+    p := new(Secp256k1Point).ScalarBaseMult(new(big.Int).SetInt64(1))
+    b := p.Marshal()
+    expected := "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+    if hex.EncodeToString(b) != expected {
+        t.Errorf("Assert failure: expected %s, actual: %x", expected, b)
+    }
+    inf := new(Secp256k1Point).SetInfinity().Marshal()
+    if len(inf) != 1 || inf[0] != 0 {
+        t.Errorf("Assert failure: expected 00, actual: %x", inf)
+    }
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+    for i := 0; i < 16; i++ {
+        k, _ := rand.Int(rand.Reader, CURVE.N)
+        p := new(Secp256k1Point).ScalarBaseMult(k)
+        q, err := new(Secp256k1Point).Unmarshal(p.Marshal())
+        if err != nil {
+            t.Fatalf("Unexpected error: %s", err)
+        }
+        if p.X.Cmp(q.X) != 0 || p.Y.Cmp(q.Y) != 0 {
+            t.Errorf("Assert failure: expected %s, actual: %s", p, q)
+        }
+    }
+    q, err := new(Secp256k1Point).Unmarshal([]byte{0x00})
+    if err != nil || !q.IsZero() {
+        t.Errorf("Assert failure: expected point at infinity")
+    }
+}
+
+func TestMarshalUncompressed(t *testing.T) {
+    p := new(Secp256k1Point).ScalarBaseMult(new(big.Int).SetInt64(1))
+    b := p.MarshalUncompressed()
+    expected := "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" +
+        "483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+    if hex.EncodeToString(b) != expected {
+        t.Errorf("Assert failure: expected %s, actual: %x", expected, b)
+    }
+    for i := 0; i < 16; i++ {
+        k, _ := rand.Int(rand.Reader, CURVE.N)
+        p := new(Secp256k1Point).ScalarBaseMult(k)
+        q, err := new(Secp256k1Point).Unmarshal(p.MarshalUncompressed())
+        if err != nil {
+            t.Fatalf("Unexpected error: %s", err)
+        }
+        if !p.Equal(q) {
+            t.Errorf("Assert failure: expected %s, actual: %s", p, q)
+        }
+    }
+    if inf := new(Secp256k1Point).SetInfinity().MarshalUncompressed(); len(inf) != 1 || inf[0] != 0 {
+        t.Errorf("Assert failure: expected 00, actual: %x", inf)
+    }
+}
+
+func TestUnmarshalFinite(t *testing.T) {
+    p := new(Secp256k1Point).ScalarBaseMult(new(big.Int).SetInt64(3))
+    q, err := new(Secp256k1Point).UnmarshalFinite(p.Marshal())
+    if err != nil || !p.Equal(q) {
+        t.Errorf("Assert failure: expected %s, actual: %s", p, q)
+    }
+    if _, err := new(Secp256k1Point).UnmarshalFinite([]byte{0x00}); err == nil {
+        t.Errorf("Assert failure: point at infinity should be rejected")
+    }
+}
+
+func TestMarshalJSON(t *testing.T) {
+    p := new(Secp256k1Point).ScalarBaseMult(new(big.Int).SetInt64(1))
+    data, err := json.Marshal(p)
+    expected := `"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"`
+    if err != nil || string(data) != expected {
+        t.Errorf("Assert failure: expected %s, actual: %s", expected, data)
+    }
+    var q Secp256k1Point
+    if err := json.Unmarshal(data, &q); err != nil || !p.Equal(&q) {
+        t.Errorf("Assert failure: expected %s, actual: %s", p, &q)
+    }
+    text, _ := new(Secp256k1Point).SetInfinity().MarshalText()
+    if string(text) != "00" {
+        t.Errorf("Assert failure: expected 00, actual: %s", text)
+    }
+    if err := q.UnmarshalText(text); err != nil || !q.IsZero() {
+        t.Errorf("Assert failure: expected point at infinity")
+    }
+    uncompressed := hex.EncodeToString(p.MarshalUncompressed())
+    if err := q.UnmarshalText([]byte(uncompressed)); err != nil || !p.Equal(&q) {
+        t.Errorf("Assert failure: expected %s, actual: %s", p, &q)
+    }
+    for _, invalid := range []string{`"zz"`, `"02"`, `12`, `"0200"`} {
+        if err := json.Unmarshal([]byte(invalid), &q); err == nil {
+            t.Errorf("Assert failure: %s should be rejected", invalid)
+        }
+    }
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+    p := new(Secp256k1Point).ScalarBaseMult(new(big.Int).SetInt64(1))
+    b := p.Marshal()
+
+    invalidPrefix := append([]byte{}, b...)
+    invalidPrefix[0] = 0x04
+    if _, err := new(Secp256k1Point).Unmarshal(invalidPrefix); err == nil {
+        t.Errorf("Assert failure: invalid prefix should be rejected")
+    }
+    if _, err := new(Secp256k1Point).Unmarshal(b[:32]); err == nil {
+        t.Errorf("Assert failure: truncated encoding should be rejected")
+    }
+    // x = 5 is not the X coordinate of any point, since 5^3 + 7 is not a square
+    notOnCurve := make([]byte, 33)
+    notOnCurve[0] = 0x02
+    notOnCurve[32] = 5
+    if _, err := new(Secp256k1Point).Unmarshal(notOnCurve); err == nil {
+        t.Errorf("Assert failure: X coordinate not on the curve should be rejected")
+    }
+    // X coordinate equal to the field prime
+    nonCanonical := append([]byte{0x02}, CURVE.P.Bytes()...)
+    if _, err := new(Secp256k1Point).Unmarshal(nonCanonical); err == nil {
+        t.Errorf("Assert failure: non canonical X coordinate should be rejected")
+    }
+
+    u := p.MarshalUncompressed()
+    if _, err := new(Secp256k1Point).Unmarshal(u[:64]); err == nil {
+        t.Errorf("Assert failure: truncated uncompressed encoding should be rejected")
+    }
+    for _, prefix := range []byte{0x02, 0x06, 0x07} {
+        invalid := append([]byte{}, u...)
+        invalid[0] = prefix
+        if _, err := new(Secp256k1Point).Unmarshal(invalid); err == nil {
+            t.Errorf("Assert failure: prefix %02x should be rejected", prefix)
+        }
+    }
+    offCurve := append([]byte{}, u...)
+    offCurve[64] ^= 1
+    if _, err := new(Secp256k1Point).Unmarshal(offCurve); err == nil {
+        t.Errorf("Assert failure: point not on the curve should be rejected")
+    }
+    // Y coordinate equal to the field prime
+    nonCanonicalY := append(append([]byte{}, u[:33]...), CURVE.P.Bytes()...)
+    if _, err := new(Secp256k1Point).Unmarshal(nonCanonicalY); err == nil {
+        t.Errorf("Assert failure: non canonical Y coordinate should be rejected")
+    }
+}
+
 func BenchmarkScalarMultP256(b *testing.B) {
     a := make([]byte, 32)
     b.ResetTimer()
     for i := 0; i < b.N; i++ {
         rand.Read(a)
-        _ = new(P256).ScalarBaseMult(new(big.Int).SetBytes(a))
+        _ = new(Secp256k1Point).ScalarBaseMult(new(big.Int).SetBytes(a))
     }
 }
@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "errors"
+    "math/big"
+)
+
+/*
+JacobianPoint is a point of secp256k1 in Jacobian coordinates (X/Z^2, Y/Z^3),
+over the fixed-width field elements of this package. Additions and doublings
+do not compute any modular inversion: the point is only normalized to affine
+coordinates by Affine, when it is encoded. A chain of additions thus costs a
+single inversion, instead of one per addition for Secp256k1Point. The zero
+value is the point at infinity.
+*/
+type JacobianPoint struct {
+    p jacobianPoint
+}
+
+/*
+NewJacobianPoint returns the point a in Jacobian coordinates.
+*/
+func NewJacobianPoint(a *Secp256k1Point) *JacobianPoint {
+    return new(JacobianPoint).SetAffine(a)
+}
+
+/*
+SetInfinity sets p to the point at infinity.
+*/
+func (p *JacobianPoint) SetInfinity() *JacobianPoint {
+    p.p.setInfinity()
+    return p
+}
+
+/*
+SetAffine sets p to the point a.
+*/
+func (p *JacobianPoint) SetAffine(a *Secp256k1Point) *JacobianPoint {
+    var b affinePoint
+    p.p.setAffine(b.setPoint(a))
+    return p
+}
+
+/*
+Set sets p to a.
+*/
+func (p *JacobianPoint) Set(a *JacobianPoint) *JacobianPoint {
+    p.p = a.p
+    return p
+}
+
+/*
+IsZero returns true if and only if p is the point at infinity.
+*/
+func (p *JacobianPoint) IsZero() bool {
+    return p.p.isInfinity()
+}
+
+/*
+Affine returns p in affine coordinates, which costs a modular inversion.
+*/
+func (p *JacobianPoint) Affine() *Secp256k1Point {
+    return p.p.toAffine().toPoint()
+}
+
+/*
+Add sets p = a + b, for any points a and b.
+*/
+func (p *JacobianPoint) Add(a, b *JacobianPoint) *JacobianPoint {
+    p.p.add(&a.p, &b.p)
+    return p
+}
+
+/*
+AddAffine sets p = a + b, where b is in affine coordinates, which is cheaper
+than Add.
+*/
+func (p *JacobianPoint) AddAffine(a *JacobianPoint, b *Secp256k1Point) *JacobianPoint {
+    var c affinePoint
+    p.p.addMixed(&a.p, c.setPoint(b))
+    return p
+}
+
+/*
+Double sets p = 2.a.
+*/
+func (p *JacobianPoint) Double(a *JacobianPoint) *JacobianPoint {
+    p.p.double(&a.p)
+    return p
+}
+
+/*
+Neg sets p = -a.
+*/
+func (p *JacobianPoint) Neg(a *JacobianPoint) *JacobianPoint {
+    p.p = a.p
+    p.p.y.neg(&a.p.y)
+    return p
+}
+
+/*
+Equal returns true if and only if p and b are the same point, comparing
+X1.Z2^2 = X2.Z1^2 and Y1.Z2^3 = Y2.Z1^3 without normalizing them.
+*/
+func (p *JacobianPoint) Equal(b *JacobianPoint) bool {
+    if p.IsZero() || b.IsZero() {
+        return p.IsZero() && b.IsZero()
+    }
+    var z1z1, z2z2, u1, u2, s1, s2 fieldElement
+    z1z1.square(&p.p.z)
+    z2z2.square(&b.p.z)
+    u1.mul(&p.p.x, &z2z2)
+    u2.mul(&b.p.x, &z1z1)
+    s1.mul(&p.p.y, &z2z2)
+    s1.mul(&s1, &b.p.z)
+    s2.mul(&b.p.y, &z1z1)
+    s2.mul(&s2, &p.p.z)
+    return u1 == u2 && s1 == s2
+}
+
+/*
+ScalarMult sets p = k.a, for any integer k. The running time depends on k.
+*/
+func (p *JacobianPoint) ScalarMult(a *JacobianPoint, k *big.Int) *JacobianPoint {
+    result, _ := p.MultiScalarMult([]*JacobianPoint{a}, []*big.Int{k})
+    return result
+}
+
+/*
+MultiScalarMult sets p to the sum of scalars[i].points[i], for every i, like
+Secp256k1Point.MultiScalarMult. The points are normalized together with a single
+modular inversion, and the result is not normalized.
+*/
+func (p *JacobianPoint) MultiScalarMult(points []*JacobianPoint, scalars []*big.Int) (*JacobianPoint, error) {
+    if len(points) != len(scalars) {
+        return nil, errors.New("number of points is different from the number of scalars")
+    }
+    jacobian := make([]jacobianPoint, len(points))
+    for i := range points {
+        if points[i] == nil {
+            return nil, errors.New("points and scalars must be defined")
+        }
+        jacobian[i] = points[i].p
+    }
+    result, err := multiScalarMult(batchToAffine(jacobian), scalars)
+    if err != nil {
+        return nil, err
+    }
+    p.p = *result
+    return p, nil
+}
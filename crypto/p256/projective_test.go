@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "testing"
+)
+
+func TestJacobianPoint(t *testing.T) {
+    points, scalars := randomTerms(10)
+    sum := new(JacobianPoint)
+    mixed := new(JacobianPoint)
+    expected := new(Secp256k1Point).SetInfinity()
+    for i := range points {
+        sum.Add(sum, NewJacobianPoint(points[i]))
+        mixed.AddAffine(mixed, points[i])
+        expected.Multiply(expected, points[i])
+    }
+    assertSamePoint(t, expected, sum.Affine())
+    assertSamePoint(t, expected, mixed.Affine())
+    if !sum.Equal(mixed) || !sum.Equal(NewJacobianPoint(expected)) {
+        t.Errorf("Assert failure: equal points in different coordinates are not equal")
+    }
+    if sum.Equal(new(JacobianPoint).Double(sum)) || sum.Equal(new(JacobianPoint)) {
+        t.Errorf("Assert failure: different points are equal")
+    }
+
+    A := NewJacobianPoint(points[0])
+    assertSamePoint(t, new(Secp256k1Point).Multiply(points[0], points[0]), new(JacobianPoint).Double(A).Affine())
+    assertSamePoint(t, new(Secp256k1Point).Multiply(points[0], points[0]), new(JacobianPoint).Add(A, A).Affine())
+    if !new(JacobianPoint).Add(A, new(JacobianPoint).Neg(A)).IsZero() {
+        t.Errorf("Assert failure: A - A is not the point at infinity")
+    }
+    if !new(JacobianPoint).Add(sum, new(JacobianPoint)).Equal(sum) || !new(JacobianPoint).Add(new(JacobianPoint), sum).Equal(sum) {
+        t.Errorf("Assert failure: A + O is not A")
+    }
+    if !new(JacobianPoint).ScalarMult(sum, CURVE.N).IsZero() {
+        t.Errorf("Assert failure: N.A is not the point at infinity")
+    }
+
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    assertSamePoint(t, new(Secp256k1Point).ScalarMult(expected, k), new(JacobianPoint).ScalarMult(sum, k).Affine())
+
+    // the points of the multi-scalar multiplication are not normalized
+    jacobian := make([]*JacobianPoint, len(points))
+    for i := range points {
+        jacobian[i] = new(JacobianPoint).Add(NewJacobianPoint(points[i]), new(JacobianPoint).Neg(A))
+        jacobian[i].AddAffine(jacobian[i], points[0])
+    }
+    actual, err := new(JacobianPoint).MultiScalarMult(jacobian, scalars)
+    if err != nil {
+        t.Fatalf("Unexpected error: %s", err)
+    }
+    assertSamePoint(t, naiveMultiScalarMult(points, scalars), actual.Affine())
+    if _, err := new(JacobianPoint).MultiScalarMult(jacobian, scalars[1:]); err == nil {
+        t.Errorf("Assert failure: different lengths accepted")
+    }
+}
+
+/*
+BenchmarkAddChain compares a sum of affine points, with an inversion per
+addition, to the same sum in Jacobian coordinates.
+*/
+func BenchmarkAddChain(b *testing.B) {
+    points, _ := randomTerms(64)
+    b.Run("affine", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            sum := new(Secp256k1Point).SetInfinity()
+            for j := range points {
+                sum.Multiply(sum, points[j])
+            }
+        }
+    })
+    b.Run("jacobian", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            sum := new(JacobianPoint)
+            for j := range points {
+                sum.AddAffine(sum, points[j])
+            }
+            sum.Affine()
+        }
+    })
+}
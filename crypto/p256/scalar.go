@@ -0,0 +1,330 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "encoding/binary"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/internal/arith"
+)
+
+const (
+    // wordBits is the bit-length of a big.Word
+    wordBits = 32 << (^uint(0) >> 63)
+)
+
+var (
+    // scalarN is the order N of secp256k1 in little-endian limbs
+    scalarN = [4]uint64{0xBFD25E8CD0364141, 0xBAAEDCE6AF48A03B, 0xFFFFFFFFFFFFFFFE, 0xFFFFFFFFFFFFFFFF}
+    // scalarC is 2^256 - N, which has 129 bits
+    scalarC = [3]uint64{0x402DA1732FC9BEBF, 0x4551231950B75FC4, 1}
+    // scalarHalf is (N - 1) / 2
+    scalarHalf = [4]uint64{0xDFE92F46681B20A0, 0x5D576E7357A4501D, 0xFFFFFFFFFFFFFFFF, 0x7FFFFFFFFFFFFFFF}
+)
+
+/*
+Scalar is an integer modulo the order N of secp256k1, represented by 4 limbs of
+64 bits in little-endian order. It is always fully reduced, so that equal scalars
+have equal limbs, and the zero value is 0. The product of 512 bits is reduced
+using the special form of N = 2^256 - C, where C has 129 bits, like the field
+elements are reduced using P = 2^256 - 2^32 - 977. Scalars replace big.Int in
+the arithmetic of the proofs; the conversions are SetBig and Big. Add, Sub, Neg,
+Mul, Square and Invert run in a time that does not depend on the values, like
+the field arithmetic, so that scalars can hold secrets.
+*/
+type Scalar struct {
+    l [4]uint64
+}
+
+/*
+SetBig sets z to b mod N.
+*/
+func (z *Scalar) SetBig(b *big.Int) *Scalar {
+    if b.Sign() < 0 || b.BitLen() > 256 {
+        b = new(big.Int).Mod(b, CURVE.N)
+    }
+    var l [4]uint64
+    for i, w := range b.Bits() {
+        l[i*wordBits/64] |= uint64(w) << uint(i*wordBits%64)
+    }
+    z.l = l
+    z.reduceOnce()
+    return z
+}
+
+/*
+Big returns z as a big.Int.
+*/
+func (z *Scalar) Big() *big.Int {
+    words := make([]big.Word, 256/wordBits)
+    for i := range words {
+        words[i] = big.Word(z.l[i*wordBits/64] >> uint(i*wordBits%64))
+    }
+    return new(big.Int).SetBits(words)
+}
+
+/*
+SetUint64 sets z to v.
+*/
+func (z *Scalar) SetUint64(v uint64) *Scalar {
+    z.l = [4]uint64{v, 0, 0, 0}
+    return z
+}
+
+/*
+SetBytes sets z to the 32-byte big-endian integer b. It returns an error if b is
+not lower than N, i.e. if the encoding is not canonical.
+*/
+func (z *Scalar) SetBytes(b []byte) (*Scalar, error) {
+    if len(b) != 32 {
+        return nil, errors.New("invalid scalar length")
+    }
+    var l [4]uint64
+    for i := 0; i < 4; i++ {
+        l[i] = binary.BigEndian.Uint64(b[24-8*i:])
+    }
+    if !lessThan(&l, &scalarN) {
+        return nil, errors.New("scalar is not lower than the order")
+    }
+    z.l = l
+    return z, nil
+}
+
+/*
+Bytes returns z as a 32-byte big-endian integer.
+*/
+func (z *Scalar) Bytes() []byte {
+    b := make([]byte, 32)
+    for i := 0; i < 4; i++ {
+        binary.BigEndian.PutUint64(b[24-8*i:], z.l[i])
+    }
+    return b
+}
+
+/*
+Set sets z = x.
+*/
+func (z *Scalar) Set(x *Scalar) *Scalar {
+    z.l = x.l
+    return z
+}
+
+/*
+IsZero returns true if and only if z is 0.
+*/
+func (z *Scalar) IsZero() bool {
+    return z.l[0]|z.l[1]|z.l[2]|z.l[3] == 0
+}
+
+/*
+Equal returns true if and only if z = x.
+*/
+func (z *Scalar) Equal(x *Scalar) bool {
+    return z.l == x.l
+}
+
+/*
+Add sets z = x + y mod N.
+*/
+func (z *Scalar) Add(x, y *Scalar) *Scalar {
+    var s, t [4]uint64
+    var c, b uint64
+    s[0], c = arith.Add64(x.l[0], y.l[0], 0)
+    s[1], c = arith.Add64(x.l[1], y.l[1], c)
+    s[2], c = arith.Add64(x.l[2], y.l[2], c)
+    s[3], c = arith.Add64(x.l[3], y.l[3], c)
+    t[0], b = arith.Sub64(s[0], scalarN[0], 0)
+    t[1], b = arith.Sub64(s[1], scalarN[1], b)
+    t[2], b = arith.Sub64(s[2], scalarN[2], b)
+    t[3], b = arith.Sub64(s[3], scalarN[3], b)
+    // s >= N if the sum overflowed 2^256 or if s - N did not borrow
+    z.l = s
+    z.cmov(&t, -(c | (b ^ 1)))
+    return z
+}
+
+/*
+Sub sets z = x - y mod N.
+*/
+func (z *Scalar) Sub(x, y *Scalar) *Scalar {
+    var d [4]uint64
+    var b uint64
+    d[0], b = arith.Sub64(x.l[0], y.l[0], 0)
+    d[1], b = arith.Sub64(x.l[1], y.l[1], b)
+    d[2], b = arith.Sub64(x.l[2], y.l[2], b)
+    d[3], b = arith.Sub64(x.l[3], y.l[3], b)
+    // add N if the subtraction borrowed
+    var c uint64
+    mask := -b
+    d[0], c = arith.Add64(d[0], scalarN[0]&mask, 0)
+    d[1], c = arith.Add64(d[1], scalarN[1]&mask, c)
+    d[2], c = arith.Add64(d[2], scalarN[2]&mask, c)
+    d[3], _ = arith.Add64(d[3], scalarN[3]&mask, c)
+    z.l = d
+    return z
+}
+
+/*
+Neg sets z = -x mod N.
+*/
+func (z *Scalar) Neg(x *Scalar) *Scalar {
+    var zero Scalar
+    return z.Sub(&zero, x)
+}
+
+/*
+Mul sets z = x.y mod N.
+*/
+func (z *Scalar) Mul(x, y *Scalar) *Scalar {
+    t := mulWide(&x.l, &y.l)
+    z.reduce(&t)
+    return z
+}
+
+/*
+Square sets z = x^2 mod N.
+*/
+func (z *Scalar) Square(x *Scalar) *Scalar {
+    return z.Mul(x, x)
+}
+
+/*
+Invert sets z = x^-1 = x^(N - 2) mod N, and z = 0 for x = 0. The exponent is
+processed by windows of 4 bits.
+*/
+func (z *Scalar) Invert(x *Scalar) *Scalar {
+    var table [16]Scalar
+    table[0].SetUint64(1)
+    for i := 1; i < 16; i++ {
+        table[i].Mul(&table[i-1], x)
+    }
+    e := scalarN
+    e[0] -= 2
+    var r Scalar
+    r.SetUint64(1)
+    for i := 63; i >= 0; i-- {
+        for j := 0; j < 4; j++ {
+            r.Square(&r)
+        }
+        r.Mul(&r, &table[(e[i/16]>>uint(4*(i%16)))&15])
+    }
+    z.l = r.l
+    return z
+}
+
+/*
+reduce sets z to the 512-bit integer t mod N. Since 2^256 = C mod N, the upper
+limbs t_hi are replaced by t_hi.C and added to the lower ones, which shrinks t
+from 512 to 386, 260, 257 and 256 bits. The number of limbs of every step is
+fixed, so that the running time does not depend on t.
+*/
+func (z *Scalar) reduce(t *[8]uint64) {
+    r := scalarFold(t[:4], t[4:8])
+    r = scalarFold(r[:4], r[4:7])
+    r = scalarFold(r[:4], r[4:5])
+    r = scalarFold(r[:4], r[4:5])
+    copy(z.l[:], r[:4])
+    z.reduceOnce()
+}
+
+/*
+scalarFold returns lo + hi.C, where lo has 4 limbs and hi at most 4 limbs.
+*/
+func scalarFold(lo, hi []uint64) [8]uint64 {
+    var r [8]uint64
+    copy(r[:4], lo)
+    for i := range hi {
+        var carry uint64
+        carry, r[i] = madd(hi[i], scalarC[0], r[i], 0)
+        carry, r[i+1] = madd(hi[i], scalarC[1], r[i+1], carry)
+        carry, r[i+2] = madd(hi[i], scalarC[2], r[i+2], carry)
+        for k := i + 3; k < 8; k++ {
+            r[k], carry = arith.Add64(r[k], carry, 0)
+        }
+    }
+    return r
+}
+
+/*
+reduceOnce subtracts N from z if z >= N, which fully reduces any z < 2^256.
+*/
+func (z *Scalar) reduceOnce() {
+    var d [4]uint64
+    var b uint64
+    d[0], b = arith.Sub64(z.l[0], scalarN[0], 0)
+    d[1], b = arith.Sub64(z.l[1], scalarN[1], b)
+    d[2], b = arith.Sub64(z.l[2], scalarN[2], b)
+    d[3], b = arith.Sub64(z.l[3], scalarN[3], b)
+    z.cmov(&d, b-1)
+}
+
+/*
+cmov sets z to the limbs l if mask is all ones and leaves z unchanged if mask is
+0, in constant time.
+*/
+func (z *Scalar) cmov(l *[4]uint64, mask uint64) {
+    z.l[0] ^= (z.l[0] ^ l[0]) & mask
+    z.l[1] ^= (z.l[1] ^ l[1]) & mask
+    z.l[2] ^= (z.l[2] ^ l[2]) & mask
+    z.l[3] ^= (z.l[3] ^ l[3]) & mask
+}
+
+/*
+isHigh returns true if and only if z > (N - 1) / 2, in which case N - z is shorter.
+*/
+func (z *Scalar) isHigh() bool {
+    return lessThan(&scalarHalf, &z.l)
+}
+
+/*
+bit returns the bit i of z.
+*/
+func (z *Scalar) bit(i int) uint {
+    if i >= 256 {
+        return 0
+    }
+    return uint(z.l[i/64]>>uint(i%64)) & 1
+}
+
+/*
+bitLen returns the bit-length of z.
+*/
+func (z *Scalar) bitLen() int {
+    for i := 3; i >= 0; i-- {
+        for b := 63; b >= 0; b-- {
+            if z.l[i]>>uint(b) != 0 {
+                return 64*i + b + 1
+            }
+        }
+    }
+    return 0
+}
+
+/*
+lessThan returns true if and only if x < y.
+*/
+func lessThan(x, y *[4]uint64) bool {
+    for i := 3; i >= 0; i-- {
+        if x[i] != y[i] {
+            return x[i] < y[i]
+        }
+    }
+    return false
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "bytes"
+    "crypto/rand"
+    "math/big"
+    "testing"
+)
+
+func TestScalarArithmetic(t *testing.T) {
+    nm1 := new(big.Int).Sub(CURVE.N, big.NewInt(1))
+    values := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), nm1, new(big.Int).Rsh(CURVE.N, 1)}
+    for i := 0; i < 64; i++ {
+        v, _ := rand.Int(rand.Reader, CURVE.N)
+        values = append(values, v)
+    }
+    for _, a := range values {
+        for _, b := range values[:8] {
+            var x, y, z Scalar
+            x.SetBig(a)
+            y.SetBig(b)
+
+            expected := new(big.Int).Add(a, b)
+            expected.Mod(expected, CURVE.N)
+            if z.Add(&x, &y).Big().Cmp(expected) != 0 {
+                t.Errorf("Assert failure: %s + %s, expected %s, actual: %s", a, b, expected, z.Big())
+            }
+            expected = new(big.Int).Sub(a, b)
+            expected.Mod(expected, CURVE.N)
+            if z.Sub(&x, &y).Big().Cmp(expected) != 0 {
+                t.Errorf("Assert failure: %s - %s, expected %s, actual: %s", a, b, expected, z.Big())
+            }
+            expected = new(big.Int).Mul(a, b)
+            expected.Mod(expected, CURVE.N)
+            if z.Mul(&x, &y).Big().Cmp(expected) != 0 {
+                t.Errorf("Assert failure: %s * %s, expected %s, actual: %s", a, b, expected, z.Big())
+            }
+        }
+        var x, z Scalar
+        x.SetBig(a)
+        if a.Sign() != 0 {
+            expected := new(big.Int).ModInverse(a, CURVE.N)
+            if z.Invert(&x).Big().Cmp(expected) != 0 {
+                t.Errorf("Assert failure: 1 / %s, expected %s, actual: %s", a, expected, z.Big())
+            }
+        }
+        if x.isHigh() != (a.Cmp(new(big.Int).Rsh(CURVE.N, 1)) > 0) {
+            t.Errorf("Assert failure: isHigh(%s)", a)
+        }
+        if x.bitLen() != a.BitLen() {
+            t.Errorf("Assert failure: bitLen(%s), expected %d, actual: %d", a, a.BitLen(), x.bitLen())
+        }
+    }
+}
+
+func TestScalarSetBig(t *testing.T) {
+    max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+    large := new(big.Int).Lsh(CURVE.N, 100)
+    for _, v := range []*big.Int{big.NewInt(-1), CURVE.N, max, large.Add(large, big.NewInt(5))} {
+        expected := new(big.Int).Mod(v, CURVE.N)
+        if actual := new(Scalar).SetBig(v).Big(); actual.Cmp(expected) != 0 {
+            t.Errorf("Assert failure: %s mod N, expected %s, actual: %s", v, expected, actual)
+        }
+    }
+}
+
+func TestScalarSetBytes(t *testing.T) {
+    nm1 := new(big.Int).Sub(CURVE.N, big.NewInt(1))
+    max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+    for _, v := range []*big.Int{big.NewInt(0), big.NewInt(1), nm1, CURVE.N, max} {
+        data := make([]byte, 32)
+        copy(data[32-len(v.Bytes()):], v.Bytes())
+        z, err := new(Scalar).SetBytes(data)
+        if (err == nil) != (v.Cmp(CURVE.N) < 0) {
+            t.Errorf("Assert failure: SetBytes(%s) returned %v", v, err)
+        }
+        if err == nil && (z.Big().Cmp(v) != 0 || !bytes.Equal(z.Bytes(), data)) {
+            t.Errorf("Assert failure: expected %s, actual: %s", v, z.Big())
+        }
+    }
+    if _, err := new(Scalar).SetBytes(make([]byte, 31)); err == nil {
+        t.Errorf("Assert failure: short encoding accepted")
+    }
+}
+
+func BenchmarkScalarMul(b *testing.B) {
+    x, _ := rand.Int(rand.Reader, CURVE.N)
+    y, _ := rand.Int(rand.Reader, CURVE.N)
+    b.Run("big", func(b *testing.B) {
+        z := new(big.Int)
+        for i := 0; i < b.N; i++ {
+            z.Mul(x, y)
+            z.Mod(z, CURVE.N)
+        }
+    })
+    b.Run("limbs", func(b *testing.B) {
+        var u, v, z Scalar
+        u.SetBig(x)
+        v.SetBig(y)
+        for i := 0; i < b.N; i++ {
+            z.Mul(&u, &v)
+        }
+    })
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ristretto255
+
+import (
+    "errors"
+    "math/big"
+)
+
+/*
+This file contains the scalar multiplications by secret scalars, such as the
+blinding factors of a prover. They run in a time that depends on the number of
+points, but not on the scalars: the formulas of the extended coordinates are
+complete and the field arithmetic has no branch, the scalars are processed by
+fixed windows of 4 bits and the multiples are read by scanning the whole table.
+*/
+
+const (
+    // secretWindow is the bit-length of the unsigned digits of the secret scalars
+    secretWindow = 4
+    // secretTableSize is the number of multiples 0.P to 15.P of every point
+    secretTableSize = 1 << secretWindow
+)
+
+/*
+lookup sets p = table[d] without branching on d nor accessing memory at an
+address that depends on d.
+*/
+func (p *edwardsPoint) lookup(table []edwardsPoint, d uint64) *edwardsPoint {
+    p.setIdentity()
+    for i := range table {
+        // mask is all ones if and only if i = d
+        v := uint64(i) ^ d
+        mask := ((v | -v) >> 63) - 1
+        p.x.cmov(&table[i].x, mask)
+        p.y.cmov(&table[i].y, mask)
+        p.z.cmov(&table[i].z, mask)
+        p.t.cmov(&table[i].t, mask)
+    }
+    return p
+}
+
+/*
+secretScalar returns the limbs of k modulo the order, whose conversion only
+depends on the number of words of k.
+*/
+func secretScalar(k *big.Int) [4]uint64 {
+    if k.Sign() < 0 || k.BitLen() > 256 {
+        k = new(big.Int).Mod(k, Order)
+    }
+    const wordBits = 32 << (^uint(0) >> 63)
+    var l [4]uint64
+    for i, w := range k.Bits() {
+        l[i*wordBits/64] |= uint64(w) << uint(i*wordBits%64)
+    }
+    return l
+}
+
+/*
+SecretMultiScalarMult sets p to the sum of scalars[i].points[i], like
+MultiScalarMult, in a time that does not depend on the scalars, which may be
+secret. The points are public.
+*/
+func (p *Point) SecretMultiScalarMult(points []*Point, scalars []*big.Int) (*Point, error) {
+    if len(points) != len(scalars) {
+        return nil, errors.New("number of points is different from the number of scalars")
+    }
+    tables := make([]edwardsPoint, len(points)*secretTableSize)
+    ks := make([][4]uint64, len(scalars))
+    for i := range points {
+        if points[i] == nil || scalars[i] == nil {
+            return nil, errors.New("points and scalars must be defined")
+        }
+        table := tables[i*secretTableSize : (i+1)*secretTableSize]
+        table[0].setIdentity()
+        table[1] = *points[i].point()
+        for d := 2; d < secretTableSize; d++ {
+            table[d].add(&table[d-1], &table[1])
+        }
+        ks[i] = secretScalar(scalars[i])
+    }
+
+    var term edwardsPoint
+    result := new(edwardsPoint).setIdentity()
+    for w := 256/secretWindow - 1; w >= 0; w-- {
+        for j := 0; j < secretWindow; j++ {
+            result.double(result)
+        }
+        for i := range points {
+            d := (ks[i][w*secretWindow/64] >> uint(w*secretWindow%64)) & (secretTableSize - 1)
+            term.lookup(tables[i*secretTableSize:(i+1)*secretTableSize], d)
+            result.add(result, &term)
+        }
+    }
+    p.p = *result
+    return p, nil
+}
+
+/*
+SecretScalarMult sets p = k.a, in a time that does not depend on k.
+*/
+func (p *Point) SecretScalarMult(a *Point, k *big.Int) *Point {
+    result, _ := p.SecretMultiScalarMult([]*Point{a}, []*big.Int{k})
+    return result
+}
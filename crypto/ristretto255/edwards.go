@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ristretto255
+
+import (
+    "math/big"
+)
+
+/*
+edwardsPoint is a point of the twisted Edwards curve -x^2 + y^2 = 1 + d.x^2.y^2
+of Ed25519, in extended coordinates: x = X/Z, y = Y/Z and x.y = T/Z. The
+formulas of Hisil, Wong, Carter and Dawson are complete on this curve.
+*/
+type edwardsPoint struct {
+    x, y, z, t fieldElement
+}
+
+var (
+    feD, feD2, feSqrtM1, feSqrtADMinusOne, feInvSqrtAMinusD, feOneMinusDSquare, feDMinusOneSquare fieldElement
+    // basepoint is the generator of Ed25519, which is also the generator of Ristretto255
+    basepoint edwardsPoint
+)
+
+func init() {
+    // d = -121665/121666
+    d := new(big.Int).ModInverse(big.NewInt(121666), fieldP)
+    d.Mul(d, big.NewInt(-121665))
+    feD.setBig(d)
+    feD2.add(&feD, &feD)
+    feSqrtM1.setBig(fromDecimal("19681161376707505956807079304988542015446066515923890162744021073123829784752"))
+    feSqrtADMinusOne.setBig(fromDecimal("25063068953384623474111414158702152701244531502492656460079210482610430750235"))
+    feInvSqrtAMinusD.setBig(fromDecimal("54469307008909316920995813868745141605393597292927456921205312896311721017578"))
+    feOneMinusDSquare.square(&feD)
+    feOneMinusDSquare.sub(&feOne, &feOneMinusDSquare)
+    feDMinusOneSquare.sub(&feD, &feOne)
+    feDMinusOneSquare.square(&feDMinusOneSquare)
+
+    basepoint.x.setBig(fromDecimal("15112221349535400772501151409588531511454012693041857206046113283949847762202"))
+    basepoint.y.setBig(fromDecimal("46316835694926478169428394003475163141307993866256225615783033603165251855960"))
+    basepoint.z = feOne
+    basepoint.t.mul(&basepoint.x, &basepoint.y)
+}
+
+/*
+setIdentity sets p to the neutral element (0, 1).
+*/
+func (p *edwardsPoint) setIdentity() *edwardsPoint {
+    p.x = fieldElement{}
+    p.y = feOne
+    p.z = feOne
+    p.t = fieldElement{}
+    return p
+}
+
+/*
+add sets p = a + b.
+*/
+func (p *edwardsPoint) add(a, b *edwardsPoint) *edwardsPoint {
+    var A, B, C, D, E, F, G, H, tmp fieldElement
+    A.sub(&a.y, &a.x)
+    tmp.sub(&b.y, &b.x)
+    A.mul(&A, &tmp)
+    B.add(&a.y, &a.x)
+    tmp.add(&b.y, &b.x)
+    B.mul(&B, &tmp)
+    C.mul(&a.t, &b.t)
+    C.mul(&C, &feD2)
+    D.mul(&a.z, &b.z)
+    D.add(&D, &D)
+    E.sub(&B, &A)
+    F.sub(&D, &C)
+    G.add(&D, &C)
+    H.add(&B, &A)
+    p.x.mul(&E, &F)
+    p.y.mul(&G, &H)
+    p.t.mul(&E, &H)
+    p.z.mul(&F, &G)
+    return p
+}
+
+/*
+double sets p = 2.a.
+*/
+func (p *edwardsPoint) double(a *edwardsPoint) *edwardsPoint {
+    var A, B, C, E, F, G, H fieldElement
+    A.square(&a.x)
+    B.square(&a.y)
+    C.square(&a.z)
+    C.add(&C, &C)
+    // the coefficient of x^2 is -1, hence D = -A
+    E.add(&a.x, &a.y)
+    E.square(&E)
+    E.sub(&E, &A)
+    E.sub(&E, &B)
+    G.sub(&B, &A)
+    F.sub(&G, &C)
+    H.add(&A, &B)
+    H.neg(&H)
+    p.x.mul(&E, &F)
+    p.y.mul(&G, &H)
+    p.t.mul(&E, &H)
+    p.z.mul(&F, &G)
+    return p
+}
+
+/*
+neg sets p = -a = (-x, y).
+*/
+func (p *edwardsPoint) neg(a *edwardsPoint) *edwardsPoint {
+    p.x.neg(&a.x)
+    p.y = a.y
+    p.z = a.z
+    p.t.neg(&a.t)
+    return p
+}
+
+/*
+fromDecimal returns the integer written in base 10.
+*/
+func fromDecimal(s string) *big.Int {
+    v, ok := new(big.Int).SetString(s, 10)
+    if !ok {
+        panic("invalid constant " + s)
+    }
+    return v
+}
@@ -0,0 +1,342 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ristretto255
+
+import (
+    "encoding/binary"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/internal/arith"
+)
+
+const (
+    // fieldC is 38 = 2^256 mod P, where the field prime is P = 2^255 - 19
+    fieldC = uint64(38)
+)
+
+/*
+fieldElement is an element of the field of curve25519, represented by 4 limbs of
+64 bits in little-endian order. It is always fully reduced, i.e. lower than P,
+so that equal elements have equal limbs.
+*/
+type fieldElement [4]uint64
+
+var (
+    fieldP = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+    // fieldSqrtExponent is (P - 5) / 8
+    fieldSqrtExponent = new(big.Int).Rsh(new(big.Int).Sub(fieldP, big.NewInt(5)), 3)
+    feOne             = fieldElement{1, 0, 0, 0}
+)
+
+/*
+setBig sets z to b mod P.
+*/
+func (z *fieldElement) setBig(b *big.Int) *fieldElement {
+    v := new(big.Int).Mod(b, fieldP)
+    bytes := make([]byte, 32)
+    vb := v.Bytes()
+    for i := range vb {
+        bytes[i] = vb[len(vb)-1-i]
+    }
+    z.setBytes(bytes)
+    return z
+}
+
+/*
+setBytes sets z to the 32-byte little-endian integer b. It returns false if b is
+not lower than P, in which case z is left unchanged.
+*/
+func (z *fieldElement) setBytes(b []byte) bool {
+    if len(b) != 32 {
+        return false
+    }
+    var v fieldElement
+    for i := 0; i < 4; i++ {
+        v[i] = binary.LittleEndian.Uint64(b[8*i:])
+    }
+    // v >= P iff v + 19 >= 2^255
+    var s fieldElement
+    var c uint64
+    s[0], c = arith.Add64(v[0], 19, 0)
+    s[1], c = arith.Add64(v[1], 0, c)
+    s[2], c = arith.Add64(v[2], 0, c)
+    s[3], c = arith.Add64(v[3], 0, c)
+    if c != 0 || s[3]>>63 != 0 {
+        return false
+    }
+    *z = v
+    return true
+}
+
+/*
+setBytesReduced sets z to the 32-byte little-endian integer b, whose top bit is
+ignored, reduced modulo P.
+*/
+func (z *fieldElement) setBytesReduced(b []byte) *fieldElement {
+    var v [4]uint64
+    for i := 0; i < 4; i++ {
+        v[i] = binary.LittleEndian.Uint64(b[8*i:])
+    }
+    v[3] &= 1<<63 - 1
+    *z = reduce(v)
+    return z
+}
+
+/*
+bytes returns z as a 32-byte little-endian integer.
+*/
+func (z *fieldElement) bytes() []byte {
+    b := make([]byte, 32)
+    for i := 0; i < 4; i++ {
+        binary.LittleEndian.PutUint64(b[8*i:], z[i])
+    }
+    return b
+}
+
+/*
+big returns z as a big.Int.
+*/
+func (z *fieldElement) big() *big.Int {
+    b := z.bytes()
+    for i := 0; i < 16; i++ {
+        b[i], b[31-i] = b[31-i], b[i]
+    }
+    return new(big.Int).SetBytes(b)
+}
+
+/*
+isZero returns true if and only if z is 0.
+*/
+func (z *fieldElement) isZero() bool {
+    return z[0]|z[1]|z[2]|z[3] == 0
+}
+
+/*
+isNegative returns true if and only if z is odd, which is the sign of the field
+elements of RFC 9496.
+*/
+func (z *fieldElement) isNegative() bool {
+    return z[0]&1 == 1
+}
+
+/*
+equal returns true if and only if z = x.
+*/
+func (z *fieldElement) equal(x *fieldElement) bool {
+    return *z == *x
+}
+
+/*
+reduce returns r mod P, for any r lower than 2^256.
+*/
+func reduce(r [4]uint64) fieldElement {
+    // 2^255 = 19 mod P
+    var c uint64
+    top := r[3] >> 63
+    r[3] &= 1<<63 - 1
+    r[0], c = arith.Add64(r[0], 19*top, 0)
+    r[1], c = arith.Add64(r[1], 0, c)
+    r[2], c = arith.Add64(r[2], 0, c)
+    r[3] += c
+    // r is lower than 2^255 + 19, subtract P if r + 19 >= 2^255
+    var s fieldElement
+    s[0], c = arith.Add64(r[0], 19, 0)
+    s[1], c = arith.Add64(r[1], 0, c)
+    s[2], c = arith.Add64(r[2], 0, c)
+    s[3] = r[3] + c
+    mask := -(s[3] >> 63)
+    s[3] &= 1<<63 - 1
+    result := fieldElement(r)
+    result.cmov(&s, mask)
+    return result
+}
+
+/*
+cmov sets z to a if mask is all ones, and leaves z unchanged if mask is 0.
+*/
+func (z *fieldElement) cmov(a *fieldElement, mask uint64) {
+    for i := range z {
+        z[i] ^= mask & (z[i] ^ a[i])
+    }
+}
+
+/*
+add sets z = x + y mod P.
+*/
+func (z *fieldElement) add(x, y *fieldElement) *fieldElement {
+    // x + y < 2P < 2^256
+    var s [4]uint64
+    var c uint64
+    s[0], c = arith.Add64(x[0], y[0], 0)
+    s[1], c = arith.Add64(x[1], y[1], c)
+    s[2], c = arith.Add64(x[2], y[2], c)
+    s[3], _ = arith.Add64(x[3], y[3], c)
+    *z = reduce(s)
+    return z
+}
+
+/*
+sub sets z = x - y mod P.
+*/
+func (z *fieldElement) sub(x, y *fieldElement) *fieldElement {
+    var d [4]uint64
+    var b uint64
+    d[0], b = arith.Sub64(x[0], y[0], 0)
+    d[1], b = arith.Sub64(x[1], y[1], b)
+    d[2], b = arith.Sub64(x[2], y[2], b)
+    d[3], b = arith.Sub64(x[3], y[3], b)
+    // if the subtraction borrowed, d + 2P = d - fieldC mod 2^256
+    c := fieldC & -b
+    d[0], b = arith.Sub64(d[0], c, 0)
+    d[1], b = arith.Sub64(d[1], 0, b)
+    d[2], b = arith.Sub64(d[2], 0, b)
+    d[3], _ = arith.Sub64(d[3], 0, b)
+    *z = reduce(d)
+    return z
+}
+
+/*
+neg sets z = -x mod P.
+*/
+func (z *fieldElement) neg(x *fieldElement) *fieldElement {
+    var zero fieldElement
+    return z.sub(&zero, x)
+}
+
+/*
+abs sets z to x or -x, whichever is not negative.
+*/
+func (z *fieldElement) abs(x *fieldElement) *fieldElement {
+    if x.isNegative() {
+        return z.neg(x)
+    }
+    *z = *x
+    return z
+}
+
+/*
+mul sets z = x.y mod P. The product of 512 bits is reduced using the special form
+of P: since 2^256 = fieldC mod P, the upper half is multiplied by fieldC and
+added to the lower half, twice.
+*/
+func (z *fieldElement) mul(x, y *fieldElement) *fieldElement {
+    var t [8]uint64
+    for i := 0; i < 4; i++ {
+        var carry uint64
+        for j := 0; j < 4; j++ {
+            hi, lo := arith.Mul64(x[i], y[j])
+            var c uint64
+            lo, c = arith.Add64(lo, t[i+j], 0)
+            hi += c
+            lo, c = arith.Add64(lo, carry, 0)
+            hi += c
+            t[i+j] = lo
+            carry = hi
+        }
+        t[i+4] = carry
+    }
+
+    // r = t[0:4] + t[4:8].fieldC, which has at most 262 bits
+    var r [4]uint64
+    var carry uint64
+    for i := 0; i < 4; i++ {
+        hi, lo := arith.Mul64(t[i+4], fieldC)
+        var c uint64
+        lo, c = arith.Add64(lo, t[i], 0)
+        hi += c
+        lo, c = arith.Add64(lo, carry, 0)
+        hi += c
+        r[i] = lo
+        carry = hi
+    }
+    // fold the remaining carry, lower than 2^6
+    var c uint64
+    r[0], c = arith.Add64(r[0], carry*fieldC, 0)
+    r[1], c = arith.Add64(r[1], 0, c)
+    r[2], c = arith.Add64(r[2], 0, c)
+    r[3], c = arith.Add64(r[3], 0, c)
+    // if the sum wrapped around 2^256, r is small and adding fieldC cannot overflow
+    r[0], c = arith.Add64(r[0], fieldC&-c, 0)
+    r[1], c = arith.Add64(r[1], 0, c)
+    r[2], c = arith.Add64(r[2], 0, c)
+    r[3], _ = arith.Add64(r[3], 0, c)
+    *z = reduce(r)
+    return z
+}
+
+/*
+square sets z = x^2 mod P.
+*/
+func (z *fieldElement) square(x *fieldElement) *fieldElement {
+    return z.mul(x, x)
+}
+
+/*
+pow sets z = x^e mod P.
+*/
+func (z *fieldElement) pow(x *fieldElement, e *big.Int) *fieldElement {
+    base := *x
+    result := feOne
+    for i := e.BitLen() - 1; i >= 0; i-- {
+        result.square(&result)
+        if e.Bit(i) == 1 {
+            result.mul(&result, &base)
+        }
+    }
+    *z = result
+    return z
+}
+
+/*
+invert sets z = x^-1 mod P, for x different from 0.
+*/
+func (z *fieldElement) invert(x *fieldElement) *fieldElement {
+    return z.setBig(new(big.Int).ModInverse(x.big(), fieldP))
+}
+
+/*
+sqrtRatio sets z to the non-negative square root of u/v if it exists, and to the
+non-negative square root of SQRT_M1.u/v otherwise, following SQRT_RATIO_M1 of
+RFC 9496. It returns true if u/v is a square. If v is 0, z is 0 and the result
+is true only if u is 0.
+*/
+func (z *fieldElement) sqrtRatio(u, v *fieldElement) bool {
+    var v3, v7, r, check, negU, negUI fieldElement
+    v3.square(v)
+    v3.mul(&v3, v)
+    v7.square(&v3)
+    v7.mul(&v7, v)
+    // r = (u.v^3).(u.v^7)^((P-5)/8)
+    r.mul(u, &v7)
+    r.pow(&r, fieldSqrtExponent)
+    r.mul(&r, u)
+    r.mul(&r, &v3)
+
+    check.square(&r)
+    check.mul(&check, v)
+    negU.neg(u)
+    negUI.mul(&negU, &feSqrtM1)
+    correctSign := check.equal(u)
+    flippedSign := check.equal(&negU)
+    flippedSignI := check.equal(&negUI)
+    if flippedSign || flippedSignI {
+        r.mul(&r, &feSqrtM1)
+    }
+    z.abs(&r)
+    return correctSign || flippedSign
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ristretto255
+
+import (
+    "math/big"
+)
+
+const (
+    // strausWindow is the bit-length of the signed digits used by Straus
+    strausWindow = 4
+    // pippengerThreshold is the number of terms from which Pippenger is faster than Straus
+    pippengerThreshold = 64
+)
+
+/*
+multiScalarMult returns the sum of scalars[i].points[i], where the scalars are
+reduced and the terms equal to zero are already dropped. It uses the interleaved
+window method of Straus for few terms and the bucket method of Pippenger for
+many terms. The running time depends on the values of the scalars.
+*/
+func multiScalarMult(points []edwardsPoint, scalars []*big.Int) *edwardsPoint {
+    bits := 0
+    for i := range scalars {
+        if scalars[i].BitLen() > bits {
+            bits = scalars[i].BitLen()
+        }
+    }
+    switch {
+    case len(points) == 0:
+        return new(edwardsPoint).setIdentity()
+    case len(points) < pippengerThreshold:
+        return straus(points, scalars, bits)
+    default:
+        return pippenger(points, scalars, bits)
+    }
+}
+
+/*
+signedDigits returns the digits of k in base 2^c, least significant first, such
+that every digit lies in [-2^(c-1), 2^(c-1)). The number of digits is enough for
+any scalar of the given bit-length, including the final carry.
+*/
+func signedDigits(k *big.Int, c uint, bits int) []int {
+    windows := (bits+int(c)-1)/int(c) + 1
+    digits := make([]int, windows)
+    radix := 1 << c
+    carry := 0
+    for w := 0; w < windows; w++ {
+        d := carry
+        for b := uint(0); b < c; b++ {
+            d += int(k.Bit(w*int(c)+int(b))) << b
+        }
+        carry = 0
+        if d >= radix/2 {
+            d -= radix
+            carry = 1
+        }
+        digits[w] = d
+    }
+    return digits
+}
+
+/*
+straus computes the multi-scalar multiplication using signed digits of
+strausWindow bits and a table with the multiples 1.P to 2^(strausWindow-1).P of
+each point.
+*/
+func straus(points []edwardsPoint, scalars []*big.Int, bits int) *edwardsPoint {
+    n := len(points)
+    size := 1 << (strausWindow - 1)
+    table := make([]edwardsPoint, n*size)
+    digits := make([][]int, n)
+    for i := 0; i < n; i++ {
+        table[i*size] = points[i]
+        for j := 1; j < size; j++ {
+            table[i*size+j].add(&table[i*size+j-1], &points[i])
+        }
+        digits[i] = signedDigits(scalars[i], strausWindow, bits)
+    }
+
+    var negated edwardsPoint
+    result := new(edwardsPoint).setIdentity()
+    for w := len(digits[0]) - 1; w >= 0; w-- {
+        for j := 0; j < strausWindow; j++ {
+            result.double(result)
+        }
+        for i := 0; i < n; i++ {
+            d := digits[i][w]
+            if d > 0 {
+                result.add(result, &table[i*size+d-1])
+            } else if d < 0 {
+                result.add(result, negated.neg(&table[i*size-d-1]))
+            }
+        }
+    }
+    return result
+}
+
+/*
+pippengerWindow returns the bit-length c of the digits that minimizes the
+number of additions of Pippenger, namely (bits/c).(n + 2^c).
+*/
+func pippengerWindow(n, bits int) uint {
+    best, bestCost := uint(2), -1
+    for c := uint(2); c <= 16; c++ {
+        cost := ((bits+int(c)-1)/int(c) + 1) * (n + (1 << c))
+        if bestCost < 0 || cost < bestCost {
+            best, bestCost = c, cost
+        }
+    }
+    return best
+}
+
+/*
+pippenger computes the multi-scalar multiplication using the bucket method. For
+each window of c bits, every point is added to the bucket of its digit, and the
+buckets are combined with a running sum, so that the j-th bucket is counted j times.
+*/
+func pippenger(points []edwardsPoint, scalars []*big.Int, bits int) *edwardsPoint {
+    n := len(points)
+    c := pippengerWindow(n, bits)
+    digits := make([][]int, n)
+    negated := make([]edwardsPoint, n)
+    for i := 0; i < n; i++ {
+        digits[i] = signedDigits(scalars[i], c, bits)
+        negated[i].neg(&points[i])
+    }
+    buckets := make([]edwardsPoint, 1<<(c-1))
+    var sum, acc edwardsPoint
+
+    result := new(edwardsPoint).setIdentity()
+    for w := len(digits[0]) - 1; w >= 0; w-- {
+        for j := uint(0); j < c; j++ {
+            result.double(result)
+        }
+        for b := range buckets {
+            buckets[b].setIdentity()
+        }
+        for i := 0; i < n; i++ {
+            d := digits[i][w]
+            if d > 0 {
+                buckets[d-1].add(&buckets[d-1], &points[i])
+            } else if d < 0 {
+                buckets[-d-1].add(&buckets[-d-1], &negated[i])
+            }
+        }
+        sum.setIdentity()
+        acc.setIdentity()
+        for b := len(buckets) - 1; b >= 0; b-- {
+            sum.add(&sum, &buckets[b])
+            acc.add(&acc, &sum)
+        }
+        result.add(result, &acc)
+    }
+    return result
+}
@@ -0,0 +1,334 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package ristretto255 implements the prime-order group Ristretto255 of RFC 9496,
+built on the Edwards form of curve25519. It is compatible with the RistrettoPoint
+of the Rust crate curve25519-dalek, so that it can be used to exchange proofs
+with the libraries of dalek-cryptography.
+*/
+package ristretto255
+
+import (
+    "encoding/hex"
+    "errors"
+    "math/big"
+)
+
+const (
+    // EncodingLength is the length of the canonical encoding of a point.
+    EncodingLength = 32
+    // UniformLength is the number of uniform bytes mapped by FromUniformBytes.
+    UniformLength = 64
+)
+
+/*
+Order is the prime order of the group, 2^252 + 27742317777372353535851937790883648493.
+*/
+var Order = fromDecimal("7237005577332262213973186563042994240857116359379907606001950938285454250989")
+
+/*
+Point is an element of Ristretto255. The zero value is the identity. Two points
+are equal if and only if they have the same encoding, even though they may be
+represented by different points of the curve.
+*/
+type Point struct {
+    p edwardsPoint
+}
+
+/*
+NewIdentity returns the identity.
+*/
+func NewIdentity() *Point {
+    p := new(Point)
+    p.p.setIdentity()
+    return p
+}
+
+/*
+NewGenerator returns the standard generator, which is the image of the base
+point of Ed25519.
+*/
+func NewGenerator() *Point {
+    return &Point{p: basepoint}
+}
+
+/*
+point returns the point of the curve of p, which is the identity for the zero value.
+*/
+func (p *Point) point() *edwardsPoint {
+    if p.p.z.isZero() {
+        return new(edwardsPoint).setIdentity()
+    }
+    return &p.p
+}
+
+/*
+Set sets p = a.
+*/
+func (p *Point) Set(a *Point) *Point {
+    p.p = *a.point()
+    return p
+}
+
+/*
+Add sets p = a + b.
+*/
+func (p *Point) Add(a, b *Point) *Point {
+    p.p.add(a.point(), b.point())
+    return p
+}
+
+/*
+Sub sets p = a - b.
+*/
+func (p *Point) Sub(a, b *Point) *Point {
+    var negated edwardsPoint
+    p.p.add(a.point(), negated.neg(b.point()))
+    return p
+}
+
+/*
+Neg sets p = -a.
+*/
+func (p *Point) Neg(a *Point) *Point {
+    p.p.neg(a.point())
+    return p
+}
+
+/*
+ScalarMult sets p = k.a, for any integer k.
+*/
+func (p *Point) ScalarMult(a *Point, k *big.Int) *Point {
+    return p.multiScalarMult([]*Point{a}, []*big.Int{k})
+}
+
+/*
+ScalarBaseMult sets p = k.G, where G is the generator.
+*/
+func (p *Point) ScalarBaseMult(k *big.Int) *Point {
+    return p.ScalarMult(NewGenerator(), k)
+}
+
+/*
+MultiScalarMult sets p to the sum of scalars[i].points[i], for every i. The
+scalars are not secret: the running time depends on their values.
+*/
+func (p *Point) MultiScalarMult(points []*Point, scalars []*big.Int) (*Point, error) {
+    if len(points) != len(scalars) {
+        return nil, errors.New("number of points is different from the number of scalars")
+    }
+    for i := range points {
+        if points[i] == nil || scalars[i] == nil {
+            return nil, errors.New("points and scalars must be defined")
+        }
+    }
+    return p.multiScalarMult(points, scalars), nil
+}
+
+func (p *Point) multiScalarMult(points []*Point, scalars []*big.Int) *Point {
+    // Drop the terms that are zero and use (-k).(-P) whenever -k is shorter than k
+    half := new(big.Int).Rsh(Order, 1)
+    ps := make([]edwardsPoint, 0, len(points))
+    ks := make([]*big.Int, 0, len(points))
+    for i := range points {
+        k := new(big.Int).Mod(scalars[i], Order)
+        if k.Sign() == 0 {
+            continue
+        }
+        point := *points[i].point()
+        if k.Cmp(half) > 0 {
+            k.Sub(Order, k)
+            point.neg(&point)
+        }
+        ps = append(ps, point)
+        ks = append(ks, k)
+    }
+    p.p = *multiScalarMult(ps, ks)
+    return p
+}
+
+/*
+Equal returns true if and only if p and q are the same element of the group.
+*/
+func (p *Point) Equal(q *Point) bool {
+    a, b := p.point(), q.point()
+    var x1y2, y1x2, y1y2, x1x2 fieldElement
+    x1y2.mul(&a.x, &b.y)
+    y1x2.mul(&a.y, &b.x)
+    y1y2.mul(&a.y, &b.y)
+    x1x2.mul(&a.x, &b.x)
+    return x1y2.equal(&y1x2) || y1y2.equal(&x1x2)
+}
+
+/*
+IsIdentity returns true if and only if p is the identity.
+*/
+func (p *Point) IsIdentity() bool {
+    return p.Equal(NewIdentity())
+}
+
+/*
+Marshal returns the canonical encoding of p with 32 bytes, section 4.3.2 of RFC
+9496. The identity is encoded as 32 zero bytes.
+*/
+func (p *Point) Marshal() []byte {
+    e := p.point()
+    var u1, u2, tmp, invSqrt, den1, den2, zInv, x, y, denInv, s fieldElement
+    // u1 = (Z + Y).(Z - Y), u2 = X.Y
+    u1.add(&e.z, &e.y)
+    tmp.sub(&e.z, &e.y)
+    u1.mul(&u1, &tmp)
+    u2.mul(&e.x, &e.y)
+    tmp.square(&u2)
+    tmp.mul(&tmp, &u1)
+    invSqrt.sqrtRatio(&feOne, &tmp)
+    den1.mul(&invSqrt, &u1)
+    den2.mul(&invSqrt, &u2)
+    zInv.mul(&den1, &den2)
+    zInv.mul(&zInv, &e.t)
+
+    tmp.mul(&e.t, &zInv)
+    if tmp.isNegative() {
+        // rotate by the 4-torsion point (SQRT_M1, 0)
+        x.mul(&e.y, &feSqrtM1)
+        y.mul(&e.x, &feSqrtM1)
+        denInv.mul(&den1, &feInvSqrtAMinusD)
+    } else {
+        x = e.x
+        y = e.y
+        denInv = den2
+    }
+    tmp.mul(&x, &zInv)
+    if tmp.isNegative() {
+        y.neg(&y)
+    }
+    s.sub(&e.z, &y)
+    s.mul(&s, &denInv)
+    s.abs(&s)
+    return s.bytes()
+}
+
+/*
+Unmarshal sets p to the point encoded by Marshal, section 4.3.1 of RFC 9496. It
+fails on every encoding that is not canonical.
+*/
+func (p *Point) Unmarshal(data []byte) (*Point, error) {
+    var s fieldElement
+    if len(data) != EncodingLength {
+        return nil, errors.New("invalid length of the ristretto255 encoding")
+    }
+    if !s.setBytes(data) || s.isNegative() {
+        return nil, errors.New("invalid ristretto255 encoding")
+    }
+    var ss, u1, u2, u2Square, v, tmp, invSqrt, denX, denY, x, y, t fieldElement
+    ss.square(&s)
+    u1.sub(&feOne, &ss)
+    u2.add(&feOne, &ss)
+    u2Square.square(&u2)
+    // v = -(D.u1^2) - u2^2
+    v.square(&u1)
+    v.mul(&v, &feD)
+    v.neg(&v)
+    v.sub(&v, &u2Square)
+    tmp.mul(&v, &u2Square)
+    wasSquare := invSqrt.sqrtRatio(&feOne, &tmp)
+    denX.mul(&invSqrt, &u2)
+    denY.mul(&invSqrt, &denX)
+    denY.mul(&denY, &v)
+    x.add(&s, &s)
+    x.mul(&x, &denX)
+    x.abs(&x)
+    y.mul(&u1, &denY)
+    t.mul(&x, &y)
+    if !wasSquare || t.isNegative() || y.isZero() {
+        return nil, errors.New("invalid ristretto255 encoding")
+    }
+    p.p = edwardsPoint{x: x, y: y, z: feOne, t: t}
+    return p, nil
+}
+
+/*
+FromUniformBytes sets p to the point derived from 64 uniformly random bytes,
+section 4.3.4 of RFC 9496, which is the function from_uniform_bytes of
+curve25519-dalek. The discrete logarithm of the result is unknown.
+*/
+func (p *Point) FromUniformBytes(b []byte) (*Point, error) {
+    if len(b) != UniformLength {
+        return nil, errors.New("invalid length of the uniform bytes")
+    }
+    var r0, r1 fieldElement
+    r0.setBytesReduced(b[:32])
+    r1.setBytesReduced(b[32:])
+    p.p.add(elligator(&r0), elligator(&r1))
+    return p, nil
+}
+
+/*
+String returns the hex encoding of the point.
+*/
+func (p *Point) String() string {
+    return "ristretto255(" + hex.EncodeToString(p.Marshal()) + ")"
+}
+
+/*
+elligator is the map MAP of section 4.3.4 of RFC 9496.
+*/
+func elligator(t *fieldElement) *edwardsPoint {
+    var r, u, v, tmp, s, c, n, w0, w1, w2, w3, sSquare fieldElement
+    // r = SQRT_M1.t^2
+    r.square(t)
+    r.mul(&r, &feSqrtM1)
+    // u = (r + 1).ONE_MINUS_D_SQ
+    u.add(&r, &feOne)
+    u.mul(&u, &feOneMinusDSquare)
+    // v = (-1 - r.D).(r + D)
+    v.mul(&r, &feD)
+    v.add(&v, &feOne)
+    v.neg(&v)
+    tmp.add(&r, &feD)
+    v.mul(&v, &tmp)
+
+    wasSquare := s.sqrtRatio(&u, &v)
+    if wasSquare {
+        c.neg(&feOne)
+    } else {
+        // s = -|s.t|
+        s.mul(&s, t)
+        s.abs(&s)
+        s.neg(&s)
+        c = r
+    }
+    // N = c.(r - 1).D_MINUS_ONE_SQ - v
+    n.sub(&r, &feOne)
+    n.mul(&n, &c)
+    n.mul(&n, &feDMinusOneSquare)
+    n.sub(&n, &v)
+
+    w0.add(&s, &s)
+    w0.mul(&w0, &v)
+    w1.mul(&n, &feSqrtADMinusOne)
+    sSquare.square(&s)
+    w2.sub(&feOne, &sSquare)
+    w3.add(&feOne, &sSquare)
+    result := new(edwardsPoint)
+    result.x.mul(&w0, &w3)
+    result.y.mul(&w2, &w1)
+    result.z.mul(&w1, &w3)
+    result.t.mul(&w0, &w2)
+    return result
+}
@@ -4,5 +4,7 @@ go 1.13
 
 require (
 	github.com/ethereum/go-ethereum v1.9.10
+	github.com/mimoo/StrobeGo v0.0.0-20181016162300-f8f6d4d2b643
 	github.com/stretchr/testify v1.4.0
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
 )
@@ -0,0 +1,46 @@
+//go:build go1.12
+// +build go1.12
+
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package arith
+
+import (
+    "math/bits"
+)
+
+/*
+Mul64 returns the 128-bit product of x and y.
+*/
+func Mul64(x, y uint64) (hi, lo uint64) {
+    return bits.Mul64(x, y)
+}
+
+/*
+Add64 returns x + y + carry and the carry out, where carry is 0 or 1.
+*/
+func Add64(x, y, carry uint64) (sum, carryOut uint64) {
+    return bits.Add64(x, y, carry)
+}
+
+/*
+Sub64 returns x - y - borrow and the borrow out, where borrow is 0 or 1.
+*/
+func Sub64(x, y, borrow uint64) (diff, borrowOut uint64) {
+    return bits.Sub64(x, y, borrow)
+}
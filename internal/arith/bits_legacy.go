@@ -0,0 +1,59 @@
+//go:build !go1.12
+// +build !go1.12
+
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package arith
+
+/*
+Mul64 returns the 128-bit product of x and y. Go versions before 1.12 do not
+provide math/bits.Mul64, so it is computed from 32-bit halves.
+*/
+func Mul64(x, y uint64) (hi, lo uint64) {
+    const mask32 = 1<<32 - 1
+    x0 := x & mask32
+    x1 := x >> 32
+    y0 := y & mask32
+    y1 := y >> 32
+    w0 := x0 * y0
+    t := x1*y0 + w0>>32
+    w1 := t & mask32
+    w2 := t >> 32
+    w1 += x0 * y1
+    hi = x1*y1 + w2 + w1>>32
+    lo = x * y
+    return
+}
+
+/*
+Add64 returns x + y + carry and the carry out, where carry is 0 or 1.
+*/
+func Add64(x, y, carry uint64) (sum, carryOut uint64) {
+    sum = x + y + carry
+    carryOut = ((x & y) | ((x | y) &^ sum)) >> 63
+    return
+}
+
+/*
+Sub64 returns x - y - borrow and the borrow out, where borrow is 0 or 1.
+*/
+func Sub64(x, y, borrow uint64) (diff, borrowOut uint64) {
+    diff = x - y - borrow
+    borrowOut = ((^x & y) | (^(x ^ y) & diff)) >> 63
+    return
+}
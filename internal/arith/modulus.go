@@ -0,0 +1,297 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package arith provides the fixed-width arithmetic of crypto/p256,
+crypto/ristretto255 and crypto/bn256: the 64-bit operations with carries of
+math/bits, also for Go versions before 1.12, and the Montgomery arithmetic
+modulo any odd modulus of at most 256 bits.
+*/
+package arith
+
+import (
+    "errors"
+    "math/big"
+)
+
+const (
+
+    // wordBits is the bit-length of a big.Word
+    wordBits = 32 << (^uint(0) >> 63)
+)
+
+/*
+Modulus is an odd modulus of at most 256 bits, such as the order of a group or
+the prime of a field, with the constants of the Montgomery multiplication modulo
+it. Its operations compute on Residue in a time that does not depend on the
+values, so that residues can hold secrets.
+*/
+type Modulus struct {
+    n [4]uint64
+    // nInv is -n^-1 mod 2^64
+    nInv uint64
+    // rr is R^2 mod n, where R = 2^256
+    rr Residue
+    // one is R mod n, the Montgomery form of 1
+    one Residue
+    big *big.Int
+}
+
+/*
+Residue is an integer modulo a Modulus in Montgomery form, x.R mod n with R = 2^256,
+represented by 4 limbs of 64 bits in little-endian order. It is always fully
+reduced and the zero value is 0.
+*/
+type Residue [4]uint64
+
+/*
+NewModulus returns the modulus n, which must be odd and lower than 2^256.
+*/
+func NewModulus(n *big.Int) (*Modulus, error) {
+    if n.Sign() <= 0 || n.Bit(0) == 0 || n.BitLen() > 256 {
+        return nil, errors.New("modulus must be odd and lower than 2^256")
+    }
+    m := &Modulus{big: new(big.Int).Set(n)}
+    m.n = limbs(n)
+    // Newton's iteration doubles the number of correct low bits of n^-1 mod 2^64
+    inv := uint64(1)
+    for i := 0; i < 6; i++ {
+        inv *= 2 - m.n[0]*inv
+    }
+    m.nInv = -inv
+    r := new(big.Int).Lsh(big.NewInt(1), 256)
+    m.one = limbs(new(big.Int).Mod(r, n))
+    m.rr = limbs(new(big.Int).Mod(new(big.Int).Mul(r, r), n))
+    return m, nil
+}
+
+/*
+Big returns the modulus as a big.Int.
+*/
+func (m *Modulus) Big() *big.Int {
+    return new(big.Int).Set(m.big)
+}
+
+/*
+limbs returns the limbs of b, which must be lower than 2^256.
+*/
+func limbs(b *big.Int) [4]uint64 {
+    var l [4]uint64
+    for i, w := range b.Bits() {
+        l[i*wordBits/64] |= uint64(w) << uint(i*wordBits%64)
+    }
+    return l
+}
+
+/*
+SetBig sets z to b mod n. The conversion of an integer between 0 and 2^256 only
+depends on the number of its words.
+*/
+func (m *Modulus) SetBig(z *Residue, b *big.Int) *Residue {
+    if b.Sign() < 0 || b.BitLen() > 256 {
+        b = new(big.Int).Mod(b, m.big)
+    }
+    // b < 2^256 and rr < n, so that the product is reduced modulo n
+    x := Residue(limbs(b))
+    return m.Mul(z, &x, &m.rr)
+}
+
+/*
+SetUint64 sets z to v mod n.
+*/
+func (m *Modulus) SetUint64(z *Residue, v uint64) *Residue {
+    x := Residue{v, 0, 0, 0}
+    return m.Mul(z, &x, &m.rr)
+}
+
+/*
+ToBig returns x as a big.Int between 0 and n - 1.
+*/
+func (m *Modulus) ToBig(x *Residue) *big.Int {
+    var l Residue
+    one := Residue{1, 0, 0, 0}
+    m.Mul(&l, x, &one)
+    words := make([]big.Word, 256/wordBits)
+    for i := range words {
+        words[i] = big.Word(l[i*wordBits/64] >> uint(i*wordBits%64))
+    }
+    return new(big.Int).SetBits(words)
+}
+
+/*
+Add sets z = x + y mod n.
+*/
+func (m *Modulus) Add(z, x, y *Residue) *Residue {
+    var s, t Residue
+    var c, b uint64
+    s[0], c = Add64(x[0], y[0], 0)
+    s[1], c = Add64(x[1], y[1], c)
+    s[2], c = Add64(x[2], y[2], c)
+    s[3], c = Add64(x[3], y[3], c)
+    t[0], b = Sub64(s[0], m.n[0], 0)
+    t[1], b = Sub64(s[1], m.n[1], b)
+    t[2], b = Sub64(s[2], m.n[2], b)
+    t[3], b = Sub64(s[3], m.n[3], b)
+    // s >= n if the sum overflowed 2^256 or if s - n did not borrow
+    *z = s
+    z.cmov(&t, -(c | (b ^ 1)))
+    return z
+}
+
+/*
+Sub sets z = x - y mod n.
+*/
+func (m *Modulus) Sub(z, x, y *Residue) *Residue {
+    var d Residue
+    var b, c uint64
+    d[0], b = Sub64(x[0], y[0], 0)
+    d[1], b = Sub64(x[1], y[1], b)
+    d[2], b = Sub64(x[2], y[2], b)
+    d[3], b = Sub64(x[3], y[3], b)
+    // add n if the subtraction borrowed
+    mask := -b
+    d[0], c = Add64(d[0], m.n[0]&mask, 0)
+    d[1], c = Add64(d[1], m.n[1]&mask, c)
+    d[2], c = Add64(d[2], m.n[2]&mask, c)
+    d[3], _ = Add64(d[3], m.n[3]&mask, c)
+    *z = d
+    return z
+}
+
+/*
+Neg sets z = -x mod n.
+*/
+func (m *Modulus) Neg(z, x *Residue) *Residue {
+    var zero Residue
+    return m.Sub(z, &zero, x)
+}
+
+/*
+Mul sets z = x.y mod n, with the Montgomery multiplication of Koç, Acar and
+Kaliski (CIOS), which computes x.y.R^-1 mod n.
+*/
+func (m *Modulus) Mul(z, x, y *Residue) *Residue {
+    var t [6]uint64
+    for i := 0; i < 4; i++ {
+        // t = t + x.y[i]
+        var c uint64
+        for j := 0; j < 4; j++ {
+            hi, lo := Mul64(x[j], y[i])
+            var cc uint64
+            lo, cc = Add64(lo, t[j], 0)
+            hi += cc
+            lo, cc = Add64(lo, c, 0)
+            hi += cc
+            t[j], c = lo, hi
+        }
+        t[4], c = Add64(t[4], c, 0)
+        t[5] = c
+
+        // t = (t + q.n) / 2^64, where q is chosen such that the division is exact
+        q := t[0] * m.nInv
+        hi, lo := Mul64(q, m.n[0])
+        _, cc := Add64(lo, t[0], 0)
+        c = hi + cc
+        for j := 1; j < 4; j++ {
+            hi, lo = Mul64(q, m.n[j])
+            lo, cc = Add64(lo, t[j], 0)
+            hi += cc
+            lo, cc = Add64(lo, c, 0)
+            hi += cc
+            t[j-1], c = lo, hi
+        }
+        t[3], cc = Add64(t[4], c, 0)
+        t[4] = t[5] + cc
+    }
+
+    // t < 2n, subtract n unless it borrows
+    var d Residue
+    var b uint64
+    d[0], b = Sub64(t[0], m.n[0], 0)
+    d[1], b = Sub64(t[1], m.n[1], b)
+    d[2], b = Sub64(t[2], m.n[2], b)
+    d[3], b = Sub64(t[3], m.n[3], b)
+    _, b = Sub64(t[4], 0, b)
+    *z = Residue{t[0], t[1], t[2], t[3]}
+    z.cmov(&d, b-1)
+    return z
+}
+
+/*
+Square sets z = x^2 mod n.
+*/
+func (m *Modulus) Square(z, x *Residue) *Residue {
+    return m.Mul(z, x, x)
+}
+
+/*
+Exp sets z = x^e mod n, where the exponent e is public.
+*/
+func (m *Modulus) Exp(z, x *Residue, e *big.Int) *Residue {
+    result := m.one
+    base := *x
+    for i := e.BitLen() - 1; i >= 0; i-- {
+        m.Square(&result, &result)
+        if e.Bit(i) == 1 {
+            m.Mul(&result, &result, &base)
+        }
+    }
+    *z = result
+    return z
+}
+
+/*
+Invert sets z = x^-1 mod n, for a prime n, as x^(n-2) by Fermat's little
+theorem. The inverse of 0 is 0.
+*/
+func (m *Modulus) Invert(z, x *Residue) *Residue {
+    return m.Exp(z, x, new(big.Int).Sub(m.big, big.NewInt(2)))
+}
+
+/*
+One sets z = 1.
+*/
+func (m *Modulus) One(z *Residue) *Residue {
+    *z = m.one
+    return z
+}
+
+/*
+IsZero returns 1 if x is 0, and 0 otherwise.
+*/
+func (x *Residue) IsZero() uint64 {
+    v := x[0] | x[1] | x[2] | x[3]
+    return ((v | -v) >> 63) ^ 1
+}
+
+/*
+Select sets z to a if cond is 1 and to b if cond is 0.
+*/
+func (z *Residue) Select(a, b *Residue, cond uint64) *Residue {
+    *z = *b
+    z.cmov(a, -cond)
+    return z
+}
+
+/*
+cmov sets z to a if mask is all ones, and leaves z unchanged if mask is 0.
+*/
+func (z *Residue) cmov(a *Residue, mask uint64) {
+    for i := range z {
+        z[i] ^= mask & (z[i] ^ a[i])
+    }
+}
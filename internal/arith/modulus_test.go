@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package arith
+
+import (
+    "crypto/elliptic"
+    "crypto/rand"
+    "math/big"
+    "testing"
+)
+
+func fromDecimal(s string) *big.Int {
+    n, _ := new(big.Int).SetString(s, 10)
+    return n
+}
+
+var moduli = []*big.Int{
+    // the orders of secp256k1, P-256, bn256 and ristretto255, and the prime of bn256
+    fromDecimal("115792089237316195423570985008687907852837564279074904382605163141518161494337"),
+    elliptic.P256().Params().N,
+    elliptic.P256().Params().P,
+    fromDecimal("21888242871839275222246405745257275088548364400416034343698204186575808495617"),
+    fromDecimal("7237005577332262213973186563042994240857116359379907606001950938285454250989"),
+    fromDecimal("21888242871839275222246405745257275088696311157297823662689037894645226208583"),
+    big.NewInt(101),
+}
+
+func TestModulusArithmetic(t *testing.T) {
+    for _, n := range moduli {
+        m, err := NewModulus(n)
+        if err != nil {
+            t.Fatalf("Unexpected error: %s", err)
+        }
+        nm1 := new(big.Int).Sub(n, big.NewInt(1))
+        values := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), nm1, n, big.NewInt(-1),
+            new(big.Int).Lsh(big.NewInt(1), 256), new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))}
+        for i := 0; i < 32; i++ {
+            v, _ := rand.Int(rand.Reader, n)
+            values = append(values, v)
+        }
+        for _, a := range values {
+            for _, b := range values[:10] {
+                var x, y, z Residue
+                m.SetBig(&x, a)
+                m.SetBig(&y, b)
+                ops := []struct {
+                    name     string
+                    actual   *Residue
+                    expected *big.Int
+                }{
+                    {"+", m.Add(new(Residue), &x, &y), new(big.Int).Add(a, b)},
+                    {"-", m.Sub(new(Residue), &x, &y), new(big.Int).Sub(a, b)},
+                    {"*", m.Mul(new(Residue), &x, &y), new(big.Int).Mul(a, b)},
+                }
+                for _, op := range ops {
+                    op.expected.Mod(op.expected, n)
+                    if m.ToBig(op.actual).Cmp(op.expected) != 0 {
+                        t.Errorf("Assert failure: %s %s %s mod %s, expected %s, actual: %s", a, op.name, b, n, op.expected, m.ToBig(op.actual))
+                    }
+                }
+                z.Select(&x, &y, 1)
+                if z != x || *z.Select(&x, &y, 0) != y {
+                    t.Errorf("Assert failure: Select")
+                }
+            }
+            var x, z Residue
+            m.SetBig(&x, a)
+            expected := new(big.Int).Mod(a, n)
+            if expected.Sign() != 0 && n.ProbablyPrime(20) {
+                expected.ModInverse(expected, n)
+                if m.ToBig(m.Invert(&z, &x)).Cmp(expected) != 0 {
+                    t.Errorf("Assert failure: %s^-1 mod %s, expected %s, actual: %s", a, n, expected, m.ToBig(&z))
+                }
+            }
+            if (x.IsZero() == 1) != (new(big.Int).Mod(a, n).Sign() == 0) {
+                t.Errorf("Assert failure: IsZero of %s", a)
+            }
+        }
+    }
+}
+
+func TestInvalidModulus(t *testing.T) {
+    for _, n := range []*big.Int{big.NewInt(0), big.NewInt(-7), big.NewInt(100), new(big.Int).Lsh(big.NewInt(1), 257)} {
+        if _, err := NewModulus(n); err == nil {
+            t.Errorf("Assert failure: modulus %s accepted", n)
+        }
+    }
+}
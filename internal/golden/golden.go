@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package golden reads and writes the known-answer vectors of the tests, which
+are stored as JSON in the testdata directory of every package. The tests
+recompute every vector and compare it with the stored one; running them with
+-update rewrites the files, so that a change of the outputs is explicit in the
+diff of testdata.
+*/
+package golden
+
+import (
+    "encoding/hex"
+    "encoding/json"
+    "flag"
+    "io"
+    "io/ioutil"
+    "testing"
+
+    "github.com/ing-bank/zkrp/util"
+)
+
+var update = flag.Bool("update", false, "rewrite the known-answer vectors in testdata")
+
+/*
+Reader returns the DeterministicReader instantiated with the hex strings seed
+and data, from which a vector reads all its random values.
+*/
+func Reader(seed, data string) io.Reader {
+    s, _ := hex.DecodeString(seed)
+    d, _ := hex.DecodeString(data)
+    return util.NewDeterministicReader(s, d)
+}
+
+/*
+Update writes the vectors returned by compute to file as indented JSON, when the
+tests run with -update.
+*/
+func Update(t testing.TB, file string, compute func() interface{}) {
+    if !*update {
+        return
+    }
+    data, err := json.MarshalIndent(compute(), "", "  ")
+    if err != nil {
+        t.Fatalf("Could not encode the vectors: %s", err)
+    }
+    if err = ioutil.WriteFile(file, append(data, '\n'), 0644); err != nil {
+        t.Fatalf("Could not write the vectors: %s", err)
+    }
+}
+
+/*
+Read decodes the vectors of file into vectors, which must be a pointer.
+*/
+func Read(t testing.TB, file string, vectors interface{}) {
+    data, err := ioutil.ReadFile(file)
+    if err != nil {
+        t.Fatalf("Could not read the vectors: %s", err)
+    }
+    if err = json.Unmarshal(data, vectors); err != nil {
+        t.Fatalf("Could not decode the vectors: %s", err)
+    }
+}
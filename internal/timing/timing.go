@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package timing measures whether the running time of a function depends on its
+secret input, for the tests of the constant-time scalar multiplications.
+*/
+package timing
+
+import (
+    "crypto/rand"
+    "math"
+    "math/big"
+    "runtime/debug"
+    "sort"
+    "time"
+)
+
+/*
+TStatistic measures f on inputs of two classes, in the style of dudect
+(Reparaz, Balasch and Verbauwhede, "Dude, is my code constant time?"): the class
+of every measurement is random, class 0 uses the input fixed and class 1 random
+inputs lower than order. It returns Welch's t-statistic of the two
+distributions of durations, after discarding the measurements above the 80th
+percentile, which are mostly caused by the scheduler. A t-statistic above 10 in
+absolute value is a leak.
+*/
+func TStatistic(measurements int, order, fixed *big.Int, f func(k *big.Int)) float64 {
+    classes := make([]byte, measurements)
+    inputs := make([]*big.Int, measurements)
+    rand.Read(classes)
+    for i := range inputs {
+        classes[i] &= 1
+        if classes[i] == 0 {
+            inputs[i] = fixed
+        } else {
+            inputs[i], _ = rand.Int(rand.Reader, order)
+        }
+    }
+    durations := make([]float64, measurements)
+    defer debug.SetGCPercent(debug.SetGCPercent(-1))
+    for i := range inputs {
+        start := time.Now()
+        f(inputs[i])
+        durations[i] = float64(time.Since(start))
+    }
+
+    sorted := append([]float64{}, durations...)
+    sort.Float64s(sorted)
+    threshold := sorted[len(sorted)*8/10]
+    var n, mean, m2 [2]float64
+    for i, d := range durations {
+        if d > threshold {
+            continue
+        }
+        // Welford's online mean and variance
+        c := classes[i]
+        n[c]++
+        delta := d - mean[c]
+        mean[c] += delta / n[c]
+        m2[c] += delta * (d - mean[c])
+    }
+    v0 := m2[0] / (n[0] - 1)
+    v1 := m2[1] / (n[1] - 1)
+    return (mean[0] - mean[1]) / math.Sqrt(v0/n[0]+v1/n[1])
+}
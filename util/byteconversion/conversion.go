@@ -121,3 +121,24 @@ func FlipBytes(bytesIn []byte) []byte {
     }
     return flippedBytes
 }
+
+/**
+ * Returns the unsigned big-endian representation of the non-negative big.Int,
+ * left padded with zeros to the given length. This is the fixed-width encoding
+ * used for elliptic curve coordinates and scalars.
+ */
+func ToFixedByteArray(in *big.Int, length int) ([]byte, error) {
+
+    if in.Sign() < 0 {
+        return nil, errors.New("Cannot convert negative big.Int to fixed-length byte array.")
+    }
+
+    bytes := in.Bytes()
+    if len(bytes) > length {
+        return nil, errors.New("big.Int does not fit into the given length.")
+    }
+
+    convertedBytes := make([]byte, length)
+    copy(convertedBytes[length-len(bytes):], bytes)
+    return convertedBytes, nil
+}
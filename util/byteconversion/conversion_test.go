@@ -183,3 +183,30 @@ func TestToBytesPos39568(t *testing.T) {
         t.Error("Assert failure: incorrect byte-array:", actualBytes)
     }
 }
+
+func TestToFixedByteArray(t *testing.T) {
+
+    actualBytes, err := ToFixedByteArray(big.NewInt(258), 4)
+    expectedBytes := []byte{0, 0, 1, 2}
+
+    if err != nil {
+        t.Error("Unexpected error.")
+    }
+
+    if !reflect.DeepEqual(actualBytes, expectedBytes) {
+        t.Error("Assert failure: incorrect bytes: ", actualBytes)
+    }
+}
+
+func TestToFixedByteArrayTooLarge(t *testing.T) {
+
+    _, err := ToFixedByteArray(big.NewInt(65536), 2)
+    if err == nil {
+        t.Error("Expected error for value larger than the given length.")
+    }
+
+    _, err = ToFixedByteArray(big.NewInt(-1), 2)
+    if err == nil {
+        t.Error("Expected error for negative value.")
+    }
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package util
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/binary"
+    "errors"
+    "io"
+    "math/big"
+)
+
+/*
+RandomScalar returns a uniformly distributed integer in [0, order), read from the
+given source of randomness. It reads the byte-length of order plus 16 bytes and
+reduces them modulo order, so that the bias is negligible and the result only
+depends on the bytes produced by random.
+*/
+func RandomScalar(random io.Reader, order *big.Int) (*big.Int, error) {
+    if random == nil || order == nil || order.Sign() <= 0 {
+        return nil, errors.New("invalid source of randomness or order")
+    }
+    buffer := make([]byte, (order.BitLen()+7)/8+16)
+    _, err := io.ReadFull(random, buffer)
+    if err != nil {
+        return nil, err
+    }
+    result := new(big.Int).SetBytes(buffer)
+    return result.Mod(result, order), nil
+}
+
+/*
+DeterministicReader is a deterministic source of randomness, implemented as the
+HMAC-DRBG of NIST SP 800-90A with SHA-256. It is instantiated as in Section 3.2
+of RFC 6979, where int2octets(x) || bits2octets(h1) is replaced by the secret seed
+and arbitrary data, each prefixed with its length. RFC 6979 does not need the
+lengths since x and h1 have a fixed width, but the seed and the data do not. Each
+call to Read is a single generate request.
+*/
+type DeterministicReader struct {
+    k, v []byte
+}
+
+/*
+NewDeterministicReader returns the deterministic source of randomness for the
+secret seed and the additional data, which should contain the secret and the
+statement that are being proven. Proofs computed with the same seed and data
+are identical, so the seed must not be reused for different secrets. Every input
+is prefixed with its length on 8 bytes, so that different splits of the same
+bytes into seed and data give different outputs.
+*/
+func NewDeterministicReader(seed []byte, data ...[]byte) *DeterministicReader {
+    material := appendWithLength(nil, seed)
+    for i := range data {
+        material = appendWithLength(material, data[i])
+    }
+    return newDeterministicReader(material)
+}
+
+/*
+appendWithLength appends the length of data on 8 bytes in big-endian order,
+followed by data.
+*/
+func appendWithLength(dst, data []byte) []byte {
+    var length [8]byte
+    binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+    return append(append(dst, length[:]...), data...)
+}
+
+/*
+newDeterministicReader instantiates the HMAC-DRBG with the seed material, which
+is int2octets(x) || bits2octets(h1) in RFC 6979.
+*/
+func newDeterministicReader(material []byte) *DeterministicReader {
+    r := &DeterministicReader{
+        k: make([]byte, sha256.Size),
+        v: make([]byte, sha256.Size),
+    }
+    for i := range r.v {
+        r.v[i] = 0x01
+    }
+    r.update(material)
+    return r
+}
+
+/*
+Read fills p with the output of the generator and updates its state.
+*/
+func (r *DeterministicReader) Read(p []byte) (int, error) {
+    n := 0
+    for n < len(p) {
+        r.v = r.mac(r.k, r.v)
+        n += copy(p[n:], r.v)
+    }
+    r.update(nil)
+    return n, nil
+}
+
+/*
+update is the HMAC_DRBG_Update function: K = HMAC_K(V || 0x00 || data),
+V = HMAC_K(V) and, if data is not empty, once more with 0x01.
+*/
+func (r *DeterministicReader) update(data []byte) {
+    r.k = r.mac(r.k, r.v, []byte{0x00}, data)
+    r.v = r.mac(r.k, r.v)
+    if len(data) == 0 {
+        return
+    }
+    r.k = r.mac(r.k, r.v, []byte{0x01}, data)
+    r.v = r.mac(r.k, r.v)
+}
+
+func (r *DeterministicReader) mac(key []byte, data ...[]byte) []byte {
+    h := hmac.New(sha256.New, key)
+    for i := range data {
+        h.Write(data[i])
+    }
+    return h.Sum(nil)
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package util
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "math/big"
+    "testing"
+)
+
+/*
+Test vector of RFC 6979, Section A.2.5: P-256 with SHA-256, message "sample".
+*/
+func TestDeterministicReaderRFC6979(t *testing.T) {
+    x, _ := hex.DecodeString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+    h1 := sha256.Sum256([]byte("sample"))
+    expected, _ := hex.DecodeString("A6E3C57DD01ABE90086538398355DD4C3B17AA873382B0F24D6129493D8AAD60")
+
+    k := make([]byte, 32)
+    _, _ = newDeterministicReader(append(x, h1[:]...)).Read(k)
+    if !bytes.Equal(k, expected) {
+        t.Errorf("Assert failure: expected %x, actual: %x", expected, k)
+    }
+}
+
+func TestDeterministicReader(t *testing.T) {
+    seed := []byte("seed")
+    a := make([]byte, 100)
+    b := make([]byte, 100)
+    _, _ = NewDeterministicReader(seed, []byte("data")).Read(a)
+    _, _ = NewDeterministicReader(seed, []byte("data")).Read(b)
+    if !bytes.Equal(a, b) {
+        t.Errorf("Assert failure: same seed and data must give the same output")
+    }
+    _, _ = NewDeterministicReader(seed, []byte("other")).Read(b)
+    if bytes.Equal(a, b) {
+        t.Errorf("Assert failure: different data must give a different output")
+    }
+}
+
+func TestDeterministicReaderFraming(t *testing.T) {
+    a := make([]byte, 32)
+    b := make([]byte, 32)
+    _, _ = NewDeterministicReader([]byte("ab"), []byte("c")).Read(a)
+    _, _ = NewDeterministicReader([]byte("a"), []byte("bc")).Read(b)
+    if bytes.Equal(a, b) {
+        t.Errorf("Assert failure: different splits of the seed and data must give different outputs")
+    }
+    _, _ = NewDeterministicReader([]byte("a"), []byte("b"), []byte("c")).Read(a)
+    _, _ = NewDeterministicReader([]byte("a"), []byte("bc"), []byte{}).Read(b)
+    if bytes.Equal(a, b) {
+        t.Errorf("Assert failure: different splits of the data must give different outputs")
+    }
+}
+
+func TestRandomScalar(t *testing.T) {
+    order := big.NewInt(1000003)
+    r := NewDeterministicReader([]byte("seed"))
+    for i := 0; i < 100; i++ {
+        k, err := RandomScalar(r, order)
+        if err != nil || k.Sign() < 0 || k.Cmp(order) >= 0 {
+            t.Fatalf("Assert failure: %v not in [0, %v), error: %v", k, order, err)
+        }
+    }
+    _, err := RandomScalar(bytes.NewReader([]byte{1, 2, 3}), order)
+    if err == nil {
+        t.Errorf("Assert failure: short source of randomness should be rejected")
+    }
+}
@@ -19,11 +19,12 @@ package util
 
 import (
     "crypto/sha256"
+    "errors"
     "math/big"
 
     "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/group"
     "github.com/ing-bank/zkrp/crypto/p256"
-    "github.com/ing-bank/zkrp/util/bn"
     "github.com/ing-bank/zkrp/util/byteconversion"
 )
 
@@ -36,18 +37,25 @@ var (
 
 /*
 Decompose receives as input a bigint x and outputs an array of integers such that
-x = sum(xi.u^i), i.e. it returns the decomposition of x into base u.
+x = sum(xi.u^i), i.e. it returns the decomposition of x into base u. Only the
+digits are required to fit into an int64, x itself may have any size.
 */
 func Decompose(x *big.Int, u int64, l int64) ([]int64, error) {
     var (
         result []int64
         i      int64
     )
+    if u < 2 {
+        return nil, errors.New("base must be greater than 1")
+    }
     result = make([]int64, l)
+    base := new(big.Int).SetInt64(u)
+    x = new(big.Int).Set(x)
+    digit := new(big.Int)
     i = 0
     for i < l {
-        result[i] = bn.Mod(x, new(big.Int).SetInt64(u)).Int64()
-        x = new(big.Int).Div(x, new(big.Int).SetInt64(u))
+        x.DivMod(x, base, digit)
+        result[i] = digit.Int64()
         i = i + 1
     }
     return result, nil
@@ -65,15 +73,29 @@ func Commit(x, r *big.Int, h *bn256.G2) (*bn256.G2, error) {
 
 /*
 CommitG1 method corresponds to the Pedersen commitment scheme. Namely, given input
-message x, and randomness r, it outputs g^x.h^r.
+message x, and randomness r, it outputs g^x.h^r, in a time that does not depend
+on x and r.
 */
-func CommitG1(x, r *big.Int, h *p256.P256) (*p256.P256, error) {
-    var C = new(p256.P256).ScalarBaseMult(x)
-    Hr := new(p256.P256).ScalarMult(h, r)
+func CommitG1(x, r *big.Int, h *p256.Secp256k1Point) (*p256.Secp256k1Point, error) {
+    var C = new(p256.Secp256k1Point).SecretScalarBaseMult(x)
+    Hr := new(p256.Secp256k1Point).SecretScalarMult(h, r)
     C.Add(C, Hr)
     return C, nil
 }
 
+/*
+CommitG1WithGroup is CommitG1 in the group of h, for instance group.P256 for NIST
+P-256: it outputs g^x.h^r, where g is the generator of the group. It runs in
+constant time in the groups that implement group.ConstantTimeGroup.
+*/
+func CommitG1WithGroup(x, r *big.Int, h group.Element) (group.Element, error) {
+    if x == nil || r == nil || h == nil {
+        return nil, errors.New("message, randomness and generator must be defined")
+    }
+    g := h.Group()
+    return group.SecretMultiScalarMult(g, []group.Element{g.Generator(), h}, []group.Scalar{x, r})
+}
+
 /*
 HashSet is responsible for the computing a Zp element given elements from GT and G2.
 */
@@ -16,3 +16,27 @@
  */
 
 package util
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+func TestCommitG1WithGroup(t *testing.T) {
+    h, _ := p256.HashToNistP256([]byte("h"), []byte("ZKRP-TEST"))
+    x, r := big.NewInt(12), big.NewInt(34)
+    C, err := CommitG1WithGroup(x, r, group.NewNistP256Element(h))
+    if err != nil {
+        t.Fatalf("Commitment failed: %s", err)
+    }
+    expected := new(p256.NistP256Point).Add(new(p256.NistP256Point).ScalarBaseMult(x), new(p256.NistP256Point).ScalarMult(h, r))
+    if !C.Equal(group.NewNistP256Element(expected)) {
+        t.Errorf("Assert failure: expected %s, actual: %s", expected, C)
+    }
+    if !C.(*group.NistP256Element).Point().IsOnCurve() {
+        t.Errorf("Assert failure: commitment is not on P-256")
+    }
+}